@@ -53,6 +53,9 @@ type AutoConfig struct {
 	ThroughputWeight  float64
 	HighCountFraction float64
 	Logger            func(format string, args ...any)
+
+	// Optimizer replaces the heuristic passes with the constraint optimizer when set
+	Optimizer *OptimizerConfig
 }
 
 type AutoResult struct {
@@ -275,6 +278,10 @@ func InitializeClaimEco(cfg AutoConfig) (*AutoResult, error) {
 		return result, err
 	}
 
+	if cfg.Optimizer != nil {
+		return runOptimizerPass(cl, cfg, result)
+	}
+
 	if err := validateClaim(cl); err != nil {
 		return result, err
 	}
@@ -0,0 +1,601 @@
+package auto
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"RueaES/eruntime"
+	"RueaES/typedef"
+)
+
+// Objective selects what the optimizer maximises.
+type Objective string
+
+const (
+	ObjectiveDefence   Objective = "defence"   // Sum of territory defence levels
+	ObjectiveEmeralds  Objective = "emeralds"  // Net emeralds per hour
+	ObjectiveResources Objective = "resources" // Net ores, wood, fish and crops per hour combined
+)
+
+// OptimizerConstraints are the hard requirements a solution must meet to be feasible.
+type OptimizerConstraints struct {
+	NonNegativeNet  bool    // Net per hour of every resource must stay at or above zero
+	MinHQLevel      int     // Minimum HQ defence level, counted like the tower menu (externals included)
+	MaxEmeraldDrain float64 // Cap on emerald upkeep per hour, 0 for no cap
+}
+
+// OptimizerConfig configures a constraint optimizer run. Zero values use the defaults.
+type OptimizerConfig struct {
+	Objective   Objective
+	Constraints OptimizerConstraints
+	Iterations  int
+	Seed        int64
+	ParetoSize  int
+}
+
+// TerritoryAssignment is the upgrade and bonus configuration chosen for one territory.
+type TerritoryAssignment struct {
+	Upgrades typedef.Upgrade `json:"upgrades"`
+	Bonuses  typedef.Bonus   `json:"bonuses"`
+}
+
+// OptimizerSolution is one assignment found by the optimizer together with its metrics.
+type OptimizerSolution struct {
+	Feasible      bool                           `json:"feasible"`
+	DefenceLevels int                            `json:"defenceLevels"`
+	HQLevel       int                            `json:"hqLevel"`
+	NetPerHour    typedef.BasicResources         `json:"netPerHour"`
+	EmeraldDrain  float64                        `json:"emeraldDrain"`
+	Assignments   map[string]TerritoryAssignment `json:"assignments"`
+}
+
+// OptimizerReport holds the best solution for the objective and the Pareto trade-offs
+// between defence, emeralds and resources seen during the search.
+type OptimizerReport struct {
+	GuildTag   string              `json:"guildTag"`
+	Objective  Objective           `json:"objective"`
+	Seed       int64               `json:"seed"`
+	Iterations int                 `json:"iterations"`
+	Best       OptimizerSolution   `json:"best"`
+	Pareto     []OptimizerSolution `json:"pareto"`
+}
+
+const (
+	defaultOptimizerIterations = 20000
+	defaultOptimizerSeed       = 1
+	defaultOptimizerParetoSize = 12
+
+	optimizerPenalty = 10.0
+)
+
+// Decision variables searched per territory
+const (
+	varDamage = iota
+	varAttack
+	varHealth
+	varDefence
+	varAura
+	varVolley
+	varEfficientResource
+	varResourceRate
+	varEfficientEmerald
+	varEmeraldRate
+	varCount
+)
+
+type optimizerLevels [varCount]int
+
+// optimizerTerritory is a territory snapshot with everything the evaluation needs
+type optimizerTerritory struct {
+	name      string
+	hq        bool
+	externals int
+	base      typedef.BasicResources
+	treasury  float64
+	fixedCost typedef.BasicResources // Upkeep of bonuses the optimizer does not touch
+	bonuses   typedef.Bonus
+	start     optimizerLevels
+}
+
+// optimizerEval is the contribution of one territory under a given assignment
+type optimizerEval struct {
+	net   typedef.BasicResources
+	drain float64
+	level int
+}
+
+type optimizer struct {
+	cfg         OptimizerConfig
+	costs       *typedef.Costs
+	territories []optimizerTerritory
+	maxLevels   optimizerLevels
+}
+
+// OptimizeClaimEco searches upgrade and bonus assignments for a guild's claim with simulated annealing.
+// The search starts from the current configuration and is reproducible for a given seed.
+// Nothing is applied; use ApplySolution to set the result.
+func OptimizeClaimEco(guildTag string, territoryNames []string, cfg OptimizerConfig) (*OptimizerReport, error) {
+	if guildTag == "" {
+		return nil, fmt.Errorf("guild tag is required")
+	}
+	switch cfg.Objective {
+	case "":
+		cfg.Objective = ObjectiveDefence
+	case ObjectiveDefence, ObjectiveEmeralds, ObjectiveResources:
+	default:
+		return nil, fmt.Errorf("unknown objective %q", cfg.Objective)
+	}
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = defaultOptimizerIterations
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = defaultOptimizerSeed
+	}
+	if cfg.ParetoSize <= 0 {
+		cfg.ParetoSize = defaultOptimizerParetoSize
+	}
+
+	costs := eruntime.GetCost()
+	if costs == nil || len(costs.Bonuses.EfficientResource.Value) == 0 || len(costs.Bonuses.ResourceRate.Value) == 0 ||
+		len(costs.Bonuses.EfficientEmeralds.Value) == 0 || len(costs.Bonuses.EmeraldsRate.Value) == 0 {
+		return nil, fmt.Errorf("upgrade costs are not loaded")
+	}
+
+	cl, err := buildClaim(guildTag, territoryNames)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := newOptimizer(cl, cfg)
+	report := opt.run()
+	report.GuildTag = guildTag
+	return report, nil
+}
+
+// ApplySolution sets the upgrades and bonuses of a solution, skipping territories that already match.
+func ApplySolution(solution *OptimizerSolution) *AutoResult {
+	result := &AutoResult{}
+	if solution == nil {
+		return result
+	}
+
+	names := make([]string, 0, len(solution.Assignments))
+	for name := range solution.Assignments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := eruntime.GetTerritory(name)
+		if t == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("territory %s no longer exists", name))
+			continue
+		}
+		assignment := solution.Assignments[name]
+
+		t.Mu.RLock()
+		unchanged := t.Options.Upgrade.Set == assignment.Upgrades && t.Options.Bonus.Set == assignment.Bonuses
+		opts := typedef.TerritoryOptions{
+			Upgrades:    assignment.Upgrades,
+			Bonuses:     assignment.Bonuses,
+			Tax:         t.Tax,
+			RoutingMode: t.RoutingMode,
+			Border:      t.Border,
+			HQ:          t.HQ,
+		}
+		t.Mu.RUnlock()
+		if unchanged {
+			continue
+		}
+
+		eruntime.Set(name, opts)
+		result.Actions = append(result.Actions, Action{Territory: name, Kind: "optimizer", Details: fmt.Sprintf(
+			"dmg %d atk %d hp %d def %d aura %d volley %d res %d%d em %d%d",
+			assignment.Upgrades.Damage, assignment.Upgrades.Attack, assignment.Upgrades.Health, assignment.Upgrades.Defence,
+			assignment.Bonuses.TowerAura, assignment.Bonuses.TowerVolley,
+			assignment.Bonuses.EfficientResource, assignment.Bonuses.ResourceRate,
+			assignment.Bonuses.EfficientEmerald, assignment.Bonuses.EmeraldRate)})
+	}
+	return result
+}
+
+// runOptimizerPass runs the constraint optimizer for a claim and applies its best solution.
+// The search starts from the applied configuration, so repeated passes converge instead of oscillating.
+func runOptimizerPass(cl *claim, cfg AutoConfig, result *AutoResult) (*AutoResult, error) {
+	names := make([]string, 0, len(cl.Territories))
+	for _, t := range cl.Territories {
+		names = append(names, t.Name)
+	}
+
+	report, err := OptimizeClaimEco(cl.GuildTag, names, *cfg.Optimizer)
+	if err != nil {
+		return result, err
+	}
+	if !report.Best.Feasible {
+		result.Warnings = append(result.Warnings, "optimizer found no solution meeting every constraint")
+	}
+
+	applied := ApplySolution(&report.Best)
+	result.Actions = append(result.Actions, applied.Actions...)
+	result.Warnings = append(result.Warnings, applied.Warnings...)
+
+	if cfg.Logger != nil {
+		cfg.Logger("Optimizer (%s, seed %d) changed %d territories, %d Pareto solutions",
+			report.Objective, report.Seed, len(applied.Actions), len(report.Pareto))
+	}
+	return result, nil
+}
+
+func newOptimizer(cl *claim, cfg OptimizerConfig) *optimizer {
+	opt := &optimizer{cfg: cfg, costs: eruntime.GetCost()}
+	c := opt.costs
+
+	opt.maxLevels = optimizerLevels{
+		varDamage:            len(c.UpgradesCost.Damage.Value) - 1,
+		varAttack:            len(c.UpgradesCost.Attack.Value) - 1,
+		varHealth:            len(c.UpgradesCost.Health.Value) - 1,
+		varDefence:           len(c.UpgradesCost.Defence.Value) - 1,
+		varAura:              bonusMaxLevel(c.Bonuses.TowerAura),
+		varVolley:            bonusMaxLevel(c.Bonuses.TowerVolley),
+		varEfficientResource: bonusMaxLevel(c.Bonuses.EfficientResource),
+		varResourceRate:      bonusMaxLevel(c.Bonuses.ResourceRate),
+		varEfficientEmerald:  bonusMaxLevel(c.Bonuses.EfficientEmeralds),
+		varEmeraldRate:       bonusMaxLevel(c.Bonuses.EmeraldsRate),
+	}
+
+	for _, t := range cl.Territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		snapshot := optimizerTerritory{
+			name:      t.Name,
+			hq:        t.HQ,
+			externals: len(t.Links.Externals),
+			base:      t.ResourceGeneration.Base,
+			treasury:  1 + t.GenerationBonus/100,
+			bonuses:   t.Options.Bonus.Set,
+		}
+		upgrades := t.Options.Upgrade.Set
+		t.Mu.RUnlock()
+
+		snapshot.start = optimizerLevels{
+			varDamage:            upgrades.Damage,
+			varAttack:            upgrades.Attack,
+			varHealth:            upgrades.Health,
+			varDefence:           upgrades.Defence,
+			varAura:              snapshot.bonuses.TowerAura,
+			varVolley:            snapshot.bonuses.TowerVolley,
+			varEfficientResource: snapshot.bonuses.EfficientResource,
+			varResourceRate:      snapshot.bonuses.ResourceRate,
+			varEfficientEmerald:  snapshot.bonuses.EfficientEmerald,
+			varEmeraldRate:       snapshot.bonuses.EmeraldRate,
+		}
+		for i := range snapshot.start {
+			snapshot.start[i] = max(0, min(snapshot.start[i], opt.maxLevels[i]))
+		}
+
+		// Bonuses outside the search keep their current levels and upkeep
+		all := opt.levelCost(snapshot.start)
+		if stats := eruntime.GetTerritoryStats(t.Name); stats != nil {
+			snapshot.fixedCost = stats.TotalCosts.Sub(&all)
+		}
+
+		opt.territories = append(opt.territories, snapshot)
+	}
+
+	sort.Slice(opt.territories, func(i, j int) bool { return opt.territories[i].name < opt.territories[j].name })
+	return opt
+}
+
+// run anneals from the current configuration and collects the best and Pareto solutions
+func (opt *optimizer) run() *OptimizerReport {
+	rng := rand.New(rand.NewSource(opt.cfg.Seed))
+
+	levels := make([]optimizerLevels, len(opt.territories))
+	evals := make([]optimizerEval, len(opt.territories))
+	for i := range opt.territories {
+		levels[i] = opt.territories[i].start
+		evals[i] = opt.evaluate(i, levels[i])
+	}
+
+	report := &OptimizerReport{Objective: opt.cfg.Objective, Seed: opt.cfg.Seed, Iterations: opt.cfg.Iterations}
+	if len(opt.territories) == 0 {
+		return report
+	}
+
+	current := opt.summarize(evals)
+	currentEnergy := opt.energy(current)
+	best := current
+	bestEnergy := currentEnergy
+	bestLevels := append([]optimizerLevels(nil), levels...)
+
+	var pareto []paretoEntry
+	pareto = opt.addPareto(pareto, current, levels)
+
+	const startTemperature, endTemperature = 1.0, 0.001
+	for iter := 0; iter < opt.cfg.Iterations; iter++ {
+		temperature := startTemperature * math.Pow(endTemperature/startTemperature, float64(iter)/float64(opt.cfg.Iterations))
+
+		ti := rng.Intn(len(opt.territories))
+		vi := rng.Intn(varCount)
+		step := 1
+		if rng.Intn(2) == 0 {
+			step = -1
+		}
+		next := levels[ti]
+		next[vi] += step
+		if next[vi] < 0 || next[vi] > opt.maxLevels[vi] {
+			continue
+		}
+
+		previousLevels, previousEval := levels[ti], evals[ti]
+		levels[ti], evals[ti] = next, opt.evaluate(ti, next)
+		candidate := opt.summarize(evals)
+		candidateEnergy := opt.energy(candidate)
+
+		delta := candidateEnergy - currentEnergy
+		if delta >= 0 || rng.Float64() < math.Exp(delta/temperature) {
+			current, currentEnergy = candidate, candidateEnergy
+			if currentEnergy > bestEnergy {
+				best, bestEnergy = current, currentEnergy
+				copy(bestLevels, levels)
+			}
+			pareto = opt.addPareto(pareto, current, levels)
+		} else {
+			levels[ti], evals[ti] = previousLevels, previousEval
+		}
+	}
+
+	report.Best = opt.solution(best, bestLevels)
+	for _, entry := range pareto {
+		report.Pareto = append(report.Pareto, opt.solution(entry.summary, entry.levels))
+	}
+	sort.Slice(report.Pareto, func(i, j int) bool {
+		return report.Pareto[i].DefenceLevels > report.Pareto[j].DefenceLevels
+	})
+	return report
+}
+
+// optimizerSummary holds the claim-wide metrics of an assignment
+type optimizerSummary struct {
+	net       typedef.BasicResources
+	drain     float64
+	defence   int
+	hqLevel   int
+	violation float64
+}
+
+type paretoEntry struct {
+	summary optimizerSummary
+	levels  []optimizerLevels
+}
+
+func (opt *optimizer) summarize(evals []optimizerEval) optimizerSummary {
+	var s optimizerSummary
+	for i, e := range evals {
+		s.net = s.net.Add(&e.net)
+		s.drain += e.drain
+		s.defence += e.level
+		if opt.territories[i].hq {
+			s.hqLevel = e.level
+		}
+	}
+
+	c := opt.cfg.Constraints
+	if c.NonNegativeNet {
+		for _, v := range []float64{s.net.Emeralds, s.net.Ores, s.net.Wood, s.net.Fish, s.net.Crops} {
+			if v < 0 {
+				s.violation += -v / 1000
+			}
+		}
+	}
+	if c.MinHQLevel > 0 && s.hqLevel < c.MinHQLevel {
+		s.violation += float64(c.MinHQLevel - s.hqLevel)
+	}
+	if c.MaxEmeraldDrain > 0 && s.drain > c.MaxEmeraldDrain {
+		s.violation += (s.drain - c.MaxEmeraldDrain) / 1000
+	}
+	return s
+}
+
+// energy is the annealing score: the objective minus a penalty for violated constraints, higher is better
+func (opt *optimizer) energy(s optimizerSummary) float64 {
+	var objective float64
+	switch opt.cfg.Objective {
+	case ObjectiveEmeralds:
+		objective = s.net.Emeralds / 1000
+	case ObjectiveResources:
+		objective = (s.net.Ores + s.net.Wood + s.net.Fish + s.net.Crops) / 1000
+	default:
+		objective = float64(s.defence) / float64(len(opt.territories))
+	}
+	return objective - optimizerPenalty*s.violation
+}
+
+// addPareto inserts a feasible summary into the archive unless it is dominated,
+// dropping entries it dominates and keeping the archive within ParetoSize.
+func (opt *optimizer) addPareto(archive []paretoEntry, s optimizerSummary, levels []optimizerLevels) []paretoEntry {
+	if s.violation > 0 {
+		return archive
+	}
+
+	for _, entry := range archive {
+		if dominates(entry.summary, s) || paretoEqual(entry.summary, s) {
+			return archive
+		}
+	}
+
+	kept := archive[:0]
+	for _, entry := range archive {
+		if !dominates(s, entry.summary) {
+			kept = append(kept, entry)
+		}
+	}
+	kept = append(kept, paretoEntry{summary: s, levels: append([]optimizerLevels(nil), levels...)})
+
+	if len(kept) > opt.cfg.ParetoSize {
+		// Drop the entry closest to another one so the archive stays spread out
+		drop, closest := 0, math.Inf(1)
+		for i := range kept {
+			for j := range kept {
+				if i != j {
+					if d := paretoDistance(kept[i].summary, kept[j].summary); d < closest {
+						drop, closest = i, d
+					}
+				}
+			}
+		}
+		kept = append(kept[:drop], kept[drop+1:]...)
+	}
+	return kept
+}
+
+func paretoAxes(s optimizerSummary) [3]float64 {
+	return [3]float64{float64(s.defence), s.net.Emeralds, s.net.Ores + s.net.Wood + s.net.Fish + s.net.Crops}
+}
+
+func dominates(a, b optimizerSummary) bool {
+	ax, bx := paretoAxes(a), paretoAxes(b)
+	better := false
+	for i := range ax {
+		if ax[i] < bx[i] {
+			return false
+		}
+		if ax[i] > bx[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+func paretoEqual(a, b optimizerSummary) bool {
+	return paretoAxes(a) == paretoAxes(b)
+}
+
+func paretoDistance(a, b optimizerSummary) float64 {
+	ax, bx := paretoAxes(a), paretoAxes(b)
+	// Defence levels and per-hour amounts differ in scale by roughly a thousand
+	return math.Abs(ax[0]-bx[0]) + math.Abs(ax[1]-bx[1])/1000 + math.Abs(ax[2]-bx[2])/1000
+}
+
+// evaluate computes the net, emerald drain and defence level of territory i under levels
+func (opt *optimizer) evaluate(i int, levels optimizerLevels) optimizerEval {
+	t := &opt.territories[i]
+	b := &opt.costs.Bonuses
+
+	resourceRate := b.ResourceRate.Value[levels[varResourceRate]]
+	emeraldRate := b.EmeraldsRate.Value[levels[varEmeraldRate]]
+	resourceMultiplier := b.EfficientResource.Value[levels[varEfficientResource]] * t.treasury * 4 / math.Max(resourceRate, 1)
+	emeraldMultiplier := b.EfficientEmeralds.Value[levels[varEfficientEmerald]] * t.treasury * 4 / math.Max(emeraldRate, 1)
+
+	generation := typedef.BasicResources{
+		Emeralds: t.base.Emeralds * emeraldMultiplier,
+		Ores:     t.base.Ores * resourceMultiplier,
+		Wood:     t.base.Wood * resourceMultiplier,
+		Fish:     t.base.Fish * resourceMultiplier,
+		Crops:    t.base.Crops * resourceMultiplier,
+	}
+	cost := opt.levelCost(levels)
+	cost = cost.Add(&t.fixedCost)
+
+	level := calcLevelInt(setLevels{
+		Upgrades: typedef.Upgrade{Damage: levels[varDamage], Attack: levels[varAttack], Health: levels[varHealth], Defence: levels[varDefence]},
+		Bonuses:  typedef.Bonus{TowerAura: levels[varAura], TowerVolley: levels[varVolley]},
+	})
+	if t.hq {
+		level += t.externals * 4
+	}
+
+	return optimizerEval{net: generation.Sub(&cost), drain: cost.Emeralds, level: level}
+}
+
+// levelCost returns the hourly upkeep of the searched upgrades and bonuses
+func (opt *optimizer) levelCost(levels optimizerLevels) typedef.BasicResources {
+	c := opt.costs
+	var cost typedef.BasicResources
+
+	addUpgrade := func(table typedef.UpgradeCosts, level int) {
+		if level >= 0 && level < len(table.Value) {
+			addResource(&cost, table.ResourceType, float64(table.Value[level]))
+		}
+	}
+	addBonus := func(table typedef.BonusCosts, level int) {
+		if level >= 0 && level < len(table.Cost) {
+			addResource(&cost, table.ResourceType, float64(table.Cost[level]))
+		}
+	}
+
+	addUpgrade(c.UpgradesCost.Damage, levels[varDamage])
+	addUpgrade(c.UpgradesCost.Attack, levels[varAttack])
+	addUpgrade(c.UpgradesCost.Health, levels[varHealth])
+	addUpgrade(c.UpgradesCost.Defence, levels[varDefence])
+	addBonus(c.Bonuses.TowerAura, levels[varAura])
+	addBonus(c.Bonuses.TowerVolley, levels[varVolley])
+	addBonus(c.Bonuses.EfficientResource, levels[varEfficientResource])
+	addBonus(c.Bonuses.ResourceRate, levels[varResourceRate])
+	addBonus(c.Bonuses.EfficientEmeralds, levels[varEfficientEmerald])
+	addBonus(c.Bonuses.EmeraldsRate, levels[varEmeraldRate])
+
+	return cost
+}
+
+// solution converts an internal summary and its levels into an exported solution
+func (opt *optimizer) solution(s optimizerSummary, levels []optimizerLevels) OptimizerSolution {
+	solution := OptimizerSolution{
+		Feasible:      s.violation == 0,
+		DefenceLevels: s.defence,
+		HQLevel:       s.hqLevel,
+		NetPerHour:    s.net,
+		EmeraldDrain:  s.drain,
+		Assignments:   make(map[string]TerritoryAssignment, len(levels)),
+	}
+	for i, l := range levels {
+		solution.Assignments[opt.territories[i].name] = opt.assignment(i, l)
+	}
+	return solution
+}
+
+// assignment converts the levels of territory i into upgrades and bonuses, keeping the bonuses outside the search
+func (opt *optimizer) assignment(i int, l optimizerLevels) TerritoryAssignment {
+	bonuses := opt.territories[i].bonuses
+	bonuses.TowerAura = l[varAura]
+	bonuses.TowerVolley = l[varVolley]
+	bonuses.EfficientResource = l[varEfficientResource]
+	bonuses.ResourceRate = l[varResourceRate]
+	bonuses.EfficientEmerald = l[varEfficientEmerald]
+	bonuses.EmeraldRate = l[varEmeraldRate]
+
+	return TerritoryAssignment{
+		Upgrades: typedef.Upgrade{Damage: l[varDamage], Attack: l[varAttack], Health: l[varHealth], Defence: l[varDefence]},
+		Bonuses:  bonuses,
+	}
+}
+
+func bonusMaxLevel(table typedef.BonusCosts) int {
+	level := len(table.Cost) - 1
+	if len(table.Value) > 0 {
+		level = min(level, len(table.Value)-1)
+	}
+	if table.MaxLevel > 0 {
+		level = min(level, table.MaxLevel)
+	}
+	return max(0, level)
+}
+
+func addResource(res *typedef.BasicResources, resource string, amount float64) {
+	switch resource {
+	case "emeralds", "emerald":
+		res.Emeralds += amount
+	case "ore", "ores":
+		res.Ores += amount
+	case "wood":
+		res.Wood += amount
+	case "fish":
+		res.Fish += amount
+	case "crops", "crop":
+		res.Crops += amount
+	}
+}
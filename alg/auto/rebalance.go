@@ -0,0 +1,434 @@
+package auto
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"RueaES/eruntime"
+	"RueaES/typedef"
+)
+
+// Suggestion kinds produced by the rebalancing advisor
+const (
+	SuggestProduction = "production" // Resource or emerald bonus change on a producer
+	SuggestDefence    = "defence"    // Defence upgrade or tower bonus downgrade
+	SuggestStorage    = "storage"    // Storage bump so boosted production is not wasted
+	SuggestTribute    = "tribute"    // Recurring tribute from an ally
+)
+
+const defaultRebalanceSteps = 40
+
+// RebalanceConfig configures the rebalancing advisor. Zero values use the defaults.
+type RebalanceConfig struct {
+	Target         typedef.BasicResources `json:"target"`         // Surplus per hour wanted on every resource
+	MaxSteps       int                    `json:"maxSteps"`       // Upper bound on bonus and upgrade changes
+	NoDowngrades   bool                   `json:"noDowngrades"`   // Never suggest defence downgrades
+	NoTributes     bool                   `json:"noTributes"`     // Never suggest tributes from allies
+	TerritoryNames []string               `json:"territoryNames"` // Restrict the advisor to these territories, the whole claim when empty
+}
+
+// RebalanceSuggestion is one change proposed by the advisor with its projected effect.
+// NetAfter is the guild net once this and every earlier suggestion are applied.
+type RebalanceSuggestion struct {
+	Kind      string                 `json:"kind"`
+	Territory string                 `json:"territory,omitempty"`
+	Change    string                 `json:"change"`
+	FromGuild string                 `json:"fromGuild,omitempty"` // Ally tag for tributes
+	Amount    typedef.BasicResources `json:"amount"`              // Tribute per hour
+	Effect    typedef.BasicResources `json:"effect"`              // Change to the guild net per hour
+	NetAfter  typedef.BasicResources `json:"netAfter"`
+}
+
+// RebalanceReport is the advisor's plan for a guild.
+type RebalanceReport struct {
+	GuildTag          string                         `json:"guildTag"`
+	Target            typedef.BasicResources         `json:"target"`
+	Current           typedef.BasicResources         `json:"current"`   // Net per hour today, tributes included
+	Projected         typedef.BasicResources         `json:"projected"` // Net per hour with every suggestion applied
+	Reached           bool                           `json:"reached"`
+	DefenceDowngrades int                            `json:"defenceDowngrades"`
+	Suggestions       []RebalanceSuggestion          `json:"suggestions"`
+	Assignments       map[string]TerritoryAssignment `json:"assignments"` // Final configuration of every changed territory
+}
+
+// RebalanceUndo holds what ApplyRebalance changed so UndoRebalance can restore it.
+type RebalanceUndo struct {
+	GuildTag string                              `json:"guildTag"`
+	Options  map[string]typedef.TerritoryOptions `json:"options"`
+	Tributes []string                            `json:"tributes"`
+}
+
+// Variables the advisor may move, by tier
+var (
+	rebalanceProductionVars = []int{varEfficientResource, varResourceRate, varEfficientEmerald, varEmeraldRate}
+	rebalanceDefenceVars    = []int{varDamage, varAttack, varHealth, varDefence, varAura, varVolley}
+)
+
+var rebalanceVarNames = [varCount]string{
+	varDamage:            "damage",
+	varAttack:            "attack",
+	varHealth:            "health",
+	varDefence:           "defence",
+	varAura:              "tower aura",
+	varVolley:            "tower volley",
+	varEfficientResource: "efficient resource",
+	varResourceRate:      "resource rate",
+	varEfficientEmerald:  "efficient emerald",
+	varEmeraldRate:       "emerald rate",
+}
+
+// AdviseRebalance proposes a small set of changes that brings every resource of a guild's net to the
+// target surplus. Production bonus changes are tried first; defence is only downgraded when no bonus
+// change helps, so the plan keeps as much defence as it can. Producers whose output grows get a storage
+// bump when their storage would overflow, and any deficit left is covered by tributes from allies.
+// Nothing is applied; use ApplyRebalance to set the plan.
+func AdviseRebalance(guildTag string, cfg RebalanceConfig) (*RebalanceReport, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	if guildTag == "" {
+		return nil, fmt.Errorf("guild tag is required")
+	}
+	if cfg.MaxSteps <= 0 {
+		cfg.MaxSteps = defaultRebalanceSteps
+	}
+
+	costs := eruntime.GetCost()
+	if costs == nil || len(costs.Bonuses.EfficientResource.Value) == 0 || len(costs.Bonuses.ResourceRate.Value) == 0 ||
+		len(costs.Bonuses.EfficientEmeralds.Value) == 0 || len(costs.Bonuses.EmeraldsRate.Value) == 0 {
+		return nil, fmt.Errorf("upgrade costs are not loaded")
+	}
+
+	cl, err := buildClaim(guildTag, cfg.TerritoryNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// The optimizer's territory model gives the net of any bonus and upgrade assignment
+	opt := newOptimizer(cl, OptimizerConfig{})
+	levels := make([]optimizerLevels, len(opt.territories))
+	evals := make([]optimizerEval, len(opt.territories))
+	report := &RebalanceReport{GuildTag: guildTag, Target: cfg.Target, Assignments: make(map[string]TerritoryAssignment)}
+	for i := range opt.territories {
+		levels[i] = opt.territories[i].start
+		evals[i] = opt.evaluate(i, levels[i])
+		report.Current = report.Current.Add(&evals[i].net)
+	}
+	if guild := guildByTag(guildTag); guild != nil {
+		tributes := tributeNetPerHour(guild)
+		report.Current = report.Current.Add(&tributes)
+	}
+
+	net := report.Current
+	for step := 0; step < cfg.MaxSteps && rebalanceDeficit(net, cfg.Target) > 0; step++ {
+		move, ok := bestRebalanceMove(opt, levels, evals, net, cfg.Target, rebalanceProductionVars, []int{1, -1})
+		kind := SuggestProduction
+		if !ok && !cfg.NoDowngrades {
+			move, ok = bestRebalanceMove(opt, levels, evals, net, cfg.Target, rebalanceDefenceVars, []int{-1})
+			kind = SuggestDefence
+		}
+		if !ok {
+			break
+		}
+
+		t := &opt.territories[move.territory]
+		from := levels[move.territory][move.variable]
+		levels[move.territory][move.variable] += move.delta
+		effect := move.eval.net.Sub(&evals[move.territory].net)
+		evals[move.territory] = move.eval
+		net = net.Add(&effect)
+		if kind == SuggestDefence {
+			report.DefenceDowngrades++
+		}
+		report.Suggestions = append(report.Suggestions, RebalanceSuggestion{
+			Kind:      kind,
+			Territory: t.name,
+			Change:    fmt.Sprintf("%s %d -> %d", rebalanceVarNames[move.variable], from, from+move.delta),
+			Effect:    effect,
+			NetAfter:  net,
+		})
+	}
+
+	for i := range opt.territories {
+		if levels[i] == opt.territories[i].start {
+			continue
+		}
+		assignment := opt.assignment(i, levels[i])
+		net = rebalanceStorage(opt, i, levels[i], &assignment, net, report)
+		report.Assignments[opt.territories[i].name] = assignment
+	}
+
+	if !cfg.NoTributes && rebalanceDeficit(net, cfg.Target) > 0 {
+		net = rebalanceTributes(guildTag, net, cfg.Target, report)
+	}
+
+	report.Projected = net
+	report.Reached = rebalanceDeficit(net, cfg.Target) == 0
+	return report, nil
+}
+
+// ApplyRebalance sets every territory assignment and creates every tribute of a plan.
+// The returned undo restores the previous configuration.
+func ApplyRebalance(report *RebalanceReport) (*RebalanceUndo, *AutoResult) {
+	result := &AutoResult{}
+	if report == nil {
+		return nil, result
+	}
+	undo := &RebalanceUndo{GuildTag: report.GuildTag, Options: make(map[string]typedef.TerritoryOptions)}
+
+	names := make([]string, 0, len(report.Assignments))
+	for name := range report.Assignments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := eruntime.GetTerritory(name)
+		if t == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("territory %s no longer exists", name))
+			continue
+		}
+		assignment := report.Assignments[name]
+
+		t.Mu.RLock()
+		previous := typedef.TerritoryOptions{
+			Upgrades:    t.Options.Upgrade.Set,
+			Bonuses:     t.Options.Bonus.Set,
+			Tax:         t.Tax,
+			RoutingMode: t.RoutingMode,
+			Border:      t.Border,
+			HQ:          t.HQ,
+		}
+		t.Mu.RUnlock()
+
+		opts := previous
+		opts.Upgrades = assignment.Upgrades
+		opts.Bonuses = assignment.Bonuses
+		eruntime.Set(name, opts)
+		undo.Options[name] = previous
+		result.Actions = append(result.Actions, Action{Territory: name, Kind: "rebalance", Details: "bonuses and upgrades updated"})
+	}
+
+	to := guildNameByTag(report.GuildTag)
+	for _, suggestion := range report.Suggestions {
+		if suggestion.Kind != SuggestTribute {
+			continue
+		}
+		id, err := eruntime.CreateScheduledTribute(guildNameByTag(suggestion.FromGuild), to, suggestion.Amount, 1, typedef.TributeSchedule{})
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("tribute from %s: %v", suggestion.FromGuild, err))
+			continue
+		}
+		undo.Tributes = append(undo.Tributes, id)
+		result.Actions = append(result.Actions, Action{Kind: "tribute", Details: suggestion.Change})
+	}
+	return undo, result
+}
+
+// UndoRebalance restores the territories and removes the tributes changed by ApplyRebalance.
+func UndoRebalance(undo *RebalanceUndo) *AutoResult {
+	result := &AutoResult{}
+	if undo == nil {
+		return result
+	}
+
+	names := make([]string, 0, len(undo.Options))
+	for name := range undo.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if eruntime.Set(name, undo.Options[name]) == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("could not restore %s", name))
+			continue
+		}
+		result.Actions = append(result.Actions, Action{Territory: name, Kind: "rebalance_undo", Details: "previous configuration restored"})
+	}
+	for _, id := range undo.Tributes {
+		if err := eruntime.DeleteTribute(id); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("tribute %s: %v", id, err))
+		}
+	}
+	return result
+}
+
+// rebalanceMove is a single level change and the territory evaluation it leads to
+type rebalanceMove struct {
+	territory int
+	variable  int
+	delta     int
+	eval      optimizerEval
+}
+
+// bestRebalanceMove finds the level change among vars and deltas that shrinks the deficit the most
+func bestRebalanceMove(opt *optimizer, levels []optimizerLevels, evals []optimizerEval, net, target typedef.BasicResources, vars, deltas []int) (rebalanceMove, bool) {
+	current := rebalanceDeficit(net, target)
+	best := rebalanceMove{}
+	bestGain := 1e-9
+	found := false
+
+	for i := range opt.territories {
+		for _, v := range vars {
+			for _, delta := range deltas {
+				next := levels[i]
+				next[v] += delta
+				if next[v] < 0 || next[v] > opt.maxLevels[v] {
+					continue
+				}
+				eval := opt.evaluate(i, next)
+				after := net.Sub(&evals[i].net)
+				after = after.Add(&eval.net)
+				if gain := current - rebalanceDeficit(after, target); gain > bestGain {
+					best = rebalanceMove{territory: i, variable: v, delta: delta, eval: eval}
+					bestGain = gain
+					found = true
+				}
+			}
+		}
+	}
+	return best, found
+}
+
+// rebalanceDeficit sums how far each resource falls short of the target
+func rebalanceDeficit(net, target typedef.BasicResources) float64 {
+	return math.Max(0, target.Emeralds-net.Emeralds) + math.Max(0, target.Ores-net.Ores) +
+		math.Max(0, target.Wood-net.Wood) + math.Max(0, target.Fish-net.Fish) + math.Max(0, target.Crops-net.Crops)
+}
+
+// rebalanceStorage bumps a changed territory's storage when its new output would overflow it,
+// using the same sizing as the auto setup, and returns the net after the storage upkeep
+func rebalanceStorage(opt *optimizer, i int, levels optimizerLevels, assignment *TerritoryAssignment, net typedef.BasicResources, report *RebalanceReport) typedef.BasicResources {
+	t := &opt.territories[i]
+	b := &opt.costs.Bonuses
+	generation := opt.evaluate(i, levels).net
+	cost := opt.levelCost(levels)
+	generation = generation.Add(&cost)
+	generation = generation.Add(&t.fixedCost)
+
+	bump := func(table typedef.BonusCosts, capacities []float64, perSecond, interval float64, level *int, name string) {
+		need := perSecond * interval
+		if need <= 0 {
+			return
+		}
+		needed := min(storageLevelFor(capacities, need), bonusMaxLevel(table))
+		if needed <= *level {
+			return
+		}
+		var effect typedef.BasicResources
+		if *level < len(table.Cost) && needed < len(table.Cost) {
+			addResource(&effect, table.ResourceType, float64(table.Cost[*level]-table.Cost[needed]))
+		}
+		net = net.Add(&effect)
+		report.Suggestions = append(report.Suggestions, RebalanceSuggestion{
+			Kind:      SuggestStorage,
+			Territory: t.name,
+			Change:    fmt.Sprintf("%s %d -> %d", name, *level, needed),
+			Effect:    effect,
+			NetAfter:  net,
+		})
+		*level = needed
+	}
+
+	resourcePerSecond := math.Max(math.Max(generation.Ores, generation.Wood), math.Max(generation.Fish, generation.Crops)) / 3600
+	bump(b.LargerResourceStorage, resourcesCapacityValues(b.LargerResourceStorage.Value), resourcePerSecond,
+		b.ResourceRate.Value[levels[varResourceRate]], &assignment.Bonuses.LargerResourceStorage, "resource storage")
+	bump(b.LargerEmeraldsStorage, emeraldCapacityValues(b.LargerEmeraldsStorage.Value), generation.Emeralds/3600,
+		b.EmeraldsRate.Value[levels[varEmeraldRate]], &assignment.Bonuses.LargerEmeraldStorage, "emerald storage")
+	return net
+}
+
+// tributeNetPerHour returns the tributes a guild receives minus those it sends, converted from the
+// per-minute guild totals to per hour
+func tributeNetPerHour(guild *typedef.Guild) typedef.BasicResources {
+	net := guild.TributeIn.Sub(&guild.TributeOut)
+	return net.MulFloat(60)
+}
+
+// rebalanceTributes covers the remaining deficit with tributes from the allies with the largest surpluses
+func rebalanceTributes(guildTag string, net, target typedef.BasicResources, report *RebalanceReport) typedef.BasicResources {
+	guild := guildByTag(guildTag)
+	if guild == nil {
+		return net
+	}
+
+	type allySurplus struct {
+		tag     string
+		surplus typedef.BasicResources
+	}
+	var allies []allySurplus
+	for _, tag := range guild.AllyTags {
+		cl, err := buildClaim(tag, nil)
+		if err != nil {
+			continue
+		}
+		surplus := claimNet(cl.Territories)
+		if ally := guildByTag(tag); ally != nil {
+			tributes := tributeNetPerHour(ally)
+			surplus = surplus.Add(&tributes)
+		}
+		allies = append(allies, allySurplus{tag: tag, surplus: surplus})
+	}
+	sort.Slice(allies, func(i, j int) bool { return allies[i].tag < allies[j].tag })
+
+	shortfall := func(res typedef.BasicResources) typedef.BasicResources {
+		return typedef.BasicResources{
+			Emeralds: math.Max(0, target.Emeralds-res.Emeralds),
+			Ores:     math.Max(0, target.Ores-res.Ores),
+			Wood:     math.Max(0, target.Wood-res.Wood),
+			Fish:     math.Max(0, target.Fish-res.Fish),
+			Crops:    math.Max(0, target.Crops-res.Crops),
+		}
+	}
+
+	for rebalanceDeficit(net, target) > 0 && len(allies) > 0 {
+		needed := shortfall(net)
+		best, bestCover := -1, 0.0
+		var bestAmount typedef.BasicResources
+		for i, ally := range allies {
+			amount := typedef.BasicResources{
+				Emeralds: math.Min(needed.Emeralds, math.Max(0, ally.surplus.Emeralds)),
+				Ores:     math.Min(needed.Ores, math.Max(0, ally.surplus.Ores)),
+				Wood:     math.Min(needed.Wood, math.Max(0, ally.surplus.Wood)),
+				Fish:     math.Min(needed.Fish, math.Max(0, ally.surplus.Fish)),
+				Crops:    math.Min(needed.Crops, math.Max(0, ally.surplus.Crops)),
+			}
+			cover := amount.Emeralds + amount.Ores + amount.Wood + amount.Fish + amount.Crops
+			if cover > bestCover {
+				best, bestCover, bestAmount = i, cover, amount
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		net = net.Add(&bestAmount)
+		report.Suggestions = append(report.Suggestions, RebalanceSuggestion{
+			Kind:      SuggestTribute,
+			Change:    fmt.Sprintf("tribute from %s", allies[best].tag),
+			FromGuild: allies[best].tag,
+			Amount:    bestAmount,
+			Effect:    bestAmount,
+			NetAfter:  net,
+		})
+		allies = append(allies[:best], allies[best+1:]...)
+	}
+	return net
+}
+
+// guildByTag finds a guild in the runtime by tag
+func guildByTag(tag string) *typedef.Guild {
+	for _, guild := range eruntime.GetGuildsInternal() {
+		if guild != nil && guild.Tag == tag {
+			return guild
+		}
+	}
+	return nil
+}
+
+// guildNameByTag returns the name tributes use for a guild tag
+func guildNameByTag(tag string) string {
+	if guild := guildByTag(tag); guild != nil {
+		return guild.Name
+	}
+	return tag
+}
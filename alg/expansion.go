@@ -0,0 +1,297 @@
+package alg
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"RueaES/typedef"
+)
+
+var ErrExpansionTooSmall = errors.New("target claim size must be larger than the current claim")
+
+const (
+	defaultExpansionPlans = 5
+	defaultExpansionBeam  = 40
+
+	// Score weights. Generation is measured in typical territories (3600 per hour of a weighted resource).
+	expansionGenerationScale = 3600.0
+	expansionRobustWeight    = 0.5
+	expansionExposureWeight  = 0.1
+)
+
+// ExpansionConfig describes what the planner should look for.
+type ExpansionConfig struct {
+	TargetSize int `json:"targetSize"` // Claim size after the expansion
+
+	// Goals weights each resource's base generation, e.g. {Crops: 1} when only crops are needed.
+	// All zero weights every resource equally.
+	Goals typedef.BasicResources `json:"goals"`
+
+	MaxPlans  int `json:"maxPlans"`  // Number of plans to return, 0 for the default
+	BeamWidth int `json:"beamWidth"` // Partial plans kept at each step, 0 for the default
+}
+
+// ExpansionPlan is a contiguous set of territories to add to a claim, in the order to take them.
+type ExpansionPlan struct {
+	Territories     []string               `json:"territories"`
+	AddedGeneration typedef.BasicResources `json:"addedGeneration"` // Base generation per hour of the added territories
+	HQConnections   int                    `json:"hqConnections"`   // Added territories directly linked to the HQ
+	HQExternals     int                    `json:"hqExternals"`     // Added territories counting as HQ externals
+	ForeignBorders  int                    `json:"foreignBorders"`  // Other guilds' territories bordering the added ones
+	Occupied        int                    `json:"occupied"`        // Added territories that another guild holds today
+	Robustness      float64                `json:"robustness"`      // Mean share of added territories with several owned neighbours, 0..1
+	Score           float64                `json:"score"`
+}
+
+// expansionState is a partial plan during the beam search
+type expansionState struct {
+	added []string
+	set   map[string]struct{}
+	score float64
+}
+
+// ComputeExpansionPlans searches contiguous expansions of a guild's claim over the trading route graph.
+// Plans are scored by the goal-weighted base generation they add, the HQ connection and external bonuses
+// they bring, how well connected the new territories are to the rest of the claim, and how many foreign
+// territories they border. Allied territories are never planned.
+func ComputeExpansionPlans(guildTag string, territories map[string]*typedef.Territory, routes map[string][]string, allies []string, cfg ExpansionConfig) ([]ExpansionPlan, error) {
+	tag := strings.TrimSpace(guildTag)
+	if tag == "" {
+		return nil, ErrGuildTagEmpty
+	}
+	if cfg.MaxPlans <= 0 {
+		cfg.MaxPlans = defaultExpansionPlans
+	}
+	if cfg.BeamWidth <= 0 {
+		cfg.BeamWidth = defaultExpansionBeam
+	}
+	if cfg.Goals == (typedef.BasicResources{}) {
+		cfg.Goals = typedef.BasicResources{Emeralds: 1, Ores: 1, Wood: 1, Fish: 1, Crops: 1}
+	}
+
+	planner := newExpansionPlanner(tag, territories, routes, allies, cfg)
+	if len(planner.owned) == 0 {
+		return nil, ErrNoTerritoriesForGuild
+	}
+	steps := cfg.TargetSize - len(planner.owned)
+	if steps <= 0 {
+		return nil, ErrExpansionTooSmall
+	}
+
+	beam := []expansionState{{set: map[string]struct{}{}}}
+	for step := 0; step < steps; step++ {
+		seen := make(map[string]struct{})
+		var next []expansionState
+		for _, state := range beam {
+			for _, candidate := range planner.frontier(state) {
+				added := append(append([]string{}, state.added...), candidate)
+				key := planKey(added)
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+
+				set := make(map[string]struct{}, len(added))
+				for _, name := range added {
+					set[name] = struct{}{}
+				}
+				grown := expansionState{added: added, set: set}
+				grown.score = planner.evaluate(grown).Score
+				next = append(next, grown)
+			}
+		}
+		if len(next) == 0 {
+			break // Nothing left to take
+		}
+
+		sort.Slice(next, func(i, j int) bool {
+			if next[i].score == next[j].score {
+				return planKey(next[i].added) < planKey(next[j].added)
+			}
+			return next[i].score > next[j].score
+		})
+		if len(next) > cfg.BeamWidth {
+			next = next[:cfg.BeamWidth]
+		}
+		beam = next
+	}
+
+	plans := make([]ExpansionPlan, 0, min(len(beam), cfg.MaxPlans))
+	for _, state := range beam {
+		if len(state.added) == 0 || len(plans) == cfg.MaxPlans {
+			break
+		}
+		plans = append(plans, planner.evaluate(state))
+	}
+	return plans, nil
+}
+
+// expansionPlanner holds the claim snapshot the search runs against
+type expansionPlanner struct {
+	cfg       ExpansionConfig
+	routes    map[string][]string
+	owned     map[string]struct{}
+	blocked   map[string]struct{} // Allied territories
+	foreign   map[string]struct{} // Territories held by other, non-allied guilds
+	hqDirect  map[string]struct{}
+	hqExtern  map[string]struct{}
+	base      map[string]typedef.BasicResources
+	ownedEdge []string // Sorted neighbours of the current claim that can be taken
+}
+
+func newExpansionPlanner(tag string, territories map[string]*typedef.Territory, routes map[string][]string, allies []string, cfg ExpansionConfig) *expansionPlanner {
+	allySet := make(map[string]struct{}, len(allies))
+	for _, ally := range allies {
+		allySet[ally] = struct{}{}
+	}
+
+	p := &expansionPlanner{
+		cfg:      cfg,
+		routes:   routes,
+		owned:    make(map[string]struct{}),
+		blocked:  make(map[string]struct{}),
+		foreign:  make(map[string]struct{}),
+		hqDirect: make(map[string]struct{}),
+		hqExtern: make(map[string]struct{}),
+		base:     make(map[string]typedef.BasicResources, len(territories)),
+	}
+
+	for name, t := range territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		owner := t.Guild.Tag
+		p.base[name] = t.ResourceGeneration.Base
+		if owner == tag && t.HQ {
+			for link := range t.Links.Direct {
+				p.hqDirect[link] = struct{}{}
+			}
+			for link := range t.Links.Externals {
+				p.hqExtern[link] = struct{}{}
+			}
+		}
+		t.Mu.RUnlock()
+
+		switch _, allied := allySet[owner]; {
+		case owner == tag:
+			p.owned[name] = struct{}{}
+		case allied:
+			p.blocked[name] = struct{}{}
+		case owner != "" && owner != "NONE":
+			p.foreign[name] = struct{}{}
+		}
+	}
+
+	edge := make(map[string]struct{})
+	for name := range p.owned {
+		for _, neighbour := range routes[name] {
+			if p.takeable(neighbour, nil) {
+				edge[neighbour] = struct{}{}
+			}
+		}
+	}
+	for name := range edge {
+		p.ownedEdge = append(p.ownedEdge, name)
+	}
+	sort.Strings(p.ownedEdge)
+	return p
+}
+
+// takeable reports whether a territory can be added to a plan that already adds the given set
+func (p *expansionPlanner) takeable(name string, added map[string]struct{}) bool {
+	if _, ok := p.base[name]; !ok {
+		return false
+	}
+	if _, ok := p.owned[name]; ok {
+		return false
+	}
+	if _, ok := p.blocked[name]; ok {
+		return false
+	}
+	_, ok := added[name]
+	return !ok
+}
+
+// frontier lists the territories adjacent to the claim plus a partial plan, sorted by name
+func (p *expansionPlanner) frontier(state expansionState) []string {
+	seen := make(map[string]struct{})
+	var names []string
+	for _, name := range p.ownedEdge {
+		if p.takeable(name, state.set) {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	for _, added := range state.added {
+		for _, neighbour := range p.routes[added] {
+			if _, ok := seen[neighbour]; ok || !p.takeable(neighbour, state.set) {
+				continue
+			}
+			seen[neighbour] = struct{}{}
+			names = append(names, neighbour)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// evaluate computes the metrics and score of a plan
+func (p *expansionPlanner) evaluate(state expansionState) ExpansionPlan {
+	plan := ExpansionPlan{Territories: state.added}
+	borders := make(map[string]struct{})
+	robust := 0.0
+
+	for _, name := range state.added {
+		base := p.base[name]
+		plan.AddedGeneration = plan.AddedGeneration.Add(&base)
+		if _, ok := p.hqDirect[name]; ok {
+			plan.HQConnections++
+		}
+		if _, ok := p.hqExtern[name]; ok {
+			plan.HQExternals++
+		}
+		if _, ok := p.foreign[name]; ok {
+			plan.Occupied++
+		}
+
+		ownedNeighbours := 0
+		for _, neighbour := range p.routes[name] {
+			_, owned := p.owned[neighbour]
+			_, planned := state.set[neighbour]
+			if owned || planned {
+				ownedNeighbours++
+				continue
+			}
+			if _, ok := p.foreign[neighbour]; ok {
+				borders[neighbour] = struct{}{}
+			}
+		}
+		// A territory reachable through a single neighbour is a chokepoint waiting to happen
+		robust += float64(min(ownedNeighbours, 3)) / 3
+	}
+
+	plan.ForeignBorders = len(borders)
+	if len(state.added) > 0 {
+		plan.Robustness = robust / float64(len(state.added))
+	}
+
+	goals := p.cfg.Goals
+	gen := plan.AddedGeneration
+	weighted := gen.Emeralds*goals.Emeralds + gen.Ores*goals.Ores + gen.Wood*goals.Wood + gen.Fish*goals.Fish + gen.Crops*goals.Crops
+
+	// HQ bonuses follow the tower multiplier: 30% per connection, 25% per external
+	plan.Score = weighted/expansionGenerationScale +
+		0.3*float64(plan.HQConnections) + 0.25*float64(plan.HQExternals) +
+		expansionRobustWeight*robust -
+		expansionExposureWeight*float64(plan.ForeignBorders)
+	return plan
+}
+
+// planKey identifies a plan by its territories regardless of order
+func planKey(names []string) string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\x00")
+}
@@ -0,0 +1,242 @@
+package alg
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"RueaES/typedef"
+)
+
+var ErrSameGuild = errors.New("rival guild must differ from the analysed guild")
+
+// RivalChokeConfig selects the two sides of a cross-guild chokepoint analysis.
+type RivalChokeConfig struct {
+	GuildTag string `json:"guildTag"`
+	RivalTag string `json:"rivalTag"`
+
+	// Alliance treats each side's allied claims as one network
+	Alliance      bool     `json:"alliance"`
+	GuildAllies   []string `json:"guildAllies,omitempty"`
+	RivalAllies   []string `json:"rivalAllies,omitempty"`
+	EmeraldWeight float64  `json:"emeraldWeight"`
+}
+
+// RivalChokeReport describes how exposed a rival's trade network is to us.
+type RivalChokeReport struct {
+	GuildTag        string   `json:"guildTag"`
+	RivalTag        string   `json:"rivalTag"`
+	Alliance        bool     `json:"alliance"`
+	GuildNetwork    []string `json:"guildNetwork"` // Guild tags counted on our side
+	RivalNetwork    []string `json:"rivalNetwork"` // Guild tags counted on the rival side
+	RivalProduction float64  `json:"rivalProduction"`
+
+	OnRivalRoutes    []RouteChoke      `json:"onRivalRoutes"`    // Our territories the rival routes through, busiest first
+	RivalChokepoints []DisconnectChoke `json:"rivalChokepoints"` // Rival territories that cut off the most production when taken
+}
+
+// RouteChoke is one of our territories that carries rival trade.
+type RouteChoke struct {
+	Territory  string  `json:"territory"`
+	Owner      string  `json:"owner"`
+	Sources    int     `json:"sources"`    // Rival territories routing through it
+	Production float64 `json:"production"` // Weighted production of those territories
+	Share      float64 `json:"share"`      // Fraction of the rival's production, 0..1
+}
+
+// DisconnectChoke is a rival territory whose capture leaves others without a route to their HQ.
+type DisconnectChoke struct {
+	Territory      string   `json:"territory"`
+	Owner          string   `json:"owner"`
+	Disconnected   []string `json:"disconnected"`
+	LostProduction float64  `json:"lostProduction"` // Weighted production of the disconnected territories
+	Share          float64  `json:"share"`
+}
+
+// ComputeRivalChokepoints analyses a rival's trade network from our side. routesTaken maps each territory
+// to the route its resources currently take to its HQ, first hop being the territory itself.
+// Disconnection follows ComputeChokepoints and only considers the rival network's own territories,
+// so a territory counts as cut off once its claim no longer links it to its guild's HQ.
+func ComputeRivalChokepoints(territories map[string]*typedef.Territory, routes map[string][]string, routesTaken map[string][]string, cfg RivalChokeConfig) (*RivalChokeReport, error) {
+	guildTag := strings.TrimSpace(cfg.GuildTag)
+	rivalTag := strings.TrimSpace(cfg.RivalTag)
+	if guildTag == "" || rivalTag == "" {
+		return nil, ErrGuildTagEmpty
+	}
+	if guildTag == rivalTag {
+		return nil, ErrSameGuild
+	}
+	if cfg.EmeraldWeight <= 0 {
+		cfg.EmeraldWeight = 1
+	}
+
+	ours := map[string]struct{}{guildTag: {}}
+	theirs := map[string]struct{}{rivalTag: {}}
+	if cfg.Alliance {
+		for _, tag := range cfg.GuildAllies {
+			ours[tag] = struct{}{}
+		}
+		for _, tag := range cfg.RivalAllies {
+			if _, shared := ours[tag]; !shared {
+				theirs[tag] = struct{}{}
+			}
+		}
+	}
+
+	report := &RivalChokeReport{
+		GuildTag:     guildTag,
+		RivalTag:     rivalTag,
+		Alliance:     cfg.Alliance,
+		GuildNetwork: sortedTags(ours),
+		RivalNetwork: sortedTags(theirs),
+	}
+
+	// Rival network nodes, their owners and HQs
+	owners := make(map[string]string)
+	hqs := make(map[string]string)
+	values := make(map[string]float64)
+	for name, t := range territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		owner, hq := t.Guild.Tag, t.HQ
+		t.Mu.RUnlock()
+		if _, ok := theirs[owner]; !ok {
+			continue
+		}
+		owners[name] = owner
+		if hq {
+			hqs[owner] = name
+			continue
+		}
+		values[name] = territoryProductionValue(t, cfg.EmeraldWeight)
+		report.RivalProduction += values[name]
+	}
+	if len(hqs) == 0 {
+		return nil, ErrNoHQForGuild
+	}
+
+	report.OnRivalRoutes = rivalRouteChokes(territories, routesTaken, ours, values, report.RivalProduction)
+	report.RivalChokepoints = rivalDisconnectChokes(routes, owners, hqs, values, report.RivalProduction)
+	return report, nil
+}
+
+// rivalRouteChokes sums the rival production passing through each of our territories
+func rivalRouteChokes(territories map[string]*typedef.Territory, routesTaken map[string][]string, ours map[string]struct{}, values map[string]float64, total float64) []RouteChoke {
+	chokes := make(map[string]*RouteChoke)
+	for source, value := range values {
+		route := routesTaken[source]
+		if len(route) < 3 {
+			continue
+		}
+		for _, hop := range route[1 : len(route)-1] {
+			t := territories[hop]
+			if t == nil {
+				continue
+			}
+			t.Mu.RLock()
+			owner := t.Guild.Tag
+			t.Mu.RUnlock()
+			if _, ok := ours[owner]; !ok {
+				continue
+			}
+			choke := chokes[hop]
+			if choke == nil {
+				choke = &RouteChoke{Territory: hop, Owner: owner}
+				chokes[hop] = choke
+			}
+			choke.Sources++
+			choke.Production += value
+		}
+	}
+
+	result := make([]RouteChoke, 0, len(chokes))
+	for _, choke := range chokes {
+		if total > 0 {
+			choke.Share = choke.Production / total
+		}
+		result = append(result, *choke)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Production != result[j].Production {
+			return result[i].Production > result[j].Production
+		}
+		return result[i].Territory < result[j].Territory
+	})
+	return result
+}
+
+// rivalDisconnectChokes removes each rival territory in turn and collects what can no longer reach its HQ
+func rivalDisconnectChokes(routes map[string][]string, owners, hqs map[string]string, values map[string]float64, total float64) []DisconnectChoke {
+	baseline := make(map[string]map[string]struct{}, len(hqs))
+	for owner, hq := range hqs {
+		baseline[owner] = reachableFrom(hq, routes, owners, "")
+	}
+
+	var result []DisconnectChoke
+	for name := range values {
+		choke := DisconnectChoke{Territory: name, Owner: owners[name]}
+		for owner, hq := range hqs {
+			reach := reachableFrom(hq, routes, owners, name)
+			for node := range baseline[owner] {
+				if node == name || owners[node] != owner || node == hq {
+					continue
+				}
+				if _, ok := reach[node]; !ok {
+					choke.Disconnected = append(choke.Disconnected, node)
+					choke.LostProduction += values[node]
+				}
+			}
+		}
+		if len(choke.Disconnected) == 0 {
+			continue
+		}
+		sort.Strings(choke.Disconnected)
+		if total > 0 {
+			choke.Share = choke.LostProduction / total
+		}
+		result = append(result, choke)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LostProduction != result[j].LostProduction {
+			return result[i].LostProduction > result[j].LostProduction
+		}
+		return result[i].Territory < result[j].Territory
+	})
+	return result
+}
+
+// reachableFrom walks the network from start, skipping the excluded territory
+func reachableFrom(start string, routes map[string][]string, network map[string]string, exclude string) map[string]struct{} {
+	seen := map[string]struct{}{start: {}}
+	queue := []string{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, nb := range routes[cur] {
+			if nb == exclude {
+				continue
+			}
+			if _, ok := network[nb]; !ok {
+				continue
+			}
+			if _, ok := seen[nb]; ok {
+				continue
+			}
+			seen[nb] = struct{}{}
+			queue = append(queue, nb)
+		}
+	}
+	return seen
+}
+
+func sortedTags(set map[string]struct{}) []string {
+	tags := make([]string, 0, len(set))
+	for tag := range set {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
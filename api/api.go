@@ -1,7 +1,9 @@
 package api
 
 import (
+	"RueaES/alg"
 	"RueaES/eruntime"
+	"RueaES/tml"
 	"RueaES/typedef"
 	"encoding/json"
 	"fmt"
@@ -212,6 +214,7 @@ func (api *API) createTerritoryStats() map[string]*TerritoryStateSafe {
 			TreasuryOverride:     territory.TreasuryOverride,
 			GenerationBonus:      territory.GenerationBonus,
 			CapturedAt:           territory.CapturedAt,
+			HeldBefore:           territory.HeldBefore,
 			ConnectedTerritories: connectedTerritories, // Direct connections
 			TradingRoutesJSON:    tradingRoutes,        // Actual trading routes
 			RouteTax:             territory.RouteTax,
@@ -292,6 +295,11 @@ func (api *API) createTributeStats() []*ActiveTributeSafe {
 			LastTransfer:    tribute.LastTransfer,
 			IsActive:        tribute.IsActive,
 			CreatedAt:       tribute.CreatedAt,
+			LastError:       tribute.LastError,
+			LastErrorTick:   tribute.LastErrorTick,
+			Schedule:        tribute.Schedule,
+			TransferCount:   tribute.TransferCount,
+			TotalSent:       tribute.TotalSent,
 		})
 	}
 
@@ -430,6 +438,24 @@ func (api *API) registerHandlers() {
 	api.handlers[MessageTypeGetAllTerritories] = api.handleGetAllTerritories
 	api.handlers[MessageTypeGetTerritories] = api.handleGetTerritories
 	api.handlers[MessageTypeGetAlternativeRoutes] = api.handleGetAlternativeRoutes
+	api.handlers[MessageTypeGetForecast] = api.handleGetForecast
+	api.handlers[MessageTypeGetAffordForecast] = api.handleGetAffordForecast
+	api.handlers[MessageTypeAnalyzeTerritoryLoss] = api.handleAnalyzeTerritoryLoss
+	api.handlers[MessageTypeCalculateTowerCombat] = api.handleCalculateTowerCombat
+	api.handlers[MessageTypeSimulateWarSeason] = api.handleSimulateWarSeason
+	api.handlers[MessageTypePlanClaimExpansion] = api.handlePlanClaimExpansion
+	api.handlers[MessageTypeOptimizeTaxPolicy] = api.handleOptimizeTaxPolicy
+	api.handlers[MessageTypeRivalChokepoints] = api.handleRivalChokepoints
+	api.handlers[MessageTypeSetTerritoriesHeld] = api.handleSetTerritoriesHeld
+	api.handlers[MessageTypeProjectTreasury] = api.handleProjectTreasury
+	api.handlers[MessageTypeTreasuryHQMove] = api.handleTreasuryHQMove
+	api.handlers[MessageTypeScheduleCapture] = api.handleScheduleCapture
+	api.handlers[MessageTypeCancelCapture] = api.handleCancelCapture
+	api.handlers[MessageTypeGetSeasonScores] = api.handleGetSeasonScores
+	api.handlers[MessageTypeResetSeasonScores] = api.handleResetSeasonScores
+	api.handlers[MessageTypeSetSeasonFormula] = api.handleSetSeasonFormula
+	api.handlers[MessageTypeCompareStrategies] = api.handleCompareStrategies
+	api.handlers[MessageTypeRunQuery] = api.handleRunQuery
 
 	// Territory editing handlers
 	api.handlers[MessageTypeSetTerritoryBonuses] = api.handleSetTerritoryBonuses
@@ -696,6 +722,7 @@ func (api *API) handleStateData(client *WSClient, message WSMessage) error {
 				TreasuryOverride:     territory.TreasuryOverride,
 				GenerationBonus:      territory.GenerationBonus,
 				CapturedAt:           territory.CapturedAt,
+				HeldBefore:           territory.HeldBefore,
 				ConnectedTerritories: connectedTerritories, // Direct connections
 				TradingRoutesJSON:    tradingRoutes,        // Actual trading routes
 				RouteTax:             territory.RouteTax,
@@ -1124,6 +1151,551 @@ func (api *API) handleGetAlternativeRoutes(client *WSClient, message WSMessage)
 	return nil
 }
 
+func (api *API) handleGetForecast(client *WSClient, message WSMessage) error {
+	var data GetForecastData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	data.TerritoryName = sanitizeAPIValue(data.TerritoryName)
+	data.GuildTag = sanitizeAPIValue(data.GuildTag)
+
+	var forecast *eruntime.StorageForecast
+	var err error
+	switch {
+	case data.TerritoryName != "":
+		forecast, err = eruntime.ForecastTerritory(data.TerritoryName)
+	case data.GuildTag != "":
+		forecast, err = eruntime.ForecastGuildHQ(data.GuildTag)
+	default:
+		return fmt.Errorf("territory_name or guild_tag is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      forecast,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleGetAffordForecast(client *WSClient, message WSMessage) error {
+	var data GetAffordForecastData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	data.TerritoryName = sanitizeAPIValue(data.TerritoryName)
+
+	var forecast eruntime.AffordabilityForecast
+	var err error
+	switch strings.ToLower(strings.TrimSpace(data.Kind)) {
+	case "upgrade", "":
+		forecast, err = eruntime.ForecastUpgradeAffordability(data.TerritoryName, strings.ToLower(data.Type), data.Level)
+	case "bonus":
+		forecast, err = eruntime.ForecastBonusAffordability(data.TerritoryName, data.Type, data.Level)
+	default:
+		return fmt.Errorf("invalid kind: %s", data.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      forecast,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleAnalyzeTerritoryLoss(client *WSClient, message WSMessage) error {
+	var data AnalyzeTerritoryLossData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	data.GuildTag = sanitizeAPIValue(data.GuildTag)
+	for i, name := range data.Territories {
+		data.Territories[i] = sanitizeAPIValue(name)
+	}
+
+	report, err := eruntime.AnalyzeTerritoryLoss(data.GuildTag, data.Territories)
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      report,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleCalculateTowerCombat(client *WSClient, message WSMessage) error {
+	var data CalculateTowerCombatData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	data.TerritoryName = sanitizeAPIValue(data.TerritoryName)
+	data.GuildTag = sanitizeAPIValue(data.GuildTag)
+
+	var result any
+	if data.TerritoryName != "" {
+		combat, err := eruntime.CalculateTowerCombat(data.TerritoryName, data.Party)
+		if err != nil {
+			return err
+		}
+		result = combat
+	} else {
+		combat, err := eruntime.CalculateGuildCombat(data.GuildTag, data.Party)
+		if err != nil {
+			return err
+		}
+		result = combat
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      result,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleSimulateWarSeason(client *WSClient, message WSMessage) error {
+	var data SimulateWarSeasonData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	report, err := eruntime.SimulateWarSeason(eruntime.WarSeasonConfig{
+		GuildTag:       sanitizeAPIValue(data.GuildTag),
+		Hours:          data.Hours,
+		Runs:           data.Runs,
+		Seed:           data.Seed,
+		Model:          eruntime.AttackModel(sanitizeAPIValue(data.Model)),
+		AttacksPerHour: data.AttacksPerHour,
+		RetakeHours:    data.RetakeHours,
+		Party:          data.Party,
+	})
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      report,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handlePlanClaimExpansion(client *WSClient, message WSMessage) error {
+	var data PlanClaimExpansionData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	plans, err := eruntime.PlanClaimExpansion(sanitizeAPIValue(data.GuildTag), alg.ExpansionConfig{
+		TargetSize: data.TargetSize,
+		Goals:      data.Goals,
+		MaxPlans:   data.MaxPlans,
+	})
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      plans,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleOptimizeTaxPolicy(client *WSClient, message WSMessage) error {
+	var data OptimizeTaxPolicyData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	rivals := make([]string, 0, len(data.Rivals))
+	for _, rival := range data.Rivals {
+		rivals = append(rivals, sanitizeAPIValue(rival))
+	}
+
+	report, err := eruntime.OptimizeTaxPolicy(eruntime.TaxPolicyConfig{
+		GuildTag:  sanitizeAPIValue(data.GuildTag),
+		Objective: eruntime.TaxPolicyObjective(sanitizeAPIValue(data.Objective)),
+		Rivals:    rivals,
+		TaxLevels: data.TaxLevels,
+	})
+	if err != nil {
+		return err
+	}
+	if data.Apply {
+		eruntime.ApplyTaxPolicy(report.Settings)
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      report,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleRivalChokepoints(client *WSClient, message WSMessage) error {
+	var data RivalChokepointsData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	report, err := eruntime.ComputeRivalChokepoints(sanitizeAPIValue(data.GuildTag), sanitizeAPIValue(data.RivalTag), data.Alliance)
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      report,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleSetTerritoriesHeld(client *WSClient, message WSMessage) error {
+	var data SetTerritoriesHeldData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(data.Territories))
+	for _, name := range data.Territories {
+		names = append(names, sanitizeAPIValue(name))
+	}
+
+	var updated int
+	if data.HeldSince != "" {
+		since, err := time.Parse(time.RFC3339, sanitizeAPIValue(data.HeldSince))
+		if err != nil {
+			return fmt.Errorf("invalid held_since: %v", err)
+		}
+		updated = eruntime.SetTerritoriesHeldSince(names, since)
+	} else {
+		updated = eruntime.SetTerritoriesHeldFor(names, time.Duration(data.HeldSeconds)*time.Second)
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      map[string]interface{}{"updated": updated},
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleProjectTreasury(client *WSClient, message WSMessage) error {
+	var data ProjectTreasuryData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	projections, err := eruntime.ProjectTreasury(sanitizeAPIValue(data.GuildTag))
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      projections,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleTreasuryHQMove(client *WSClient, message WSMessage) error {
+	var data TreasuryHQMoveData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	impact, err := eruntime.TreasuryHQMoveImpact(sanitizeAPIValue(data.GuildTag), sanitizeAPIValue(data.TerritoryName))
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      impact,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleScheduleCapture(client *WSClient, message WSMessage) error {
+	var data ScheduleCaptureData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	tick := data.Tick
+	if tick == 0 {
+		tick = eruntime.GetCurrentTick() + data.InSeconds
+	}
+	id, err := eruntime.ScheduleCapture(sanitizeAPIValue(data.TerritoryName), sanitizeAPIValue(data.GuildTag), tick, data.SetHQ)
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      map[string]interface{}{"capture_id": id, "tick": tick},
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleCancelCapture(client *WSClient, message WSMessage) error {
+	var data CancelCaptureData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	if err := eruntime.CancelScheduledCapture(sanitizeAPIValue(data.CaptureID)); err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      map[string]interface{}{"capture_id": data.CaptureID},
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleGetSeasonScores(client *WSClient, message WSMessage) error {
+	scores := SeasonScoresResponse{
+		Scores:   eruntime.GetSeasonScores(),
+		Captures: eruntime.GetScheduledCaptures(),
+		Formula:  eruntime.GetSeasonRatingFormula(),
+		Tick:     eruntime.GetCurrentTick(),
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      scores,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleResetSeasonScores(client *WSClient, message WSMessage) error {
+	eruntime.ResetSeasonScores()
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      nil,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleSetSeasonFormula(client *WSClient, message WSMessage) error {
+	var formula typedef.SeasonRatingFormula
+	if err := api.parseMessageData(message.Data, &formula); err != nil {
+		return err
+	}
+
+	if err := eruntime.SetSeasonRatingFormula(formula); err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      eruntime.GetSeasonRatingFormula(),
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleCompareStrategies(client *WSClient, message WSMessage) error {
+	var data CompareStrategiesData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	for i := range data.Strategies {
+		for j := range data.Strategies[i].Captures {
+			capture := &data.Strategies[i].Captures[j]
+			capture.Territory = sanitizeAPIValue(capture.Territory)
+			capture.GuildTag = sanitizeAPIValue(capture.GuildTag)
+		}
+	}
+
+	results, err := eruntime.CompareSeasonStrategies(data.Hours, data.Strategies)
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      results,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
+func (api *API) handleRunQuery(client *WSClient, message WSMessage) error {
+	var data RunQueryData
+	if err := api.parseMessageData(message.Data, &data); err != nil {
+		return err
+	}
+
+	result, err := tml.Run(data.Query)
+	if err != nil {
+		return err
+	}
+
+	response := WSMessage{
+		Type:      MessageTypeAck,
+		RequestID: message.RequestID,
+		Data:      result,
+		Timestamp: time.Now(),
+	}
+
+	select {
+	case client.send <- response:
+	default:
+	}
+
+	return nil
+}
+
 func (api *API) handleGetAllTerritories(client *WSClient, message WSMessage) error {
 	// Get all territories from eruntime
 	territories := eruntime.GetTerritories()
@@ -1150,6 +1722,7 @@ func (api *API) handleGetAllTerritories(client *WSClient, message WSMessage) err
 				TreasuryOverride:     territory.TreasuryOverride,
 				GenerationBonus:      territory.GenerationBonus,
 				CapturedAt:           territory.CapturedAt,
+				HeldBefore:           territory.HeldBefore,
 				ConnectedTerritories: connectedTerritories, // Direct connections
 				TradingRoutesJSON:    tradingRoutes,        // Actual trading routes
 				RouteTax:             territory.RouteTax,
@@ -1533,12 +2106,23 @@ func (api *API) handleCreateTribute(client *WSClient, message WSMessage) error {
 	}
 
 	// Create the tribute using eruntime function
-	tributeID, err := eruntime.CreateGuildToGuildTribute(
-		fromGuildName,
-		toGuildName,
-		data.AmountPerHour,
-		data.IntervalMinutes,
-	)
+	var tributeID string
+	if data.Schedule != nil {
+		tributeID, err = eruntime.CreateScheduledTribute(
+			fromGuildName,
+			toGuildName,
+			data.AmountPerHour,
+			data.IntervalMinutes,
+			*data.Schedule,
+		)
+	} else {
+		tributeID, err = eruntime.CreateGuildToGuildTribute(
+			fromGuildName,
+			toGuildName,
+			data.AmountPerHour,
+			data.IntervalMinutes,
+		)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create tribute: %w", err)
 	}
@@ -1565,27 +2149,9 @@ func (api *API) handleEditTribute(client *WSClient, message WSMessage) error {
 		return err
 	}
 
-	// Get the existing tribute
-	tribute := eruntime.GetTribute(data.TributeID)
-	if tribute == nil {
-		return fmt.Errorf("tribute with ID %s not found", data.TributeID)
-	}
-
-	// Update fields if provided
-	if data.AmountPerHour != nil {
-		tribute.AmountPerHour = *data.AmountPerHour
-		// Recalculate amount per minute
-		tribute.AmountPerMinute = typedef.BasicResources{
-			Emeralds: data.AmountPerHour.Emeralds / 60,
-			Ores:     data.AmountPerHour.Ores / 60,
-			Wood:     data.AmountPerHour.Wood / 60,
-			Fish:     data.AmountPerHour.Fish / 60,
-			Crops:    data.AmountPerHour.Crops / 60,
-		}
-	}
-
-	if data.IntervalMinutes != nil {
-		tribute.IntervalMinutes = *data.IntervalMinutes
+	// Validates every field before changing any of them
+	if err := eruntime.EditTribute(data.TributeID, data.AmountPerHour, data.IntervalMinutes, data.Schedule); err != nil {
+		return fmt.Errorf("failed to edit tribute: %w", err)
 	}
 
 	// Send acknowledgment
@@ -1729,6 +2295,11 @@ func (api *API) handleGetTributes(client *WSClient, message WSMessage) error {
 			LastTransfer:    tribute.LastTransfer,
 			IsActive:        tribute.IsActive,
 			CreatedAt:       tribute.CreatedAt,
+			LastError:       tribute.LastError,
+			LastErrorTick:   tribute.LastErrorTick,
+			Schedule:        tribute.Schedule,
+			TransferCount:   tribute.TransferCount,
+			TotalSent:       tribute.TotalSent,
 		})
 	}
 
@@ -1773,6 +2344,11 @@ func (api *API) handleGetTributeStats(client *WSClient, message WSMessage) error
 			LastTransfer:    tribute.LastTransfer,
 			IsActive:        tribute.IsActive,
 			CreatedAt:       tribute.CreatedAt,
+			LastError:       tribute.LastError,
+			LastErrorTick:   tribute.LastErrorTick,
+			Schedule:        tribute.Schedule,
+			TransferCount:   tribute.TransferCount,
+			TotalSent:       tribute.TotalSent,
 		}
 	} else {
 		// Get all tributes
@@ -1790,6 +2366,11 @@ func (api *API) handleGetTributeStats(client *WSClient, message WSMessage) error
 				LastTransfer:    tribute.LastTransfer,
 				IsActive:        tribute.IsActive,
 				CreatedAt:       tribute.CreatedAt,
+				LastError:       tribute.LastError,
+				LastErrorTick:   tribute.LastErrorTick,
+				Schedule:        tribute.Schedule,
+				TransferCount:   tribute.TransferCount,
+				TotalSent:       tribute.TotalSent,
 			})
 		}
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"RueaES/eruntime"
 	"RueaES/typedef"
 	"time"
 )
@@ -33,6 +34,24 @@ const (
 	MessageTypeGetAllTerritories MessageType = "get_all_territories"
 	MessageTypeGetTerritories    MessageType = "get_territories"
 	MessageTypeGetAlternativeRoutes MessageType = "get_alternative_routes"
+	MessageTypeGetForecast          MessageType = "get_forecast"
+	MessageTypeGetAffordForecast    MessageType = "get_affordability_forecast"
+	MessageTypeAnalyzeTerritoryLoss MessageType = "analyze_territory_loss"
+	MessageTypeCalculateTowerCombat MessageType = "calculate_tower_combat"
+	MessageTypeSimulateWarSeason    MessageType = "simulate_war_season"
+	MessageTypePlanClaimExpansion   MessageType = "plan_claim_expansion"
+	MessageTypeOptimizeTaxPolicy    MessageType = "optimize_tax_policy"
+	MessageTypeRivalChokepoints     MessageType = "analyze_rival_chokepoints"
+	MessageTypeSetTerritoriesHeld   MessageType = "set_territories_held"
+	MessageTypeProjectTreasury      MessageType = "project_treasury"
+	MessageTypeTreasuryHQMove       MessageType = "check_treasury_hq_move"
+	MessageTypeScheduleCapture      MessageType = "schedule_capture"
+	MessageTypeCancelCapture        MessageType = "cancel_scheduled_capture"
+	MessageTypeGetSeasonScores      MessageType = "get_season_scores"
+	MessageTypeResetSeasonScores    MessageType = "reset_season_scores"
+	MessageTypeSetSeasonFormula     MessageType = "set_season_formula"
+	MessageTypeCompareStrategies    MessageType = "compare_season_strategies"
+	MessageTypeRunQuery             MessageType = "run_query"
 
 	// Territory editing message types
 	MessageTypeSetTerritoryBonuses     MessageType = "set_territory_bonuses"
@@ -101,6 +120,7 @@ type TerritoryStateSafe struct {
 	TreasuryOverride     typedef.TreasuryOverride   `json:"treasury_override"`
 	GenerationBonus      float64                    `json:"generation_bonus"`
 	CapturedAt           uint64                     `json:"captured_at"`
+	HeldBefore           uint64                     `json:"held_before"`
 	ConnectedTerritories []string                   `json:"connected_territories"`
 	TradingRoutesJSON    [][]string                 `json:"trading_routes"`
 	RouteTax             float64                    `json:"route_tax"`
@@ -124,15 +144,20 @@ type GuildStateSafe struct {
 }
 
 type ActiveTributeSafe struct {
-	ID              string                 `json:"id"`
-	FromGuildName   string                 `json:"from_guild"`
-	ToGuildName     string                 `json:"to_guild"`
-	AmountPerHour   typedef.BasicResources `json:"amount_per_hour"`
-	AmountPerMinute typedef.BasicResources `json:"amount_per_minute"`
-	IntervalMinutes uint32                 `json:"interval_minutes"`
-	LastTransfer    uint64                 `json:"last_transfer"`
-	IsActive        bool                   `json:"is_active"`
-	CreatedAt       uint64                 `json:"created_at"`
+	ID              string                  `json:"id"`
+	FromGuildName   string                  `json:"from_guild"`
+	ToGuildName     string                  `json:"to_guild"`
+	AmountPerHour   typedef.BasicResources  `json:"amount_per_hour"`
+	AmountPerMinute typedef.BasicResources  `json:"amount_per_minute"`
+	IntervalMinutes uint32                  `json:"interval_minutes"`
+	LastTransfer    uint64                  `json:"last_transfer"`
+	IsActive        bool                    `json:"is_active"`
+	CreatedAt       uint64                  `json:"created_at"`
+	LastError       string                  `json:"last_error,omitempty"`
+	LastErrorTick   uint64                  `json:"last_error_tick,omitempty"`
+	Schedule        typedef.TributeSchedule `json:"schedule"`
+	TransferCount   uint32                  `json:"transfer_count"`
+	TotalSent       typedef.BasicResources  `json:"total_sent"`
 }
 
 // Incoming message data structures
@@ -199,6 +224,127 @@ type AlternativeRoutesResponse struct {
 	BoundedRoutes      []AlternativeRouteInfo `json:"bounded_routes,omitempty"`
 }
 
+// GetForecastData requests a storage forecast.
+// Set TerritoryName for a single territory, or GuildTag for the guild-wide HQ forecast.
+type GetForecastData struct {
+	TerritoryName string `json:"territory_name,omitempty"`
+	GuildTag      string `json:"guild_tag,omitempty"`
+}
+
+// GetAffordForecastData requests when a territory can afford an upgrade or bonus level.
+// Kind is "upgrade" or "bonus"; bonus types use camelCase keys (e.g. "resourceRate").
+type GetAffordForecastData struct {
+	TerritoryName string `json:"territory_name"`
+	Kind          string `json:"kind"`
+	Type          string `json:"type"`
+	Level         int    `json:"level"`
+}
+
+// AnalyzeTerritoryLossData requests a what-if report for a guild losing the given territories.
+type AnalyzeTerritoryLossData struct {
+	GuildTag    string   `json:"guild_tag"`
+	Territories []string `json:"territories"`
+}
+
+// CalculateTowerCombatData requests a combat estimate for one territory, or for every territory
+// of a guild when no territory is given. Party defaults to the configured war party.
+type CalculateTowerCombatData struct {
+	TerritoryName string            `json:"territory_name,omitempty"`
+	GuildTag      string            `json:"guild_tag,omitempty"`
+	Party         *typedef.WarParty `json:"party,omitempty"`
+}
+
+// SimulateWarSeasonData requests a Monte Carlo war season for a guild. Zero values use the defaults.
+type SimulateWarSeasonData struct {
+	GuildTag       string            `json:"guild_tag"`
+	Hours          int               `json:"hours,omitempty"`
+	Runs           int               `json:"runs,omitempty"`
+	Seed           int64             `json:"seed,omitempty"`
+	Model          string            `json:"model,omitempty"` // "uniform", "border" or "value"
+	AttacksPerHour float64           `json:"attacks_per_hour,omitempty"`
+	RetakeHours    int               `json:"retake_hours,omitempty"`
+	Party          *typedef.WarParty `json:"party,omitempty"`
+}
+
+// PlanClaimExpansionData requests contiguous expansion plans for a guild.
+// Goals weights each resource's generation; leave it empty to weight them equally.
+type PlanClaimExpansionData struct {
+	GuildTag   string                 `json:"guild_tag"`
+	TargetSize int                    `json:"target_size"`
+	Goals      typedef.BasicResources `json:"goals"`
+	MaxPlans   int                    `json:"max_plans,omitempty"`
+}
+
+// OptimizeTaxPolicyData requests tax and border recommendations for a guild's territories.
+// Apply sets the recommended settings once the search is done.
+type OptimizeTaxPolicyData struct {
+	GuildTag  string    `json:"guild_tag"`
+	Objective string    `json:"objective,omitempty"` // "revenue" or "deny"
+	Rivals    []string  `json:"rivals,omitempty"`
+	TaxLevels []float64 `json:"tax_levels,omitempty"`
+	Apply     bool      `json:"apply,omitempty"`
+}
+
+// RivalChokepointsData requests a cross-guild chokepoint analysis.
+// Alliance treats both guilds' allied claims as part of their networks.
+type RivalChokepointsData struct {
+	GuildTag string `json:"guild_tag"`
+	RivalTag string `json:"rival_tag"`
+	Alliance bool   `json:"alliance,omitempty"`
+}
+
+// SetTerritoriesHeldData backdates the capture time of territories. HeldSince is an RFC 3339 date
+// and takes precedence over HeldSeconds when both are given.
+type SetTerritoriesHeldData struct {
+	Territories []string `json:"territories"`
+	HeldSeconds int64    `json:"held_seconds,omitempty"`
+	HeldSince   string   `json:"held_since,omitempty"`
+}
+
+// ProjectTreasuryData requests the treasury milestones of a guild's territories.
+type ProjectTreasuryData struct {
+	GuildTag string `json:"guild_tag"`
+}
+
+// TreasuryHQMoveData checks how moving a guild's HQ affects its treasury bonuses.
+type TreasuryHQMoveData struct {
+	GuildTag      string `json:"guild_tag,omitempty"`
+	TerritoryName string `json:"territory_name"`
+}
+
+// ScheduleCaptureData queues a capture. InSeconds counts from the current tick and is used when Tick is 0.
+type ScheduleCaptureData struct {
+	TerritoryName string `json:"territory_name"`
+	GuildTag      string `json:"guild_tag"`
+	Tick          uint64 `json:"tick,omitempty"`
+	InSeconds     uint64 `json:"in_seconds,omitempty"`
+	SetHQ         bool   `json:"set_hq,omitempty"`
+}
+
+// CancelCaptureData removes a scheduled capture.
+type CancelCaptureData struct {
+	CaptureID string `json:"capture_id"`
+}
+
+// SeasonScoresResponse is the live season rating together with what drives it.
+type SeasonScoresResponse struct {
+	Scores   []typedef.SeasonScore       `json:"scores"`
+	Captures []typedef.ScheduledCapture  `json:"captures"`
+	Formula  typedef.SeasonRatingFormula `json:"formula"`
+	Tick     uint64                      `json:"tick"`
+}
+
+// CompareStrategiesData scores holding strategies over the next Hours.
+type CompareStrategiesData struct {
+	Hours      int                       `json:"hours"`
+	Strategies []eruntime.SeasonStrategy `json:"strategies"`
+}
+
+// RunQueryData carries a territory query such as `select where guild = "ABC"`
+type RunQueryData struct {
+	Query string `json:"query"`
+}
+
 // Territory editing message data structures
 type SetTerritoryBonusesData struct {
 	TerritoryName string        `json:"territory_name"`
@@ -240,16 +386,18 @@ type SetTradingRouteData struct {
 
 // Tribute management data structures
 type CreateTributeData struct {
-	FromGuildTag    string                 `json:"from_guild_tag"`
-	ToGuildTag      string                 `json:"to_guild_tag"`
-	AmountPerHour   typedef.BasicResources `json:"amount_per_hour"`
-	IntervalMinutes uint32                 `json:"interval_minutes"`
+	FromGuildTag    string                   `json:"from_guild_tag"`
+	ToGuildTag      string                   `json:"to_guild_tag"`
+	AmountPerHour   typedef.BasicResources   `json:"amount_per_hour"`
+	IntervalMinutes uint32                   `json:"interval_minutes"`
+	Schedule        *typedef.TributeSchedule `json:"schedule,omitempty"` // Optional: one-shot, limited, windowed or conditional schedule
 }
 
 type EditTributeData struct {
-	TributeID       string                  `json:"tribute_id"`
-	AmountPerHour   *typedef.BasicResources `json:"amount_per_hour,omitempty"`  // Optional: update amount
-	IntervalMinutes *uint32                 `json:"interval_minutes,omitempty"` // Optional: update interval
+	TributeID       string                   `json:"tribute_id"`
+	AmountPerHour   *typedef.BasicResources  `json:"amount_per_hour,omitempty"`  // Optional: update amount
+	IntervalMinutes *uint32                  `json:"interval_minutes,omitempty"` // Optional: update interval
+	Schedule        *typedef.TributeSchedule `json:"schedule,omitempty"`         // Optional: replace the schedule and reset its counters
 }
 
 type TributeActionData struct {
@@ -3,6 +3,7 @@ package app
 import (
 	"image"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -12,6 +13,21 @@ import (
 const (
 	chokepointButtonLabel = "Analyse Chokepoints"
 	hqButtonLabel         = "Find HQ Spots"
+	lossButtonLabel       = "Analyse Loss"
+	lossResultLines       = 4
+	hqResultLines         = 3
+	seasonButtonLabel     = "Simulate Season"
+	seasonResultLines     = 3
+	expansionButtonLabel  = "Plan Expansion"
+	expansionResultLines  = 4
+	taxRevenueLabel       = "Maximise Revenue"
+	taxDenyLabel          = "Deny Rivals"
+	taxApplyLabel         = "Apply Recommendation"
+	taxResultLines        = 3
+	rivalButtonLabel      = "Analyse Rival"
+	allianceButtonLabel   = "Analyse Alliances"
+	rivalResultLines      = 4
+	analysisColumns       = 3
 )
 
 // AnalysisModal displays analysis tools using reusable menu components.
@@ -33,11 +49,46 @@ type AnalysisModal struct {
 	onAnalyze   func(string)
 	onHQAnalyze func(string)
 	guildValue  string
+	hqResult    []*MenuText
+
+	// What-if loss module state
+	lossInput         *MenuTextInput
+	lossAnalyseButton *MenuButton
+	lossResult        []*MenuText
+	onLossAnalyze     func(string, []string)
+
+	// War season module state
+	seasonInput     *MenuTextInput
+	seasonButton    *MenuButton
+	seasonResult    []*MenuText
+	onSeasonAnalyze func(string, float64)
+
+	// Expansion planner state
+	expansionSizeInput  *MenuTextInput
+	expansionGoalsInput *MenuTextInput
+	expansionButton     *MenuButton
+	expansionResult     []*MenuText
+	onExpansionPlan     func(string, int, []string)
+
+	// Tax policy state
+	taxRevenueButton *MenuButton
+	taxDenyButton    *MenuButton
+	taxApplyButton   *MenuButton
+	taxResult        []*MenuText
+	onTaxPolicy      func(string, string)
+	onTaxApply       func()
+
+	// Rival chokepoint state
+	rivalInput     *MenuTextInput
+	rivalButton    *MenuButton
+	allianceButton *MenuButton
+	rivalResult    []*MenuText
+	onRivalAnalyze func(string, string, bool)
 }
 
 // NewAnalysisModal creates a new analysis modal.
 func NewAnalysisModal() *AnalysisModal {
-	modal := NewEnhancedModal("Analysis", 760, 520)
+	modal := NewEnhancedModal("Analysis", 1560, 700)
 	am := &AnalysisModal{modal: modal}
 	am.buildUI()
 	return am
@@ -53,6 +104,103 @@ func (am *AnalysisModal) SetOnHQAnalyze(cb func(string)) {
 	am.onHQAnalyze = cb
 }
 
+// SetOnLossAnalyze registers the callback invoked when the what-if loss button is pressed.
+func (am *AnalysisModal) SetOnLossAnalyze(cb func(string, []string)) {
+	am.onLossAnalyze = cb
+}
+
+// SetOnSeasonAnalyze registers the callback invoked when the war season button is pressed.
+func (am *AnalysisModal) SetOnSeasonAnalyze(cb func(string, float64)) {
+	am.onSeasonAnalyze = cb
+}
+
+// SetOnExpansionPlan registers the callback invoked when the expansion planner button is pressed.
+func (am *AnalysisModal) SetOnExpansionPlan(cb func(string, int, []string)) {
+	am.onExpansionPlan = cb
+}
+
+// SetOnTaxPolicy registers the callback invoked with the guild and objective when a tax policy button is pressed.
+func (am *AnalysisModal) SetOnTaxPolicy(cb func(string, string)) {
+	am.onTaxPolicy = cb
+}
+
+// SetOnTaxApply registers the callback invoked when the tax recommendation should be applied.
+func (am *AnalysisModal) SetOnTaxApply(cb func()) {
+	am.onTaxApply = cb
+}
+
+// SetOnRivalAnalyze registers the callback invoked with the guild, rival and alliance mode
+// when a rival chokepoint button is pressed.
+func (am *AnalysisModal) SetOnRivalAnalyze(cb func(string, string, bool)) {
+	am.onRivalAnalyze = cb
+}
+
+// SetHQResult replaces the lines shown beneath the HQ analysis button.
+func (am *AnalysisModal) SetHQResult(lines []string) {
+	for i, line := range am.hqResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// SetLossResult replaces the lines shown beneath the what-if loss controls.
+func (am *AnalysisModal) SetLossResult(lines []string) {
+	for i, line := range am.lossResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// SetSeasonResult replaces the lines shown beneath the war season controls.
+func (am *AnalysisModal) SetSeasonResult(lines []string) {
+	for i, line := range am.seasonResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// SetExpansionResult replaces the lines shown beneath the expansion planner controls.
+func (am *AnalysisModal) SetExpansionResult(lines []string) {
+	for i, line := range am.expansionResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// SetTaxResult replaces the lines shown beneath the tax policy controls.
+func (am *AnalysisModal) SetTaxResult(lines []string) {
+	for i, line := range am.taxResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// SetRivalResult replaces the lines shown beneath the rival chokepoint controls.
+func (am *AnalysisModal) SetRivalResult(lines []string) {
+	for i, line := range am.rivalResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
 // SetStatus updates the status text shown beneath the controls.
 func (am *AnalysisModal) SetStatus(msg string) {
 	if am.statusText != nil {
@@ -79,6 +227,35 @@ func (am *AnalysisModal) SetAnalyzing(inProgress bool) {
 			am.hqAnalyseButton.SetText(hqButtonLabel)
 		}
 	}
+	if am.lossAnalyseButton != nil {
+		am.lossAnalyseButton.SetEnabled(!inProgress)
+		if inProgress {
+			am.lossAnalyseButton.SetText("Analysing...")
+		} else {
+			am.lossAnalyseButton.SetText(lossButtonLabel)
+		}
+	}
+	if am.seasonButton != nil {
+		am.seasonButton.SetEnabled(!inProgress)
+		if inProgress {
+			am.seasonButton.SetText("Simulating...")
+		} else {
+			am.seasonButton.SetText(seasonButtonLabel)
+		}
+	}
+	if am.expansionButton != nil {
+		am.expansionButton.SetEnabled(!inProgress)
+		if inProgress {
+			am.expansionButton.SetText("Planning...")
+		} else {
+			am.expansionButton.SetText(expansionButtonLabel)
+		}
+	}
+	for _, button := range []*MenuButton{am.taxRevenueButton, am.taxDenyButton, am.taxApplyButton, am.rivalButton, am.allianceButton} {
+		if button != nil {
+			button.SetEnabled(!inProgress)
+		}
+	}
 }
 
 // SetGuildOptions updates the dropdown choices for guild selection.
@@ -140,6 +317,14 @@ func (am *AnalysisModal) Hide() {
 		am.guildDropdown.InputFocused = false
 		am.guildDropdown.IsOpen = false
 	}
+	if am.lossInput != nil {
+		am.lossInput.SetFocused(false)
+	}
+	for _, input := range []*MenuTextInput{am.seasonInput, am.expansionSizeInput, am.expansionGoalsInput, am.rivalInput} {
+		if input != nil {
+			input.SetFocused(false)
+		}
+	}
 }
 
 // IsVisible reports whether the modal is visible.
@@ -214,7 +399,12 @@ func (am *AnalysisModal) HasTextInputFocused() bool {
 	if am.guildDropdown != nil && (am.guildDropdown.InputFocused || am.guildDropdown.IsOpen) {
 		return true
 	}
-	return false
+	for _, input := range []*MenuTextInput{am.seasonInput, am.expansionSizeInput, am.expansionGoalsInput, am.rivalInput} {
+		if input != nil && input.focused {
+			return true
+		}
+	}
+	return am.lossInput != nil && am.lossInput.focused
 }
 
 func (am *AnalysisModal) buildUI() {
@@ -222,7 +412,7 @@ func (am *AnalysisModal) buildUI() {
 		am.Hide()
 	})
 	am.closeButton.SetRedButtonStyle()
-	am.cards = []*Card{am.buildChokepointCard(), am.buildPlaceholderCard()}
+	am.cards = []*Card{am.buildChokepointCard(), am.buildLossCard(), am.buildSeasonCard(), am.buildExpansionCard(), am.buildTaxPolicyCard(), am.buildRivalCard()}
 }
 
 // buildChokepointCard builds the UI elements for chokepoint and HQ analyses.
@@ -233,7 +423,7 @@ func (am *AnalysisModal) buildChokepointCard() *Card {
 
 	title := NewMenuText("Analysis Tools", TextOptions{FontSize: 18, Height: 26, Color: EnhancedUIColors.Text})
 	intro := NewMenuText("Score chokepoints for a guild and tint the map.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
-	hqIntro := NewMenuText("Find the strongest HQ spots (connections + externals).", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+	hqIntro := NewMenuText("Find the strongest HQ spots (connections + externals, then simulated).", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
 
 	am.guildDropdown = NewFilterableDropdown(0, 0, 260, 32, am.guildOptions, func(option FilterableDropdownOption) {
 		am.guildValue = strings.TrimSpace(option.Value)
@@ -291,24 +481,315 @@ func (am *AnalysisModal) buildChokepointCard() *Card {
 	am.statusText = NewMenuText("", TextOptions{FontSize: 14, Height: 18, Color: EnhancedUIColors.TextSecondary})
 	hint := NewMenuText("Press A or Esc to close the modal.", TextOptions{FontSize: 12, Height: 16, Color: EnhancedUIColors.TextPlaceholder})
 
-	card.elements = append(card.elements, title, intro, dropdownElement, am.analyseButton, hqIntro, am.hqAnalyseButton, am.statusText, hint)
+	card.elements = append(card.elements, title, intro, dropdownElement, am.analyseButton, hqIntro, am.hqAnalyseButton)
+	am.hqResult = make([]*MenuText, hqResultLines)
+	for i := range am.hqResult {
+		am.hqResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.hqResult[i])
+	}
+	card.elements = append(card.elements, am.statusText, hint)
 	return card
 }
 
-// buildPlaceholderCard adds a secondary card for future analyses.
-func (am *AnalysisModal) buildPlaceholderCard() *Card {
+// buildLossCard builds the what-if territory loss controls.
+// The loss is analysed for the guild selected in the chokepoint card.
+func (am *AnalysisModal) buildLossCard() *Card {
 	card := NewCard().Inline(false)
 	card.SetBackgroundColor(EnhancedUIColors.Surface)
 	card.SetBorderColor(EnhancedUIColors.Border)
 
-	title := NewMenuText("Additional Analyses", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
-	body := NewMenuText("More analysis tools will appear here soon.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+	title := NewMenuText("What-if Loss", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("See what the selected guild loses if these territories fall.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 720 // Clamped to the card width
+	inputOpts.Height = 30
+	inputOpts.MaxLength = 500
+	inputOpts.FontSize = 14
+	inputOpts.Placeholder = "e.g. Detlas, Ragni"
+	am.lossInput = NewMenuTextInput("Territories (comma separated)", "", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	am.lossAnalyseButton = NewMenuButton(lossButtonLabel, btnOpts, func() {
+		if am.onLossAnalyze == nil {
+			return
+		}
+		if am.guildDropdown != nil {
+			if selected, ok := am.guildDropdown.GetSelected(); ok {
+				am.guildValue = strings.TrimSpace(selected.Value)
+			}
+		}
+		if strings.TrimSpace(am.guildValue) == "" {
+			am.SetStatus("Select a guild to analyse")
+			return
+		}
+
+		var territories []string
+		for _, name := range strings.Split(am.lossInput.GetValue(), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				territories = append(territories, name)
+			}
+		}
+		if len(territories) == 0 {
+			am.SetLossResult([]string{"Enter at least one territory"})
+			return
+		}
+		am.onLossAnalyze(strings.TrimSpace(am.guildValue), territories)
+	})
 
-	card.elements = append(card.elements, title, body)
+	card.elements = append(card.elements, title, intro, am.lossInput, am.lossAnalyseButton)
+	am.lossResult = make([]*MenuText, lossResultLines)
+	for i := range am.lossResult {
+		am.lossResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.lossResult[i])
+	}
+	return card
+}
+
+// buildSeasonCard builds the war season simulator controls.
+// The season is simulated for the guild selected in the chokepoint card.
+func (am *AnalysisModal) buildSeasonCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("War Season (3 days, 200 runs, configured war party)", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 200
+	inputOpts.Height = 30
+	inputOpts.MaxLength = 6
+	inputOpts.FontSize = 14
+	am.seasonInput = NewMenuTextInput("Attacks per hour", "2", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	am.seasonButton = NewMenuButton(seasonButtonLabel, btnOpts, func() {
+		if am.onSeasonAnalyze == nil {
+			return
+		}
+		if am.guildDropdown != nil {
+			if selected, ok := am.guildDropdown.GetSelected(); ok {
+				am.guildValue = strings.TrimSpace(selected.Value)
+			}
+		}
+		if strings.TrimSpace(am.guildValue) == "" {
+			am.SetStatus("Select a guild to analyse")
+			return
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(am.seasonInput.GetValue()), 64)
+		if err != nil || rate <= 0 {
+			am.SetSeasonResult([]string{"Attacks per hour must be a positive number"})
+			return
+		}
+		am.onSeasonAnalyze(strings.TrimSpace(am.guildValue), rate)
+	})
+
+	card.elements = append(card.elements, title, am.seasonInput, am.seasonButton)
+	am.seasonResult = make([]*MenuText, seasonResultLines)
+	for i := range am.seasonResult {
+		am.seasonResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.seasonResult[i])
+	}
+	return card
+}
+
+// buildExpansionCard builds the claim expansion planner controls.
+// Plans are made for the guild selected in the chokepoint card.
+func (am *AnalysisModal) buildExpansionCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("Claim Expansion", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("Plans contiguous expansions; the best plan is tinted brightest.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 720 // Clamped to the card width
+	inputOpts.Height = 30
+	inputOpts.FontSize = 14
+	inputOpts.MaxLength = 6
+	inputOpts.Placeholder = "e.g. 40"
+	am.expansionSizeInput = NewMenuTextInput("Target claim size", "", inputOpts, nil)
+
+	inputOpts.MaxLength = 100
+	inputOpts.Placeholder = "e.g. crops, fish (empty for all)"
+	am.expansionGoalsInput = NewMenuTextInput("Resource goals", "", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	am.expansionButton = NewMenuButton(expansionButtonLabel, btnOpts, func() {
+		if am.onExpansionPlan == nil {
+			return
+		}
+		if am.guildDropdown != nil {
+			if selected, ok := am.guildDropdown.GetSelected(); ok {
+				am.guildValue = strings.TrimSpace(selected.Value)
+			}
+		}
+		if strings.TrimSpace(am.guildValue) == "" {
+			am.SetStatus("Select a guild to analyse")
+			return
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(am.expansionSizeInput.GetValue()))
+		if err != nil || size <= 0 {
+			am.SetExpansionResult([]string{"Target claim size must be a positive whole number"})
+			return
+		}
+		var goals []string
+		for _, goal := range strings.Split(am.expansionGoalsInput.GetValue(), ",") {
+			if goal = strings.TrimSpace(goal); goal != "" {
+				goals = append(goals, goal)
+			}
+		}
+		am.onExpansionPlan(strings.TrimSpace(am.guildValue), size, goals)
+	})
+
+	card.elements = append(card.elements, title, intro, am.expansionSizeInput, am.expansionGoalsInput, am.expansionButton)
+	am.expansionResult = make([]*MenuText, expansionResultLines)
+	for i := range am.expansionResult {
+		am.expansionResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.expansionResult[i])
+	}
+	return card
+}
+
+// buildTaxPolicyCard builds the border and tax policy optimizer controls.
+// The policy is optimized for the guild selected in the chokepoint card.
+func (am *AnalysisModal) buildTaxPolicyCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("Border & Tax Policy", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("Reroutes foreign guilds under candidate settings; busiest routes tinted.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	optimize := func(objective string) func() {
+		return func() {
+			if am.onTaxPolicy == nil {
+				return
+			}
+			if am.guildDropdown != nil {
+				if selected, ok := am.guildDropdown.GetSelected(); ok {
+					am.guildValue = strings.TrimSpace(selected.Value)
+				}
+			}
+			if strings.TrimSpace(am.guildValue) == "" {
+				am.SetStatus("Select a guild to analyse")
+				return
+			}
+			am.onTaxPolicy(strings.TrimSpace(am.guildValue), objective)
+		}
+	}
+	am.taxRevenueButton = NewMenuButton(taxRevenueLabel, btnOpts, optimize("revenue"))
+	am.taxDenyButton = NewMenuButton(taxDenyLabel, btnOpts, optimize("deny"))
+	am.taxApplyButton = NewMenuButton(taxApplyLabel, btnOpts, func() {
+		if am.onTaxApply != nil {
+			am.onTaxApply()
+		}
+	})
+
+	card.elements = append(card.elements, title, intro, am.taxRevenueButton, am.taxDenyButton)
+	am.taxResult = make([]*MenuText, taxResultLines)
+	for i := range am.taxResult {
+		am.taxResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.taxResult[i])
+	}
+	card.elements = append(card.elements, am.taxApplyButton)
+	return card
+}
+
+// buildRivalCard builds the cross-guild chokepoint controls.
+// The selected guild in the chokepoint card is our side; the rival is typed in.
+func (am *AnalysisModal) buildRivalCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("Rival Chokepoints", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("Our territories on their routes, and theirs that cut them off.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 720 // Clamped to the card width
+	inputOpts.Height = 30
+	inputOpts.FontSize = 14
+	inputOpts.MaxLength = 8
+	inputOpts.Placeholder = "Guild tag"
+	am.rivalInput = NewMenuTextInput("Rival guild", "", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	analyse := func(alliance bool) func() {
+		return func() {
+			if am.onRivalAnalyze == nil {
+				return
+			}
+			if am.guildDropdown != nil {
+				if selected, ok := am.guildDropdown.GetSelected(); ok {
+					am.guildValue = strings.TrimSpace(selected.Value)
+				}
+			}
+			if strings.TrimSpace(am.guildValue) == "" {
+				am.SetStatus("Select a guild to analyse")
+				return
+			}
+			rival := strings.TrimSpace(am.rivalInput.GetValue())
+			if rival == "" {
+				am.SetRivalResult([]string{"Enter the rival guild's tag"})
+				return
+			}
+			am.onRivalAnalyze(strings.TrimSpace(am.guildValue), rival, alliance)
+		}
+	}
+	am.rivalButton = NewMenuButton(rivalButtonLabel, btnOpts, analyse(false))
+	am.allianceButton = NewMenuButton(allianceButtonLabel, btnOpts, analyse(true))
+
+	card.elements = append(card.elements, title, intro, am.rivalInput, am.rivalButton, am.allianceButton)
+	am.rivalResult = make([]*MenuText, rivalResultLines)
+	for i := range am.rivalResult {
+		am.rivalResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.rivalResult[i])
+	}
 	return card
 }
 
 // layoutCards computes card rectangles within the modal content area.
+// Cards fill the columns, each going to whichever column is currently shortest.
 func (am *AnalysisModal) layoutCards() []Rect {
 	if am.modal == nil {
 		return nil
@@ -319,8 +800,12 @@ func (am *AnalysisModal) layoutCards() []Rect {
 	contentBounds := image.Rect(cx, cy, cx+cw, cy+ch)
 	cx += padding
 	cw -= padding * 2
-	currentY := cy + padding
 	spacing := 16
+	columnWidth := (cw - spacing*(analysisColumns-1)) / analysisColumns
+	var columnY [analysisColumns]int
+	for i := range columnY {
+		columnY[i] = cy + padding
+	}
 	rects := make([]Rect, len(am.cards))
 
 	for i, card := range am.cards {
@@ -329,9 +814,15 @@ func (am *AnalysisModal) layoutCards() []Rect {
 			continue
 		}
 
+		column := 0
+		for i := range columnY {
+			if columnY[i] < columnY[column] {
+				column = i
+			}
+		}
 		height := card.GetMinHeight()
-		rects[i] = Rect{X: cx, Y: currentY, Width: cw, Height: height}
-		currentY += height + spacing
+		rects[i] = Rect{X: cx + column*(columnWidth+spacing), Y: columnY[column], Width: columnWidth, Height: height}
+		columnY[column] += height + spacing
 	}
 
 	// Update dropdown container bounds to keep the menu within the modal
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"RueaES/eruntime"
+	"RueaES/storage"
+)
+
+// annotationLayerOptions lists the annotation layers with their size and visibility
+func annotationLayerOptions() []FilterableDropdownOption {
+	layers := eruntime.GetAnnotationLayers()
+	options := make([]FilterableDropdownOption, 0, len(layers))
+	for _, layer := range layers {
+		display := fmt.Sprintf("%s (%d)", layer.Name, len(layer.Annotations))
+		if !layer.Visible {
+			display += " (hidden)"
+		}
+		options = append(options, FilterableDropdownOption{Display: display, Value: layer.Name})
+	}
+	return options
+}
+
+// ExportAnnotations asks where to save the named layer, or every layer when layer is empty, as JSON
+func ExportAnnotations(layer string) {
+	fileManager := GetFileSystemManager()
+	if fileManager == nil {
+		return
+	}
+	fileManager.ShowCustomSaveDialogue("Export Annotations", []string{".json"}, func(path string) {
+		var data []byte
+		var err error
+		if layer == "" {
+			data, err = eruntime.ExportAnnotationsJSON()
+		} else {
+			data, err = eruntime.ExportAnnotationsJSON(layer)
+		}
+		if err != nil {
+			showResultToast("Failed to export annotations: "+err.Error(), false)
+			return
+		}
+		if err := storage.WriteFileAtomic(path, data, 0644); err != nil {
+			showResultToast("Failed to write "+filepath.Base(path)+": "+err.Error(), false)
+			return
+		}
+		showResultToast("Annotations exported to "+path, true)
+	})
+}
+
+// ImportAnnotations asks for an exported annotations file and adds its layers, replacing layers of
+// the same name
+func ImportAnnotations() {
+	fileManager := GetFileSystemManager()
+	if fileManager == nil {
+		return
+	}
+	fileManager.ShowCustomOpenDialogue("Import Annotations", []string{".json"}, func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			showResultToast("Failed to read "+filepath.Base(path)+": "+err.Error(), false)
+			return
+		}
+		count, err := eruntime.ImportAnnotationsJSON(data)
+		if err != nil {
+			showResultToast("Failed to import annotations: "+err.Error(), false)
+			return
+		}
+		showResultToast(fmt.Sprintf("Imported %d annotation layers", count), true)
+	})
+}
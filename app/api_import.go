@@ -4,9 +4,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
+	"time"
+
+	"RueaES/eruntime"
+	"RueaES/storage"
 )
 
+// defaultWynncraftAPIBaseURL is the Wynncraft API used when no other base URL is configured
+const defaultWynncraftAPIBaseURL = "https://api.wynncraft.com"
+
+// athenaGuildListURL is the guild list used when no other base URL is configured
+const athenaGuildListURL = "https://athena.wynntils.com/cache/get/guildList"
+
+// apiImportSettingsFile stores where the territory import reads the territory list from
+const apiImportSettingsFile = "api_import.json"
+
+// APIImportSettings selects the source of the guild and territory lists. Saved files take
+// precedence over the base URL, so the import works offline; the base URL allows a local
+// stand-in server, which serves the guild list under /cache/get/guildList.
+type APIImportSettings struct {
+	BaseURL       string `json:"baseUrl,omitempty"`       // Defaults to the Wynncraft API
+	TerritoryFile string `json:"territoryFile,omitempty"` // Saved /v3/guild/list/territory response
+	GuildFile     string `json:"guildFile,omitempty"`     // Saved Athena guild list response
+}
+
 // GuildListResponse represents the response from the Athena API
 type GuildListResponse []GuildInfo
 
@@ -22,7 +45,8 @@ type TerritoryListResponse map[string]TerritoryInfo
 
 // TerritoryInfo represents a territory from the Wynncraft API
 type TerritoryInfo struct {
-	Guild GuildReference `json:"guild"`
+	Guild    GuildReference `json:"guild"`
+	Acquired string         `json:"acquired"` // When the guild took the territory, e.g. 2024-05-01T12:34:56.789000Z
 }
 
 // GuildReference represents a guild reference in the territory API
@@ -30,20 +54,12 @@ type GuildReference struct {
 	Name string `json:"name"`
 }
 
-// ImportGuildsFromAPI imports guilds from the Athena API
+// ImportGuildsFromAPI imports guilds from the configured guild list source
 func (gm *EnhancedGuildManager) ImportGuildsFromAPI() (int, int, error) {
-	// Fetch guild list from Athena API
-	resp, err := http.Get("https://athena.wynntils.com/cache/get/guildList")
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to fetch guild list: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse the response
-	var guildListResp GuildListResponse
-	err = json.NewDecoder(resp.Body).Decode(&guildListResp)
+	// Fetch guild list from the configured source
+	guildListResp, err := fetchGuildList(LoadAPIImportSettings())
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to parse guild list: %v", err)
+		return 0, 0, err
 	}
 
 	// Import guilds
@@ -107,18 +123,10 @@ func (gm *EnhancedGuildManager) ImportTerritoriesFromAPI() (int, int, error) {
 		return 0, 0, fmt.Errorf("guild claim manager is not initialized")
 	}
 
-	// Fetch territory list from Wynncraft API
-	resp, err := http.Get("https://api.wynncraft.com/v3/guild/list/territory")
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to fetch territory list: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Parse the response
-	var territoryListResp TerritoryListResponse
-	err = json.NewDecoder(resp.Body).Decode(&territoryListResp)
+	// Fetch territory list from the configured source
+	territoryListResp, asOf, err := fetchTerritoryList(LoadAPIImportSettings())
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to parse territory list: %v", err)
+		return 0, 0, err
 	}
 
 	// IMPORTANT: Suspend automatic redraws during bulk import to avoid hundreds of redraw calls
@@ -135,6 +143,7 @@ func (gm *EnhancedGuildManager) ImportTerritoriesFromAPI() (int, int, error) {
 
 	// Batch processing for performance
 	claims := make([]GuildClaim, 0, len(territoryListResp))
+	acquired := make(map[string]eruntime.TerritoryAcquisition, len(territoryListResp))
 	for territoryName, territoryInfo := range territoryListResp {
 		// Skip if the guild name is empty
 		if territoryInfo.Guild.Name == "" {
@@ -163,6 +172,9 @@ func (gm *EnhancedGuildManager) ImportTerritoriesFromAPI() (int, int, error) {
 				GuildName:     guildName,
 				GuildTag:      guildTag,
 			})
+			if at, ok := parseAcquired(territoryInfo.Acquired); ok {
+				acquired[territoryName] = eruntime.TerritoryAcquisition{GuildName: guildName, At: at}
+			}
 			importedCount++
 		} else {
 			skippedCount++
@@ -174,5 +186,160 @@ func (gm *EnhancedGuildManager) ImportTerritoriesFromAPI() (int, int, error) {
 		claimManager.AddClaimsBatch(claims)
 	}
 
+	// Setting the owners marks every territory as captured now, backdate them to when they were
+	// really taken so treasury matches the game. Claims the cooldown refused keep their holds.
+	if len(acquired) > 0 {
+		eruntime.SetTerritoriesAcquired(acquired, asOf)
+	}
+
 	return importedCount, skippedCount, nil
 }
+
+// LoadAPIImportSettings reads the territory import source from the data directory
+func LoadAPIImportSettings() APIImportSettings {
+	var settings APIImportSettings
+	data, err := storage.ReadDataFile(apiImportSettingsFile)
+	if err != nil {
+		return settings
+	}
+	_ = json.Unmarshal(data, &settings)
+	return settings
+}
+
+// SaveAPIImportSettings stores the territory import source in the data directory
+func SaveAPIImportSettings(settings APIImportSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteDataFile(apiImportSettingsFile, data, 0644)
+}
+
+// territoryListURL returns the territory list endpoint under a base URL
+func (s APIImportSettings) territoryListURL() string {
+	base := strings.TrimSpace(s.BaseURL)
+	if base == "" {
+		base = defaultWynncraftAPIBaseURL
+	}
+	return strings.TrimRight(base, "/") + "/v3/guild/list/territory"
+}
+
+// guildListURL returns the guild list endpoint, Athena unless a custom base URL is configured
+func (s APIImportSettings) guildListURL() string {
+	base := strings.TrimRight(strings.TrimSpace(s.BaseURL), "/")
+	if base == "" || base == defaultWynncraftAPIBaseURL {
+		return athenaGuildListURL
+	}
+	return base + "/cache/get/guildList"
+}
+
+// IsOffline reports whether the import reads from somewhere other than the Wynncraft API
+func (s APIImportSettings) IsOffline() bool {
+	base := strings.TrimRight(strings.TrimSpace(s.BaseURL), "/")
+	return s.TerritoryFile != "" || (base != "" && base != defaultWynncraftAPIBaseURL)
+}
+
+// fetchGuildList reads the guild list from the saved file or the base URL. A saved territory
+// list without a saved guild list and a custom base URL has no offline guild source, so it fails
+// rather than reaching Athena.
+func fetchGuildList(settings APIImportSettings) (GuildListResponse, error) {
+	var guildListResp GuildListResponse
+
+	if settings.GuildFile != "" {
+		data, err := os.ReadFile(settings.GuildFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read guild file: %v", err)
+		}
+		if err := json.Unmarshal(data, &guildListResp); err != nil {
+			return nil, fmt.Errorf("failed to parse guild list: %v", err)
+		}
+		return guildListResp, nil
+	}
+
+	url := settings.guildListURL()
+	if settings.IsOffline() && url == athenaGuildListURL {
+		return nil, fmt.Errorf("no saved guild list is configured")
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch guild list: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch guild list: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&guildListResp); err != nil {
+		return nil, fmt.Errorf("failed to parse guild list: %v", err)
+	}
+	return guildListResp, nil
+}
+
+// fetchTerritoryList reads the territory list from the saved file or the base URL. It also
+// returns the real-world time the list describes, which acquired times are measured against: the
+// Date of the response, or for a saved file, which doesn't record when it was fetched, the newest
+// acquired time in it as a lower bound.
+func fetchTerritoryList(settings APIImportSettings) (TerritoryListResponse, time.Time, error) {
+	var territoryListResp TerritoryListResponse
+
+	if settings.TerritoryFile != "" {
+		data, err := os.ReadFile(settings.TerritoryFile)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to read territory file: %v", err)
+		}
+		if err := json.Unmarshal(data, &territoryListResp); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to parse territory list: %v", err)
+		}
+		return territoryListResp, newestAcquired(territoryListResp), nil
+	}
+
+	resp, err := http.Get(settings.territoryListURL())
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to fetch territory list: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("failed to fetch territory list: %s", resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&territoryListResp); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse territory list: %v", err)
+	}
+
+	asOf := time.Now()
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		asOf = date
+	}
+	return territoryListResp, asOf, nil
+}
+
+// newestAcquired returns the latest acquired time of the list, or now when it has none
+func newestAcquired(territories TerritoryListResponse) time.Time {
+	var newest time.Time
+	for _, info := range territories {
+		if at, ok := parseAcquired(info.Acquired); ok && at.After(newest) {
+			newest = at
+		}
+	}
+	if newest.IsZero() {
+		return time.Now()
+	}
+	return newest
+}
+
+// parseAcquired parses an acquired timestamp of the territory list. Timestamps without a zone
+// are UTC.
+func parseAcquired(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, true
+	}
+	if at, err := time.Parse("2006-01-02T15:04:05.999999999", value); err == nil {
+		return at, true
+	}
+	return time.Time{}, false
+}
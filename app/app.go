@@ -447,6 +447,28 @@ func (s *State) Update() error {
 		return nil
 	}
 
+	// The autosave history picker also blocks the app while open
+	if autoSaveHistory := GetAutoSaveHistoryModal(); autoSaveHistory.IsVisible() {
+		autoSaveHistory.Update()
+		return nil
+	}
+
+	// Share code previews block the app until applied or cancelled
+	if shareCode := GetShareCodeModal(); shareCode.IsVisible() {
+		shareCode.Update()
+		return nil
+	}
+
+	if territoryCSV := GetTerritoryCSVModal(); territoryCSV.IsVisible() {
+		territoryCSV.Update()
+		return nil
+	}
+
+	if costProfile := GetCostProfileModal(); costProfile.IsVisible() {
+		costProfile.Update()
+		return nil
+	}
+
 	// Update panic notification first and check if it consumes input
 	panicNotifier := GetPanicNotifier()
 	if panicNotifier.IsVisible() {
@@ -665,6 +687,22 @@ func (s *State) Draw(screen *ebiten.Image) {
 		lockWarning.Draw(screen)
 	}
 
+	if autoSaveHistory := GetAutoSaveHistoryModal(); autoSaveHistory.IsVisible() {
+		autoSaveHistory.Draw(screen)
+	}
+
+	if shareCode := GetShareCodeModal(); shareCode.IsVisible() {
+		shareCode.Draw(screen)
+	}
+
+	if territoryCSV := GetTerritoryCSVModal(); territoryCSV.IsVisible() {
+		territoryCSV.Draw(screen)
+	}
+
+	if costProfile := GetCostProfileModal(); costProfile.IsVisible() {
+		costProfile.Draw(screen)
+	}
+
 	// Draw panic notification on top of absolutely everything
 	panicNotifier := GetPanicNotifier()
 	if panicNotifier.IsVisible() {
@@ -757,6 +795,9 @@ func New() *Game {
 	// If a lock was detected before UI initialization, show the warning immediately
 	ShowScheduledLockWarning()
 
+	// Offer to restore an earlier autosave when enabled and history exists
+	ShowAutoSaveHistoryOnStartup()
+
 	// Initialize the menu and settings screen
 	game.state.menu = game.createMenu()
 	game.state.settingsScreen = NewSettingsScreen(settingsModule)
@@ -1,19 +1,34 @@
 package app
 
 import (
+	"fmt"
 	"image"
 	"sort"
+	"strconv"
 	"strings"
 
 	"RueaES/alg/auto"
+	"RueaES/typedef"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
 const (
 	autoSetupAlgoLabel = "AutoSetup"
+
+	optimizerResultLines    = 3
+	defaultOptimizerHQLevel = 0
+	rebalanceResultLines    = 4
+	autoSetupColumns        = 2
 )
 
+// optimizerObjectives maps the optimizer entries of the algorithm dropdown to their objective
+var optimizerObjectives = map[string]auto.Objective{
+	"optimizer_defence":   auto.ObjectiveDefence,
+	"optimizer_emeralds":  auto.ObjectiveEmeralds,
+	"optimizer_resources": auto.ObjectiveResources,
+}
+
 // AutoSetupModal shows controls for the auto economy optimizer.
 type AutoSetupModal struct {
 	modal *EnhancedModal
@@ -22,6 +37,17 @@ type AutoSetupModal struct {
 	closeButton *EnhancedButton
 	statusText  *MenuText
 
+	hqLevelInput    *MenuTextInput
+	maxDrainInput   *MenuTextInput
+	seedInput       *MenuTextInput
+	optimizeButton  *MenuButton
+	optimizerResult []*MenuText
+
+	rebalanceTargetInput *MenuTextInput
+	rebalanceResult      []*MenuText
+	rebalanceReport      *auto.RebalanceReport // Last advice, applied on request
+	rebalanceUndo        *auto.RebalanceUndo   // Last applied advice, undone on request
+
 	suppressInputFrames int
 
 	guildDropdown *FilterableDropdown
@@ -36,7 +62,7 @@ type AutoSetupModal struct {
 
 // NewAutoSetupModal creates the modal UI.
 func NewAutoSetupModal() *AutoSetupModal {
-	modal := NewEnhancedModal("Auto Setup", 720, 520)
+	modal := NewEnhancedModal("Auto Setup", 1200, 700)
 	am := &AutoSetupModal{modal: modal}
 	am.buildUI()
 	return am
@@ -99,6 +125,11 @@ func (am *AutoSetupModal) Hide() {
 		am.guildDropdown.InputFocused = false
 		am.guildDropdown.IsOpen = false
 	}
+	for _, input := range []*MenuTextInput{am.hqLevelInput, am.maxDrainInput, am.seedInput, am.rebalanceTargetInput} {
+		if input != nil {
+			input.SetFocused(false)
+		}
+	}
 }
 
 // IsVisible reports whether the modal is open.
@@ -183,6 +214,11 @@ func (am *AutoSetupModal) HasTextInputFocused() bool {
 	if am.algDropdown != nil && (am.algDropdown.InputFocused || am.algDropdown.IsOpen) {
 		return true
 	}
+	for _, input := range []*MenuTextInput{am.hqLevelInput, am.maxDrainInput, am.seedInput, am.rebalanceTargetInput} {
+		if input != nil && input.focused {
+			return true
+		}
+	}
 	return false
 }
 
@@ -191,7 +227,7 @@ func (am *AutoSetupModal) buildUI() {
 		am.Hide()
 	})
 	am.closeButton.SetRedButtonStyle()
-	am.cards = []*Card{am.buildControlCard()}
+	am.cards = []*Card{am.buildControlCard(), am.buildOptimizerCard(), am.buildRebalanceCard()}
 }
 
 func (am *AutoSetupModal) buildControlCard() *Card {
@@ -202,7 +238,12 @@ func (am *AutoSetupModal) buildControlCard() *Card {
 	title := NewMenuText("Auto Economy Setup", TextOptions{FontSize: 18, Height: 26, Color: EnhancedUIColors.Text})
 	intro := NewMenuText("Continuously optimizes a guild claim. Default loop: every 60 ticks.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
 
-	am.algOptions = []FilterableDropdownOption{{Display: autoSetupAlgoLabel, Value: "auto_setup"}}
+	am.algOptions = []FilterableDropdownOption{
+		{Display: autoSetupAlgoLabel, Value: "auto_setup"},
+		{Display: "Optimizer: Defence", Value: "optimizer_defence"},
+		{Display: "Optimizer: Emeralds", Value: "optimizer_emeralds"},
+		{Display: "Optimizer: Resources", Value: "optimizer_resources"},
+	}
 	am.algDropdown = NewFilterableDropdown(0, 0, 240, 32, am.algOptions, func(option FilterableDropdownOption) {
 		am.algValue = strings.TrimSpace(option.Value)
 	})
@@ -228,7 +269,7 @@ func (am *AutoSetupModal) buildControlCard() *Card {
 	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
 
 	startBtn := NewMenuButton("Start", btnOpts, func() {
-		if am.algValue != "auto_setup" {
+		if _, ok := optimizerObjectives[am.algValue]; !ok && am.algValue != "auto_setup" {
 			return
 		}
 		tag := strings.TrimSpace(am.guildValue)
@@ -238,7 +279,7 @@ func (am *AutoSetupModal) buildControlCard() *Card {
 			}
 			return
 		}
-		cfg := auto.AutoConfig{GuildTag: tag}
+		cfg := auto.AutoConfig{GuildTag: tag, Optimizer: am.optimizerConfig()}
 		loop := auto.LoopConfig{Mode: auto.LoopEveryTicks, EveryTicks: 60}
 		auto.StartAuto(cfg, loop)
 	})
@@ -266,6 +307,313 @@ func (am *AutoSetupModal) buildControlCard() *Card {
 	return card
 }
 
+func (am *AutoSetupModal) buildOptimizerCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("Constraint Optimizer", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("Keeps every resource net non-negative. Same seed, same result.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 200
+	inputOpts.Height = 30
+	inputOpts.MaxLength = 10
+	inputOpts.FontSize = 14
+	am.hqLevelInput = NewMenuTextInput("Minimum HQ level", strconv.Itoa(defaultOptimizerHQLevel), inputOpts, nil)
+	am.maxDrainInput = NewMenuTextInput("Max emerald drain/h, 0 for none", "0", inputOpts, nil)
+	am.seedInput = NewMenuTextInput("Seed", "1", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	am.optimizeButton = NewMenuButton("Optimize Once", btnOpts, func() {
+		am.runOptimizerOnce()
+	})
+
+	card.elements = append(card.elements, title, intro, am.hqLevelInput, am.maxDrainInput, am.seedInput, am.optimizeButton)
+	am.optimizerResult = make([]*MenuText, optimizerResultLines)
+	for i := range am.optimizerResult {
+		am.optimizerResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.optimizerResult[i])
+	}
+	return card
+}
+
+// optimizerConfig returns the optimizer settings for the selected algorithm, or nil for the heuristic setup
+func (am *AutoSetupModal) optimizerConfig() *auto.OptimizerConfig {
+	objective, ok := optimizerObjectives[am.algValue]
+	if !ok {
+		return nil
+	}
+
+	cfg := &auto.OptimizerConfig{
+		Objective:   objective,
+		Constraints: auto.OptimizerConstraints{NonNegativeNet: true, MinHQLevel: defaultOptimizerHQLevel},
+		Seed:        1,
+	}
+	if am.hqLevelInput != nil {
+		if level, err := strconv.Atoi(strings.TrimSpace(am.hqLevelInput.GetValue())); err == nil && level > 0 {
+			cfg.Constraints.MinHQLevel = level
+		}
+	}
+	if am.maxDrainInput != nil {
+		if drain, err := strconv.ParseFloat(strings.TrimSpace(am.maxDrainInput.GetValue()), 64); err == nil && drain > 0 {
+			cfg.Constraints.MaxEmeraldDrain = drain
+		}
+	}
+	if am.seedInput != nil {
+		if seed, err := strconv.ParseInt(strings.TrimSpace(am.seedInput.GetValue()), 10, 64); err == nil {
+			cfg.Seed = seed
+		}
+	}
+	return cfg
+}
+
+// runOptimizerOnce runs a single optimizer pass in the background and applies the best solution
+func (am *AutoSetupModal) runOptimizerOnce() {
+	cfg := am.optimizerConfig()
+	if cfg == nil {
+		am.setOptimizerResult("Select an optimizer objective first.")
+		return
+	}
+	tag := strings.TrimSpace(am.guildValue)
+	if tag == "" {
+		am.setOptimizerResult("Select a guild first.")
+		return
+	}
+	if auto.IsAutoRunning() {
+		am.setOptimizerResult("Stop the running auto setup first.")
+		return
+	}
+
+	am.setOptimizerResult("Optimizing...")
+	go func() {
+		report, err := auto.OptimizeClaimEco(tag, nil, *cfg)
+		if err != nil {
+			am.setOptimizerResult("Optimizer failed: " + err.Error())
+			return
+		}
+
+		applied := auto.ApplySolution(&report.Best)
+		best := report.Best
+		feasible := "feasible"
+		if !best.Feasible {
+			feasible = "constraints not met"
+		}
+		am.setOptimizerResult(
+			fmt.Sprintf("Applied to %d territories (%s, seed %d)", len(applied.Actions), feasible, report.Seed),
+			fmt.Sprintf("Defence %d  HQ %d  em %+.0f/h  drain %.0f/h", best.DefenceLevels, best.HQLevel, best.NetPerHour.Emeralds, best.EmeraldDrain),
+			fmt.Sprintf("Pareto: %s", optimizerParetoSummary(report.Pareto)),
+		)
+	}()
+}
+
+// optimizerParetoSummary lists the defence and emerald trade-offs of the Pareto set
+func optimizerParetoSummary(pareto []auto.OptimizerSolution) string {
+	if len(pareto) == 0 {
+		return "no feasible trade-offs found"
+	}
+	parts := make([]string, 0, len(pareto))
+	for i, solution := range pareto {
+		if i == 4 {
+			parts = append(parts, fmt.Sprintf("+%d more", len(pareto)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("def %d / em %+.0f", solution.DefenceLevels, solution.NetPerHour.Emeralds))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// setOptimizerResult replaces the optimizer result lines, clearing any not given
+func (am *AutoSetupModal) setOptimizerResult(lines ...string) {
+	for i, line := range am.optimizerResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+func (am *AutoSetupModal) buildRebalanceCard() *Card {
+	card := NewCard().Inline(false)
+	card.SetBackgroundColor(EnhancedUIColors.Surface)
+	card.SetBorderColor(EnhancedUIColors.Border)
+
+	title := NewMenuText("Rebalancing Advisor", TextOptions{FontSize: 16, Height: 22, Color: EnhancedUIColors.Text})
+	intro := NewMenuText("Fewest changes to reach the surplus; defence is downgraded last.", TextOptions{FontSize: 14, Height: 20, Color: EnhancedUIColors.TextSecondary})
+
+	inputOpts := DefaultTextInputOptions()
+	inputOpts.Width = 320
+	inputOpts.Height = 30
+	inputOpts.MaxLength = 60
+	inputOpts.FontSize = 14
+	inputOpts.Placeholder = "one value, or em, ore, wood, fish, crops"
+	am.rebalanceTargetInput = NewMenuTextInput("Target surplus per hour", "0", inputOpts, nil)
+
+	btnOpts := DefaultButtonOptions()
+	btnOpts.Height = 32
+	btnOpts.FontSize = 15
+	btnOpts.BackgroundColor = EnhancedUIColors.Button
+	btnOpts.HoverColor = EnhancedUIColors.BorderActive
+	btnOpts.PressedColor = EnhancedUIColors.ButtonActive
+	btnOpts.BorderColor = EnhancedUIColors.BorderGreen
+
+	adviseButton := NewMenuButton("Advise", btnOpts, func() {
+		am.runRebalanceAdvisor()
+	})
+	applyButton := NewMenuButton("Apply Advice", btnOpts, func() {
+		am.applyRebalance()
+	})
+	undoButton := NewMenuButton("Undo Last Apply", btnOpts, func() {
+		am.undoRebalance()
+	})
+
+	card.elements = append(card.elements, title, intro, am.rebalanceTargetInput, adviseButton, applyButton, undoButton)
+	am.rebalanceResult = make([]*MenuText, rebalanceResultLines)
+	for i := range am.rebalanceResult {
+		am.rebalanceResult[i] = NewMenuText("", TextOptions{FontSize: 13, Height: 18, Color: EnhancedUIColors.TextSecondary})
+		card.elements = append(card.elements, am.rebalanceResult[i])
+	}
+	return card
+}
+
+// rebalanceTarget parses the target input: one value for every resource, or five in emerald, ore, wood, fish, crops order
+func (am *AutoSetupModal) rebalanceTarget() (typedef.BasicResources, error) {
+	var values []float64
+	for _, field := range strings.Split(am.rebalanceTargetInput.GetValue(), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return typedef.BasicResources{}, fmt.Errorf("'%s' is not a number", field)
+		}
+		values = append(values, value)
+	}
+
+	switch len(values) {
+	case 0:
+		return typedef.BasicResources{}, nil
+	case 1:
+		v := values[0]
+		return typedef.BasicResources{Emeralds: v, Ores: v, Wood: v, Fish: v, Crops: v}, nil
+	case 5:
+		return typedef.BasicResources{Emeralds: values[0], Ores: values[1], Wood: values[2], Fish: values[3], Crops: values[4]}, nil
+	default:
+		return typedef.BasicResources{}, fmt.Errorf("enter one value or five (em, ore, wood, fish, crops)")
+	}
+}
+
+// runRebalanceAdvisor computes advice for the selected guild without applying it
+func (am *AutoSetupModal) runRebalanceAdvisor() {
+	tag := strings.TrimSpace(am.guildValue)
+	if tag == "" {
+		am.setRebalanceResult("Select a guild first.")
+		return
+	}
+	target, err := am.rebalanceTarget()
+	if err != nil {
+		am.setRebalanceResult(err.Error())
+		return
+	}
+
+	am.setRebalanceResult("Advising...")
+	go func() {
+		report, err := auto.AdviseRebalance(tag, auto.RebalanceConfig{Target: target})
+		if err != nil {
+			am.setRebalanceResult("Advisor failed: " + err.Error())
+			return
+		}
+		am.rebalanceReport = report
+
+		if len(report.Suggestions) == 0 {
+			am.setRebalanceResult("Nothing to change: every resource already meets the target.")
+			return
+		}
+
+		changes := make([]string, 0, len(report.Suggestions))
+		for _, suggestion := range report.Suggestions {
+			if suggestion.Territory != "" {
+				changes = append(changes, suggestion.Territory+" "+suggestion.Change)
+			} else {
+				changes = append(changes, suggestion.Change)
+			}
+		}
+		if len(changes) > 3 {
+			changes = append(changes[:3], fmt.Sprintf("+%d more", len(report.Suggestions)-3))
+		}
+
+		reached := "target reached"
+		if !report.Reached {
+			reached = "target not reached"
+		}
+		now, after := report.Current, report.Projected
+		am.setRebalanceResult(
+			fmt.Sprintf("%d changes, %d defence downgrades, %s", len(report.Suggestions), report.DefenceDowngrades, reached),
+			fmt.Sprintf("Now/h: em %+.0f ore %+.0f wood %+.0f fish %+.0f crops %+.0f", now.Emeralds, now.Ores, now.Wood, now.Fish, now.Crops),
+			fmt.Sprintf("After/h: em %+.0f ore %+.0f wood %+.0f fish %+.0f crops %+.0f", after.Emeralds, after.Ores, after.Wood, after.Fish, after.Crops),
+			strings.Join(changes, ", "),
+		)
+	}()
+}
+
+// applyRebalance applies the last advice and keeps what it replaced for undo
+func (am *AutoSetupModal) applyRebalance() {
+	report := am.rebalanceReport
+	if report == nil {
+		am.setRebalanceResult("Run the advisor first.")
+		return
+	}
+	if auto.IsAutoRunning() {
+		am.setRebalanceResult("Stop the running auto setup first.")
+		return
+	}
+
+	am.rebalanceReport = nil
+	go func() {
+		undo, applied := auto.ApplyRebalance(report)
+		am.rebalanceUndo = undo
+		lines := []string{fmt.Sprintf("Applied %d changes for %s. Undo restores the previous setup.", len(applied.Actions), report.GuildTag)}
+		am.setRebalanceResult(append(lines, applied.Warnings...)...)
+	}()
+}
+
+// undoRebalance restores the configuration replaced by the last applied advice
+func (am *AutoSetupModal) undoRebalance() {
+	undo := am.rebalanceUndo
+	if undo == nil {
+		am.setRebalanceResult("Nothing to undo.")
+		return
+	}
+
+	am.rebalanceUndo = nil
+	go func() {
+		restored := auto.UndoRebalance(undo)
+		lines := []string{fmt.Sprintf("Restored %d territories and removed %d tributes.", len(restored.Actions), len(undo.Tributes))}
+		am.setRebalanceResult(append(lines, restored.Warnings...)...)
+	}()
+}
+
+// setRebalanceResult replaces the advisor result lines, clearing any not given
+func (am *AutoSetupModal) setRebalanceResult(lines ...string) {
+	for i, line := range am.rebalanceResult {
+		if i < len(lines) {
+			line.SetText(lines[i])
+		} else {
+			line.SetText("")
+		}
+	}
+}
+
+// layoutCards places each card in whichever column is currently shorter
 func (am *AutoSetupModal) layoutCards() []Rect {
 	if am.modal == nil {
 		return nil
@@ -277,8 +625,12 @@ func (am *AutoSetupModal) layoutCards() []Rect {
 	padding := 12
 	cx += padding
 	cw -= padding * 2
-	currentY := cy + padding
 	spacing := 16
+	columnWidth := (cw - spacing*(autoSetupColumns-1)) / autoSetupColumns
+	var columnY [autoSetupColumns]int
+	for i := range columnY {
+		columnY[i] = cy + padding
+	}
 	rects := make([]Rect, len(am.cards))
 
 	for i, card := range am.cards {
@@ -286,9 +638,15 @@ func (am *AutoSetupModal) layoutCards() []Rect {
 			rects[i] = Rect{}
 			continue
 		}
+		column := 0
+		for c := range columnY {
+			if columnY[c] < columnY[column] {
+				column = c
+			}
+		}
 		height := card.GetMinHeight()
-		rects[i] = Rect{X: cx, Y: currentY, Width: cw, Height: height}
-		currentY += height + spacing
+		rects[i] = Rect{X: cx + column*(columnWidth+spacing), Y: columnY[column], Width: columnWidth, Height: height}
+		columnY[column] += height + spacing
 	}
 
 	if am.guildDropdown != nil {
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"fmt"
+	"image/color"
+	"sync"
+	"time"
+
+	"RueaES/eruntime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+)
+
+const (
+	autoSaveRowHeight   = 30
+	autoSaveVisibleRows = 10
+)
+
+// AutoSaveHistoryModal lists the autosave history and restores the selected entry.
+type AutoSaveHistoryModal struct {
+	modal         *EnhancedModal
+	restoreButton *EnhancedButton
+	closeButton   *EnhancedButton
+	visible       bool
+	entries       []eruntime.AutoSaveEntry
+	selected      int
+	scroll        int
+	font          font.Face
+	buttonWidth   int
+	buttonHeight  int
+}
+
+var (
+	globalAutoSaveHistory *AutoSaveHistoryModal
+	autoSaveHistoryOnce   sync.Once
+)
+
+// GetAutoSaveHistoryModal returns the global autosave history modal instance.
+func GetAutoSaveHistoryModal() *AutoSaveHistoryModal {
+	autoSaveHistoryOnce.Do(func() {
+		m := &AutoSaveHistoryModal{
+			modal:        NewEnhancedModal("Restore from Autosave History", 620, 140+autoSaveVisibleRows*autoSaveRowHeight),
+			font:         loadWynncraftFont(16),
+			buttonWidth:  150,
+			buttonHeight: 40,
+			selected:     -1,
+		}
+
+		m.restoreButton = NewEnhancedButton("Restore", 0, 0, m.buttonWidth, m.buttonHeight, m.restoreSelected)
+		m.restoreButton.SetGreenButtonStyle()
+
+		m.closeButton = NewEnhancedButton("Keep Current", 0, 0, m.buttonWidth, m.buttonHeight, func() {
+			m.Hide()
+		})
+		m.closeButton.SetGrayButtonStyle()
+
+		globalAutoSaveHistory = m
+	})
+	return globalAutoSaveHistory
+}
+
+// ShowAutoSaveHistoryOnStartup opens the picker when enabled and there is history to restore from.
+func ShowAutoSaveHistoryOnStartup() {
+	if !eruntime.GetAutoSaveSettings().PickerOnStartup {
+		return
+	}
+	if entries, err := eruntime.GetAutoSaveHistory(); err == nil && len(entries) > 0 {
+		GetAutoSaveHistoryModal().Show()
+	}
+}
+
+// Show reloads the history and makes the modal visible.
+func (m *AutoSaveHistoryModal) Show() {
+	entries, err := eruntime.GetAutoSaveHistory()
+	if err != nil {
+		NewToast().
+			Text("Failed to read autosave history: "+err.Error(), ToastOption{Colour: color.RGBA{255, 100, 100, 255}}).
+			AutoClose(time.Second * 5).
+			Show()
+		return
+	}
+
+	m.entries = entries
+	m.selected = -1
+	m.scroll = 0
+	m.visible = true
+	m.modal.Show()
+	m.updateButtonPositions()
+}
+
+// Hide hides the modal.
+func (m *AutoSaveHistoryModal) Hide() {
+	m.visible = false
+	m.modal.Hide()
+}
+
+// IsVisible returns whether the modal is currently shown.
+func (m *AutoSaveHistoryModal) IsVisible() bool {
+	return m != nil && m.visible
+}
+
+func (m *AutoSaveHistoryModal) restoreSelected() {
+	if m.selected < 0 || m.selected >= len(m.entries) {
+		return
+	}
+	entry := m.entries[m.selected]
+	m.Hide()
+
+	if err := eruntime.RestoreAutoSave(entry.Name); err != nil {
+		NewToast().
+			Text("Failed to restore autosave: "+err.Error(), ToastOption{Colour: color.RGBA{255, 100, 100, 255}}).
+			AutoClose(time.Second * 5).
+			Show()
+		return
+	}
+	NewToast().
+		Text(fmt.Sprintf("Restored autosave from %s (tick %d)", entry.SavedAt.Format("2006-01-02 15:04:05"), entry.Tick), ToastOption{Colour: color.RGBA{100, 255, 100, 255}}).
+		AutoClose(time.Second * 3).
+		Show()
+}
+
+// listArea returns the position of the first row
+func (m *AutoSaveHistoryModal) listArea() (int, int, int) {
+	contentX, contentY, contentW, _ := m.modal.GetContentArea()
+	return contentX, contentY + 30, contentW
+}
+
+// Update processes input for the modal and returns true if it consumed input.
+func (m *AutoSaveHistoryModal) Update() bool {
+	if !m.IsVisible() {
+		return false
+	}
+
+	m.modal.Update()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.Hide()
+		return true
+	}
+
+	_, wheelY := ebiten.Wheel()
+	if wheelY > 0 && m.scroll > 0 {
+		m.scroll--
+	} else if wheelY < 0 && m.scroll+autoSaveVisibleRows < len(m.entries) {
+		m.scroll++
+	}
+
+	mx, my := ebiten.CursorPosition()
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		listX, listY, listW := m.listArea()
+		if mx >= listX && mx < listX+listW && my >= listY && my < listY+autoSaveVisibleRows*autoSaveRowHeight {
+			if row := m.scroll + (my-listY)/autoSaveRowHeight; row < len(m.entries) {
+				m.selected = row
+			}
+		}
+	}
+
+	m.restoreButton.enabled = m.selected >= 0
+	m.restoreButton.Update(mx, my)
+	m.closeButton.Update(mx, my)
+
+	// Always consume input while visible to block the rest of the app.
+	return true
+}
+
+// Draw renders the modal contents.
+func (m *AutoSaveHistoryModal) Draw(screen *ebiten.Image) {
+	if !m.IsVisible() {
+		return
+	}
+
+	m.modal.Draw(screen)
+
+	contentX, contentY, _, _ := m.modal.GetContentArea()
+	text.Draw(screen, "Select an autosave to restore, newest first. Scroll for older entries.", m.font, contentX, contentY+14, EnhancedUIColors.TextSecondary)
+
+	listX, listY, listW := m.listArea()
+	if len(m.entries) == 0 {
+		text.Draw(screen, "No autosaves in the history yet.", m.font, listX, listY+20, EnhancedUIColors.Text)
+	}
+
+	mx, my := ebiten.CursorPosition()
+	for i := 0; i < autoSaveVisibleRows && m.scroll+i < len(m.entries); i++ {
+		index := m.scroll + i
+		entry := m.entries[index]
+		rowY := listY + i*autoSaveRowHeight
+
+		background := EnhancedUIColors.ItemBackground
+		switch {
+		case index == m.selected:
+			background = EnhancedUIColors.ItemSelected
+		case mx >= listX && mx < listX+listW && my >= rowY && my < rowY+autoSaveRowHeight:
+			background = EnhancedUIColors.ItemHover
+		}
+		vector.DrawFilledRect(screen, float32(listX), float32(rowY), float32(listW), autoSaveRowHeight-2, background, false)
+
+		label := fmt.Sprintf("%s   tick %d   %.1f KB", entry.SavedAt.Format("2006-01-02 15:04:05"), entry.Tick, float64(entry.Size)/1024)
+		text.Draw(screen, label, m.font, listX+10, rowY+20, EnhancedUIColors.Text)
+	}
+
+	m.restoreButton.Draw(screen)
+	m.closeButton.Draw(screen)
+}
+
+func (m *AutoSaveHistoryModal) updateButtonPositions() {
+	bounds := m.modal.GetBounds()
+	spacing := 16
+	totalWidth := 2*m.buttonWidth + spacing
+	startX := bounds.Min.X + (bounds.Dx()-totalWidth)/2
+	buttonY := bounds.Max.Y - 60
+
+	m.restoreButton.SetPosition(startX, buttonY)
+	m.closeButton.SetPosition(startX+m.buttonWidth+spacing, buttonY)
+}
@@ -0,0 +1,309 @@
+package app
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"RueaES/eruntime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+const (
+	costProfileLineHeight   = 20
+	costProfileVisibleLines = 20
+)
+
+// CostProfileModal compares a cost profile with the costs in use and switches to it on
+// confirmation
+type CostProfileModal struct {
+	modal        *EnhancedModal
+	switchButton *EnhancedButton
+	closeButton  *EnhancedButton
+	visible      bool
+	profile      string
+	switched     bool
+	onSwitched   func()
+	lines        []previewLine
+	scroll       int
+	font         font.Face
+	buttonWidth  int
+	buttonHeight int
+}
+
+var (
+	globalCostProfileModal *CostProfileModal
+	costProfileModalOnce   sync.Once
+)
+
+// GetCostProfileModal returns the global cost profile modal
+func GetCostProfileModal() *CostProfileModal {
+	costProfileModalOnce.Do(func() {
+		m := &CostProfileModal{
+			modal:        NewEnhancedModal("Cost Profile", 760, 150+costProfileVisibleLines*costProfileLineHeight),
+			font:         loadWynncraftFont(16),
+			buttonWidth:  150,
+			buttonHeight: 40,
+		}
+
+		m.switchButton = NewEnhancedButton("Switch", 0, 0, m.buttonWidth, m.buttonHeight, m.switchProfile)
+		m.switchButton.SetGreenButtonStyle()
+
+		m.closeButton = NewEnhancedButton("Close", 0, 0, m.buttonWidth, m.buttonHeight, func() {
+			m.Hide()
+		})
+		m.closeButton.SetGrayButtonStyle()
+
+		globalCostProfileModal = m
+	})
+	return globalCostProfileModal
+}
+
+// costProfileLabel names a profile for display, the empty name being the built-in costs
+func costProfileLabel(name string) string {
+	if name == "" {
+		return "Built-in"
+	}
+	return name
+}
+
+// Show opens the comparison of a profile with the costs in use. onSwitched runs after switching.
+func (m *CostProfileModal) Show(profile string, onSwitched func()) {
+	costs, err := eruntime.LoadCostProfile(profile)
+	if err != nil {
+		showResultToast("Failed to load cost profile: "+err.Error(), false)
+		return
+	}
+
+	m.profile = profile
+	m.switched = false
+	m.onSwitched = onSwitched
+	m.scroll = 0
+	m.lines = costDiffLines(eruntime.DiffCosts(eruntime.GetCost(), &costs))
+	m.lines = append(m.lines, previewLine{})
+	m.lines = append(m.lines, upkeepChangeLines(eruntime.CompareGuildUpkeep(&costs))...)
+	m.visible = true
+	m.modal.Show()
+
+	m.switchButton.enabled = true
+	m.updateButtonPositions()
+}
+
+// Hide closes the comparison
+func (m *CostProfileModal) Hide() {
+	m.visible = false
+	m.modal.Hide()
+}
+
+// IsVisible returns whether the comparison is shown
+func (m *CostProfileModal) IsVisible() bool {
+	return m != nil && m.visible
+}
+
+// costDiffLines lists the cost and multiplier changes grouped by upgrade or bonus
+func costDiffLines(diffs []eruntime.CostDiff) []previewLine {
+	normal := EnhancedUIColors.Text
+	changed := color.RGBA{255, 200, 100, 255}
+
+	if len(diffs) == 0 {
+		return []previewLine{{text: "Costs and multipliers are identical", color: normal}}
+	}
+
+	lines := []previewLine{{text: fmt.Sprintf("%d cost and multiplier changes", len(diffs)), color: normal}}
+	item := ""
+	for _, diff := range diffs {
+		if diff.Item != item {
+			item = diff.Item
+			lines = append(lines, previewLine{text: item, color: normal})
+		}
+		field := diff.Field
+		if diff.Level >= 0 {
+			field = fmt.Sprintf("%s level %d", diff.Field, diff.Level)
+		}
+		lines = append(lines, previewLine{
+			text:  fmt.Sprintf("    %s: %s -> %s", field, diff.From, diff.To),
+			color: changed,
+		})
+	}
+	return lines
+}
+
+// upkeepChangeLines lists the hourly upkeep change of every guild
+func upkeepChangeLines(changes []eruntime.GuildUpkeepChange) []previewLine {
+	normal := EnhancedUIColors.Text
+	secondary := EnhancedUIColors.TextSecondary
+	more := color.RGBA{255, 120, 120, 255}
+	less := color.RGBA{120, 220, 120, 255}
+
+	lines := []previewLine{{text: "Guild upkeep per hour", color: normal}}
+	for _, change := range changes {
+		delta := change.Delta()
+		var parts []string
+		lineColor := secondary
+		for _, r := range []struct {
+			name  string
+			value float64
+		}{
+			{"emeralds", delta.Emeralds},
+			{"ores", delta.Ores},
+			{"wood", delta.Wood},
+			{"fish", delta.Fish},
+			{"crops", delta.Crops},
+		} {
+			if r.value == 0 {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%+.0f %s", r.value, r.name))
+			if r.value > 0 {
+				lineColor = more
+			} else if lineColor == secondary {
+				lineColor = less
+			}
+		}
+		summary := "unchanged"
+		if len(parts) > 0 {
+			summary = strings.Join(parts, ", ")
+		}
+		lines = append(lines, previewLine{
+			text:  fmt.Sprintf("    %s [%s] (%d territories): %s", change.Guild, change.Tag, change.Territories, summary),
+			color: lineColor,
+		})
+	}
+	if len(changes) == 0 {
+		lines = append(lines, previewLine{text: "    No guild owns territories", color: secondary})
+	}
+	return lines
+}
+
+func (m *CostProfileModal) switchProfile() {
+	if m.switched {
+		return
+	}
+	changes, err := eruntime.SwitchCostProfile(m.profile)
+	if err != nil {
+		showResultToast("Failed to switch cost profile: "+err.Error(), false)
+		return
+	}
+
+	// Keep the modal open as the report of what the switch changed
+	m.switched = true
+	m.switchButton.enabled = false
+	m.scroll = 0
+	m.lines = upkeepChangeLines(changes)
+	showResultToast("Switched to cost profile "+costProfileLabel(m.profile), true)
+	if m.onSwitched != nil {
+		m.onSwitched()
+	}
+}
+
+// Update handles input while the comparison is open and returns true if it consumed input
+func (m *CostProfileModal) Update() bool {
+	if !m.IsVisible() {
+		return false
+	}
+
+	m.modal.Update()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.Hide()
+		return true
+	}
+
+	_, wheelY := ebiten.Wheel()
+	if wheelY > 0 && m.scroll > 0 {
+		m.scroll--
+	} else if wheelY < 0 && m.scroll+costProfileVisibleLines < len(m.lines) {
+		m.scroll++
+	}
+
+	mx, my := ebiten.CursorPosition()
+	m.switchButton.Update(mx, my)
+	m.closeButton.Update(mx, my)
+	return true
+}
+
+// Draw renders the comparison
+func (m *CostProfileModal) Draw(screen *ebiten.Image) {
+	if !m.IsVisible() {
+		return
+	}
+
+	m.modal.Draw(screen)
+
+	contentX, contentY, _, _ := m.modal.GetContentArea()
+	header := fmt.Sprintf("Costs in use compared with %s, nothing has changed yet.", costProfileLabel(m.profile))
+	if m.switched {
+		header = "Switched to " + costProfileLabel(m.profile) + "."
+	}
+	text.Draw(screen, header, m.font, contentX, contentY+14, EnhancedUIColors.TextSecondary)
+
+	for i := 0; i < costProfileVisibleLines && m.scroll+i < len(m.lines); i++ {
+		line := m.lines[m.scroll+i]
+		text.Draw(screen, line.text, m.font, contentX, contentY+44+i*costProfileLineHeight, line.color)
+	}
+
+	m.switchButton.Draw(screen)
+	m.closeButton.Draw(screen)
+}
+
+func (m *CostProfileModal) updateButtonPositions() {
+	bounds := m.modal.GetBounds()
+	spacing := 16
+	totalWidth := 2*m.buttonWidth + spacing
+	startX := bounds.Min.X + (bounds.Dx()-totalWidth)/2
+	buttonY := bounds.Max.Y - 60
+
+	m.switchButton.SetPosition(startX, buttonY)
+	m.closeButton.SetPosition(startX+m.buttonWidth+spacing, buttonY)
+}
+
+// costProfileOptions lists the built-in costs followed by the stored profiles
+func costProfileOptions() []FilterableDropdownOption {
+	options := []FilterableDropdownOption{{Display: "Built-in", Value: ""}}
+	for _, profile := range eruntime.ListCostProfiles() {
+		display := profile.Name
+		if profile.Active {
+			display += " (active)"
+		}
+		if profile.Error != "" {
+			display += " (invalid)"
+		}
+		options = append(options, FilterableDropdownOption{Display: display, Value: profile.Name})
+	}
+	return options
+}
+
+// ImportCostProfile asks for a JSON file in the upgrades.json format and stores it as a cost
+// profile named name, or after the file when name is empty
+func ImportCostProfile(name string, onImported func(name string)) {
+	fileManager := GetFileSystemManager()
+	if fileManager == nil {
+		return
+	}
+	fileManager.ShowCustomOpenDialogue("Import Cost Profile", []string{".json"}, func(path string) {
+		profile := strings.TrimSpace(name)
+		if profile == "" {
+			profile = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			showResultToast("Failed to read "+filepath.Base(path)+": "+err.Error(), false)
+			return
+		}
+		if err := eruntime.ImportCostProfile(profile, data); err != nil {
+			showResultToast("Failed to import cost profile: "+err.Error(), false)
+			return
+		}
+		showResultToast("Imported cost profile "+profile, true)
+		if onImported != nil {
+			onImported(profile)
+		}
+	})
+}
@@ -1678,6 +1678,7 @@ type EdgeMenu struct {
 	lastMousePressed        bool    // Previous frame's mouse button state
 	towerStatsUpdateTime    float64 // Timer for periodic tower stats updates
 	tradingRoutesUpdateTime float64 // Timer for periodic trading routes updates
+	forecastUpdateTime      float64 // Timer for periodic forecast updates
 
 	// State preservation for trading routes
 	tradingRouteStates map[string]bool // Map of route title -> expanded state
@@ -2000,6 +2001,7 @@ func (m *EdgeMenu) Update(screenWidth, screenHeight int, deltaTime float64) bool
 	m.lastUpdateTime += deltaTime
 	m.towerStatsUpdateTime += deltaTime
 	m.tradingRoutesUpdateTime += deltaTime
+	m.forecastUpdateTime += deltaTime
 
 	// Check if any pointer is currently pressed (mouse or touch)
 	pointerPressed := primaryPressed() || ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) || ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle)
@@ -2021,6 +2023,12 @@ func (m *EdgeMenu) Update(screenWidth, screenHeight int, deltaTime float64) bool
 		// fmt.Printf("DEBUG: Periodic trading routes update for territory: %s\n", m.currentTerritory)
 	}
 
+	// Re-validate the forecast every second as the simulation advances
+	if m.forecastUpdateTime >= 1.0 && !pointerPressed && m.currentTerritory != "" {
+		m.forecastUpdateTime = 0.0
+		m.UpdateForecast(m.currentTerritory)
+	}
+
 	// Update menu data every 50ms for territory stats, but only if no mouse buttons are pressed
 	if m.lastUpdateTime >= 0.05 {
 		m.lastUpdateTime = 0.0
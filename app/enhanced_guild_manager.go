@@ -2790,30 +2790,41 @@ func (gm *EnhancedGuildManager) runAPIImport() {
 
 	// Import guilds first
 	importedGuilds, skippedGuilds, err := gm.ImportGuildsFromAPI()
+	guildMsg := fmt.Sprintf("Imported %d guilds, skipped %d existing", importedGuilds, skippedGuilds)
 	if err != nil {
 		errMsg := fmt.Sprintf("Error importing guilds: %v", err)
 		// fmt.Printf("[GUILD_MANAGER] %s\n", errMsg)
 
-		// Show error toast with dismiss button
+		// An offline territory source doesn't need the guild list, its territories are matched
+		// against the guilds already known
+		if LoadAPIImportSettings().IsOffline() {
+			guildMsg = errMsg
+			NewToast().
+				Text("Guild Import Skipped", ToastOption{Colour: color.RGBA{255, 200, 100, 255}}).
+				Text(errMsg, ToastOption{Colour: color.RGBA{255, 230, 200, 255}}).
+				AutoClose(time.Second * 5).
+				Show()
+		} else {
+			// Show error toast with dismiss button
+			NewToast().
+				Text("Import Failed", ToastOption{Colour: color.RGBA{255, 100, 100, 255}}).
+				Text(errMsg, ToastOption{Colour: color.RGBA{255, 200, 200, 255}}).
+				Button("Dismiss", func() {}, 0, 0, ToastOption{Colour: color.RGBA{255, 120, 120, 255}}).
+				AutoClose(time.Second * 10).
+				Show()
+			return
+		}
+	} else {
+		// Show result message for guilds with success styling
+		// fmt.Printf("[GUILD_MANAGER] %s\n", guildMsg)
+
 		NewToast().
-			Text("Import Failed", ToastOption{Colour: color.RGBA{255, 100, 100, 255}}).
-			Text(errMsg, ToastOption{Colour: color.RGBA{255, 200, 200, 255}}).
-			Button("Dismiss", func() {}, 0, 0, ToastOption{Colour: color.RGBA{255, 120, 120, 255}}).
-			AutoClose(time.Second * 10).
+			Text("Guild Import Complete", ToastOption{Colour: color.RGBA{100, 255, 100, 255}}).
+			Text(guildMsg, ToastOption{Colour: color.RGBA{200, 255, 200, 255}}).
+			AutoClose(time.Second * 5).
 			Show()
-		return
 	}
 
-	// Show result message for guilds with success styling
-	guildMsg := fmt.Sprintf("Imported %d guilds, skipped %d existing", importedGuilds, skippedGuilds)
-	// fmt.Printf("[GUILD_MANAGER] %s\n", guildMsg)
-
-	NewToast().
-		Text("Guild Import Complete", ToastOption{Colour: color.RGBA{100, 255, 100, 255}}).
-		Text(guildMsg, ToastOption{Colour: color.RGBA{200, 255, 200, 255}}).
-		AutoClose(time.Second * 5).
-		Show()
-
 	// Import territories next
 	importedTerritories, skippedTerritories, err := gm.ImportTerritoriesFromAPI()
 	if err != nil {
@@ -58,6 +58,12 @@ var EnhancedUIColors = struct {
 	Selection:       color.RGBA{65, 105, 225, 128},  // Semi-transparent Royal Blue
 }
 
+// previewLine is one coloured line of a scrollable modal preview
+type previewLine struct {
+	text  string
+	color color.Color
+}
+
 // EnhancedModal represents a modal with dark overlay background
 type EnhancedModal struct {
 	Title         string
@@ -720,9 +720,9 @@ func (fsd *FileSystemDialogue) confirmSelection() {
 		return
 	}
 
-	// But if it isn't ended with an extension, add the default extension of .lz4
-	if fsd.dialogueType == FileDialogueSave && !strings.HasSuffix(selectedPath, ".lz4") {
-		selectedPath += ".lz4"
+	// Add the default extension if the name doesn't have one the dialogue accepts
+	if fsd.dialogueType == FileDialogueSave {
+		selectedPath = fsd.withSaveExtension(selectedPath)
 	}
 
 	// Check file extension if filters are specified
@@ -747,6 +747,31 @@ func (fsd *FileSystemDialogue) confirmSelection() {
 	fsd.Hide()
 }
 
+// withSaveExtension adds .lz4 to state file names that don't end with a state extension, and the
+// first allowed extension to any other file name without an allowed one
+func (fsd *FileSystemDialogue) withSaveExtension(path string) string {
+	isStateDialogue := len(fsd.allowedExts) == 0
+	for _, ext := range fsd.allowedExts {
+		if ext == ".lz4" {
+			isStateDialogue = true
+		}
+	}
+	if isStateDialogue {
+		if !strings.HasSuffix(path, ".lz4") && !strings.HasSuffix(path, ".etf") {
+			return path + ".lz4"
+		}
+		return path
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, allowedExt := range fsd.allowedExts {
+		if ext == strings.ToLower(allowedExt) {
+			return path
+		}
+	}
+	return path + fsd.allowedExts[0]
+}
+
 // Draw renders the file system dialogue
 func (fsd *FileSystemDialogue) Draw(screen *ebiten.Image) {
 	if !fsd.IsVisible() {
@@ -70,8 +70,8 @@ func InitializeFileSystemManager(inputManager *InputManager) {
 	globalFileSystemManager.SetOnFileOpened(func(filepath string, content []byte) error {
 		// fmt.Printf("[FILE] onFileOpened callback called for: %s\n", filepath)
 
-		// Check if this is a state file (has .lz4 extension)
-		if filepath != "" && (strings.HasSuffix(filepath, ".lz4") || strings.Contains(filepath, "state")) {
+		// Check if this is a state file (has .lz4 or .etf extension)
+		if filepath != "" && (strings.HasSuffix(filepath, ".lz4") || strings.HasSuffix(filepath, ".etf") || strings.Contains(filepath, "state")) {
 			// Show import modal for state files
 			ShowStateImportModal(filepath)
 		} else {
@@ -207,7 +207,7 @@ func (fsm *FileSystemManager) ShowOpenDialogue() {
 	}
 
 	// Define supported file extensions for state files
-	allowedExts := []string{".lz4", ".ruea"}
+	allowedExts := []string{".lz4", ".etf", ".ruea"}
 
 	// Create new open dialogue
 	fsm.openDialogue = NewFileSystemDialogue(FileDialogueOpen, "Load State", allowedExts)
@@ -238,7 +238,7 @@ func (fsm *FileSystemManager) ShowSaveDialogue() {
 	}
 
 	// Define supported file extensions for state files
-	allowedExts := []string{".lz4", ".ruea"}
+	allowedExts := []string{".lz4", ".etf", ".ruea"}
 
 	// Create new save dialogue
 	fsm.saveDialogue = NewFileSystemDialogue(FileDialogueSave, "Save State", allowedExts)
@@ -260,6 +260,38 @@ func (fsm *FileSystemManager) ShowSaveDialogue() {
 	// log.Println("[FILE] Save dialogue displayed")
 }
 
+// ShowCustomOpenDialogue displays an open dialogue for files other than states, calling
+// onSelected with the chosen path instead of the state callbacks
+func (fsm *FileSystemManager) ShowCustomOpenDialogue(title string, allowedExts []string, onSelected func(filepath string)) {
+	if fsm.openDialogue != nil {
+		fsm.openDialogue.Hide()
+	}
+
+	fsm.openDialogue = NewFileSystemDialogue(FileDialogueOpen, title, allowedExts)
+	fsm.openDialogue.SetCurrentPath(fsm.workingDirectory)
+	fsm.openDialogue.SetOnFileSelected(func(path string) {
+		fsm.workingDirectory = filepath.Dir(path)
+		onSelected(path)
+	})
+	fsm.openDialogue.Show()
+}
+
+// ShowCustomSaveDialogue displays a save dialogue for files other than states, calling
+// onSelected with the chosen path instead of the state callbacks
+func (fsm *FileSystemManager) ShowCustomSaveDialogue(title string, allowedExts []string, onSelected func(filepath string)) {
+	if fsm.saveDialogue != nil {
+		fsm.saveDialogue.Hide()
+	}
+
+	fsm.saveDialogue = NewFileSystemDialogue(FileDialogueSave, title, allowedExts)
+	fsm.saveDialogue.SetCurrentPath(fsm.workingDirectory)
+	fsm.saveDialogue.SetOnFileSelected(func(path string) {
+		fsm.workingDirectory = filepath.Dir(path)
+		onSelected(path)
+	})
+	fsm.saveDialogue.Show()
+}
+
 // handleFileOpen processes opening a selected file
 func (fsm *FileSystemManager) handleFileOpen(filepath string) {
 	// log.Printf("[FILE] Opening file: %s", filepath)
@@ -340,7 +372,7 @@ func (fsm *FileSystemManager) hasValidExtension(filepath string) bool {
 		return false
 	}
 	ext := strings.ToLower(filepath[strings.LastIndex(filepath, "."):])
-	validExts := []string{".json", ".lz4", ".ruea", ".txt", ".cfg", ".config"}
+	validExts := []string{".json", ".lz4", ".etf", ".ruea", ".txt", ".cfg", ".config"}
 
 	for _, validExt := range validExts {
 		if ext == validExt {
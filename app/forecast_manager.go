@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"RueaES/eruntime"
+)
+
+var forecastResourceLabels = map[string]string{
+	"emeralds": "Emerald",
+	"ores":     "Ore",
+	"wood":     "Wood",
+	"fish":     "Fish",
+	"crops":    "Crop",
+}
+
+var forecastResourceColors = map[string]color.RGBA{
+	"emeralds": {0, 255, 0, 255},
+	"ores":     {180, 180, 180, 255},
+	"wood":     {139, 69, 19, 255},
+	"fish":     {0, 150, 255, 255},
+	"crops":    {255, 255, 0, 255},
+}
+
+// UpdateForecast updates only the Forecast section with a forecast for the current tick
+func (m *EdgeMenu) UpdateForecast(territoryName string) {
+	for _, element := range m.elements {
+		if collapsible, ok := element.(*CollapsibleMenu); ok {
+			if collapsible.title == "Forecast" {
+				collapsible.elements = collapsible.elements[:0]
+				if collapsible.revealStart == nil {
+					collapsible.revealStart = make(map[EdgeMenuElement]time.Time)
+				}
+
+				populateForecastMenu(collapsible, territoryName)
+
+				revealDoneAt := time.Now().Add(-time.Second)
+				for _, child := range collapsible.elements {
+					if r, ok := child.(interface{ SetRevealProgress(float64) }); ok {
+						r.SetRevealProgress(1)
+					}
+					collapsible.revealStart[child] = revealDoneAt
+				}
+				return
+			}
+		}
+	}
+}
+
+// populateForecastMenu fills a collapsible menu with storage and affordability forecasts
+func populateForecastMenu(menu *CollapsibleMenu, territoryName string) {
+	forecast, err := eruntime.ForecastTerritory(territoryName)
+	if err != nil {
+		menu.Text("Forecast unavailable", DefaultTextOptions())
+		return
+	}
+
+	if forecast.GuildWide {
+		menu.Text("Guild-wide net flow", DefaultTextOptions())
+	}
+
+	for _, res := range forecast.Resources {
+		options := DefaultTextOptions()
+		options.Color = forecastResourceColors[res.Resource]
+
+		label := forecastResourceLabels[res.Resource]
+		var status string
+		switch {
+		case res.TicksToFull == 0:
+			status = "full"
+		case res.TicksToEmpty == 0:
+			status = "empty"
+		case res.TicksToFull > 0:
+			status = "full in " + formatForecastTicks(res.TicksToFull)
+		case res.TicksToEmpty > 0:
+			status = "empty in " + formatForecastTicks(res.TicksToEmpty)
+		default:
+			status = "stable"
+		}
+		menu.Text(fmt.Sprintf("%s: %s (%+d/h)", label, status, int(res.NetPerHour)), options)
+	}
+
+	if len(forecast.Pending) == 0 {
+		return
+	}
+
+	menu.Spacer(DefaultSpacerOptions())
+	for _, pending := range forecast.Pending {
+		options := DefaultTextOptions()
+		options.Color = forecastResourceColors[pending.Resource]
+
+		var status string
+		switch {
+		case pending.AffordableNow:
+			status = "affordable"
+		case pending.TicksToAfford < 0:
+			status = "not affordable at current rates"
+		default:
+			status = "affordable in " + formatForecastTicks(pending.TicksToAfford)
+		}
+		menu.Text(fmt.Sprintf("%s %d: %s", pending.Type, pending.Level, status), options)
+	}
+}
+
+// formatForecastTicks formats a tick count (1 tick = 1 second) as a short duration
+func formatForecastTicks(ticks int64) string {
+	d := time.Duration(ticks) * time.Second
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
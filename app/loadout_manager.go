@@ -60,6 +60,7 @@ type LoadoutManager struct {
 	deleteButtonHovered  map[int]bool
 	mergeButtonHovered   map[int]bool
 	replaceButtonHovered map[int]bool
+	shareButtonHovered   map[int]bool
 	importButtonHovered  bool
 	exportButtonHovered  bool
 	closeButtonHovered   bool
@@ -78,6 +79,7 @@ func NewLoadoutManager() *LoadoutManager {
 		deleteButtonHovered:  make(map[int]bool),
 		mergeButtonHovered:   make(map[int]bool),
 		replaceButtonHovered: make(map[int]bool),
+		shareButtonHovered:   make(map[int]bool),
 		isApplyingLoadout:    false,
 		selectedTerritories:  make(map[string]bool),
 		applyUIVisible:       false,
@@ -318,6 +320,13 @@ func (lm *LoadoutManager) handleClick(mx, my int) {
 		itemX := x + 20              // Match the drawLoadoutItem call: x+20
 		itemWidth := panelWidth - 60 // Match the drawLoadoutItem call: width-60 = 740
 
+		// Share button - coordinates relative to item position
+		shareButtonX := itemX + itemWidth - 285
+		if mx >= shareButtonX && mx <= shareButtonX+50 && my >= itemY+10 && my <= itemY+40 {
+			lm.shareLoadout(itemIndex)
+			return
+		}
+
 		// Edit button - coordinates relative to item position
 		editButtonX := itemX + itemWidth - 230
 		if mx >= editButtonX && mx <= editButtonX+50 && my >= itemY+10 && my <= itemY+40 {
@@ -377,6 +386,7 @@ func (lm *LoadoutManager) updateHoverStates(mx, my int) {
 		lm.deleteButtonHovered[i] = false
 		lm.mergeButtonHovered[i] = false
 		lm.replaceButtonHovered[i] = false
+		lm.shareButtonHovered[i] = false
 	}
 
 	// Loadout list item hovers (use same logic as drawing and click detection)
@@ -400,6 +410,10 @@ func (lm *LoadoutManager) updateHoverStates(mx, my int) {
 		itemX := x + 20              // Match the drawLoadoutItem call: x+20
 		itemWidth := panelWidth - 60 // Match the drawLoadoutItem call: width-60 = 740
 
+		// Share button hover - coordinates relative to item position (aligned with drawn button)
+		shareButtonX := itemX + itemWidth - 285
+		lm.shareButtonHovered[itemIndex] = mx >= shareButtonX && mx <= shareButtonX+50 && my >= itemY+10 && my <= itemY+40
+
 		// Edit button hover - coordinates relative to item position (aligned with drawn button)
 		editButtonX := itemX + itemWidth - 230
 		lm.editButtonHovered[itemIndex] = mx >= editButtonX && mx <= editButtonX+50 && my >= itemY+10 && my <= itemY+40
@@ -1304,6 +1318,16 @@ func (lm *LoadoutManager) drawLoadoutItem(screen *ebiten.Image, x, y, width int,
 	summaryColor := color.RGBA{180, 180, 180, 255}
 	text.Draw(screen, summaryText, smallFont, x+10, y+35, summaryColor)
 
+	// Share button
+	shareButtonX := x + width - 285
+	shareButtonColor := color.RGBA{100, 80, 150, 255}
+	if lm.shareButtonHovered[index] {
+		shareButtonColor = color.RGBA{130, 100, 200, 255}
+	}
+	vector.DrawFilledRect(screen, float32(shareButtonX), float32(y+10), 50, 30, shareButtonColor, false)
+	vector.StrokeRect(screen, float32(shareButtonX), float32(y+10), 50, 30, 2, color.RGBA{100, 100, 120, 255}, false)
+	text.Draw(screen, "Share", smallFont, shareButtonX+9, y+25, color.RGBA{255, 255, 255, 255})
+
 	// Edit button
 	editButtonX := x + width - 230
 	editButtonColor := color.RGBA{70, 100, 150, 255}
@@ -1475,6 +1499,12 @@ func (lm *LoadoutManager) importLoadouts() {
 		return
 	}
 
+	// Share codes get a preview before anything is added
+	if eruntime.IsShareCode(string(clipboardData)) {
+		PasteShareCode()
+		return
+	}
+
 	var importData LoadoutImportExport
 	err := json.Unmarshal(clipboardData, &importData)
 	if err != nil {
@@ -1560,6 +1590,40 @@ func (lm *LoadoutManager) exportLoadouts() {
 		Show()
 }
 
+// shareLoadout copies a share code for a loadout to the clipboard
+func (lm *LoadoutManager) shareLoadout(index int) {
+	if index < 0 || index >= len(lm.loadouts) {
+		return
+	}
+	loadout := lm.loadouts[index]
+	CopyShareCode("Loadout", func() (string, error) {
+		return eruntime.LoadoutShareCode(loadout)
+	})
+}
+
+// AddSharedLoadout adds a loadout from a share code, numbering the name if it is taken.
+// It returns the name the loadout was added under.
+func (lm *LoadoutManager) AddSharedLoadout(loadout typedef.Loadout) string {
+	base := loadout.Name
+	for n := 2; ; n++ {
+		taken := false
+		for _, existing := range lm.loadouts {
+			if existing.Name == loadout.Name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			break
+		}
+		loadout.Name = fmt.Sprintf("%s (%d)", base, n)
+	}
+
+	lm.loadouts = append(lm.loadouts, loadout)
+	lm.saveToFile()
+	return loadout.Name
+}
+
 // Global loadout manager instance
 var globalLoadoutManager *LoadoutManager
 
@@ -11,7 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"RueaES/alg"
 	"RueaES/eruntime" // Add eruntime import
+	"RueaES/tml"
 	"RueaES/typedef"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -30,16 +32,6 @@ func parseResourceValue(valueStr string) int {
 	return 0
 }
 
-// Marker represents a location marker on the map
-type Marker struct {
-	X         float64
-	Y         float64
-	Label     string
-	Color     color.RGBA
-	Size      float64
-	IsVisible bool
-}
-
 type rangeFilterState struct {
 	key         string
 	label       string
@@ -93,11 +85,14 @@ type MapView struct {
 	showCoordinates bool      // Toggle coordinate display
 	selectedRegionX int       // X coordinate of selected region
 	selectedRegionY int       // Y coordinate of selected region
-	markers         []Marker  // List of markers on the map
 	showTerritories bool      // Toggle territory display
 	lastClickTime   time.Time // Time of last click for double-click detection
 	lastUpdateTime  time.Time // Time of last update for delta time calculation
 
+	// Annotation layers cached between edits
+	annotations    []typedef.AnnotationLayer
+	annotationsVer uint64 // eruntime.AnnotationsVersion of the cached layers
+
 	// Map loading from Wynntils GitHub
 	mapManager *MapManager // Manager for loading map data
 	isLoading  bool        // Whether the map is currently loading
@@ -144,6 +139,8 @@ type MapView struct {
 		borderMode         string
 		routeCompatibility string
 		textQuery          string
+		territoryQuery     string
+		compiledQuery      *tml.Query
 	}
 
 	// State management menu for tick controls
@@ -168,6 +165,11 @@ type MapView struct {
 	// Analysis modal
 	analysisModal   *AnalysisModal
 	analysisRunning bool
+	taxPolicy       *eruntime.TaxPolicyReport // Last tax policy recommendation, applied on request
+
+	// HQ move waiting for confirmation because it lowers treasury bonuses
+	pendingHQMove        string
+	pendingHQMoveWarning string
 
 	// Auto setup modal
 	autoSetupModal *AutoSetupModal
@@ -227,7 +229,6 @@ func NewMapView() *MapView {
 		showTerritories: true,  // Territories enabled by default
 		selectedRegionX: -1,
 		selectedRegionY: -1,
-		markers:         make([]Marker, 0),
 		lastClickTime:   time.Time{}, // Initialize lastClickTime
 
 		// Initialize map manager
@@ -276,6 +277,8 @@ func NewMapView() *MapView {
 			borderMode         string
 			routeCompatibility string
 			textQuery          string
+			territoryQuery     string
+			compiledQuery      *tml.Query
 		}{
 			ranges:             make(map[string]*rangeFilterState),
 			hqMode:             "all",
@@ -308,6 +311,12 @@ func NewMapView() *MapView {
 	if mapView.analysisModal != nil {
 		mapView.analysisModal.SetOnAnalyze(mapView.runChokepointAnalysis)
 		mapView.analysisModal.SetOnHQAnalyze(mapView.runHQPlacementAnalysis)
+		mapView.analysisModal.SetOnLossAnalyze(mapView.runTerritoryLossAnalysis)
+		mapView.analysisModal.SetOnSeasonAnalyze(mapView.runWarSeasonSimulation)
+		mapView.analysisModal.SetOnExpansionPlan(mapView.runExpansionPlanner)
+		mapView.analysisModal.SetOnTaxPolicy(mapView.runTaxPolicyOptimizer)
+		mapView.analysisModal.SetOnTaxApply(mapView.applyTaxPolicy)
+		mapView.analysisModal.SetOnRivalAnalyze(mapView.runRivalChokepointAnalysis)
 	}
 	if mapView.autoSetupModal != nil {
 		mapView.refreshAutoSetupGuildOptions()
@@ -388,6 +397,29 @@ func NewMapView() *MapView {
 		// fmt.Println("[MAP] Territory guild assignments updated after state change")
 	})
 
+	// Notify users when a tribute can no longer be delivered
+	eruntime.SetTributeErrorCallback(func(tributeID, message string) {
+		NewToast().
+			Text("Tribute could not be delivered", ToastOption{Colour: color.RGBA{255, 180, 80, 255}}).
+			Text(message, ToastOption{Colour: color.RGBA{255, 220, 200, 255}}).
+			AutoClose(5 * time.Second).
+			Show()
+	})
+
+	// Tell users which saved manual routes could not be restored after loading a state
+	eruntime.SetManualRoutesMissingCallback(func(missing []string) {
+		toast := NewToast().
+			Text(fmt.Sprintf("%d saved manual route(s) no longer exist", len(missing)), ToastOption{Colour: color.RGBA{255, 180, 80, 255}})
+		for i, route := range missing {
+			if i == 5 {
+				toast.Text(fmt.Sprintf("...and %d more", len(missing)-i), ToastOption{Colour: color.RGBA{255, 220, 200, 255}})
+				break
+			}
+			toast.Text(route, ToastOption{Colour: color.RGBA{255, 220, 200, 255}})
+		}
+		toast.AutoClose(8 * time.Second).Show()
+	})
+
 	// Register GPU compute failure callback to notify users and revert to CPU mode
 	eruntime.SetGPUComputeFailureCallback(func(message, stackTrace string) {
 		toast := NewToast().
@@ -1308,8 +1340,8 @@ func (m *MapView) drawOverlayElements(screen *ebiten.Image) {
 		m.territoriesManager.DrawTerritories(screen, m.scale, m.offsetX, m.offsetY, m.hoveredTerritory)
 	}
 
-	// Draw markers
-	m.drawMarkers(screen, m.offsetX)
+	// Draw annotation layers
+	m.drawAnnotations(screen, m.offsetX)
 
 	// Draw selected region highlight if any
 	m.drawSelectedRegion(screen, m.offsetX)
@@ -1376,40 +1408,83 @@ func (m *MapView) drawOverlayElements(screen *ebiten.Image) {
 	}
 }
 
-// drawMarkers draws all visible markers on the map
-func (m *MapView) drawMarkers(screen *ebiten.Image, mapOffsetX float64) {
-	if len(m.markers) == 0 || !m.mapManager.IsLoaded() {
+// drawAnnotations draws the visible annotation layers in order, so later layers are on top
+func (m *MapView) drawAnnotations(screen *ebiten.Image, mapOffsetX float64) {
+	if !m.mapManager.IsLoaded() {
 		return
 	}
 
-	for _, marker := range m.markers {
-		if !marker.IsVisible {
+	// Layers only change on edits, so copy them again only when the version moves
+	if version := eruntime.AnnotationsVersion(); version != m.annotationsVer {
+		m.annotations = eruntime.GetAnnotationLayers()
+		m.annotationsVer = version
+	}
+
+	toScreen := func(p typedef.AnnotationPoint) (float32, float32) {
+		return float32(p.X*m.scale + mapOffsetX), float32(p.Y*m.scale + m.offsetY)
+	}
+
+	for _, layer := range m.annotations {
+		if !layer.Visible {
 			continue
 		}
+		for _, annotation := range layer.Annotations {
+			if !annotation.Visible || len(annotation.Points) == 0 {
+				continue
+			}
 
-		// Calculate screen position from map coordinates
-		screenX := marker.X*m.scale + mapOffsetX
-		screenY := marker.Y*m.scale + m.offsetY
+			annotationColor := layer.Color.ToRGBA()
+			if annotation.Color != nil {
+				annotationColor = annotation.Color.ToRGBA()
+			}
+			x, y := toScreen(annotation.Points[0])
 
-		// Scale marker size with map scale
-		size := marker.Size * m.scale
+			switch annotation.Kind {
+			case typedef.AnnotationMarker:
+				size := annotation.Size
+				if size <= 0 {
+					size = 10
+				}
+				radius := float32(size * m.scale / 2)
+				vector.FillCircle(screen, x, y, radius+1, color.RGBA{255, 255, 255, 200}, true)
+				vector.FillCircle(screen, x, y, radius, annotationColor, true)
+
+				// Draw caption if we're zoomed in enough
+				if m.scale > 0.5 && annotation.Text != "" {
+					ebitenutil.DebugPrintAt(screen, annotation.Text, int(x)-len(annotation.Text)*3, int(y-radius)-14)
+				}
 
-		// Draw marker circle
-		radius := size / 2
-		vector.DrawFilledCircle(screen, float32(screenX), float32(screenY), float32(radius), marker.Color, false)
+			case typedef.AnnotationPolyline, typedef.AnnotationPolygon:
+				width := annotation.Size
+				if width <= 0 {
+					width = 2
+				}
+				var path vector.Path
+				path.MoveTo(x, y)
+				for _, p := range annotation.Points[1:] {
+					px, py := toScreen(p)
+					path.LineTo(px, py)
+				}
+
+				if annotation.Kind == typedef.AnnotationPolygon {
+					path.Close()
+					fill := &vector.DrawPathOptions{AntiAlias: true}
+					fill.ColorScale.ScaleWithColor(color.RGBA{annotationColor.R, annotationColor.G, annotationColor.B, 255})
+					fill.ColorScale.ScaleAlpha(0.25 * float32(annotationColor.A) / 255)
+					vector.FillPath(screen, &path, nil, fill)
+				}
 
-		// Draw marker outline
-		outlineColor := color.RGBA{255, 255, 255, 200}
-		vector.DrawFilledCircle(screen, float32(screenX), float32(screenY), float32(radius+1), outlineColor, false)
+				stroke := &vector.DrawPathOptions{AntiAlias: true}
+				stroke.ColorScale.ScaleWithColor(annotationColor)
+				vector.StrokePath(screen, &path, &vector.StrokeOptions{
+					Width:    float32(width * m.scale),
+					LineCap:  vector.LineCapRound,
+					LineJoin: vector.LineJoinRound,
+				}, stroke)
 
-		// Draw label if we're zoomed in enough
-		if m.scale > 0.5 && marker.Label != "" {
-			ebitenutil.DebugPrintAt(
-				screen,
-				marker.Label,
-				int(screenX-float64(len(marker.Label)*3)),
-				int(screenY-radius-10),
-			)
+			case typedef.AnnotationLabel:
+				ebitenutil.DebugPrintAt(screen, annotation.Text, int(x)-len(annotation.Text)*3, int(y)-8)
+			}
 		}
 	}
 }
@@ -2488,23 +2563,29 @@ func (m *MapView) ToggleTerritories() {
 	m.showTerritories = !m.showTerritories
 }
 
-// AddMarker adds a new marker to the map
+// markersLayer is the annotation layer the add and clear marker keybinds work on
+const markersLayer = "Markers"
+
+// AddMarker adds a marker annotation to the Markers layer
 func (m *MapView) AddMarker(x, y float64, label string, markerColor color.RGBA, size float64) {
-	// unused now
-	marker := Marker{
-		X:         x,
-		Y:         y,
-		Label:     label,
-		Color:     markerColor,
-		Size:      size,
-		IsVisible: true,
-	}
-	m.markers = append(m.markers, marker)
+	markerRGBA := typedef.RGBAColorFromColor(markerColor)
+	_, err := eruntime.AddAnnotation(markersLayer, typedef.Annotation{
+		Kind:    typedef.AnnotationMarker,
+		Points:  []typedef.AnnotationPoint{{X: x, Y: y}},
+		Text:    label,
+		Color:   &markerRGBA,
+		Size:    size,
+		Visible: true,
+	})
+	if err != nil {
+		showResultToast("Failed to add marker: "+err.Error(), false)
+	}
 }
 
-// ClearMarkers removes all markers from the map
+// ClearMarkers removes the Markers annotation layer
 func (m *MapView) ClearMarkers() {
-	m.markers = make([]Marker, 0)
+	// The layer does not exist until the first marker is added
+	_ = eruntime.RemoveAnnotationLayer(markersLayer)
 }
 
 // GetMapCoordinates converts screen coordinates to world coordinates
@@ -2806,21 +2887,10 @@ func (m *MapView) setupContextMenu(mouseX, mouseY int) {
 			Divider().
 			ContextMenu("Apply Loadout", "", true, loadoutSubmenu).
 			Option("Set as HQ", "", !eruntime.GetTerritory(clickedTerritory).HQ, func() {
-				// Set the clicked territory as HQ
-				territory := eruntime.GetTerritory(clickedTerritory)
-				if territory == nil {
-					return
+				// Set the clicked territory as HQ, showing the treasury warning in its menu if needed
+				if !m.requestHQMove(clickedTerritory) {
+					m.OpenTerritoryMenu(clickedTerritory)
 				}
-
-				// Set HQ field as true
-				eruntime.Set(clickedTerritory, typedef.TerritoryOptions{
-					Upgrades:    territory.Options.Upgrade.Set,
-					Bonuses:     territory.Options.Bonus.Set,
-					Tax:         territory.Tax,
-					RoutingMode: territory.RoutingMode,
-					Border:      territory.Border,
-					HQ:          true,
-				})
 			})
 	} else {
 		// Context menu for empty map area
@@ -3035,6 +3105,50 @@ func (m *MapView) populateTerritoryMenu(territoryName string) {
 		updateProductionValues()
 	})
 
+	// Held time controls backdate the capture so treasury can be planned without an override
+	treasuryOutlookText := NewMenuText(treasuryOutlook(territory.Name, territory.Guild.Tag), DefaultTextOptions())
+	productionMenu.AddElement(treasuryOutlookText)
+
+	heldInputOptions := DefaultTextInputOptions()
+	heldInputOptions.Width = 220
+	heldInputOptions.MaxLength = 25
+	heldInputOptions.Placeholder = "e.g. 5d12h or 2026-10-01"
+	heldInput := NewMenuTextInput("Held for / since", "", heldInputOptions, nil)
+	productionMenu.AddElement(heldInput)
+
+	setHeld := func(wholeGuild bool) {
+		held, err := parseHeldTime(heldInput.GetValue())
+		if err != nil {
+			treasuryOutlookText.SetText(err.Error())
+			return
+		}
+
+		names := []string{territory.Name}
+		if wholeGuild {
+			names = names[:0]
+			for _, t := range eruntime.GetTerritories() {
+				if t == nil {
+					continue
+				}
+				t.Mu.RLock()
+				if t.Guild.Tag == territory.Guild.Tag {
+					names = append(names, t.Name)
+				}
+				t.Mu.RUnlock()
+			}
+		}
+		eruntime.SetTerritoriesHeldFor(names, held)
+
+		treasuryOutlookText.SetText(treasuryOutlook(territory.Name, territory.Guild.Tag))
+		updateProductionValues()
+	}
+	productionMenu.Button("Set Held Time", DefaultButtonOptions(), func() {
+		setHeld(false)
+	})
+	productionMenu.Button(fmt.Sprintf("Set Held Time for All of %s", territory.Guild.Tag), DefaultButtonOptions(), func() {
+		setHeld(true)
+	})
+
 	// Connected Territories (collapsible)
 	// connectedMenu := m.edgeMenu.CollapsibleMenu("Connected Territories", DefaultCollapsibleMenuOptions())
 	// if len(territory.ConnectedTerritories) > 0 {
@@ -3225,6 +3339,40 @@ func (m *MapView) populateTerritoryMenu(territoryName string) {
 	currentLevelTextOptions.Color = currentLevelColor
 	currentMenu.Text(fmt.Sprintf("%s (%d)", currentLevelString, territory.LevelInt), currentLevelTextOptions)
 
+	// War estimate against the configured war party, using the current stats
+	if combat, err := eruntime.CalculateTowerCombat(territory.Name, nil); err == nil {
+		party := eruntime.GetRuntimeOptions().WarParty
+		if party.Players <= 0 {
+			party = typedef.DefaultWarParty()
+		}
+		partyLabel := fmt.Sprintf("War (%d players", party.Players)
+		if party.Tanked {
+			partyLabel += ", tanked)"
+		} else {
+			partyLabel += ")"
+		}
+		warMenu := towerStatsMenu.CollapsibleMenu(partyLabel, DefaultCollapsibleMenuOptions())
+		warMenu.Text(fmt.Sprintf("Effective HP: %.0f", combat.EffectiveHealth), DefaultTextOptions())
+		warMenu.Text(fmt.Sprintf("Tower DPS: %.0f (%d targets)", combat.TowerDPS, combat.Targets), DefaultTextOptions())
+		if combat.AuraDPS > 0 || combat.VolleyDPS > 0 {
+			warMenu.Text(fmt.Sprintf("Aura: %.0f/s  Volley: %.0f/s", combat.AuraDPS, combat.VolleyDPS), DefaultTextOptions())
+		}
+		if combat.HQ {
+			warMenu.Text(fmt.Sprintf("HQ multiplier: %.2fx", combat.ExternalMultiplier), DefaultTextOptions())
+		}
+		warMenu.Text(fmt.Sprintf("Party DPS: %.0f", combat.PartyDPS), DefaultTextOptions())
+		warMenu.Text("Time to capture: "+formatForecastTicks(int64(math.Ceil(combat.TimeToCapture))), DefaultTextOptions())
+
+		outcomeOptions := DefaultTextOptions()
+		if combat.Capturable {
+			outcomeOptions.Color = color.RGBA{128, 255, 0, 255}
+			warMenu.Text("Outcome: captured", outcomeOptions)
+		} else {
+			outcomeOptions.Color = color.RGBA{255, 80, 80, 255}
+			warMenu.Text("Outcome: party wiped after "+formatForecastTicks(int64(combat.PartySurvival)), outcomeOptions)
+		}
+	}
+
 	// Links (collapsible) - Links to connections and externals
 	totalBonus := 0
 	linksMenu := m.edgeMenu.CollapsibleMenu("Links", DefaultCollapsibleMenuOptions())
@@ -3393,6 +3541,10 @@ func (m *MapView) populateTerritoryMenu(territoryName string) {
 		int(territory.Storage.At.Crops), int(territory.Storage.Capacity.Crops)*10, int(transitCrops), int(territoryStats.CurrentGeneration.Crops),
 		color.RGBA{255, 255, 0, 255}) // Yellow for crops
 
+	// Forecast (collapsible) - time to full/empty and pending upgrade affordability
+	forecastMenu := m.edgeMenu.CollapsibleMenu("Forecast", DefaultCollapsibleMenuOptions())
+	populateForecastMenu(forecastMenu, territoryName)
+
 	// Trading Routes (collapsible)
 	routesMenu := m.edgeMenu.CollapsibleMenu("Trading Routes", DefaultCollapsibleMenuOptions())
 	if len(territory.TradingRoutes) > 0 {
@@ -3800,18 +3952,16 @@ func (m *MapView) populateTerritoryMenu(territoryName string) {
 		hqButtonText = "Already HQ"
 	}
 
+	if !territory.HQ && m.pendingHQMove == territoryName {
+		warningOptions := DefaultTextOptions()
+		warningOptions.Color = color.RGBA{255, 170, 80, 255}
+		m.edgeMenu.Text(m.pendingHQMoveWarning, warningOptions)
+		hqButtonText = "Confirm HQ Move"
+	}
+
 	m.edgeMenu.Button(hqButtonText, hqButtonOptions, func() {
 		if !territory.HQ {
-			// Call the Set function to set this territory as HQ
-			opts := typedef.TerritoryOptions{
-				Upgrades:    territory.Options.Upgrade.Set,
-				Bonuses:     territory.Options.Bonus.Set,
-				Tax:         territory.Tax,
-				RoutingMode: territory.RoutingMode,
-				Border:      territory.Border,
-				HQ:          true,
-			}
-			eruntime.Set(territoryName, opts)
+			m.requestHQMove(territoryName)
 			// Refresh the menu to show updated state
 			m.populateTerritoryMenu(territoryName)
 		}
@@ -3833,6 +3983,38 @@ func (m *MapView) populateTerritoryMenu(territoryName string) {
 	m.edgeMenu.RestoreCollapsedStates(collapsedStates)
 }
 
+// requestHQMove sets the territory as its guild's HQ. A move that lowers treasury bonuses is held back
+// with a warning until it is requested a second time; it returns whether the HQ was moved.
+func (m *MapView) requestHQMove(territoryName string) bool {
+	territory := eruntime.GetTerritory(territoryName)
+	if territory == nil || territory.HQ {
+		return false
+	}
+
+	if m.pendingHQMove != territoryName {
+		impact, err := eruntime.TreasuryHQMoveImpact(territory.Guild.Tag, territoryName)
+		if err == nil && len(impact.TreasuryLowered) > 0 {
+			loss := impact.TreasuryLoss
+			m.pendingHQMove = territoryName
+			m.pendingHQMoveWarning = fmt.Sprintf("Lowers treasury on %d territories (-%.0f em, -%.0f res per hour)",
+				len(impact.TreasuryLowered), loss.Emeralds, loss.Ores+loss.Wood+loss.Fish+loss.Crops)
+			return false
+		}
+	}
+	m.pendingHQMove = ""
+	m.pendingHQMoveWarning = ""
+
+	eruntime.Set(territoryName, typedef.TerritoryOptions{
+		Upgrades:    territory.Options.Upgrade.Set,
+		Bonuses:     territory.Options.Bonus.Set,
+		Tax:         territory.Tax,
+		RoutingMode: territory.RoutingMode,
+		Border:      territory.Border,
+		HQ:          true,
+	})
+	return true
+}
+
 // OpenTerritoryMenu populates and displays the territory EdgeMenu while ensuring other menus are closed as needed.
 func (m *MapView) OpenTerritoryMenu(territoryName string) {
 	if m.edgeMenu == nil {
@@ -4294,6 +4476,8 @@ func (m *MapView) resetAllFilters() {
 	m.filterOptions.borderMode = "all"
 	m.filterOptions.routeCompatibility = "all"
 	m.filterOptions.textQuery = ""
+	m.filterOptions.territoryQuery = ""
+	m.filterOptions.compiledQuery = nil
 }
 
 func (m *MapView) addRangeControl(section *CollapsibleMenu, state *rangeFilterState) {
@@ -4412,6 +4596,23 @@ func (m *MapView) buildFilterMenu() {
 	searchSection.Text("Use * for wildcards. Press / for /regex/gi.", searchHelp)
 	searchSection.SetCollapsed(false)
 
+	querySection := m.filterMenu.CollapsibleMenu("Territory Query", DefaultCollapsibleMenuOptions())
+	queryInputOpts := DefaultTextInputOptions()
+	queryInputOpts.Width = 260
+	queryInputOpts.MaxLength = 300
+	queryInputOpts.Placeholder = "select where level >= high"
+	queryInputOpts.FontSize = 14
+	queryStatus := NewMenuText("", searchHelp)
+	queryInput := NewMenuTextInput("Query", m.filterOptions.territoryQuery, queryInputOpts, func(value string) {
+		m.filterOptions.territoryQuery = value
+		queryStatus.SetText(m.compileTerritoryQuery())
+		m.onFiltersChanged()
+	})
+	querySection.AddElement(queryInput)
+	queryStatus.SetText(m.compileTerritoryQuery())
+	querySection.AddElement(queryStatus)
+	querySection.Text("e.g. select where guild = \"ABC\" and resource = crops", searchHelp)
+
 	modeSection := m.filterMenu.CollapsibleMenu("Matching Mode", DefaultCollapsibleMenuOptions())
 	modeOptions := DefaultToggleSwitchOptions()
 	modeOptions.Options = []string{"Contains", "Any", "Approximate"}
@@ -4796,6 +4997,25 @@ func (m *MapView) buildFilterMenu() {
 	resourceSection.SetCollapsed(false)
 }
 
+// compileTerritoryQuery parses the territory query filter and returns a status line for the menu.
+// Invalid queries and set queries leave the filter inactive.
+func (m *MapView) compileTerritoryQuery() string {
+	m.filterOptions.compiledQuery = nil
+	source := strings.TrimSpace(m.filterOptions.territoryQuery)
+	if source == "" {
+		return "Leave empty to skip the query."
+	}
+	query, err := tml.Parse(source)
+	if err != nil {
+		return err.Error()
+	}
+	if query.Kind != tml.QuerySelect {
+		return "Only select queries can filter the map."
+	}
+	m.filterOptions.compiledQuery = query
+	return "Query active."
+}
+
 func (m *MapView) onFiltersChanged() {
 	m.applyFilters()
 }
@@ -4979,12 +5199,19 @@ func (m *MapView) applyFilters() {
 
 	matches := make(map[string]float64)
 	anyConditions := false
+	territoryQuery := m.filterOptions.compiledQuery
 
 	for _, t := range territories {
 		if t == nil {
 			continue
 		}
 
+		// The query snapshot takes the territory lock itself
+		var querySnapshot *tml.Snapshot
+		if territoryQuery != nil {
+			querySnapshot = tml.NewSnapshot(t)
+		}
+
 		t.Mu.RLock()
 		name := t.Name
 		opts := t.Options
@@ -5183,6 +5410,24 @@ func (m *MapView) applyFilters() {
 			}
 		}
 
+		if territoryQuery != nil {
+			conditions++
+			cond := territoryQuery.Match(querySnapshot)
+			if approxMode {
+				if cond {
+					weight = math.Min(weight, 1)
+				} else {
+					weight = 0
+				}
+			} else {
+				if cond {
+					matchAny = true
+				} else {
+					matchAll = false
+				}
+			}
+		}
+
 		var matched bool
 		if approxMode {
 			if conditions == 0 {
@@ -5437,7 +5682,7 @@ func (m *MapView) runChokepointAnalysis(guildTag string) {
 	m.analysisModal.SetStatus("Analysis complete. Switch to Analysis view to visualize.")
 }
 
-// runHQPlacementAnalysis finds the top HQ candidates for the chosen guild and pushes them to the Analysis view.
+// runHQPlacementAnalysis simulates the top HQ candidates for the chosen guild and pushes them to the Analysis view.
 func (m *MapView) runHQPlacementAnalysis(guildTag string) {
 	if m.analysisModal == nil {
 		return
@@ -5462,29 +5707,41 @@ func (m *MapView) runHQPlacementAnalysis(guildTag string) {
 		m.analysisModal.SetAnalyzing(false)
 	}()
 
-	candidates, err := eruntime.ComputeHQSuggestionsForGuild(guildTag)
+	simulations, err := eruntime.SimulateHQPlacements(guildTag, 0, 0)
 	if err != nil {
 		m.analysisModal.SetStatus(err.Error())
 		return
 	}
 
-	if len(candidates) == 0 {
+	if len(simulations) == 0 {
 		m.analysisModal.SetStatus("No HQ candidates found")
 		return
 	}
 
 	limit := 5
-	if len(candidates) < limit {
-		limit = len(candidates)
+	if len(simulations) < limit {
+		limit = len(simulations)
 	}
 
+	// Simulation scores can be negative; shift them so the weakest shown spot still gets a tint
+	lowest := simulations[limit-1].Score
 	scores := make(map[string]float64, limit)
 	ordered := make([]string, 0, limit)
+	lines := make([]string, 0, limit)
 	for i := 0; i < limit; i++ {
-		cand := candidates[i]
-		scores[cand.Name] = cand.Score
-		ordered = append(ordered, cand.Name)
+		sim := simulations[i]
+		scores[sim.Name] = sim.Score - lowest + 0.1
+		ordered = append(ordered, sim.Name)
+		net := sim.NetPerHour.Emeralds + sim.NetPerHour.Ores + sim.NetPerHour.Wood + sim.NetPerHour.Fish + sim.NetPerHour.Crops
+		tax := sim.RouteTaxPerHour.Emeralds + sim.RouteTaxPerHour.Ores + sim.RouteTaxPerHour.Wood + sim.RouteTaxPerHour.Fish + sim.RouteTaxPerHour.Crops
+		line := fmt.Sprintf("%d. %s  conn %.1f  EHP %s  net %+.0f/h  tax %.0f/h  deficits %d",
+			i+1, sim.Name, sim.ConnectionScore, eruntime.FormatValue(sim.EffectiveHealth), net, tax, len(sim.Deficits)+len(sim.Unreachable))
+		if len(sim.TreasuryLowered) > 0 {
+			line += fmt.Sprintf("  treasury down %d", len(sim.TreasuryLowered))
+		}
+		lines = append(lines, line)
 	}
+	m.analysisModal.SetHQResult(lines)
 
 	if m.territoryViewSwitcher != nil {
 		m.territoryViewSwitcher.SetAnalysisResults(guildTag, scores)
@@ -5494,6 +5751,348 @@ func (m *MapView) runHQPlacementAnalysis(guildTag string) {
 	m.analysisModal.SetStatus(fmt.Sprintf("Top HQ spots: %s", strings.Join(ordered, ", ")))
 }
 
+// runTerritoryLossAnalysis reports what the chosen guild would lose along with the given territories
+// and tints the removed, cut off and re-taxed territories in the Analysis view.
+func (m *MapView) runTerritoryLossAnalysis(guildTag string, territories []string) {
+	if m.analysisModal == nil {
+		return
+	}
+
+	if m.analysisRunning {
+		m.analysisModal.SetStatus("Analysis already running...")
+		return
+	}
+
+	m.analysisRunning = true
+	m.analysisModal.SetAnalyzing(true)
+	defer func() {
+		m.analysisRunning = false
+		m.analysisModal.SetAnalyzing(false)
+	}()
+
+	report, err := eruntime.AnalyzeTerritoryLoss(guildTag, territories)
+	if err != nil {
+		m.analysisModal.SetLossResult([]string{err.Error()})
+		return
+	}
+
+	cutOff := "None cut off from the HQ"
+	if report.HQLost {
+		cutOff = fmt.Sprintf("HQ lost: %d territories cut off", len(report.CutOff))
+	} else if len(report.CutOff) > 0 {
+		cutOff = fmt.Sprintf("Cut off (%d): %s", len(report.CutOff), strings.Join(limitNames(report.CutOff, 6), ", "))
+	}
+
+	lost := report.LostProduction
+	production := fmt.Sprintf("Lost per hour: %.0f emeralds, %.0f ores, %.0f wood, %.0f fish, %.0f crops",
+		lost.Emeralds, lost.Ores, lost.Wood, lost.Fish, lost.Crops)
+
+	taxes := "No route tax changes"
+	if len(report.RouteTaxChanges) > 0 {
+		changes := make([]string, 0, len(report.RouteTaxChanges))
+		for _, change := range report.RouteTaxChanges {
+			changes = append(changes, fmt.Sprintf("%s %.0f%%->%.0f%%", change.Territory, change.Before*100, change.After*100))
+		}
+		taxes = fmt.Sprintf("Tax changes (%d): %s", len(changes), strings.Join(limitNames(changes, 4), ", "))
+	}
+
+	warnings := "No new warnings"
+	if len(report.NewWarnings) > 0 {
+		items := make([]string, 0, len(report.NewWarnings))
+		for _, warning := range report.NewWarnings {
+			items = append(items, fmt.Sprintf("%s (%s)", warning.Territory, strings.Join(warning.Warnings, ", ")))
+		}
+		warnings = fmt.Sprintf("New warnings: %s", strings.Join(limitNames(items, 3), ", "))
+	}
+
+	m.analysisModal.SetLossResult([]string{cutOff, production, taxes, warnings})
+
+	scores := make(map[string]float64, len(report.Removed)+len(report.CutOff)+len(report.RouteTaxChanges))
+	for _, change := range report.RouteTaxChanges {
+		scores[change.Territory] = 0.3
+	}
+	for _, name := range report.CutOff {
+		scores[name] = 0.7
+	}
+	for _, name := range report.Removed {
+		scores[name] = 1.0
+	}
+	if m.territoryViewSwitcher != nil {
+		m.territoryViewSwitcher.SetAnalysisResults(report.GuildTag, scores)
+		m.territoryViewSwitcher.SetCurrentView(ViewAnalysis)
+	}
+}
+
+// runWarSeasonSimulation simulates a war season in the background and tints the most lost territories
+func (m *MapView) runWarSeasonSimulation(guildTag string, attacksPerHour float64) {
+	if m.analysisModal == nil {
+		return
+	}
+
+	if m.analysisRunning {
+		m.analysisModal.SetStatus("Analysis already running...")
+		return
+	}
+
+	m.analysisRunning = true
+	m.analysisModal.SetAnalyzing(true)
+	m.analysisModal.SetSeasonResult([]string{"Simulating..."})
+
+	// Hundreds of seasons take a while, so the simulation stays off the UI thread
+	go func() {
+		defer func() {
+			m.analysisRunning = false
+			m.analysisModal.SetAnalyzing(false)
+		}()
+
+		report, err := eruntime.SimulateWarSeason(eruntime.WarSeasonConfig{GuildTag: guildTag, AttacksPerHour: attacksPerHour})
+		if err != nil {
+			m.analysisModal.SetSeasonResult([]string{err.Error()})
+			return
+		}
+
+		income := report.ExpectedIncomePerHour
+		baseline := report.BaselineIncome.Emeralds / float64(report.Config.Hours)
+		incomeLine := fmt.Sprintf("Income/h: %+.0f em (no wars %+.0f), %+.0f resources",
+			income.Emeralds, baseline, income.Ores+income.Wood+income.Fish+income.Crops)
+
+		hqLine := fmt.Sprintf("HQ survives %.0f%% of seasons, %.1f captures per season", report.HQSurvival*100, report.ExpectedLosses)
+		if report.MeanHQLossHour >= 0 {
+			hqLine += fmt.Sprintf(", HQ falls around hour %.0f", report.MeanHQLossHour)
+		}
+
+		lostLine := "No territories lost"
+		if len(report.MostLost) > 0 {
+			items := make([]string, 0, len(report.MostLost))
+			for _, loss := range report.MostLost {
+				items = append(items, fmt.Sprintf("%s %.0f%%", loss.Territory, loss.LossRate*100))
+			}
+			lostLine = "Most lost: " + strings.Join(limitNames(items, 4), ", ")
+		}
+		m.analysisModal.SetSeasonResult([]string{incomeLine, hqLine, lostLine})
+
+		scores := make(map[string]float64, len(report.MostLost))
+		for _, loss := range report.MostLost {
+			scores[loss.Territory] = loss.LossRate
+		}
+		if m.territoryViewSwitcher != nil {
+			m.territoryViewSwitcher.SetAnalysisResults(guildTag, scores)
+			m.territoryViewSwitcher.SetCurrentView(ViewAnalysis)
+		}
+	}()
+}
+
+// runExpansionPlanner plans claim expansions and overlays them on the map, best plan brightest
+func (m *MapView) runExpansionPlanner(guildTag string, targetSize int, goals []string) {
+	if m.analysisModal == nil {
+		return
+	}
+
+	if m.analysisRunning {
+		m.analysisModal.SetStatus("Analysis already running...")
+		return
+	}
+
+	// Listed resources count fully; the rest only break ties between otherwise equal plans
+	weights := typedef.BasicResources{Emeralds: 1, Ores: 1, Wood: 1, Fish: 1, Crops: 1}
+	if len(goals) > 0 {
+		weights = typedef.BasicResources{Emeralds: 0.1, Ores: 0.1, Wood: 0.1, Fish: 0.1, Crops: 0.1}
+		for _, goal := range goals {
+			switch strings.ToLower(goal) {
+			case "emerald", "emeralds":
+				weights.Emeralds = 1
+			case "ore", "ores":
+				weights.Ores = 1
+			case "wood":
+				weights.Wood = 1
+			case "fish":
+				weights.Fish = 1
+			case "crop", "crops":
+				weights.Crops = 1
+			default:
+				m.analysisModal.SetExpansionResult([]string{fmt.Sprintf("Unknown resource '%s'", goal)})
+				return
+			}
+		}
+	}
+
+	m.analysisRunning = true
+	m.analysisModal.SetAnalyzing(true)
+	defer func() {
+		m.analysisRunning = false
+		m.analysisModal.SetAnalyzing(false)
+	}()
+
+	plans, err := eruntime.PlanClaimExpansion(guildTag, alg.ExpansionConfig{TargetSize: targetSize, Goals: weights, MaxPlans: expansionResultLines})
+	if err != nil {
+		m.analysisModal.SetExpansionResult([]string{err.Error()})
+		return
+	}
+	if len(plans) == 0 {
+		m.analysisModal.SetExpansionResult([]string{"No territories left to expand into"})
+		return
+	}
+
+	lines := make([]string, 0, len(plans))
+	scores := make(map[string]float64)
+	for i, plan := range plans {
+		gen := plan.AddedGeneration
+		lines = append(lines, fmt.Sprintf("%d. %s  +%.0f em +%.0f res/h  HQ +%d/+%d  borders %d",
+			i+1, strings.Join(limitNames(plan.Territories, 4), ", "), gen.Emeralds, gen.Ores+gen.Wood+gen.Fish+gen.Crops,
+			plan.HQConnections, plan.HQExternals, plan.ForeignBorders))
+
+		tint := 1.0 - 0.2*float64(i)
+		for _, name := range plan.Territories {
+			scores[name] = math.Max(scores[name], tint)
+		}
+	}
+	m.analysisModal.SetExpansionResult(lines)
+
+	if m.territoryViewSwitcher != nil {
+		m.territoryViewSwitcher.SetAnalysisResults(guildTag, scores)
+		m.territoryViewSwitcher.SetCurrentView(ViewAnalysis)
+	}
+}
+
+// runTaxPolicyOptimizer searches tax and border settings in the background and tints the busiest transit territories
+func (m *MapView) runTaxPolicyOptimizer(guildTag, objective string) {
+	if m.analysisModal == nil {
+		return
+	}
+
+	if m.analysisRunning {
+		m.analysisModal.SetStatus("Analysis already running...")
+		return
+	}
+
+	m.analysisRunning = true
+	m.analysisModal.SetAnalyzing(true)
+	m.analysisModal.SetTaxResult([]string{"Rerouting foreign guilds..."})
+
+	// Every candidate setting reroutes the map's foreign territories, so this stays off the UI thread
+	go func() {
+		defer func() {
+			m.analysisRunning = false
+			m.analysisModal.SetAnalyzing(false)
+		}()
+
+		report, err := eruntime.OptimizeTaxPolicy(eruntime.TaxPolicyConfig{GuildTag: guildTag, Objective: eruntime.TaxPolicyObjective(objective)})
+		if err != nil {
+			m.analysisModal.SetTaxResult([]string{err.Error()})
+			return
+		}
+		m.taxPolicy = report
+
+		now, best := report.Current, report.Recommended
+		basicTotal := func(res typedef.BasicResources) float64 {
+			return res.Emeralds + res.Ores + res.Wood + res.Fish + res.Crops
+		}
+		totalLine := fmt.Sprintf("Tax/h: %.0f -> %.0f, rival throughput/h: %.0f -> %.0f",
+			basicTotal(now.Revenue), basicTotal(best.Revenue), now.RivalThroughput, best.RivalThroughput)
+
+		var changes []string
+		scores := make(map[string]float64, len(report.Settings))
+		for _, setting := range report.Settings {
+			scores[setting.Territory] = setting.Throughput
+			if !setting.Changed {
+				continue
+			}
+			if setting.Border == typedef.BorderClosed {
+				changes = append(changes, setting.Territory+" closed")
+			} else {
+				changes = append(changes, fmt.Sprintf("%s %.0f%%", setting.Territory, setting.Tax*100))
+			}
+		}
+		changeLine := "Current settings are already the best found"
+		if len(changes) > 0 {
+			changeLine = "Set: " + strings.Join(limitNames(changes, 4), ", ")
+		}
+		routeLine := fmt.Sprintf("%d foreign territories rerouted, %d rival routes cut off", len(report.Rerouted), best.CutOffRoutes)
+		m.analysisModal.SetTaxResult([]string{totalLine, changeLine, routeLine})
+
+		if m.territoryViewSwitcher != nil {
+			m.territoryViewSwitcher.SetAnalysisResults(guildTag, scores)
+			m.territoryViewSwitcher.SetCurrentView(ViewAnalysis)
+		}
+	}()
+}
+
+// applyTaxPolicy applies the last tax policy recommendation to the live state
+func (m *MapView) applyTaxPolicy() {
+	if m.analysisModal == nil {
+		return
+	}
+	if m.taxPolicy == nil {
+		m.analysisModal.SetTaxResult([]string{"Run the optimizer first"})
+		return
+	}
+	applied := eruntime.ApplyTaxPolicy(m.taxPolicy.Settings)
+	m.taxPolicy = nil
+	m.analysisModal.SetTaxResult([]string{fmt.Sprintf("Applied new settings to %d territories", applied)})
+}
+
+// runRivalChokepointAnalysis compares two guilds' networks and tints both sides by the share of rival production at stake
+func (m *MapView) runRivalChokepointAnalysis(guildTag, rivalTag string, alliance bool) {
+	if m.analysisModal == nil {
+		return
+	}
+
+	if m.analysisRunning {
+		m.analysisModal.SetStatus("Analysis already running...")
+		return
+	}
+
+	m.analysisRunning = true
+	m.analysisModal.SetAnalyzing(true)
+	defer func() {
+		m.analysisRunning = false
+		m.analysisModal.SetAnalyzing(false)
+	}()
+
+	report, err := eruntime.ComputeRivalChokepoints(guildTag, rivalTag, alliance)
+	if err != nil {
+		m.analysisModal.SetRivalResult([]string{err.Error()})
+		return
+	}
+
+	scores := make(map[string]float64, len(report.OnRivalRoutes)+len(report.RivalChokepoints))
+	routeItems := make([]string, 0, len(report.OnRivalRoutes))
+	for _, choke := range report.OnRivalRoutes {
+		scores[choke.Territory] = choke.Share
+		routeItems = append(routeItems, fmt.Sprintf("%s %.0f%%", choke.Territory, choke.Share*100))
+	}
+	cutItems := make([]string, 0, len(report.RivalChokepoints))
+	for _, choke := range report.RivalChokepoints {
+		scores[choke.Territory] = choke.Share
+		cutItems = append(cutItems, fmt.Sprintf("%s %.0f%% (%d cut)", choke.Territory, choke.Share*100, len(choke.Disconnected)))
+	}
+
+	networkLine := fmt.Sprintf("[%s] vs [%s]", strings.Join(report.GuildNetwork, ", "), strings.Join(report.RivalNetwork, ", "))
+	routeLine := "No rival routes pass through our territories"
+	if len(routeItems) > 0 {
+		routeLine = "On their routes: " + strings.Join(limitNames(routeItems, 3), ", ")
+	}
+	cutLine := "No single capture cuts their network"
+	if len(cutItems) > 0 {
+		cutLine = "Take to cut off: " + strings.Join(limitNames(cutItems, 3), ", ")
+	}
+	m.analysisModal.SetRivalResult([]string{networkLine, routeLine, cutLine, "Tint shows the share of rival production at stake"})
+
+	if m.territoryViewSwitcher != nil {
+		m.territoryViewSwitcher.SetAnalysisResults(guildTag, scores)
+		m.territoryViewSwitcher.SetCurrentView(ViewAnalysis)
+	}
+}
+
+// limitNames truncates a list for display, noting how many entries were left out
+func limitNames(names []string, limit int) []string {
+	if len(names) <= limit {
+		return names
+	}
+	limited := append([]string{}, names[:limit]...)
+	return append(limited, fmt.Sprintf("+%d more", len(names)-limit))
+}
+
 // GetTerritoriesManager returns the territories manager
 func (m *MapView) GetTerritoriesManager() *TerritoriesManager {
 	return m.territoriesManager
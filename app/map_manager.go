@@ -1,12 +1,13 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
 
-	"RueaES/assets"
+	"RueaES/eruntime"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
@@ -45,16 +46,15 @@ func (mm *MapManager) LoadMapAsync() {
 func (mm *MapManager) LoadMapData() error {
 	// fmt.Println("Loading local map image...")
 
-	// Load the embedded map image
-	file, err := assets.AssetFiles.Open("main-map.png")
+	// Load the map image of the active map pack
+	data, err := eruntime.ActiveMapPack().ReadImage()
 	if err != nil {
-		mm.loadError = fmt.Errorf("failed to open embedded map file: %v", err)
+		mm.loadError = fmt.Errorf("failed to open map image: %v", err)
 		return mm.loadError
 	}
-	defer file.Close()
 
 	// Decode the image
-	img, _, err := image.Decode(file)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		mm.loadError = fmt.Errorf("failed to decode map image: %v", err)
 		return mm.loadError
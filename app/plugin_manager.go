@@ -1,6 +1,7 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
@@ -623,11 +624,26 @@ func (pm *PluginManager) handleCommandUpdateTribute(args map[string]any) (int, m
 			return pluginhost.HostErrBadArgument, nil
 		}
 	}
-	if amount == nil && interval == nil {
+	schedule := (*typedef.TributeSchedule)(nil)
+	if scheduleVal, ok := args["schedule"]; ok {
+		converted, ok := toTributeSchedule(scheduleVal)
+		if !ok {
+			return pluginhost.HostErrBadArgument, nil
+		}
+		schedule = &converted
+	}
+	if amount == nil && interval == nil && schedule == nil {
 		return pluginhost.HostErrBadArgument, nil
 	}
-	if err := eruntime.UpdateTribute(id, amount, interval); err != nil {
-		return pluginhost.HostErrInternal, map[string]any{"error": err.Error()}
+	if amount != nil || interval != nil {
+		if err := eruntime.UpdateTribute(id, amount, interval); err != nil {
+			return pluginhost.HostErrInternal, map[string]any{"error": err.Error()}
+		}
+	}
+	if schedule != nil {
+		if err := eruntime.SetTributeSchedule(id, *schedule); err != nil {
+			return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+		}
 	}
 	return pluginhost.HostOK, nil
 }
@@ -749,6 +765,8 @@ func (pm *PluginManager) handleCommandGetTribute() (int, map[string]any) {
 			"last_transfer":         t.LastTransfer,
 			"next_transfer_minutes": nextMinutes,
 			"next_transfer_ticks":   nextTicks,
+			"transfer_count":        t.TransferCount,
+			"schedule":              tributeScheduleMap(t.Schedule),
 			"amount_per_hour": map[string]any{
 				"emeralds": t.AmountPerHour.Emeralds,
 				"ores":     t.AmountPerHour.Ores,
@@ -794,6 +812,13 @@ func (pm *PluginManager) handleCommandCreateTribute(args map[string]any) (int, m
 	if err != nil {
 		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
 	}
+	if scheduleVal, ok := args["schedule"]; ok {
+		schedule, ok := toTributeSchedule(scheduleVal)
+		if !ok {
+			return pluginhost.HostErrBadArgument, nil
+		}
+		tribute.Schedule = schedule
+	}
 	if err := eruntime.AddTribute(tribute); err != nil {
 		return pluginhost.HostErrInternal, map[string]any{"error": err.Error()}
 	}
@@ -844,6 +869,109 @@ func (pm *PluginManager) handleCommandSetTributeActive(args map[string]any) (int
 	return pluginhost.HostOK, nil
 }
 
+// Annotation layers cross the plugin ABI as JSON in the ExportAnnotationsJSON format, since
+// responses only carry flat values.
+func (pm *PluginManager) handleCommandGetAnnotations(args map[string]any) (int, map[string]any) {
+	var layers []string
+	if name, ok := args["layer"].(string); ok && name != "" {
+		layers = append(layers, name)
+	}
+	data, err := eruntime.ExportAnnotationsJSON(layers...)
+	if err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, map[string]any{"json": string(data)}
+}
+
+func (pm *PluginManager) handleCommandImportAnnotations(args map[string]any) (int, map[string]any) {
+	var data []byte
+	switch val := args["json"].(type) {
+	case string:
+		data = []byte(val)
+	case []byte:
+		data = val
+	default:
+		return pluginhost.HostErrBadArgument, nil
+	}
+	count, err := eruntime.ImportAnnotationsJSON(data)
+	if err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, map[string]any{"layers": count}
+}
+
+func (pm *PluginManager) handleCommandAddAnnotation(args map[string]any) (int, map[string]any) {
+	layer, ok := args["layer"].(string)
+	if !ok || layer == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	var annotation typedef.Annotation
+	if !toJSONValue(args["annotation"], &annotation) {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	id, err := eruntime.AddAnnotation(layer, annotation)
+	if err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, map[string]any{"id": id}
+}
+
+func (pm *PluginManager) handleCommandUpdateAnnotation(args map[string]any) (int, map[string]any) {
+	layer, ok := args["layer"].(string)
+	if !ok || layer == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	var annotation typedef.Annotation
+	if !toJSONValue(args["annotation"], &annotation) || annotation.ID == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	if err := eruntime.UpdateAnnotation(layer, annotation); err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, nil
+}
+
+func (pm *PluginManager) handleCommandRemoveAnnotation(args map[string]any) (int, map[string]any) {
+	layer, ok := args["layer"].(string)
+	if !ok || layer == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	id, ok := args["id"].(string)
+	if !ok || id == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	if err := eruntime.RemoveAnnotation(layer, id); err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, nil
+}
+
+func (pm *PluginManager) handleCommandRemoveAnnotationLayer(args map[string]any) (int, map[string]any) {
+	layer, ok := args["layer"].(string)
+	if !ok || layer == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	if err := eruntime.RemoveAnnotationLayer(layer); err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, nil
+}
+
+func (pm *PluginManager) handleCommandSetAnnotationLayerVisible(args map[string]any) (int, map[string]any) {
+	layer, ok := args["layer"].(string)
+	if !ok || layer == "" {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	visible, ok := toBool(args["visible"])
+	if !ok {
+		return pluginhost.HostErrBadArgument, nil
+	}
+	if err := eruntime.SetAnnotationLayerVisible(layer, visible); err != nil {
+		return pluginhost.HostErrBadArgument, map[string]any{"error": err.Error()}
+	}
+	return pluginhost.HostOK, nil
+}
+
 // NewPluginManager creates the plugin manager UI and state tracker.
 func NewPluginManager() *PluginManager {
 	screenW, screenH := ebiten.WindowSize()
@@ -947,6 +1075,20 @@ func NewPluginManager() *PluginManager {
 			return pm.handleCommandDeleteTribute(args)
 		case "set_tribute_active":
 			return pm.handleCommandSetTributeActive(args)
+		case "get_annotations":
+			return pm.handleCommandGetAnnotations(args)
+		case "import_annotations":
+			return pm.handleCommandImportAnnotations(args)
+		case "add_annotation":
+			return pm.handleCommandAddAnnotation(args)
+		case "update_annotation":
+			return pm.handleCommandUpdateAnnotation(args)
+		case "remove_annotation":
+			return pm.handleCommandRemoveAnnotation(args)
+		case "remove_annotation_layer":
+			return pm.handleCommandRemoveAnnotationLayer(args)
+		case "set_annotation_layer_visible":
+			return pm.handleCommandSetAnnotationLayerVisible(args)
 		default:
 			return pluginhost.HostErrUnsupported, nil
 		}
@@ -1815,6 +1957,108 @@ func toBasicResources(v any) (typedef.BasicResources, bool) {
 	return res, true
 }
 
+// toJSONValue decodes a plugin argument holding JSON text, or an already decoded object, into out
+func toJSONValue(v any, out any) bool {
+	var data []byte
+	switch val := v.(type) {
+	case string:
+		data = []byte(val)
+	case []byte:
+		data = val
+	case map[string]any:
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return false
+		}
+		data = encoded
+	default:
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+var tributeKindNames = []string{"recurring", "one_shot", "limited", "windowed", "conditional"}
+
+var tributeConditionNames = []string{"sender_above", "receiver_below"}
+
+// toTributeSchedule converts a plugin schedule object such as
+// {"kind": "limited", "max_transfers": 10} into a tribute schedule.
+func toTributeSchedule(v any) (typedef.TributeSchedule, bool) {
+	schedule := typedef.TributeSchedule{}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return schedule, false
+	}
+	kindFound := false
+	if kind, ok := m["kind"].(string); ok {
+		for i, name := range tributeKindNames {
+			if name == kind {
+				schedule.Kind = typedef.TributeKind(i)
+				kindFound = true
+			}
+		}
+	}
+	if !kindFound {
+		return schedule, false
+	}
+	if f, ok := toFloat(m["fire_at"]); ok && f >= 0 {
+		schedule.FireAt = uint64(f)
+	}
+	if f, ok := toFloat(m["max_transfers"]); ok && f >= 0 {
+		schedule.MaxTransfers = uint32(f)
+	}
+	if f, ok := toFloat(m["max_total"]); ok {
+		schedule.MaxTotal = f
+	}
+	if f, ok := toFloat(m["window_start"]); ok && f >= 0 {
+		schedule.WindowStart = uint64(f)
+	}
+	if f, ok := toFloat(m["window_end"]); ok && f >= 0 {
+		schedule.WindowEnd = uint64(f)
+	}
+	if condition, ok := m["condition"].(string); ok {
+		found := false
+		for i, name := range tributeConditionNames {
+			if name == condition {
+				schedule.Condition = typedef.TributeCondition(i)
+				found = true
+			}
+		}
+		if !found {
+			return schedule, false
+		}
+	}
+	if resource, ok := m["resource"].(string); ok {
+		schedule.Resource = resource
+	}
+	if f, ok := toFloat(m["threshold"]); ok {
+		schedule.Threshold = f
+	}
+	return schedule, true
+}
+
+func tributeScheduleMap(schedule typedef.TributeSchedule) map[string]any {
+	kind := "recurring"
+	if schedule.Kind >= 0 && int(schedule.Kind) < len(tributeKindNames) {
+		kind = tributeKindNames[schedule.Kind]
+	}
+	condition := "sender_above"
+	if schedule.Condition >= 0 && int(schedule.Condition) < len(tributeConditionNames) {
+		condition = tributeConditionNames[schedule.Condition]
+	}
+	return map[string]any{
+		"kind":          kind,
+		"fire_at":       schedule.FireAt,
+		"max_transfers": schedule.MaxTransfers,
+		"max_total":     schedule.MaxTotal,
+		"window_start":  schedule.WindowStart,
+		"window_end":    schedule.WindowEnd,
+		"condition":     condition,
+		"resource":      schedule.Resource,
+		"threshold":     schedule.Threshold,
+	}
+}
+
 func wrapTextFace(face font.Face, content string, maxWidth int) []string {
 	if maxWidth <= 0 {
 		return []string{content}
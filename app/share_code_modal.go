@@ -0,0 +1,312 @@
+package app
+
+import (
+	"fmt"
+	"image/color"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"RueaES/eruntime"
+	"RueaES/typedef"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.design/x/clipboard"
+	"golang.org/x/image/font"
+)
+
+const (
+	shareCodeLineHeight   = 20
+	shareCodeVisibleLines = 16
+)
+
+// ShareCodeModal previews a pasted share code and applies it on confirmation
+type ShareCodeModal struct {
+	modal        *EnhancedModal
+	applyButton  *EnhancedButton
+	cancelButton *EnhancedButton
+	visible      bool
+	code         *eruntime.ShareCode
+	lines        []previewLine
+	scroll       int
+	font         font.Face
+	buttonWidth  int
+	buttonHeight int
+}
+
+var (
+	globalShareCodeModal *ShareCodeModal
+	shareCodeModalOnce   sync.Once
+)
+
+// GetShareCodeModal returns the global share code preview modal
+func GetShareCodeModal() *ShareCodeModal {
+	shareCodeModalOnce.Do(func() {
+		m := &ShareCodeModal{
+			modal:        NewEnhancedModal("Import Share Code", 640, 150+shareCodeVisibleLines*shareCodeLineHeight),
+			font:         loadWynncraftFont(16),
+			buttonWidth:  150,
+			buttonHeight: 40,
+		}
+
+		m.applyButton = NewEnhancedButton("Apply", 0, 0, m.buttonWidth, m.buttonHeight, m.apply)
+		m.applyButton.SetGreenButtonStyle()
+
+		m.cancelButton = NewEnhancedButton("Cancel", 0, 0, m.buttonWidth, m.buttonHeight, func() {
+			m.Hide()
+		})
+		m.cancelButton.SetGrayButtonStyle()
+
+		globalShareCodeModal = m
+	})
+	return globalShareCodeModal
+}
+
+// CopyShareCode encodes a share code into the clipboard, reporting the outcome with a toast
+func CopyShareCode(what string, encode func() (string, error)) {
+	if runtime.GOOS == "js" {
+		showResultToast("Clipboard is not supported on this platform", false)
+		return
+	}
+	code, err := encode()
+	if err != nil {
+		showResultToast("Failed to create share code: "+err.Error(), false)
+		return
+	}
+	clipboard.Write(clipboard.FmtText, []byte(code))
+	showResultToast(fmt.Sprintf("%s code copied to clipboard (%d characters)", what, len(code)), true)
+}
+
+// PasteShareCode reads a share code from the clipboard and opens the preview
+func PasteShareCode() {
+	if runtime.GOOS == "js" {
+		showResultToast("Clipboard is not supported on this platform", false)
+		return
+	}
+	data := clipboard.Read(clipboard.FmtText)
+	if len(data) == 0 {
+		showResultToast("No share code in clipboard", false)
+		return
+	}
+	code, err := eruntime.DecodeShareCode(string(data))
+	if err != nil {
+		showResultToast("Invalid share code: "+err.Error(), false)
+		return
+	}
+	GetShareCodeModal().Show(code)
+}
+
+// Show opens the preview for a decoded share code
+func (m *ShareCodeModal) Show(code *eruntime.ShareCode) {
+	m.code = code
+	m.scroll = 0
+	m.lines = m.previewLines(code)
+	m.visible = true
+	m.modal.Show()
+
+	switch code.Kind {
+	case eruntime.ShareCodeLoadout:
+		m.applyButton.Text = "Add Loadout"
+	case eruntime.ShareCodeClaim:
+		m.applyButton.Text = "Apply Claim"
+	default:
+		m.applyButton.Text = "Apply"
+	}
+	m.updateButtonPositions()
+}
+
+// Hide closes the preview
+func (m *ShareCodeModal) Hide() {
+	m.visible = false
+	m.code = nil
+	m.modal.Hide()
+}
+
+// IsVisible returns whether the preview is shown
+func (m *ShareCodeModal) IsVisible() bool {
+	return m != nil && m.visible
+}
+
+// previewLines describes the share code and what applying it would change
+func (m *ShareCodeModal) previewLines(code *eruntime.ShareCode) []previewLine {
+	normal := EnhancedUIColors.Text
+	secondary := EnhancedUIColors.TextSecondary
+	changed := color.RGBA{255, 200, 100, 255}
+	warning := color.RGBA{255, 120, 120, 255}
+
+	lines := []previewLine{{text: "Type: " + code.Kind.String(), color: normal}}
+
+	if code.Kind == eruntime.ShareCodeLoadout {
+		l := code.Loadout
+		lines = append(lines,
+			previewLine{text: "Name: " + l.Name, color: normal},
+			previewLine{text: fmt.Sprintf("Tax: %.1f%%  Ally Tax: %.1f%%", l.Tax.Tax*100, l.Tax.Ally*100), color: secondary},
+			previewLine{text: fmt.Sprintf("Routing: %s  Border: %s", getRoutingModeString(l.RoutingMode), getBorderString(l.Border)), color: secondary},
+			previewLine{text: fmt.Sprintf("Upgrades: Damage %d, Attack %d, Health %d, Defence %d",
+				l.Upgrades.Damage, l.Upgrades.Attack, l.Upgrades.Health, l.Upgrades.Defence), color: secondary},
+		)
+		if bonuses := formatSharedBonuses(l.Bonuses); bonuses != "" {
+			lines = append(lines, previewLine{text: "Bonuses: " + bonuses, color: secondary})
+		}
+		return lines
+	}
+
+	preview := eruntime.PreviewShareCode(code)
+	if code.Kind == eruntime.ShareCodeClaim {
+		lines = append(lines, previewLine{text: fmt.Sprintf("Guild: %s [%s]", code.GuildName, code.GuildTag), color: normal})
+		if preview.GuildMissing {
+			lines = append(lines, previewLine{text: "This guild is not in the guild list, add it before applying.", color: warning})
+		}
+	}
+	lines = append(lines, previewLine{
+		text: fmt.Sprintf("%d territories: %d changed, %d unchanged, %d not on this map",
+			len(code.Territories), len(preview.Changed), len(preview.Unchanged), len(preview.Missing)),
+		color: normal,
+	})
+	if len(preview.OwnerChanges) > 0 {
+		lines = append(lines, previewLine{text: fmt.Sprintf("%d territories change owner", len(preview.OwnerChanges)), color: changed})
+	}
+
+	status := make(map[string]string, len(code.Territories))
+	for _, name := range preview.Changed {
+		status[name] = "changed"
+	}
+	for _, name := range preview.Unchanged {
+		status[name] = "unchanged"
+	}
+	for _, name := range preview.Missing {
+		status[name] = "missing"
+	}
+	owners := make(map[string]bool, len(preview.OwnerChanges))
+	for _, name := range preview.OwnerChanges {
+		owners[name] = true
+	}
+
+	for _, t := range code.Territories {
+		line := fmt.Sprintf("%s  -  tax %.0f%%, %s, %s, upgrades %d", t.Name, t.Options.Tax.Tax*100,
+			strings.ToLower(getBorderString(t.Options.Border)), strings.ToLower(getRoutingModeString(t.Options.RoutingMode)),
+			getTotalUpgradeLevels(t.Options.Upgrades))
+		if t.Options.HQ {
+			line += ", HQ"
+		}
+		lineColor := secondary
+		switch {
+		case status[t.Name] == "missing":
+			line += "  (not on map)"
+			lineColor = warning
+		case owners[t.Name]:
+			line += "  (new owner)"
+			lineColor = changed
+		case status[t.Name] == "changed":
+			line += "  (changed)"
+			lineColor = changed
+		}
+		lines = append(lines, previewLine{text: line, color: lineColor})
+	}
+	return lines
+}
+
+// formatSharedBonuses lists the bonuses set above level 0
+func formatSharedBonuses(b typedef.Bonus) string {
+	levels := []struct {
+		name  string
+		level int
+	}{
+		{"Stronger Minions", b.StrongerMinions}, {"Multi-Attack", b.TowerMultiAttack}, {"Aura", b.TowerAura},
+		{"Volley", b.TowerVolley}, {"Gathering XP", b.GatheringExperience}, {"Mob XP", b.MobExperience},
+		{"Mob Damage", b.MobDamage}, {"PvP Damage", b.PvPDamage}, {"XP Seeking", b.XPSeeking},
+		{"Tome Seeking", b.TomeSeeking}, {"Emerald Seeking", b.EmeraldSeeking},
+		{"Resource Storage", b.LargerResourceStorage}, {"Emerald Storage", b.LargerEmeraldStorage},
+		{"Efficient Resource", b.EfficientResource}, {"Efficient Emerald", b.EfficientEmerald},
+		{"Resource Rate", b.ResourceRate}, {"Emerald Rate", b.EmeraldRate},
+	}
+	var parts []string
+	for _, l := range levels {
+		if l.level > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", l.name, l.level))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (m *ShareCodeModal) apply() {
+	code := m.code
+	if code == nil {
+		return
+	}
+
+	if code.Kind == eruntime.ShareCodeLoadout {
+		name := GetLoadoutManager().AddSharedLoadout(*code.Loadout)
+		m.Hide()
+		showResultToast(fmt.Sprintf("Added loadout %s", name), true)
+		return
+	}
+
+	applied, err := eruntime.ApplyShareCode(code)
+	if err != nil {
+		showResultToast(err.Error(), false)
+		return
+	}
+	m.Hide()
+	showResultToast(fmt.Sprintf("Applied share code to %d territories", applied), true)
+}
+
+// Update handles input while the preview is open and returns true if it consumed input
+func (m *ShareCodeModal) Update() bool {
+	if !m.IsVisible() {
+		return false
+	}
+
+	m.modal.Update()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.Hide()
+		return true
+	}
+
+	_, wheelY := ebiten.Wheel()
+	if wheelY > 0 && m.scroll > 0 {
+		m.scroll--
+	} else if wheelY < 0 && m.scroll+shareCodeVisibleLines < len(m.lines) {
+		m.scroll++
+	}
+
+	mx, my := ebiten.CursorPosition()
+	m.applyButton.Update(mx, my)
+	m.cancelButton.Update(mx, my)
+	return true
+}
+
+// Draw renders the preview
+func (m *ShareCodeModal) Draw(screen *ebiten.Image) {
+	if !m.IsVisible() {
+		return
+	}
+
+	m.modal.Draw(screen)
+
+	contentX, contentY, _, _ := m.modal.GetContentArea()
+	text.Draw(screen, "Review the share code before applying it.", m.font, contentX, contentY+14, EnhancedUIColors.TextSecondary)
+
+	for i := 0; i < shareCodeVisibleLines && m.scroll+i < len(m.lines); i++ {
+		line := m.lines[m.scroll+i]
+		text.Draw(screen, line.text, m.font, contentX, contentY+44+i*shareCodeLineHeight, line.color)
+	}
+
+	m.applyButton.Draw(screen)
+	m.cancelButton.Draw(screen)
+}
+
+func (m *ShareCodeModal) updateButtonPositions() {
+	bounds := m.modal.GetBounds()
+	spacing := 16
+	totalWidth := 2*m.buttonWidth + spacing
+	startX := bounds.Min.X + (bounds.Dx()-totalWidth)/2
+	buttonY := bounds.Max.Y - 60
+
+	m.applyButton.SetPosition(startX, buttonY)
+	m.cancelButton.SetPosition(startX+m.buttonWidth+spacing, buttonY)
+}
@@ -62,14 +62,14 @@ var StateImportOptions = []StateImportOption{
 		Label:        "Guilds",
 		Description:  "Guild list name, colors and tag",
 		Dependencies: []string{},
-		Dependents:   []string{"territories", "territory_config", "territory_data", "in_transit"},
+		Dependents:   []string{"territories", "territory_config", "territory_data", "in_transit", "manual_routes", "season"},
 	},
 	{
 		ID:           "territories",
 		Label:        "Territories",
 		Description:  "Guilds' territories",
 		Dependencies: []string{"guilds"},
-		Dependents:   []string{"territory_config", "territory_data", "in_transit"},
+		Dependents:   []string{"territory_config", "territory_data", "in_transit", "manual_routes", "season"},
 	},
 	{
 		ID:           "territory_config",
@@ -99,6 +99,20 @@ var StateImportOptions = []StateImportOption{
 		Dependencies: []string{"guilds", "territories", "territory_data"},
 		Dependents:   []string{},
 	},
+	{
+		ID:           "manual_routes",
+		Label:        "Manual Routes",
+		Description:  "Hand-picked trading routes between territories and HQs",
+		Dependencies: []string{"guilds", "territories"},
+		Dependents:   []string{},
+	},
+	{
+		ID:           "season",
+		Label:        "Season Rating",
+		Description:  "Scheduled captures, season scores and the rating formula",
+		Dependencies: []string{"guilds", "territories"},
+		Dependents:   []string{},
+	},
 	{
 		ID:           "loadouts",
 		Label:        "Loadouts",
@@ -106,6 +120,13 @@ var StateImportOptions = []StateImportOption{
 		Dependencies: []string{},
 		Dependents:   []string{},
 	},
+	{
+		ID:           "annotations",
+		Label:        "Annotations",
+		Description:  "Map markers, paths, zones and labels, replacing layers of the same name",
+		Dependencies: []string{},
+		Dependents:   []string{},
+	},
 }
 
 // NewStateImportModal creates a new state import modal
@@ -132,6 +153,10 @@ func (sim *StateImportModal) Show(filePath string) {
 	if err != nil {
 		sim.fileVersion = "Unknown"
 		// fmt.Printf("[STATE] Failed to get file version: %v\n", err)
+	} else if err := eruntime.CheckStateVersion(version); err != nil {
+		sim.fileVersion = version + " (unsupported)"
+	} else if version != eruntime.CurrentStateVersion {
+		sim.fileVersion = version + " (upgraded to " + eruntime.CurrentStateVersion + " on import)"
 	} else {
 		sim.fileVersion = version
 	}
@@ -139,7 +164,7 @@ func (sim *StateImportModal) Show(filePath string) {
 	// Calculate modal dimensions and position
 	screenW, screenH := ebiten.WindowSize()
 	sim.modalW = 600
-	sim.modalH = 500
+	sim.modalH = 140 + len(sim.options)*45 // Room for one checkbox row per option plus buttons
 	sim.modalX = (screenW - sim.modalW) / 2
 	sim.modalY = (screenH - sim.modalH) / 2
 
@@ -3,6 +3,7 @@ package app
 import (
 	"RueaES/eruntime"
 	"RueaES/pluginhost"
+	"RueaES/tml"
 	"RueaES/typedef"
 	"fmt"
 	"image/color"
@@ -100,6 +101,25 @@ type StateManagementMenu struct {
 	currentColorKey string
 	keybindInputs   map[string]*MenuTextInput
 
+	// Share code inputs
+	shareGuildTag string
+	shareQuery    string
+
+	// Territory CSV column mapping
+	csvMapping string
+
+	// Base URL of the Wynncraft API for the territory import
+	apiBaseURL string
+
+	// Cost profile selection and the name to import or save under
+	costProfile     string
+	costProfileName string
+
+	// Annotation layer selection, refreshed when the layers change
+	annotationLayer    string
+	annotationDropdown *FilterableDropdown
+	annotationsVersion uint64
+
 	// Track time for stats updates
 	lastStatsUpdate float64
 }
@@ -430,6 +450,21 @@ func (smm *StateManagementMenu) Show() {
 		eruntime.SetRuntimeOptions(opts)
 	})
 
+	optionsSection.Spacer(DefaultSpacerOptions())
+	optionsSection.Text("Tribute Delivery", DefaultTextOptions())
+	optionsSection.Text("Routed tributes travel between HQs and pay taxes.", DefaultTextOptions())
+	tributeDeliveryOpts := DefaultToggleSwitchOptions()
+	tributeDeliveryOpts.Options = []string{"Routed", "Direct"}
+	tributeDeliveryIndex := 0
+	if eruntime.GetRuntimeOptions().DirectTributes {
+		tributeDeliveryIndex = 1
+	}
+	optionsSection.ToggleSwitch("Tribute Delivery", tributeDeliveryIndex, tributeDeliveryOpts, func(index int, value string) {
+		opts := eruntime.GetRuntimeOptions()
+		opts.DirectTributes = index == 1
+		eruntime.SetRuntimeOptions(opts)
+	})
+
 	optionsSection.Spacer(DefaultSpacerOptions())
 
 	// Encode Treasury toggle switch
@@ -583,6 +618,64 @@ func (smm *StateManagementMenu) Show() {
 		costDropdown.SetPlaceholder("Select a cost provider...")
 	}
 
+	// Named cost profiles, e.g. one per balance patch
+	optionsSection.Spacer(DefaultSpacerOptions())
+	costProfileSection := optionsSection.CollapsibleMenu("Cost Profiles", DefaultCollapsibleMenuOptions())
+	costProfileSection.Text("Compare costs and guild upkeep,", DefaultTextOptions())
+	costProfileSection.Text("then switch profiles live.", DefaultTextOptions())
+	smm.costProfile = eruntime.ActiveCostProfile()
+	costProfileDropdown := NewFilterableDropdown(0, 0, 260, 32, costProfileOptions(), func(option FilterableDropdownOption) {
+		smm.costProfile = option.Value
+	})
+	costProfileDropdown.SetSelected(smm.costProfile)
+	costProfileDropdown.SetPlaceholder("Select a cost profile...")
+	refreshCostProfiles := func() {
+		costProfileDropdown.SetOptions(costProfileOptions())
+		costProfileDropdown.SetSelected(smm.costProfile)
+	}
+	costProfileSection.AddElement(newDropdownEdgeElement(costProfileDropdown, nil, 40))
+	costProfileSection.Button("Compare and Switch...", DefaultButtonOptions(), func() {
+		GetCostProfileModal().Show(smm.costProfile, refreshCostProfiles)
+	})
+	costProfileSection.Button("Delete Profile", DefaultButtonOptions(), func() {
+		if smm.costProfile == "" {
+			showResultToast("The built-in costs can't be deleted", false)
+			return
+		}
+		if err := eruntime.DeleteCostProfile(smm.costProfile); err != nil {
+			showResultToast("Failed to delete cost profile: "+err.Error(), false)
+			return
+		}
+		showResultToast("Deleted cost profile "+smm.costProfile, true)
+		smm.costProfile = ""
+		refreshCostProfiles()
+	})
+
+	costProfileSection.Spacer(DefaultSpacerOptions())
+	costProfileNameOpts := DefaultTextInputOptions()
+	costProfileNameOpts.Width = 260
+	costProfileNameOpts.MaxLength = 64
+	costProfileNameOpts.Placeholder = "Defaults to the file name"
+	costProfileSection.TextInput("Profile Name", smm.costProfileName, costProfileNameOpts, func(value string) {
+		smm.costProfileName = value
+	})
+	costProfileSection.Button("Import Profile JSON...", DefaultButtonOptions(), func() {
+		ImportCostProfile(smm.costProfileName, func(name string) {
+			smm.costProfile = name
+			refreshCostProfiles()
+		})
+	})
+	costProfileSection.Button("Save Current Costs as Profile", DefaultButtonOptions(), func() {
+		name := strings.TrimSpace(smm.costProfileName)
+		if err := eruntime.SaveCostProfile(name); err != nil {
+			showResultToast("Failed to save cost profile: "+err.Error(), false)
+			return
+		}
+		showResultToast("Saved the costs in use as "+name, true)
+		smm.costProfile = name
+		refreshCostProfiles()
+	})
+
 	// Keybind configuration
 	optionsSection.Spacer(DefaultSpacerOptions())
 
@@ -871,6 +964,81 @@ func (smm *StateManagementMenu) Show() {
 		}
 	})
 
+	// Map packs replace the territories, links, image and costs of the built-in map
+	mapSection.Spacer(DefaultSpacerOptions())
+	mapPackSection := mapSection.CollapsibleMenu("Map Pack", DefaultCollapsibleMenuOptions())
+	mapPackSection.Text("Install packs in "+eruntime.MapPacksDir(), DefaultTextOptions())
+	mapPackSection.Text("Takes effect on next start", DefaultTextOptions())
+	for _, pack := range eruntime.ListMapPacks() {
+		name := pack.Manifest.Name
+		if pack.Manifest.Version != "" && pack.Path != "" {
+			name += " " + pack.Manifest.Version
+		}
+		label := name
+		if pack.Active {
+			label += " (active)"
+		} else if pack.Selected {
+			label += " (next start)"
+		}
+		packPath := pack.Path
+		packErr := pack.Error
+		warnings := pack.Warnings
+		mapPackSection.Button(label, DefaultButtonOptions(), func() {
+			if packErr != "" {
+				showResultToast("Invalid map pack: "+packErr, false)
+				return
+			}
+			if err := eruntime.SelectMapPack(packPath); err != nil {
+				showResultToast("Invalid map pack: "+err.Error(), false)
+				return
+			}
+			message := name + " selected, restart to switch maps"
+			if warnings > 0 {
+				message += fmt.Sprintf(" (%d warnings)", warnings)
+			}
+			showResultToast(message, true)
+		})
+	}
+
+	// Annotation layers are saved with the state and drawn over the territories
+	mapSection.Spacer(DefaultSpacerOptions())
+	annotationSection := mapSection.CollapsibleMenu("Annotations", DefaultCollapsibleMenuOptions())
+	annotationSection.Text("Markers, paths, zones and labels", DefaultTextOptions())
+	annotationSection.Text("saved with the state.", DefaultTextOptions())
+	smm.annotationsVersion = eruntime.AnnotationsVersion()
+	smm.annotationDropdown = NewFilterableDropdown(0, 0, 260, 32, annotationLayerOptions(), func(option FilterableDropdownOption) {
+		smm.annotationLayer = option.Value
+	})
+	smm.annotationDropdown.SetSelected(smm.annotationLayer)
+	smm.annotationDropdown.SetPlaceholder("Select a layer...")
+	annotationSection.AddElement(newDropdownEdgeElement(smm.annotationDropdown, nil, 40))
+	annotationSection.Button("Show / Hide Layer", DefaultButtonOptions(), func() {
+		layer, ok := eruntime.GetAnnotationLayer(smm.annotationLayer)
+		if !ok {
+			showResultToast("Select an annotation layer first", false)
+			return
+		}
+		if err := eruntime.SetAnnotationLayerVisible(layer.Name, !layer.Visible); err != nil {
+			showResultToast(err.Error(), false)
+		}
+	})
+	annotationSection.Button("Delete Layer", DefaultButtonOptions(), func() {
+		if err := eruntime.RemoveAnnotationLayer(smm.annotationLayer); err != nil {
+			showResultToast("Select an annotation layer first", false)
+			return
+		}
+		showResultToast("Deleted annotation layer "+smm.annotationLayer, true)
+		smm.annotationLayer = ""
+	})
+	annotationSection.Spacer(DefaultSpacerOptions())
+	annotationSection.Text("Exports the selected layer, or all", DefaultTextOptions())
+	annotationSection.Button("Export Annotations JSON...", DefaultButtonOptions(), func() {
+		ExportAnnotations(smm.annotationLayer)
+	})
+	annotationSection.Button("Import Annotations JSON...", DefaultButtonOptions(), func() {
+		ImportAnnotations()
+	})
+
 	// Throughput curve slider: -5..5 (0 = linear, >0 brightens faster, <0 brightens slower)
 	optionsSection.Spacer(DefaultSpacerOptions())
 	optionsSection.Text("Throughput Curve", DefaultTextOptions())
@@ -987,6 +1155,65 @@ func (smm *StateManagementMenu) Show() {
 		eruntime.SetRuntimeOptions(opts)
 	})
 
+	// War party used by the tower combat calculator
+	optionsSection.Spacer(DefaultSpacerOptions())
+	warOptions := DefaultCollapsibleMenuOptions()
+	warOptions.Collapsed = true
+	warSection := optionsSection.CollapsibleMenu("War Party", warOptions)
+	warSection.Text("Used for time-to-capture estimates", DefaultTextOptions())
+	currentParty := currentOpts.WarParty
+	if currentParty.Players <= 0 {
+		currentParty = typedef.DefaultWarParty()
+	}
+
+	playersOpts := DefaultSliderOptions()
+	playersOpts.MinValue = 1
+	playersOpts.MaxValue = 20
+	playersOpts.Step = 1
+	playersOpts.ValueFormat = "%.0f"
+	playersOpts.ShowValue = true
+	warSection.AddElement(NewMenuSlider("Players", float64(currentParty.Players), playersOpts, func(val float64) {
+		opts := eruntime.GetRuntimeOptions()
+		opts.WarParty.Players = int(val)
+		eruntime.SetRuntimeOptions(opts)
+	}))
+
+	dpsOpts := DefaultSliderOptions()
+	dpsOpts.MinValue = 1000
+	dpsOpts.MaxValue = 200000
+	dpsOpts.Step = 1000
+	dpsOpts.ValueFormat = "%.0f"
+	dpsOpts.ShowValue = true
+	warSection.AddElement(NewMenuSlider("DPS per Player", currentParty.PlayerDPS, dpsOpts, func(val float64) {
+		opts := eruntime.GetRuntimeOptions()
+		opts.WarParty.PlayerDPS = val
+		eruntime.SetRuntimeOptions(opts)
+	}))
+
+	healthOpts := DefaultSliderOptions()
+	healthOpts.MinValue = 1000
+	healthOpts.MaxValue = 100000
+	healthOpts.Step = 1000
+	healthOpts.ValueFormat = "%.0f"
+	healthOpts.ShowValue = true
+	warSection.AddElement(NewMenuSlider("Player Health", currentParty.PlayerHealth, healthOpts, func(val float64) {
+		opts := eruntime.GetRuntimeOptions()
+		opts.WarParty.PlayerHealth = val
+		eruntime.SetRuntimeOptions(opts)
+	}))
+
+	tankOpts := DefaultToggleSwitchOptions()
+	tankOpts.Options = []string{"Tanked", "Untanked"}
+	tankIndex := 0
+	if !currentParty.Tanked {
+		tankIndex = 1
+	}
+	warSection.ToggleSwitch("Tank", tankIndex, tankOpts, func(index int, value string) {
+		opts := eruntime.GetRuntimeOptions()
+		opts.WarParty.Tanked = index == 0
+		eruntime.SetRuntimeOptions(opts)
+	})
+
 	// --- Credits ---
 	creditsSection := smm.menu.CollapsibleMenu("Credits", DefaultCollapsibleMenuOptions())
 	creditsSection.Text("Ruea Economy Studio", DefaultTextOptions())
@@ -1024,6 +1251,191 @@ func (smm *StateManagementMenu) Show() {
 		}
 	})
 
+	// Autosave history and interval settings
+	autoSaveSection := loadSaveSection.CollapsibleMenu("Autosave", DefaultCollapsibleMenuOptions())
+	autoSaveSettings := eruntime.GetAutoSaveSettings()
+	autoSaveSection.Text("Autosaves are kept as a rotating history.", DefaultTextOptions())
+
+	historySliderOpts := DefaultSliderOptions()
+	historySliderOpts.MinValue = 0
+	historySliderOpts.MaxValue = 100
+	historySliderOpts.Step = 1
+	historySliderOpts.ValueFormat = "%.0f"
+	autoSaveSection.Slider("History Size", float64(autoSaveSettings.HistorySize), historySliderOpts, func(val float64) {
+		settings := eruntime.GetAutoSaveSettings()
+		settings.HistorySize = int(math.Round(val))
+		if err := eruntime.SetAutoSaveSettings(settings); err != nil {
+			fmt.Printf("[STATE_MGMT] Failed to save autosave settings: %v\n", err)
+		}
+	})
+
+	intervalSliderOpts := DefaultSliderOptions()
+	intervalSliderOpts.MinValue = 0
+	intervalSliderOpts.MaxValue = 1440
+	intervalSliderOpts.Step = 1
+	intervalSliderOpts.ValueFormat = "%.0f"
+	autoSaveSection.Text("Interval autosave in minutes, 0 is off.", DefaultTextOptions())
+	autoSaveSection.Slider("Interval (min)", float64(autoSaveSettings.IntervalMinutes), intervalSliderOpts, func(val float64) {
+		settings := eruntime.GetAutoSaveSettings()
+		settings.IntervalMinutes = int(math.Round(val))
+		if err := eruntime.SetAutoSaveSettings(settings); err != nil {
+			fmt.Printf("[STATE_MGMT] Failed to save autosave settings: %v\n", err)
+		}
+	})
+
+	autoSaveSection.Text("Restore Picker on Startup", DefaultTextOptions())
+	pickerToggleOpts := DefaultToggleSwitchOptions()
+	pickerToggleOpts.Options = []string{"On", "Off"}
+	pickerIndex := 1
+	if autoSaveSettings.PickerOnStartup {
+		pickerIndex = 0
+	}
+	autoSaveSection.ToggleSwitch("Restore Picker on Startup", pickerIndex, pickerToggleOpts, func(index int, value string) {
+		settings := eruntime.GetAutoSaveSettings()
+		settings.PickerOnStartup = index == 0
+		if err := eruntime.SetAutoSaveSettings(settings); err != nil {
+			fmt.Printf("[STATE_MGMT] Failed to save autosave settings: %v\n", err)
+		}
+	})
+
+	autoSaveSection.Button("Restore Autosave...", saveLoadButtonOpts, func() {
+		GetAutoSaveHistoryModal().Show()
+	})
+
+	// Share codes for pasting setups in chat
+	shareSection := loadSaveSection.CollapsibleMenu("Share Codes", DefaultCollapsibleMenuOptions())
+	shareSection.Text("Copy a guild claim by tag.", DefaultTextOptions())
+	shareInputOpts := DefaultTextInputOptions()
+	shareInputOpts.Width = 260
+	shareInputOpts.MaxLength = 200
+	shareInputOpts.Placeholder = "Guild tag"
+	shareSection.TextInput("Guild Tag", smm.shareGuildTag, shareInputOpts, func(value string) {
+		smm.shareGuildTag = strings.TrimSpace(value)
+	})
+	shareSection.Button("Copy Claim Code", saveLoadButtonOpts, func() {
+		tag := smm.shareGuildTag
+		CopyShareCode("Claim", func() (string, error) {
+			return eruntime.ClaimShareCode(tag)
+		})
+	})
+
+	shareSection.Text("Copy territory setups selected by a query.", DefaultTextOptions())
+	queryInputOpts := shareInputOpts
+	queryInputOpts.Placeholder = "select where guild = \"TAG\" and hq"
+	shareSection.TextInput("Query", smm.shareQuery, queryInputOpts, func(value string) {
+		smm.shareQuery = value
+	})
+	shareSection.Button("Copy Territory Code", saveLoadButtonOpts, func() {
+		query := smm.shareQuery
+		CopyShareCode("Territory", func() (string, error) {
+			parsed, err := tml.Parse(query)
+			if err != nil {
+				return "", err
+			}
+			if parsed.Kind != tml.QuerySelect {
+				return "", fmt.Errorf("only select queries can pick territories")
+			}
+			result, err := parsed.Execute()
+			if err != nil {
+				return "", err
+			}
+			return eruntime.TerritoriesShareCode(result.Matched)
+		})
+	})
+
+	shareSection.Spacer(DefaultSpacerOptions())
+	shareSection.Button("Paste Share Code...", saveLoadButtonOpts, func() {
+		PasteShareCode()
+	})
+
+	// Territory configurations as CSV for spreadsheets
+	csvSection := loadSaveSection.CollapsibleMenu("Territory CSV", DefaultCollapsibleMenuOptions())
+	csvSection.Text("Exports owner, HQ, upgrades, bonuses, tax,", DefaultTextOptions())
+	csvSection.Text("border, routing, treasury and storage.", DefaultTextOptions())
+	csvSection.Button("Export Territories CSV...", saveLoadButtonOpts, func() {
+		ExportTerritoryCSV()
+	})
+	csvSection.Spacer(DefaultSpacerOptions())
+	csvSection.Text("Map other headers to columns, e.g. Owner=guild", DefaultTextOptions())
+	csvMappingOpts := DefaultTextInputOptions()
+	csvMappingOpts.Width = 260
+	csvMappingOpts.MaxLength = 500
+	csvMappingOpts.Placeholder = "Header=column; ..."
+	csvSection.TextInput("Column Mapping", smm.csvMapping, csvMappingOpts, func(value string) {
+		smm.csvMapping = value
+	})
+	csvSection.Button("Import Territories CSV...", saveLoadButtonOpts, func() {
+		ImportTerritoryCSV(smm.csvMapping)
+	})
+
+	// Source of the guild and territory import, including acquired times for treasury
+	apiImportSection := loadSaveSection.CollapsibleMenu("Wynncraft API Import", DefaultCollapsibleMenuOptions())
+	apiImportSection.Text("Imports owners and capture times.", DefaultTextOptions())
+	apiImportSection.Text("Use a local server or a saved", DefaultTextOptions())
+	apiImportSection.Text("guild and territory list to work", DefaultTextOptions())
+	apiImportSection.Text("offline.", DefaultTextOptions())
+	smm.apiBaseURL = LoadAPIImportSettings().BaseURL
+	apiBaseURLOpts := DefaultTextInputOptions()
+	apiBaseURLOpts.Width = 260
+	apiBaseURLOpts.MaxLength = 200
+	apiBaseURLOpts.Placeholder = defaultWynncraftAPIBaseURL
+	apiImportSection.TextInput("API Base URL", smm.apiBaseURL, apiBaseURLOpts, func(value string) {
+		smm.apiBaseURL = value
+	})
+	apiImportSection.Button("Use API at Base URL", saveLoadButtonOpts, func() {
+		settings := APIImportSettings{BaseURL: strings.TrimSpace(smm.apiBaseURL)}
+		if err := SaveAPIImportSettings(settings); err != nil {
+			showResultToast("Failed to save import settings: "+err.Error(), false)
+			return
+		}
+		showResultToast("Importing from "+settings.territoryListURL()+" and "+settings.guildListURL(), true)
+	})
+	apiImportSection.Button("Use Saved Territory List...", saveLoadButtonOpts, func() {
+		fileManager := GetFileSystemManager()
+		if fileManager == nil {
+			return
+		}
+		fileManager.ShowCustomOpenDialogue("Saved Territory List", []string{".json"}, func(path string) {
+			settings := LoadAPIImportSettings()
+			settings.TerritoryFile = path
+			if _, _, err := fetchTerritoryList(settings); err != nil {
+				showResultToast(err.Error(), false)
+				return
+			}
+			if err := SaveAPIImportSettings(settings); err != nil {
+				showResultToast("Failed to save import settings: "+err.Error(), false)
+				return
+			}
+			showResultToast("Territories import from "+path, true)
+		})
+	})
+	apiImportSection.Button("Use Saved Guild List...", saveLoadButtonOpts, func() {
+		fileManager := GetFileSystemManager()
+		if fileManager == nil {
+			return
+		}
+		fileManager.ShowCustomOpenDialogue("Saved Guild List", []string{".json"}, func(path string) {
+			settings := LoadAPIImportSettings()
+			settings.GuildFile = path
+			if _, err := fetchGuildList(settings); err != nil {
+				showResultToast(err.Error(), false)
+				return
+			}
+			if err := SaveAPIImportSettings(settings); err != nil {
+				showResultToast("Failed to save import settings: "+err.Error(), false)
+				return
+			}
+			showResultToast("Guilds import from "+path, true)
+		})
+	})
+	apiImportSection.Button("Import Guilds and Territories", saveLoadButtonOpts, func() {
+		gm := GetEnhancedGuildManager()
+		if gm == nil || gm.apiImportInProgress {
+			return
+		}
+		go gm.runAPIImport()
+	})
+
 	// Add spacer before Reset button
 	loadSaveSection.Spacer(DefaultSpacerOptions())
 
@@ -1114,6 +1526,22 @@ func (smm *StateManagementMenu) Update(deltaTime float64) {
 				newStatsText := fmt.Sprintf("Elapsed: %d tick (%02d:%02d:%02d)", elapsedTicks, hours, minutes, seconds)
 				smm.statsText.SetText(newStatsText)
 			}
+
+			// Refresh the annotation layers when they change, but not while the list is open
+			if smm.annotationDropdown != nil && !smm.annotationDropdown.IsOpen {
+				if version := eruntime.AnnotationsVersion(); version != smm.annotationsVersion {
+					smm.annotationsVersion = version
+					if _, ok := eruntime.GetAnnotationLayer(smm.annotationLayer); !ok {
+						smm.annotationLayer = ""
+					}
+					smm.annotationDropdown.SetOptions(annotationLayerOptions())
+					if smm.annotationLayer == "" {
+						smm.annotationDropdown.ClearSelection()
+					} else {
+						smm.annotationDropdown.SetSelected(smm.annotationLayer)
+					}
+				}
+			}
 		}
 	}
 }
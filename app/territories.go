@@ -22,11 +22,10 @@ import (
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-// Constants for game to pixel coordinate conversion offsets
-const (
-	OffsetX float64 = 2382
-	OffsetY float64 = 6572
-)
+// mapImageOffset returns the game to pixel coordinate conversion offsets of the active map image
+func mapImageOffset() (float64, float64) {
+	return eruntime.ActiveMapPack().ImageOffset()
+}
 
 // fontYOffset compensates for broken font being too high
 const fontYOffset = 12
@@ -823,12 +822,13 @@ func (tm *TerritoriesManager) stripJSONComments(jsonText string) string {
 
 // processTerritoriesBorders converts game coordinates to pixel coordinates for all territories
 func (tm *TerritoriesManager) processTerritoriesBorders() {
+	offsetX, offsetY := mapImageOffset()
 	for name, territory := range tm.Territories {
 		if len(territory.Location.Start) >= 2 && len(territory.Location.End) >= 2 {
-			x1 := territory.Location.Start[0] + OffsetX
-			y1 := territory.Location.Start[1] + OffsetY
-			x2 := territory.Location.End[0] + OffsetX
-			y2 := territory.Location.End[1] + OffsetY
+			x1 := territory.Location.Start[0] + offsetX
+			y1 := territory.Location.Start[1] + offsetY
+			x2 := territory.Location.End[0] + offsetX
+			y2 := territory.Location.End[1] + offsetY
 
 			// Ensure x1,y1 is the top-left corner and x2,y2 is bottom-right
 			if x1 > x2 {
@@ -1643,16 +1643,17 @@ func (tm *TerritoriesManager) DrawTerritories(screen *ebiten.Image, scale, viewX
 	if tm.IsTiebreakResolutionActive() {
 		hoveredTerritoryForTerritories = ""
 	}
+	offsetX, offsetY := mapImageOffset()
 	for _, name := range territoryNames {
 		territory, ok := tm.Territories[name]
 		if !ok || len(territory.Location.Start) < 2 || len(territory.Location.End) < 2 {
 			continue
 		}
 		// Rectangle as polygon (future: use real shapes)
-		x1 := float32((territory.Location.Start[0]+OffsetX)*scale + viewX)
-		y1 := float32((territory.Location.Start[1]+OffsetY)*scale + viewY)
-		x2 := float32((territory.Location.End[0]+OffsetX)*scale + viewX)
-		y2 := float32((territory.Location.End[1]+OffsetY)*scale + viewY)
+		x1 := float32((territory.Location.Start[0]+offsetX)*scale + viewX)
+		y1 := float32((territory.Location.Start[1]+offsetY)*scale + viewY)
+		x2 := float32((territory.Location.End[0]+offsetX)*scale + viewX)
+		y2 := float32((territory.Location.End[1]+offsetY)*scale + viewY)
 		pts := [][2]float32{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}}
 
 		// Use cached guild color for better performance
@@ -1989,7 +1990,7 @@ func (tm *TerritoriesManager) OpenGuildManagement() {
 
 // loadUpgradeData loads upgrade configuration from JSON
 func (tm *TerritoriesManager) loadUpgradeData() {
-	data, err := assets.AssetFiles.ReadFile("upgrades.json")
+	data, err := eruntime.ActiveMapPack().ReadCosts()
 	if err != nil {
 		return
 	}
@@ -2322,6 +2323,7 @@ func (tm *TerritoriesManager) drawHQOverlays(screen *ebiten.Image, scale, viewX,
 	// Calculate scale factor to maintain constant icon size
 	iconScale := iconSize / float64(max(hqWidth, hqHeight))
 
+	offsetX, offsetY := mapImageOffset()
 	for _, name := range territoryNames {
 		territory, ok := tm.Territories[name]
 		if !ok {
@@ -2356,8 +2358,8 @@ func (tm *TerritoriesManager) drawHQOverlays(screen *ebiten.Image, scale, viewX,
 		centerY := (territory.Location.Start[1] + territory.Location.End[1]) / 2
 
 		// Convert to screen coordinates
-		screenX := (centerX+OffsetX)*scale + viewX
-		screenY := (centerY+OffsetY)*scale + viewY
+		screenX := (centerX+offsetX)*scale + viewX
+		screenY := (centerY+offsetY)*scale + viewY
 
 		// Calculate icon position (centered on territory)
 		iconX := screenX - (iconSize / 2)
@@ -0,0 +1,267 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"RueaES/eruntime"
+	"RueaES/storage"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+const (
+	territoryCSVLineHeight   = 20
+	territoryCSVVisibleLines = 20
+)
+
+// TerritoryCSVModal shows the dry run of a territory CSV import and applies it on confirmation
+type TerritoryCSVModal struct {
+	modal        *EnhancedModal
+	applyButton  *EnhancedButton
+	cancelButton *EnhancedButton
+	visible      bool
+	plan         *eruntime.TerritoryCSVImport
+	source       string
+	lines        []previewLine
+	scroll       int
+	font         font.Face
+	buttonWidth  int
+	buttonHeight int
+}
+
+var (
+	globalTerritoryCSVModal *TerritoryCSVModal
+	territoryCSVModalOnce   sync.Once
+)
+
+// GetTerritoryCSVModal returns the global territory CSV import modal
+func GetTerritoryCSVModal() *TerritoryCSVModal {
+	territoryCSVModalOnce.Do(func() {
+		m := &TerritoryCSVModal{
+			modal:        NewEnhancedModal("Import Territory CSV", 760, 150+territoryCSVVisibleLines*territoryCSVLineHeight),
+			font:         loadWynncraftFont(16),
+			buttonWidth:  150,
+			buttonHeight: 40,
+		}
+
+		m.applyButton = NewEnhancedButton("Apply", 0, 0, m.buttonWidth, m.buttonHeight, m.apply)
+		m.applyButton.SetGreenButtonStyle()
+
+		m.cancelButton = NewEnhancedButton("Cancel", 0, 0, m.buttonWidth, m.buttonHeight, func() {
+			m.Hide()
+		})
+		m.cancelButton.SetGrayButtonStyle()
+
+		globalTerritoryCSVModal = m
+	})
+	return globalTerritoryCSVModal
+}
+
+// ExportTerritoryCSV asks for a file name and writes every territory to it as CSV
+func ExportTerritoryCSV() {
+	fileManager := GetFileSystemManager()
+	if fileManager == nil {
+		return
+	}
+	fileManager.ShowCustomSaveDialogue("Export Territories CSV", []string{".csv"}, func(path string) {
+		var buf bytes.Buffer
+		if err := eruntime.ExportTerritoriesCSV(&buf); err != nil {
+			showResultToast("Failed to export territories: "+err.Error(), false)
+			return
+		}
+		if err := storage.WriteFileAtomic(path, buf.Bytes(), 0644); err != nil {
+			showResultToast("Failed to write "+filepath.Base(path)+": "+err.Error(), false)
+			return
+		}
+		showResultToast("Territories exported to "+path, true)
+	})
+}
+
+// ImportTerritoryCSV asks for a CSV file and opens the dry run of importing it. mappingText holds
+// "Header=column" pairs for headers that don't match a column name.
+func ImportTerritoryCSV(mappingText string) {
+	mapping, err := eruntime.ParseTerritoryCSVMapping(mappingText)
+	if err != nil {
+		showResultToast("Invalid column mapping: "+err.Error(), false)
+		return
+	}
+	fileManager := GetFileSystemManager()
+	if fileManager == nil {
+		return
+	}
+	fileManager.ShowCustomOpenDialogue("Import Territories CSV", []string{".csv"}, func(path string) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			showResultToast("Failed to read "+filepath.Base(path)+": "+err.Error(), false)
+			return
+		}
+		plan, err := eruntime.PlanTerritoryCSV(bytes.NewReader(data), mapping)
+		if err != nil {
+			showResultToast("Invalid CSV: "+err.Error(), false)
+			return
+		}
+		GetTerritoryCSVModal().Show(plan, filepath.Base(path))
+	})
+}
+
+// Show opens the dry run of an import
+func (m *TerritoryCSVModal) Show(plan *eruntime.TerritoryCSVImport, source string) {
+	m.plan = plan
+	m.source = source
+	m.scroll = 0
+	m.lines = m.previewLines(plan)
+	m.visible = true
+	m.modal.Show()
+
+	m.applyButton.enabled = len(plan.Rows) > 0
+	m.applyButton.Text = fmt.Sprintf("Apply %d", len(plan.Rows))
+	m.updateButtonPositions()
+}
+
+// Hide closes the dry run
+func (m *TerritoryCSVModal) Hide() {
+	m.visible = false
+	m.plan = nil
+	m.modal.Hide()
+}
+
+// IsVisible returns whether the dry run is shown
+func (m *TerritoryCSVModal) IsVisible() bool {
+	return m != nil && m.visible
+}
+
+// previewLines lists the column mapping, the rejected rows and the per territory changes
+func (m *TerritoryCSVModal) previewLines(plan *eruntime.TerritoryCSVImport) []previewLine {
+	normal := EnhancedUIColors.Text
+	secondary := EnhancedUIColors.TextSecondary
+	changed := color.RGBA{255, 200, 100, 255}
+	warning := color.RGBA{255, 120, 120, 255}
+
+	lines := []previewLine{{
+		text: fmt.Sprintf("%d territories change, %d unchanged, %d rows rejected, %d unknown territories",
+			len(plan.Rows), plan.Unchanged, len(plan.Errors), len(plan.Unknown)),
+		color: normal,
+	}}
+
+	headers := make([]string, 0, len(plan.Mapping))
+	for header := range plan.Mapping {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	mapped := make([]string, 0, len(headers))
+	for _, header := range headers {
+		if header == plan.Mapping[header] {
+			mapped = append(mapped, header)
+		} else {
+			mapped = append(mapped, header+" -> "+plan.Mapping[header])
+		}
+	}
+	lines = append(lines, previewLine{text: "Columns: " + strings.Join(mapped, ", "), color: secondary})
+	if len(plan.Ignored) > 0 {
+		lines = append(lines, previewLine{text: "Ignored columns: " + strings.Join(plan.Ignored, ", "), color: secondary})
+	}
+
+	for _, rowErr := range plan.Errors {
+		lines = append(lines, previewLine{text: rowErr.Error(), color: warning})
+	}
+	for _, unknown := range plan.Unknown {
+		line := fmt.Sprintf("row %d: %q is not a territory", unknown.Row, unknown.Name)
+		if len(unknown.Suggestions) > 0 {
+			line += ", did you mean " + strings.Join(unknown.Suggestions, " or ") + "?"
+		}
+		lines = append(lines, previewLine{text: line, color: warning})
+	}
+
+	for _, row := range plan.Rows {
+		lines = append(lines, previewLine{text: fmt.Sprintf("%s (row %d)", row.Territory, row.Row), color: normal})
+		for _, change := range row.Changes {
+			lines = append(lines, previewLine{
+				text:  fmt.Sprintf("    %s: %s -> %s", change.Column, change.From, change.To),
+				color: changed,
+			})
+		}
+	}
+	return lines
+}
+
+func (m *TerritoryCSVModal) apply() {
+	plan := m.plan
+	if plan == nil {
+		return
+	}
+
+	applied, err := plan.Apply()
+	if err != nil {
+		showResultToast(err.Error(), false)
+		return
+	}
+	m.Hide()
+	showResultToast(fmt.Sprintf("Imported %d territories from %s", applied, m.source), true)
+}
+
+// Update handles input while the dry run is open and returns true if it consumed input
+func (m *TerritoryCSVModal) Update() bool {
+	if !m.IsVisible() {
+		return false
+	}
+
+	m.modal.Update()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		m.Hide()
+		return true
+	}
+
+	_, wheelY := ebiten.Wheel()
+	if wheelY > 0 && m.scroll > 0 {
+		m.scroll--
+	} else if wheelY < 0 && m.scroll+territoryCSVVisibleLines < len(m.lines) {
+		m.scroll++
+	}
+
+	mx, my := ebiten.CursorPosition()
+	m.applyButton.Update(mx, my)
+	m.cancelButton.Update(mx, my)
+	return true
+}
+
+// Draw renders the dry run
+func (m *TerritoryCSVModal) Draw(screen *ebiten.Image) {
+	if !m.IsVisible() {
+		return
+	}
+
+	m.modal.Draw(screen)
+
+	contentX, contentY, _, _ := m.modal.GetContentArea()
+	text.Draw(screen, "Dry run of "+m.source+", nothing has changed yet.", m.font, contentX, contentY+14, EnhancedUIColors.TextSecondary)
+
+	for i := 0; i < territoryCSVVisibleLines && m.scroll+i < len(m.lines); i++ {
+		line := m.lines[m.scroll+i]
+		text.Draw(screen, line.text, m.font, contentX, contentY+44+i*territoryCSVLineHeight, line.color)
+	}
+
+	m.applyButton.Draw(screen)
+	m.cancelButton.Draw(screen)
+}
+
+func (m *TerritoryCSVModal) updateButtonPositions() {
+	bounds := m.modal.GetBounds()
+	spacing := 16
+	totalWidth := 2*m.buttonWidth + spacing
+	startX := bounds.Min.X + (bounds.Dx()-totalWidth)/2
+	buttonY := bounds.Max.Y - 60
+
+	m.applyButton.SetPosition(startX, buttonY)
+	m.cancelButton.SetPosition(startX+m.buttonWidth+spacing, buttonY)
+}
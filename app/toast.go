@@ -153,6 +153,18 @@ func (tb *ToastBuilder) Show() {
 	globalToastManager.AddToast(tb.toast)
 }
 
+// showResultToast shows a short success or failure message
+func showResultToast(message string, ok bool) {
+	colour := color.RGBA{255, 100, 100, 255}
+	if ok {
+		colour = color.RGBA{100, 255, 100, 255}
+	}
+	NewToast().
+		Text(message, ToastOption{Colour: colour}).
+		AutoClose(time.Second * 4).
+		Show()
+}
+
 // addCloseButton adds a close button to the toast
 func (tb *ToastBuilder) addCloseButton() {
 	closeButton := ToastButton{
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"RueaES/eruntime"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseHeldTime reads a held duration such as "5d12h" or "90m", or a capture date such as
+// "2026-10-01" or an RFC 3339 timestamp, and returns how long the territory has been held.
+func parseHeldTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("enter a duration or a date")
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if since, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			held := time.Since(since)
+			if held < 0 {
+				return 0, fmt.Errorf("date is in the future")
+			}
+			return held, nil
+		}
+	}
+
+	// time.ParseDuration has no day unit, so days are split off first
+	var held time.Duration
+	if i := strings.Index(value, "d"); i > 0 {
+		days, err := strconv.ParseFloat(value[:i], 64)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid day count %q", value[:i])
+		}
+		held = time.Duration(days * float64(24*time.Hour))
+		value = value[i+1:]
+	}
+	if value != "" {
+		rest, err := time.ParseDuration(value)
+		if err != nil || rest < 0 {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		held += rest
+	}
+	return held, nil
+}
+
+// formatHeldSeconds formats a number of seconds as days, hours and minutes
+func formatHeldSeconds(seconds uint64) string {
+	days := seconds / 86400
+	hours := seconds % 86400 / 3600
+	minutes := seconds % 3600 / 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// treasuryOutlook describes when a territory reaches its next treasury level and what it gains
+func treasuryOutlook(territoryName, guildTag string) string {
+	projections, err := eruntime.ProjectTreasury(guildTag)
+	if err != nil {
+		return err.Error()
+	}
+
+	for _, p := range projections {
+		if p.Territory != territoryName {
+			continue
+		}
+		held := fmt.Sprintf("Held %s, treasury %s", formatHeldSeconds(p.HeldFor), eruntime.TreasuryLevelName(p.Level))
+		switch {
+		case p.Overridden:
+			return held + " (overridden)"
+		case len(p.Milestones) == 0:
+			return held
+		}
+		next := p.Milestones[0]
+		gain := next.Gain.Emeralds + next.Gain.Ores + next.Gain.Wood + next.Gain.Fish + next.Gain.Crops
+		return fmt.Sprintf("%s; %s in %s (%.1f%%, +%.0f/h)", held, next.Name, formatHeldSeconds(next.Remaining), next.Bonus, gain)
+	}
+	return "Territory not found"
+}
@@ -8,6 +8,7 @@ import (
 	"image/color"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -50,6 +51,12 @@ type TributeMenu struct {
 	fishInput    *EnhancedTextInput
 	cropInput    *EnhancedTextInput
 
+	// Schedule controls; schedule holds the kind and condition, the inputs hold the numbers
+	schedule         typedef.TributeSchedule
+	scheduleResource int
+	scheduleInputA   *EnhancedTextInput
+	scheduleInputB   *EnhancedTextInput
+
 	// UI constants
 	menuWidth   int
 	menuHeight  int
@@ -187,6 +194,7 @@ func (tm *TributeMenu) resetEditState() {
 	tm.fromGuildIndex = 0
 	tm.toGuildIndex = 0
 	tm.resourceAmounts = ResourceAmounts{}
+	tm.schedule = typedef.TributeSchedule{}
 }
 
 // Update handles input and updates the menu state
@@ -238,6 +246,12 @@ func (tm *TributeMenu) Update() bool {
 		if tm.cropInput != nil {
 			tm.cropInput.UpdateWithSkipInput(shouldSkipTextInput)
 		}
+		if tm.scheduleInputA != nil {
+			tm.scheduleInputA.UpdateWithSkipInput(shouldSkipTextInput)
+		}
+		if tm.scheduleInputB != nil {
+			tm.scheduleInputB.UpdateWithSkipInput(shouldSkipTextInput)
+		}
 	}
 
 	// Handle mouse clicks after dropdowns have had a chance to handle them
@@ -320,6 +334,7 @@ func (tm *TributeMenu) editTribute(tribute *typedef.ActiveTribute) {
 		Fish:     tribute.AmountPerHour.Fish,
 		Crops:    tribute.AmountPerHour.Crops,
 	}
+	tm.schedule = tribute.Schedule
 
 	// Initialize dropdown selections
 	tm.initializeDropdownSelections()
@@ -465,21 +480,22 @@ func (tm *TributeMenu) saveTribute() {
 		Crops:    cropAmount,
 	}
 
-	var err error
-	if tm.editingTribute != nil {
-		// Update existing tribute (delete and recreate for simplicity)
-		err = eruntime.DeleteTribute(tm.editingTribute.ID)
-		if err != nil {
-			return
-		}
-	}
-
 	// Create new tribute (1 minute interval)
-	_, err = eruntime.CreateNewTribute(fromGuildName, toGuildName, amount, 1)
+	_, err := eruntime.CreateScheduledTribute(fromGuildName, toGuildName, amount, 1, tm.buildSchedule())
 	if err != nil {
+		NewToast().Text("Could not save tribute: "+err.Error(), ToastOption{Colour: color.RGBA{255, 150, 100, 255}}).
+			AutoClose(5 * time.Second).
+			Show()
 		return
 	}
 
+	if tm.editingTribute != nil {
+		// Update existing tribute (replace it with the new one for simplicity)
+		if err := eruntime.DeleteTribute(tm.editingTribute.ID); err != nil {
+			return
+		}
+	}
+
 	// Go back to list screen
 	tm.state = TributeMenuList
 	tm.resetEditState()
@@ -647,6 +663,10 @@ func (tm *TributeMenu) drawTributeItem(screen *ebiten.Image, x, y, width int, tr
 		statusColor = color.RGBA{255, 100, 100, 255}
 		statusText = "Disabled"
 	}
+	if tribute.IsActive && tribute.LastError != "" {
+		statusColor = color.RGBA{255, 180, 80, 255}
+		statusText = "Undeliverable: " + tribute.LastError
+	}
 
 	// From/To names
 	fromName := tribute.FromGuildName
@@ -660,6 +680,9 @@ func (tm *TributeMenu) drawTributeItem(screen *ebiten.Image, x, y, width int, tr
 
 	// Main tribute info
 	tributeText := fmt.Sprintf("%s -> %s | %s", fromName, toName, statusText)
+	if summary := tributeScheduleSummary(tribute); summary != "" {
+		tributeText += " | " + summary
+	}
 	text.Draw(screen, tributeText, loadWynncraftFont(16), x+10, y+15, statusColor)
 
 	// Resource amounts (show per-minute amounts directly from AmountPerMinute)
@@ -874,6 +897,8 @@ func (tm *TributeMenu) drawEditScreen(screen *ebiten.Image) {
 	infoText := "Use Nil Sink/Source to spawn in/sink resources."
 	text.Draw(screen, infoText, loadWynncraftFont(14), menuX+30, y, color.RGBA{180, 180, 180, 255})
 
+	tm.drawScheduleSection(screen, menuX, menuY)
+
 	// NOW Draw dropdowns LAST so they appear on top of everything else
 	// Draw the non-focused dropdown first, then the focused one on top
 	if tm.fromGuildDropdown != nil && tm.toGuildDropdown != nil {
@@ -990,6 +1015,8 @@ func (tm *TributeMenu) handleEditClick(mx, my, menuX, menuY int) {
 		tm.saveTribute()
 		return
 	}
+
+	tm.handleScheduleClick(mx, my, menuX, menuY)
 }
 
 // initializeInputs creates and configures the dropdown and text input components
@@ -1022,6 +1049,10 @@ func (tm *TributeMenu) initializeInputs() {
 	tm.woodInput = TextInput("0", 0, 0, 100, 25, 10)
 	tm.fishInput = TextInput("0", 0, 0, 100, 25, 10)
 	tm.cropInput = TextInput("0", 0, 0, 100, 25, 10)
+
+	// Schedule input fields
+	tm.scheduleInputA = TextInput("0", 0, 0, scheduleInputWidth, scheduleControlHeight, 10)
+	tm.scheduleInputB = TextInput("0", 0, 0, scheduleInputWidth, scheduleControlHeight, 10)
 }
 
 // updateDropdownOptions updates the dropdown options with current guild data
@@ -1076,6 +1107,8 @@ func (tm *TributeMenu) initializeDropdownSelections() {
 	if tm.cropInput != nil {
 		tm.cropInput.Value = fmt.Sprintf("%.0f", tm.resourceAmounts.Crops)
 	}
+
+	tm.initializeScheduleInputs()
 }
 
 // updateHoverStates updates hover states for UI elements
@@ -1249,6 +1282,12 @@ func (tm *TributeMenu) unfocusAllTextInputs() {
 	if tm.cropInput != nil {
 		tm.cropInput.Focused = false
 	}
+	if tm.scheduleInputA != nil {
+		tm.scheduleInputA.Focused = false
+	}
+	if tm.scheduleInputB != nil {
+		tm.scheduleInputB.Focused = false
+	}
 }
 
 // HasTextInputFocused returns true if any text input is currently focused
@@ -1273,6 +1312,12 @@ func (tm *TributeMenu) HasTextInputFocused() bool {
 	if tm.cropInput != nil && tm.cropInput.Focused {
 		return true
 	}
+	if tm.scheduleInputA != nil && tm.scheduleInputA.Focused {
+		return true
+	}
+	if tm.scheduleInputB != nil && tm.scheduleInputB.Focused {
+		return true
+	}
 
 	// Check if any dropdown has input focused
 	if tm.fromGuildDropdown != nil && tm.fromGuildDropdown.InputFocused {
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"RueaES/eruntime"
+	"RueaES/typedef"
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+var tributeKindLabels = []string{"Recurring", "One-shot", "Limited", "Windowed", "Conditional"}
+
+var tributeConditionLabels = []string{"Sender above", "Receiver below"}
+
+var tributeScheduleResources = []string{"emeralds", "ores", "wood", "fish", "crops"}
+
+// tributeKindLabel returns the label of a schedule kind, tolerating kinds this build doesn't know
+func tributeKindLabel(kind typedef.TributeKind) string {
+	if kind < 0 || int(kind) >= len(tributeKindLabels) {
+		return fmt.Sprintf("Kind %d", kind)
+	}
+	return tributeKindLabels[kind]
+}
+
+// tributeConditionLabel returns the label of a schedule condition, tolerating unknown conditions
+func tributeConditionLabel(condition typedef.TributeCondition) string {
+	if condition < 0 || int(condition) >= len(tributeConditionLabels) {
+		return fmt.Sprintf("Condition %d", condition)
+	}
+	return tributeConditionLabels[condition]
+}
+
+// Schedule section layout, to the right of the resource inputs
+const (
+	scheduleLabelOffsetX  = 430
+	scheduleControlOffset = 560
+	scheduleFirstRowY     = 230
+	scheduleRowHeight     = 30
+	scheduleButtonWidth   = 150
+	scheduleInputWidth    = 100
+	scheduleControlHeight = 25
+)
+
+// scheduleRowY returns the label baseline of a row in the schedule section
+func scheduleRowY(menuY, row int) int {
+	return menuY + scheduleFirstRowY + row*scheduleRowHeight
+}
+
+// scheduleControlAt reports whether a point is inside the control of a schedule row
+func scheduleControlAt(mx, my, menuX, menuY, row, width int) bool {
+	x := menuX + scheduleControlOffset
+	y := scheduleRowY(menuY, row) - 18
+	return mx >= x && mx <= x+width && my >= y && my <= y+scheduleControlHeight
+}
+
+// scheduleInputRows returns the rows used by the A and B inputs for the current kind (-1 when unused)
+func (tm *TributeMenu) scheduleInputRows() (int, int) {
+	switch tm.schedule.Kind {
+	case typedef.TributeOneShot:
+		return 1, -1
+	case typedef.TributeLimited, typedef.TributeWindowed:
+		return 1, 2
+	case typedef.TributeConditional:
+		return 3, -1
+	default:
+		return -1, -1
+	}
+}
+
+// scheduleInputLabels returns the labels of the A and B inputs for the current kind
+func (tm *TributeMenu) scheduleInputLabels() (string, string) {
+	switch tm.schedule.Kind {
+	case typedef.TributeOneShot:
+		return "Send in (min):", ""
+	case typedef.TributeLimited:
+		return "Max transfers:", "Max total:"
+	case typedef.TributeWindowed:
+		return "Start in (min):", "Duration (min):"
+	case typedef.TributeConditional:
+		return "Threshold:", ""
+	default:
+		return "", ""
+	}
+}
+
+// initializeScheduleInputs fills the schedule inputs from the schedule being edited.
+// Tick based fields are shown relative to the current tick.
+func (tm *TributeMenu) initializeScheduleInputs() {
+	if tm.scheduleInputA == nil || tm.scheduleInputB == nil {
+		return
+	}
+
+	now := eruntime.GetCurrentTick()
+	minutesFromNow := func(tick uint64) uint64 {
+		if tick <= now {
+			return 0
+		}
+		return (tick - now) / 60
+	}
+
+	a, b := "0", "0"
+	switch tm.schedule.Kind {
+	case typedef.TributeOneShot:
+		a = strconv.FormatUint(minutesFromNow(tm.schedule.FireAt), 10)
+	case typedef.TributeLimited:
+		a = strconv.FormatUint(uint64(tm.schedule.MaxTransfers), 10)
+		b = fmt.Sprintf("%.0f", tm.schedule.MaxTotal)
+	case typedef.TributeWindowed:
+		a = strconv.FormatUint(minutesFromNow(tm.schedule.WindowStart), 10)
+		if tm.schedule.WindowEnd > tm.schedule.WindowStart {
+			b = strconv.FormatUint((tm.schedule.WindowEnd-tm.schedule.WindowStart)/60, 10)
+		}
+	case typedef.TributeConditional:
+		a = fmt.Sprintf("%.0f", tm.schedule.Threshold)
+	}
+	tm.scheduleInputA.Value = a
+	tm.scheduleInputB.Value = b
+
+	tm.scheduleResource = 0
+	for i, resource := range tributeScheduleResources {
+		if resource == tm.schedule.Resource {
+			tm.scheduleResource = i
+		}
+	}
+}
+
+// buildSchedule converts the schedule inputs into a tribute schedule
+func (tm *TributeMenu) buildSchedule() typedef.TributeSchedule {
+	parse := func(input *EnhancedTextInput) float64 {
+		if input == nil {
+			return 0
+		}
+		val, err := strconv.ParseFloat(input.Value, 64)
+		if err != nil || val < 0 {
+			return 0
+		}
+		return val
+	}
+	a := parse(tm.scheduleInputA)
+	b := parse(tm.scheduleInputB)
+	now := eruntime.GetCurrentTick()
+
+	schedule := typedef.TributeSchedule{Kind: tm.schedule.Kind}
+	switch schedule.Kind {
+	case typedef.TributeOneShot:
+		schedule.FireAt = now + uint64(a)*60
+	case typedef.TributeLimited:
+		schedule.MaxTransfers = uint32(a)
+		schedule.MaxTotal = b
+	case typedef.TributeWindowed:
+		schedule.WindowStart = now + uint64(a)*60
+		if b > 0 {
+			schedule.WindowEnd = schedule.WindowStart + uint64(b)*60
+		}
+	case typedef.TributeConditional:
+		schedule.Condition = tm.schedule.Condition
+		schedule.Resource = tributeScheduleResources[tm.scheduleResource]
+		schedule.Threshold = a
+	}
+	return schedule
+}
+
+// drawScheduleSection draws the schedule controls of the edit screen
+func (tm *TributeMenu) drawScheduleSection(screen *ebiten.Image, menuX, menuY int) {
+	labelX := menuX + scheduleLabelOffsetX
+	controlX := menuX + scheduleControlOffset
+	white := color.RGBA{255, 255, 255, 255}
+
+	text.Draw(screen, "Schedule:", loadWynncraftFont(18), labelX, scheduleRowY(menuY, 0)-30, white)
+
+	tm.drawScheduleButton(screen, controlX, scheduleRowY(menuY, 0), tributeKindLabel(tm.schedule.Kind))
+	text.Draw(screen, "Type:", loadWynncraftFont(16), labelX, scheduleRowY(menuY, 0), white)
+
+	if tm.schedule.Kind == typedef.TributeConditional {
+		text.Draw(screen, "When:", loadWynncraftFont(16), labelX, scheduleRowY(menuY, 1), white)
+		tm.drawScheduleButton(screen, controlX, scheduleRowY(menuY, 1), tributeConditionLabel(tm.schedule.Condition))
+		text.Draw(screen, "Resource:", loadWynncraftFont(16), labelX, scheduleRowY(menuY, 2), white)
+		tm.drawScheduleButton(screen, controlX, scheduleRowY(menuY, 2), tributeScheduleResources[tm.scheduleResource])
+	}
+
+	rowA, rowB := tm.scheduleInputRows()
+	labelA, labelB := tm.scheduleInputLabels()
+	if rowA >= 0 && tm.scheduleInputA != nil {
+		text.Draw(screen, labelA, loadWynncraftFont(16), labelX, scheduleRowY(menuY, rowA), white)
+		tm.scheduleInputA.X = controlX
+		tm.scheduleInputA.Y = scheduleRowY(menuY, rowA) - 18
+		tm.scheduleInputA.Draw(screen)
+	}
+	if rowB >= 0 && tm.scheduleInputB != nil {
+		text.Draw(screen, labelB, loadWynncraftFont(16), labelX, scheduleRowY(menuY, rowB), white)
+		tm.scheduleInputB.X = controlX
+		tm.scheduleInputB.Y = scheduleRowY(menuY, rowB) - 18
+		tm.scheduleInputB.Draw(screen)
+	}
+
+	hint := ""
+	switch tm.schedule.Kind {
+	case typedef.TributeOneShot:
+		hint = "Sends the hourly amount once."
+	case typedef.TributeLimited:
+		hint = "0 means no limit. Total sums all resources."
+	case typedef.TributeWindowed:
+		hint = "Duration 0 keeps the window open."
+	case typedef.TributeConditional:
+		hint = "Checks HQ storage every minute."
+	}
+	if hint != "" {
+		text.Draw(screen, hint, loadWynncraftFont(14), labelX, scheduleRowY(menuY, 4), color.RGBA{180, 180, 180, 255})
+	}
+}
+
+// drawScheduleButton draws a button that cycles through values when clicked
+func (tm *TributeMenu) drawScheduleButton(screen *ebiten.Image, x, labelY int, label string) {
+	y := labelY - 18
+	tm.drawRect(screen, float32(x), float32(y), scheduleButtonWidth, scheduleControlHeight, color.RGBA{70, 100, 150, 255}, false)
+	tm.drawRect(screen, float32(x), float32(y), scheduleButtonWidth, scheduleControlHeight, color.RGBA{100, 100, 120, 255}, true)
+	text.Draw(screen, label, loadWynncraftFont(14), x+8, labelY, color.RGBA{255, 255, 255, 255})
+}
+
+// handleScheduleClick handles clicks on the schedule controls, returning true if one was hit
+func (tm *TributeMenu) handleScheduleClick(mx, my, menuX, menuY int) bool {
+	if scheduleControlAt(mx, my, menuX, menuY, 0, scheduleButtonWidth) {
+		next := tm.schedule.Kind + 1
+		if next < 0 || int(next) >= len(tributeKindLabels) {
+			next = typedef.TributeRecurring
+		}
+		tm.schedule = typedef.TributeSchedule{Kind: next}
+		tm.initializeScheduleInputs()
+		return true
+	}
+
+	if tm.schedule.Kind == typedef.TributeConditional {
+		if scheduleControlAt(mx, my, menuX, menuY, 1, scheduleButtonWidth) {
+			next := tm.schedule.Condition + 1
+			if next < 0 || int(next) >= len(tributeConditionLabels) {
+				next = typedef.TributeSenderAbove
+			}
+			tm.schedule.Condition = next
+			return true
+		}
+		if scheduleControlAt(mx, my, menuX, menuY, 2, scheduleButtonWidth) {
+			tm.scheduleResource = (tm.scheduleResource + 1) % len(tributeScheduleResources)
+			return true
+		}
+	}
+
+	rowA, rowB := tm.scheduleInputRows()
+	if rowA >= 0 && scheduleControlAt(mx, my, menuX, menuY, rowA, scheduleInputWidth) {
+		tm.focusTextInput(tm.scheduleInputA)
+		return true
+	}
+	if rowB >= 0 && scheduleControlAt(mx, my, menuX, menuY, rowB, scheduleInputWidth) {
+		tm.focusTextInput(tm.scheduleInputB)
+		return true
+	}
+	return false
+}
+
+// tributeScheduleSummary describes a tribute's schedule and progress for the list screen
+func tributeScheduleSummary(tribute *typedef.ActiveTribute) string {
+	schedule := tribute.Schedule
+	now := eruntime.GetCurrentTick()
+
+	switch schedule.Kind {
+	case typedef.TributeOneShot:
+		if tribute.TransferCount > 0 {
+			return "One-shot, sent"
+		}
+		if schedule.FireAt > now {
+			return "One-shot in " + formatForecastTicks(int64(schedule.FireAt-now))
+		}
+		return "One-shot, pending"
+	case typedef.TributeLimited:
+		if schedule.MaxTransfers > 0 {
+			return fmt.Sprintf("Limited %d/%d", tribute.TransferCount, schedule.MaxTransfers)
+		}
+		return fmt.Sprintf("Limited %.0f/%.0f", tribute.TotalSent.Emeralds+tribute.TotalSent.Ores+
+			tribute.TotalSent.Wood+tribute.TotalSent.Fish+tribute.TotalSent.Crops, schedule.MaxTotal)
+	case typedef.TributeWindowed:
+		if schedule.WindowStart > now {
+			return "Window opens in " + formatForecastTicks(int64(schedule.WindowStart-now))
+		}
+		if schedule.WindowEnd > now {
+			return "Window closes in " + formatForecastTicks(int64(schedule.WindowEnd-now))
+		}
+		if schedule.WindowEnd == 0 {
+			return "Window open"
+		}
+		return "Window closed"
+	case typedef.TributeConditional:
+		return fmt.Sprintf("%s %.0f %s", tributeConditionLabel(schedule.Condition), schedule.Threshold, schedule.Resource)
+	}
+	return ""
+}
@@ -0,0 +1,389 @@
+package eruntime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"RueaES/typedef"
+)
+
+// annotationsExportType marks a JSON document written by ExportAnnotationsJSON
+const annotationsExportType = "annotations"
+
+// annotationState holds the annotation layers. They have their own lock so the map can draw them
+// every frame without waiting on the tick loop.
+type annotationState struct {
+	mu      sync.RWMutex
+	layers  []typedef.AnnotationLayer
+	version uint64 // Bumped on every change so readers can cache
+}
+
+var annotations annotationState
+
+// annotationsExport is the JSON document annotations are exported and imported as
+type annotationsExport struct {
+	Type   string                    `json:"type"`
+	Layers []typedef.AnnotationLayer `json:"layers"`
+}
+
+// AnnotationsVersion returns a number that changes whenever any layer changes
+func AnnotationsVersion() uint64 {
+	annotations.mu.RLock()
+	defer annotations.mu.RUnlock()
+	return annotations.version
+}
+
+// GetAnnotationLayers returns a copy of every layer in drawing order
+func GetAnnotationLayers() []typedef.AnnotationLayer {
+	annotations.mu.RLock()
+	defer annotations.mu.RUnlock()
+	return copyAnnotationLayers(annotations.layers)
+}
+
+// GetAnnotationLayer returns a copy of the named layer
+func GetAnnotationLayer(name string) (typedef.AnnotationLayer, bool) {
+	annotations.mu.RLock()
+	defer annotations.mu.RUnlock()
+	if i := annotationLayerIndexUnsafe(name); i >= 0 {
+		return copyAnnotationLayers(annotations.layers[i : i+1])[0], true
+	}
+	return typedef.AnnotationLayer{}, false
+}
+
+// SetAnnotationLayer adds a layer or replaces the layer of the same name. Annotations without an
+// ID get one.
+func SetAnnotationLayer(layer typedef.AnnotationLayer) error {
+	layer = copyAnnotationLayers([]typedef.AnnotationLayer{layer})[0]
+	if err := prepareAnnotationLayer(&layer); err != nil {
+		return err
+	}
+
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	if i := annotationLayerIndexUnsafe(layer.Name); i >= 0 {
+		annotations.layers[i] = layer
+	} else {
+		annotations.layers = append(annotations.layers, layer)
+	}
+	annotations.version++
+	return nil
+}
+
+// RemoveAnnotationLayer deletes a layer and its annotations
+func RemoveAnnotationLayer(name string) error {
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	i := annotationLayerIndexUnsafe(name)
+	if i < 0 {
+		return fmt.Errorf("annotation layer %q not found", name)
+	}
+	annotations.layers = append(annotations.layers[:i], annotations.layers[i+1:]...)
+	annotations.version++
+	return nil
+}
+
+// SetAnnotationLayerVisible shows or hides a whole layer
+func SetAnnotationLayerVisible(name string, visible bool) error {
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	i := annotationLayerIndexUnsafe(name)
+	if i < 0 {
+		return fmt.Errorf("annotation layer %q not found", name)
+	}
+	annotations.layers[i].Visible = visible
+	annotations.version++
+	return nil
+}
+
+// AddAnnotation adds an annotation to a layer, creating a visible layer with the annotation's
+// color if there is none. It returns the ID of the annotation.
+func AddAnnotation(layerName string, annotation typedef.Annotation) (string, error) {
+	layerName = strings.TrimSpace(layerName)
+	if layerName == "" {
+		return "", fmt.Errorf("layer name is empty")
+	}
+	annotation = copyAnnotation(annotation)
+	if annotation.ID == "" {
+		annotation.ID = newAnnotationID()
+	}
+	if err := validateAnnotation(annotation); err != nil {
+		return "", err
+	}
+
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	i := annotationLayerIndexUnsafe(layerName)
+	if i < 0 {
+		layer := typedef.AnnotationLayer{Name: layerName, Color: typedef.RGBAColor{R: 255, A: 255}, Visible: true}
+		if annotation.Color != nil {
+			layer.Color = *annotation.Color
+		}
+		annotations.layers = append(annotations.layers, layer)
+		i = len(annotations.layers) - 1
+	}
+	for _, existing := range annotations.layers[i].Annotations {
+		if existing.ID == annotation.ID {
+			return "", fmt.Errorf("annotation %q already exists in layer %q", annotation.ID, layerName)
+		}
+	}
+	annotations.layers[i].Annotations = append(annotations.layers[i].Annotations, annotation)
+	annotations.version++
+	return annotation.ID, nil
+}
+
+// UpdateAnnotation replaces the annotation with the same ID in a layer
+func UpdateAnnotation(layerName string, annotation typedef.Annotation) error {
+	annotation = copyAnnotation(annotation)
+	if err := validateAnnotation(annotation); err != nil {
+		return err
+	}
+
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	i := annotationLayerIndexUnsafe(layerName)
+	if i < 0 {
+		return fmt.Errorf("annotation layer %q not found", layerName)
+	}
+	for j, existing := range annotations.layers[i].Annotations {
+		if existing.ID == annotation.ID {
+			annotations.layers[i].Annotations[j] = annotation
+			annotations.version++
+			return nil
+		}
+	}
+	return fmt.Errorf("annotation %q not found in layer %q", annotation.ID, layerName)
+}
+
+// RemoveAnnotation deletes one annotation from a layer
+func RemoveAnnotation(layerName, id string) error {
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	i := annotationLayerIndexUnsafe(layerName)
+	if i < 0 {
+		return fmt.Errorf("annotation layer %q not found", layerName)
+	}
+	list := annotations.layers[i].Annotations
+	for j := range list {
+		if list[j].ID == id {
+			annotations.layers[i].Annotations = append(list[:j], list[j+1:]...)
+			annotations.version++
+			return nil
+		}
+	}
+	return fmt.Errorf("annotation %q not found in layer %q", id, layerName)
+}
+
+// ClearAnnotations removes every layer
+func ClearAnnotations() {
+	annotations.mu.Lock()
+	annotations.layers = nil
+	annotations.version++
+	annotations.mu.Unlock()
+}
+
+// ExportAnnotationsJSON writes the named layers, or every layer when no names are given, as JSON
+func ExportAnnotationsJSON(layerNames ...string) ([]byte, error) {
+	doc := annotationsExport{Type: annotationsExportType, Layers: []typedef.AnnotationLayer{}}
+	if len(layerNames) == 0 {
+		doc.Layers = GetAnnotationLayers()
+	}
+	for _, name := range layerNames {
+		layer, ok := GetAnnotationLayer(name)
+		if !ok {
+			return nil, fmt.Errorf("annotation layer %q not found", name)
+		}
+		doc.Layers = append(doc.Layers, layer)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ImportAnnotationsJSON adds the layers of an exported document, replacing layers of the same
+// name. Nothing is imported if any layer is invalid. It returns the number of layers imported.
+func ImportAnnotationsJSON(data []byte) (int, error) {
+	var doc annotationsExport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, fmt.Errorf("invalid annotations file: %v", err)
+	}
+	if doc.Type != annotationsExportType {
+		return 0, fmt.Errorf("invalid file type: expected '%s', got '%s'", annotationsExportType, doc.Type)
+	}
+	for i := range doc.Layers {
+		if err := prepareAnnotationLayer(&doc.Layers[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	for _, layer := range doc.Layers {
+		if i := annotationLayerIndexUnsafe(layer.Name); i >= 0 {
+			annotations.layers[i] = layer
+		} else {
+			annotations.layers = append(annotations.layers, layer)
+		}
+	}
+	annotations.version++
+	return len(doc.Layers), nil
+}
+
+// mergeAnnotationLayers adds the layers of a selectively imported state, replacing layers of the
+// same name like ImportAnnotationsJSON
+func mergeAnnotationLayers(layers []typedef.AnnotationLayer) {
+	valid := loadableAnnotationLayers(layers)
+	if len(valid) == 0 {
+		return
+	}
+
+	annotations.mu.Lock()
+	defer annotations.mu.Unlock()
+	for _, layer := range valid {
+		if i := annotationLayerIndexUnsafe(layer.Name); i >= 0 {
+			annotations.layers[i] = layer
+		} else {
+			annotations.layers = append(annotations.layers, layer)
+		}
+	}
+	annotations.version++
+}
+
+// replaceAnnotationLayers swaps every layer for those of a loaded state
+func replaceAnnotationLayers(layers []typedef.AnnotationLayer) {
+	valid := loadableAnnotationLayers(layers)
+
+	annotations.mu.Lock()
+	annotations.layers = valid
+	annotations.version++
+	annotations.mu.Unlock()
+}
+
+// loadableAnnotationLayers copies the layers of a state file. Invalid layers and annotations are
+// dropped rather than failing the load.
+func loadableAnnotationLayers(layers []typedef.AnnotationLayer) []typedef.AnnotationLayer {
+	valid := make([]typedef.AnnotationLayer, 0, len(layers))
+	for _, layer := range copyAnnotationLayers(layers) {
+		kept := layer.Annotations[:0]
+		seen := make(map[string]bool, len(layer.Annotations))
+		for _, annotation := range layer.Annotations {
+			if annotation.ID == "" {
+				annotation.ID = newAnnotationID()
+			}
+			if !seen[annotation.ID] && validateAnnotation(annotation) == nil {
+				seen[annotation.ID] = true
+				kept = append(kept, annotation)
+			}
+		}
+		layer.Annotations = kept
+		layer.Name = strings.TrimSpace(layer.Name)
+		if layer.Name != "" && annotationLayerIndex(valid, layer.Name) < 0 {
+			valid = append(valid, layer)
+		}
+	}
+	return valid
+}
+
+// prepareAnnotationLayer validates a layer, assigning IDs to annotations that have none
+func prepareAnnotationLayer(layer *typedef.AnnotationLayer) error {
+	layer.Name = strings.TrimSpace(layer.Name)
+	if layer.Name == "" {
+		return fmt.Errorf("layer name is empty")
+	}
+	seen := make(map[string]bool, len(layer.Annotations))
+	for i := range layer.Annotations {
+		annotation := &layer.Annotations[i]
+		if annotation.ID == "" {
+			annotation.ID = newAnnotationID()
+		}
+		if seen[annotation.ID] {
+			return fmt.Errorf("layer %q: duplicate annotation ID %q", layer.Name, annotation.ID)
+		}
+		seen[annotation.ID] = true
+		if err := validateAnnotation(*annotation); err != nil {
+			return fmt.Errorf("layer %q: %v", layer.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateAnnotation checks that an annotation has the points its kind needs
+func validateAnnotation(annotation typedef.Annotation) error {
+	minPoints, maxPoints := 1, 1
+	switch annotation.Kind {
+	case typedef.AnnotationMarker:
+	case typedef.AnnotationLabel:
+		if strings.TrimSpace(annotation.Text) == "" {
+			return fmt.Errorf("annotation %q: labels need text", annotation.ID)
+		}
+	case typedef.AnnotationPolyline:
+		minPoints, maxPoints = 2, math.MaxInt
+	case typedef.AnnotationPolygon:
+		minPoints, maxPoints = 3, math.MaxInt
+	default:
+		return fmt.Errorf("annotation %q: unknown kind %q, expected marker, polyline, polygon or label", annotation.ID, annotation.Kind)
+	}
+
+	if len(annotation.Points) < minPoints || len(annotation.Points) > maxPoints {
+		if minPoints == maxPoints {
+			return fmt.Errorf("annotation %q: a %s has exactly %d point", annotation.ID, annotation.Kind, minPoints)
+		}
+		return fmt.Errorf("annotation %q: a %s needs at least %d points", annotation.ID, annotation.Kind, minPoints)
+	}
+	for _, p := range annotation.Points {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("annotation %q: point is not a finite number", annotation.ID)
+		}
+	}
+	if annotation.Size < 0 || math.IsNaN(annotation.Size) || math.IsInf(annotation.Size, 0) {
+		return fmt.Errorf("annotation %q: size must be a positive number", annotation.ID)
+	}
+	return nil
+}
+
+func annotationLayerIndexUnsafe(name string) int {
+	return annotationLayerIndex(annotations.layers, name)
+}
+
+func annotationLayerIndex(layers []typedef.AnnotationLayer, name string) int {
+	name = strings.TrimSpace(name)
+	for i := range layers {
+		if strings.EqualFold(layers[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyAnnotationLayers deep copies layers so callers can't modify the shared slices
+func copyAnnotationLayers(layers []typedef.AnnotationLayer) []typedef.AnnotationLayer {
+	if layers == nil {
+		return nil
+	}
+	out := make([]typedef.AnnotationLayer, len(layers))
+	for i, layer := range layers {
+		out[i] = layer
+		out[i].Annotations = make([]typedef.Annotation, len(layer.Annotations))
+		for j, annotation := range layer.Annotations {
+			out[i].Annotations[j] = copyAnnotation(annotation)
+		}
+	}
+	return out
+}
+
+func copyAnnotation(annotation typedef.Annotation) typedef.Annotation {
+	annotation.Points = append([]typedef.AnnotationPoint(nil), annotation.Points...)
+	if annotation.Color != nil {
+		c := *annotation.Color
+		annotation.Color = &c
+	}
+	return annotation
+}
+
+func newAnnotationID() string {
+	bytes := make([]byte, 6)
+	rand.Read(bytes)
+	return "ann_" + hex.EncodeToString(bytes)
+}
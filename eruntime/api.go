@@ -2,7 +2,6 @@ package eruntime
 
 import (
 	"RueaES/typedef"
-	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -34,9 +33,21 @@ type TerritoryChangeCallback func(territoryName string)
 // GPUComputeFailureCallback is a function type for notifications when GPU compute fails.
 type GPUComputeFailureCallback func(message, stackTrace string)
 
+// TributeErrorCallback is a function type for notifications when a tribute cannot be delivered.
+type TributeErrorCallback func(tributeID, message string)
+
 // Global callback for territory changes
 var territoryChangeCallback TerritoryChangeCallback
 
+// Global callback for undeliverable tributes
+var tributeErrorCallback TributeErrorCallback
+
+// ManualRoutesMissingCallback is a function type for notifications when saved manual routes no longer exist after loading a state.
+type ManualRoutesMissingCallback func(missing []string)
+
+// Global callback for saved manual routes that could not be restored
+var manualRoutesMissingCallback ManualRoutesMissingCallback
+
 // Global callback for GPU compute failures
 var gpuComputeFailureCallback GPUComputeFailureCallback
 
@@ -60,6 +71,16 @@ func SetTerritoryChangeCallback(callback TerritoryChangeCallback) {
 	territoryChangeCallback = callback
 }
 
+// SetTributeErrorCallback allows external packages to register for undeliverable tribute notifications
+func SetTributeErrorCallback(callback TributeErrorCallback) {
+	tributeErrorCallback = callback
+}
+
+// SetManualRoutesMissingCallback allows external packages to be told which saved manual routes could not be restored
+func SetManualRoutesMissingCallback(callback ManualRoutesMissingCallback) {
+	manualRoutesMissingCallback = callback
+}
+
 // SetGPUComputeFailureCallback allows external packages to register GPU compute failure notifications
 func SetGPUComputeFailureCallback(callback GPUComputeFailureCallback) {
 	gpuComputeFailureCallback = callback
@@ -415,7 +436,7 @@ func SetGuild(territory string, guild typedef.Guild) *typedef.Territory {
 			t.Mu.Lock()
 
 			// impose 10 minutes cooldown, skip if therritory was acquired by another guild less than 10 minutes ago
-			if st.runtimeOptions.ImposeCooldown && st.tick-t.CapturedAt < 600 {
+			if st.runtimeOptions.ImposeCooldown && territoryHeldFor(t, st.tick) < 600 {
 				// skip
 				continue
 			}
@@ -429,6 +450,7 @@ func SetGuild(territory string, guild typedef.Guild) *typedef.Territory {
 			// If territory changes ownership, set captured time and reset treasury
 			if oldGuildName != guild.Name || oldGuildTag != guild.Tag {
 				t.CapturedAt = st.tick
+				t.HeldBefore = 0
 				t.Treasury = typedef.TreasuryLevelVeryLow
 
 				// If territory changes ownership, it should no longer be an HQ
@@ -469,7 +491,7 @@ func SetGuildT(territory *typedef.Territory, guild typedef.Guild) *typedef.Terri
 		return nil
 	}
 
-	if st.runtimeOptions.ImposeCooldown && st.tick-territory.CapturedAt < 600 {
+	if st.runtimeOptions.ImposeCooldown && territoryHeldFor(territory, st.tick) < 600 {
 		return territory
 	}
 
@@ -484,6 +506,7 @@ func SetGuildT(territory *typedef.Territory, guild typedef.Guild) *typedef.Terri
 	// If territory changes ownership, set captured time and reset treasury
 	if oldGuildName != guild.Name || oldGuildTag != guild.Tag {
 		territory.CapturedAt = st.tick
+		territory.HeldBefore = 0
 		territory.Treasury = typedef.TreasuryLevelVeryLow
 
 		// Remove from old guild's HQ map entry if it was an HQ
@@ -522,7 +545,7 @@ func SetGuildBatch(opts map[string]*typedef.Guild) []*typedef.Territory {
 			continue
 		}
 
-		if st.runtimeOptions.ImposeCooldown && st.tick-t.CapturedAt < 600 {
+		if st.runtimeOptions.ImposeCooldown && territoryHeldFor(t, st.tick) < 600 {
 			continue
 		}
 
@@ -772,6 +795,9 @@ func Reset() {
 	st.stateLoading = true
 	st.manualRouteToHQ = make(map[string]int)
 	st.manualRouteFromHQ = make(map[string]int)
+	st.scheduledCaptures = nil
+	st.seasonScores = make(map[string]*typedef.SeasonScore)
+	ClearAnnotations()
 
 	// Clean up transit manager
 	if st.transitManager != nil {
@@ -817,6 +843,7 @@ func Reset() {
 			territory.Treasury = typedef.TreasuryLevelVeryLow
 			territory.GenerationBonus = 0.0
 			territory.CapturedAt = 0
+			territory.HeldBefore = 0
 
 			// Reset tax to default (5%)
 			territory.Tax = typedef.TerritoryTax{
@@ -902,7 +929,9 @@ func Reset() {
 		ShowEmeraldGenerators:       st.runtimeOptions.ShowEmeraldGenerators,
 		PluginKeybinds:              st.runtimeOptions.PluginKeybinds,
 		ImposeCooldown:              st.runtimeOptions.ImposeCooldown,
+		DirectTributes:              st.runtimeOptions.DirectTributes,
 		SidemenuAnimations:          st.runtimeOptions.SidemenuAnimations,
+		WarParty:                    st.runtimeOptions.WarParty,
 	}
 
 	// Recreate transit manager
@@ -1365,7 +1394,8 @@ func IsAutoSaveEnabled() bool {
 	return autoSaveEnabled
 }
 
-// TriggerAutoSave performs an auto-save if enough time has passed and auto-save is enabled
+// TriggerAutoSave performs an auto-save if enough time has passed and auto-save is enabled.
+// The autosave is written atomically and copied into the autosave history.
 func TriggerAutoSave() {
 	if !autoSaveEnabled {
 		return
@@ -1385,10 +1415,9 @@ func TriggerAutoSave() {
 
 	// Perform auto-save in a goroutine to avoid blocking the main thread
 	go func() {
-		autosavePath := storage.DataFile("autosave.lz4")
-		SaveStateToFile(autosavePath)
-
-		lastAutoSaveTime = time.Now()
+		if err := writeAutoSave(); err != nil {
+			fmt.Printf("[AUTOSAVE] Autosave failed: %v\n", err)
+		}
 	}()
 }
 
@@ -1400,19 +1429,20 @@ func LoadAutoSave() bool {
 		return false
 	}
 
-	// Check if autosave.lz4 exists
-	autosavePath := storage.DataFile("autosave.lz4")
+	// Check if autosave.lz4 exists, falling back to the history when it is missing or damaged
+	autosavePath := storage.DataFile(autoSaveFile)
 	if _, err := os.Stat(autosavePath); os.IsNotExist(err) {
 		// fmt.Println("[AUTOSAVE] No auto-save file found")
-		autoSaveWasLoadedOnStartup = false
-		return false
+		_, autoSaveWasLoadedOnStartup = loadNewestAutoSaveHistory()
+		return autoSaveWasLoadedOnStartup
 	}
 
 	// fmt.Println("[AUTOSAVE] Auto-save file found, loading...")
 	err := LoadStateFromFile(autosavePath)
 	if err != nil {
-		autoSaveWasLoadedOnStartup = false
-		return false
+		fmt.Printf("[AUTOSAVE] Failed to load %s: %v\n", autoSaveFile, err)
+		_, autoSaveWasLoadedOnStartup = loadNewestAutoSaveHistory()
+		return autoSaveWasLoadedOnStartup
 	}
 
 	// fmt.Println("[AUTOSAVE] Auto-save loaded successfully")
@@ -1449,16 +1479,12 @@ func NotifyGuildSpecificUpdate(guildName string) {
 }
 
 func GetStateBytesInfo(data []byte) (version string, err error) {
-	jsonData, err := decompressLZ4(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to decompress LZ4 data: %w", err)
-	}
-
+	// Decode just the version, other fields are skipped
 	var stateInfo struct {
 		Version string `json:"version"`
 	}
-	if err := json.Unmarshal(jsonData, &stateInfo); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
+	if err := decodeStateData(data, &stateInfo); err != nil {
+		return "", err
 	}
 
 	return stateInfo.Version, nil
@@ -1472,19 +1498,5 @@ func GetStateFileInfo(filePath string) (version string, err error) {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Decompress LZ4 data using existing helper function
-	jsonData, err := decompressLZ4(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to decompress LZ4 data: %w", err)
-	}
-
-	// Parse JSON to extract just the version
-	var stateInfo struct {
-		Version string `json:"version"`
-	}
-	if err := json.Unmarshal(jsonData, &stateInfo); err != nil {
-		return "", fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	return stateInfo.Version, nil
+	return GetStateBytesInfo(data)
 }
@@ -0,0 +1,267 @@
+package eruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"RueaES/storage"
+	"RueaES/typedef"
+)
+
+const (
+	autoSaveFile             = "autosave.lz4"
+	autoSaveHistoryDir       = "autosaves"
+	autoSaveSettingsFile     = "autosave_settings.json"
+	autoSaveHistorySpacing   = time.Minute // Minimum time between two history entries
+	autoSaveHistoryTimestamp = "20060102-150405"
+	maxAutoSaveHistory       = 100
+	maxAutoSaveInterval      = 24 * 60 // Minutes
+)
+
+// autoSaveHistoryName matches history entries, which are named after their save time and tick
+var autoSaveHistoryName = regexp.MustCompile(`^autosave-(\d{8}-\d{6})-t(\d+)\.lz4$`)
+
+// AutoSaveEntry is an autosave kept in the history.
+type AutoSaveEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	SavedAt time.Time `json:"savedAt"`
+	Tick    uint64    `json:"tick"`
+	Size    int64     `json:"size"`
+}
+
+var (
+	autoSaveMu          sync.Mutex // Serializes autosave writes
+	lastAutoSaveHistory time.Time
+
+	autoSaveSettingsMu sync.RWMutex
+	autoSaveSettings   = typedef.DefaultAutoSaveSettings()
+	autoSaveIntervalCh chan struct{} // Closed to stop the running interval autosave loop
+)
+
+// loadAutoSaveSettings reads the saved autosave settings and starts the interval autosave
+func loadAutoSaveSettings() {
+	settings := typedef.DefaultAutoSaveSettings()
+	if data, err := storage.ReadDataFile(autoSaveSettingsFile); err == nil {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			fmt.Printf("[AUTOSAVE] Ignoring %s: %v\n", autoSaveSettingsFile, err)
+			settings = typedef.DefaultAutoSaveSettings()
+		}
+	}
+	clampAutoSaveSettings(&settings)
+
+	autoSaveSettingsMu.Lock()
+	autoSaveSettings = settings
+	autoSaveSettingsMu.Unlock()
+	restartAutoSaveInterval(settings.IntervalMinutes)
+}
+
+// clampAutoSaveSettings keeps the settings within their supported ranges
+func clampAutoSaveSettings(settings *typedef.AutoSaveSettings) {
+	settings.HistorySize = max(0, min(settings.HistorySize, maxAutoSaveHistory))
+	settings.IntervalMinutes = max(0, min(settings.IntervalMinutes, maxAutoSaveInterval))
+}
+
+// GetAutoSaveSettings returns the current autosave settings
+func GetAutoSaveSettings() typedef.AutoSaveSettings {
+	autoSaveSettingsMu.RLock()
+	defer autoSaveSettingsMu.RUnlock()
+	return autoSaveSettings
+}
+
+// SetAutoSaveSettings applies and saves the autosave settings. Shrinking the history removes the
+// oldest entries right away.
+func SetAutoSaveSettings(settings typedef.AutoSaveSettings) error {
+	clampAutoSaveSettings(&settings)
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteDataFile(autoSaveSettingsFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save autosave settings: %v", err)
+	}
+
+	autoSaveSettingsMu.Lock()
+	previous := autoSaveSettings
+	autoSaveSettings = settings
+	autoSaveSettingsMu.Unlock()
+
+	if previous.IntervalMinutes != settings.IntervalMinutes {
+		restartAutoSaveInterval(settings.IntervalMinutes)
+	}
+	if settings.HistorySize < previous.HistorySize {
+		autoSaveMu.Lock()
+		pruneAutoSaveHistory(settings.HistorySize)
+		autoSaveMu.Unlock()
+	}
+	return nil
+}
+
+// restartAutoSaveInterval stops the interval autosave loop and starts a new one unless minutes is 0
+func restartAutoSaveInterval(minutes int) {
+	autoSaveSettingsMu.Lock()
+	defer autoSaveSettingsMu.Unlock()
+
+	if autoSaveIntervalCh != nil {
+		close(autoSaveIntervalCh)
+		autoSaveIntervalCh = nil
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	autoSaveIntervalCh = stop
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				st.mu.RLock()
+				loading := st.stateLoading
+				st.mu.RUnlock()
+				if autoSaveEnabled && !loading {
+					if err := writeAutoSave(); err != nil {
+						fmt.Printf("[AUTOSAVE] Interval autosave failed: %v\n", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// writeAutoSave saves the state to the autosave file and copies it into the history,
+// at most once per autoSaveHistorySpacing
+func writeAutoSave() error {
+	autoSaveMu.Lock()
+	defer autoSaveMu.Unlock()
+
+	path := storage.DataFile(autoSaveFile)
+	tick, err := saveStateToFile(path)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	lastAutoSaveTime = now
+
+	settings := GetAutoSaveSettings()
+	if settings.HistorySize <= 0 || now.Sub(lastAutoSaveHistory) < autoSaveHistorySpacing {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read autosave for history: %v", err)
+	}
+	dir := storage.DataFile(autoSaveHistoryDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create autosave history: %v", err)
+	}
+	name := fmt.Sprintf("autosave-%s-t%d.lz4", now.Format(autoSaveHistoryTimestamp), tick)
+	if err := storage.WriteFileAtomic(filepath.Join(dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write autosave history: %v", err)
+	}
+	lastAutoSaveHistory = now
+
+	pruneAutoSaveHistory(settings.HistorySize)
+	return nil
+}
+
+// pruneAutoSaveHistory removes the oldest history entries beyond keep. Caller must hold autoSaveMu.
+func pruneAutoSaveHistory(keep int) {
+	entries, err := GetAutoSaveHistory()
+	if err != nil {
+		return
+	}
+	for _, entry := range entries[min(keep, len(entries)):] {
+		_ = os.Remove(entry.Path)
+	}
+}
+
+// GetAutoSaveHistory lists the autosave history, newest first
+func GetAutoSaveHistory() ([]AutoSaveEntry, error) {
+	dir := storage.DataFile(autoSaveHistoryDir)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read autosave history: %v", err)
+	}
+
+	var entries []AutoSaveEntry
+	for _, file := range files {
+		match := autoSaveHistoryName.FindStringSubmatch(file.Name())
+		if match == nil || file.IsDir() {
+			continue
+		}
+		savedAt, err := time.ParseInLocation(autoSaveHistoryTimestamp, match[1], time.Local)
+		if err != nil {
+			continue
+		}
+		tick, err := strconv.ParseUint(match[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		entry := AutoSaveEntry{
+			Name:    file.Name(),
+			Path:    filepath.Join(dir, file.Name()),
+			SavedAt: savedAt,
+			Tick:    tick,
+		}
+		if info, err := file.Info(); err == nil {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].SavedAt.Equal(entries[j].SavedAt) {
+			return entries[i].SavedAt.After(entries[j].SavedAt)
+		}
+		return entries[i].Tick > entries[j].Tick
+	})
+	return entries, nil
+}
+
+// RestoreAutoSave loads the named history entry, replacing the current state
+func RestoreAutoSave(name string) error {
+	entries, err := GetAutoSaveHistory()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name == name {
+			return LoadStateFromFile(entry.Path)
+		}
+	}
+	return fmt.Errorf("autosave %s not found", name)
+}
+
+// loadNewestAutoSaveHistory loads the newest history entry that loads cleanly, used when the
+// autosave file itself is missing or damaged. It returns the name of the loaded entry.
+func loadNewestAutoSaveHistory() (string, bool) {
+	entries, err := GetAutoSaveHistory()
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if err := LoadStateFromFile(entry.Path); err != nil {
+			fmt.Printf("[AUTOSAVE] Skipping %s: %v\n", entry.Name, err)
+			continue
+		}
+		return entry.Name, true
+	}
+	return "", false
+}
@@ -395,6 +395,12 @@ func calculateGenerationInternalWithTick(territory *typedef.Territory, tick uint
 
 // Helper function to calculate total costs for all upgrades and bonuses
 func calculateTotalCosts(territory *typedef.Territory) typedef.BasicResources {
+	return calculateTotalCostsWith(&st.costs, territory)
+}
+
+// calculateTotalCostsWith calculates the hourly upgrade and bonus costs of a territory under the
+// given cost table
+func calculateTotalCostsWith(costs *typedef.Costs, territory *typedef.Territory) typedef.BasicResources {
 	cost := typedef.BasicResources{}
 
 	// Helper function to safely get cost with bounds checking
@@ -406,29 +412,29 @@ func calculateTotalCosts(territory *typedef.Territory) typedef.BasicResources {
 	}
 
 	// Upgrades
-	cost.Ores += getCost(st.costs.UpgradesCost.Damage.Value, territory.Options.Upgrade.Set.Damage)
-	cost.Crops += getCost(st.costs.UpgradesCost.Attack.Value, territory.Options.Upgrade.Set.Attack)
-	cost.Fish += getCost(st.costs.UpgradesCost.Defence.Value, territory.Options.Upgrade.Set.Defence)
-	cost.Wood += getCost(st.costs.UpgradesCost.Health.Value, territory.Options.Upgrade.Set.Health)
+	cost.Ores += getCost(costs.UpgradesCost.Damage.Value, territory.Options.Upgrade.Set.Damage)
+	cost.Crops += getCost(costs.UpgradesCost.Attack.Value, territory.Options.Upgrade.Set.Attack)
+	cost.Fish += getCost(costs.UpgradesCost.Defence.Value, territory.Options.Upgrade.Set.Defence)
+	cost.Wood += getCost(costs.UpgradesCost.Health.Value, territory.Options.Upgrade.Set.Health)
 
 	// Bonuses with bounds checking
-	cost.Wood += getCost(st.costs.Bonuses.StrongerMinions.Cost, territory.Options.Bonus.Set.StrongerMinions)
-	cost.Fish += getCost(st.costs.Bonuses.TowerMultiAttack.Cost, territory.Options.Bonus.Set.TowerMultiAttack)
-	cost.Crops += getCost(st.costs.Bonuses.TowerAura.Cost, territory.Options.Bonus.Set.TowerAura)
-	cost.Ores += getCost(st.costs.Bonuses.TowerVolley.Cost, territory.Options.Bonus.Set.TowerVolley)
-	cost.Wood += getCost(st.costs.Bonuses.GatheringExperience.Cost, territory.Options.Bonus.Set.GatheringExperience)
-	cost.Fish += getCost(st.costs.Bonuses.MobExperience.Cost, territory.Options.Bonus.Set.MobExperience)
-	cost.Wood += getCost(st.costs.Bonuses.MobDamage.Cost, territory.Options.Bonus.Set.MobDamage)
-	cost.Wood += getCost(st.costs.Bonuses.PvPDamage.Cost, territory.Options.Bonus.Set.PvPDamage)
-	cost.Emeralds += getCost(st.costs.Bonuses.XPSeeking.Cost, territory.Options.Bonus.Set.XPSeeking)
-	cost.Fish += getCost(st.costs.Bonuses.TomeSeeking.Cost, territory.Options.Bonus.Set.TomeSeeking)
-	cost.Wood += getCost(st.costs.Bonuses.EmeraldsSeeking.Cost, territory.Options.Bonus.Set.EmeraldSeeking)
-	cost.Emeralds += getCost(st.costs.Bonuses.LargerResourceStorage.Cost, territory.Options.Bonus.Set.LargerResourceStorage)
-	cost.Wood += getCost(st.costs.Bonuses.LargerEmeraldsStorage.Cost, territory.Options.Bonus.Set.LargerEmeraldStorage)
-	cost.Emeralds += getCost(st.costs.Bonuses.EfficientResource.Cost, territory.Options.Bonus.Set.EfficientResource)
-	cost.Ores += getCost(st.costs.Bonuses.EfficientEmeralds.Cost, territory.Options.Bonus.Set.EfficientEmerald)
-	cost.Emeralds += getCost(st.costs.Bonuses.ResourceRate.Cost, territory.Options.Bonus.Set.ResourceRate)
-	cost.Crops += getCost(st.costs.Bonuses.EmeraldsRate.Cost, territory.Options.Bonus.Set.EmeraldRate)
+	cost.Wood += getCost(costs.Bonuses.StrongerMinions.Cost, territory.Options.Bonus.Set.StrongerMinions)
+	cost.Fish += getCost(costs.Bonuses.TowerMultiAttack.Cost, territory.Options.Bonus.Set.TowerMultiAttack)
+	cost.Crops += getCost(costs.Bonuses.TowerAura.Cost, territory.Options.Bonus.Set.TowerAura)
+	cost.Ores += getCost(costs.Bonuses.TowerVolley.Cost, territory.Options.Bonus.Set.TowerVolley)
+	cost.Wood += getCost(costs.Bonuses.GatheringExperience.Cost, territory.Options.Bonus.Set.GatheringExperience)
+	cost.Fish += getCost(costs.Bonuses.MobExperience.Cost, territory.Options.Bonus.Set.MobExperience)
+	cost.Wood += getCost(costs.Bonuses.MobDamage.Cost, territory.Options.Bonus.Set.MobDamage)
+	cost.Wood += getCost(costs.Bonuses.PvPDamage.Cost, territory.Options.Bonus.Set.PvPDamage)
+	cost.Emeralds += getCost(costs.Bonuses.XPSeeking.Cost, territory.Options.Bonus.Set.XPSeeking)
+	cost.Fish += getCost(costs.Bonuses.TomeSeeking.Cost, territory.Options.Bonus.Set.TomeSeeking)
+	cost.Wood += getCost(costs.Bonuses.EmeraldsSeeking.Cost, territory.Options.Bonus.Set.EmeraldSeeking)
+	cost.Emeralds += getCost(costs.Bonuses.LargerResourceStorage.Cost, territory.Options.Bonus.Set.LargerResourceStorage)
+	cost.Wood += getCost(costs.Bonuses.LargerEmeraldsStorage.Cost, territory.Options.Bonus.Set.LargerEmeraldStorage)
+	cost.Emeralds += getCost(costs.Bonuses.EfficientResource.Cost, territory.Options.Bonus.Set.EfficientResource)
+	cost.Ores += getCost(costs.Bonuses.EfficientEmeralds.Cost, territory.Options.Bonus.Set.EfficientEmerald)
+	cost.Emeralds += getCost(costs.Bonuses.ResourceRate.Cost, territory.Options.Bonus.Set.ResourceRate)
+	cost.Crops += getCost(costs.Bonuses.EmeraldsRate.Cost, territory.Options.Bonus.Set.EmeraldRate)
 
 	return cost
 }
@@ -532,12 +538,17 @@ func calculateAffordableCosts(territory *typedef.Territory) typedef.BasicResourc
 
 // Helper function to calculate resource generation based on affordable levels
 func calculateResourceGeneration(territory *typedef.Territory, resourceRate, emeraldRate, efficientResource, efficientEmerald int) (typedef.BasicResources, typedef.BasicResourcesSecond) {
+	return calculateResourceGenerationWith(&st.costs, territory, resourceRate, emeraldRate, efficientResource, efficientEmerald)
+}
+
+// calculateResourceGenerationWith calculates a territory's generation under the given cost table
+func calculateResourceGenerationWith(costs *typedef.Costs, territory *typedef.Territory, resourceRate, emeraldRate, efficientResource, efficientEmerald int) (typedef.BasicResources, typedef.BasicResourcesSecond) {
 	// Get base generation
 	baseGen := territory.ResourceGeneration.Base
 
 	// Calculate multipliers (these affect total generation per hour)
-	resourceMultiplier := float64(st.costs.Bonuses.EfficientResource.Value[efficientResource])
-	emeraldMultiplier := float64(st.costs.Bonuses.EfficientEmeralds.Value[efficientEmerald])
+	resourceMultiplier := float64(costs.Bonuses.EfficientResource.Value[efficientResource])
+	emeraldMultiplier := float64(costs.Bonuses.EfficientEmeralds.Value[efficientEmerald])
 
 	// Apply treasury bonus (percentage boost)
 	treasuryBonus := 1.0 + territory.GenerationBonus/100.0
@@ -553,8 +564,8 @@ func calculateResourceGeneration(territory *typedef.Territory, resourceRate, eme
 	baseEmeraldGenPerHour := baseGen.Emeralds * emeraldMultiplier * treasuryBonus
 
 	// Get rate intervals (how often generation happens)
-	resourceRateSeconds := float64(st.costs.Bonuses.ResourceRate.Value[resourceRate])
-	emeraldRateSeconds := float64(st.costs.Bonuses.EmeraldsRate.Value[emeraldRate])
+	resourceRateSeconds := float64(costs.Bonuses.ResourceRate.Value[resourceRate])
+	emeraldRateSeconds := float64(costs.Bonuses.EmeraldsRate.Value[emeraldRate])
 
 	// Set DeltaTime for resource and emerald generation
 	territory.ResourceGeneration.ResourceDeltaTime = uint8(resourceRateSeconds)
@@ -855,6 +866,11 @@ func doGenerate(territory *typedef.Territory) {
 
 // calculateTowerStats calculates the current tower stats based on the "At" upgrade levels
 func calculateTowerStats(territory *typedef.Territory) typedef.TowerStats {
+	return calculateTowerStatsWith(&st.costs, territory)
+}
+
+// calculateTowerStatsWith calculates the tower stats of a territory under the given cost table
+func calculateTowerStatsWith(costs *typedef.Costs, territory *typedef.Territory) typedef.TowerStats {
 	// Get the actual affordable upgrade levels (At values)
 	damageLevel := territory.Options.Upgrade.At.Damage
 	attackLevel := territory.Options.Upgrade.At.Attack
@@ -864,26 +880,26 @@ func calculateTowerStats(territory *typedef.Territory) typedef.TowerStats {
 	// Clamp levels to valid ranges
 	if damageLevel < 0 {
 		damageLevel = 0
-	} else if damageLevel >= len(st.costs.UpgradeMultiplier.Damage) {
-		damageLevel = len(st.costs.UpgradeMultiplier.Damage) - 1
+	} else if damageLevel >= len(costs.UpgradeMultiplier.Damage) {
+		damageLevel = len(costs.UpgradeMultiplier.Damage) - 1
 	}
 
 	if attackLevel < 0 {
 		attackLevel = 0
-	} else if attackLevel >= len(st.costs.UpgradeMultiplier.Attack) {
-		attackLevel = len(st.costs.UpgradeMultiplier.Attack) - 1
+	} else if attackLevel >= len(costs.UpgradeMultiplier.Attack) {
+		attackLevel = len(costs.UpgradeMultiplier.Attack) - 1
 	}
 
 	if healthLevel < 0 {
 		healthLevel = 0
-	} else if healthLevel >= len(st.costs.UpgradeMultiplier.Health) {
-		healthLevel = len(st.costs.UpgradeMultiplier.Health) - 1
+	} else if healthLevel >= len(costs.UpgradeMultiplier.Health) {
+		healthLevel = len(costs.UpgradeMultiplier.Health) - 1
 	}
 
 	if defenceLevel < 0 {
 		defenceLevel = 0
-	} else if defenceLevel >= len(st.costs.UpgradeMultiplier.Defence) {
-		defenceLevel = len(st.costs.UpgradeMultiplier.Defence) - 1
+	} else if defenceLevel >= len(costs.UpgradeMultiplier.Defence) {
+		defenceLevel = len(costs.UpgradeMultiplier.Defence) - 1
 	}
 
 	// base
@@ -894,10 +910,10 @@ func calculateTowerStats(territory *typedef.Territory) typedef.TowerStats {
 	baseDefence := 0.1 // 10%
 
 	// Apply upgrade multipliers
-	damageMultiplier := st.costs.UpgradeMultiplier.Damage[damageLevel]
-	attackMultiplier := st.costs.UpgradeMultiplier.Attack[attackLevel]
-	healthMultiplier := st.costs.UpgradeMultiplier.Health[healthLevel]
-	defenceMultiplier := st.costs.UpgradeMultiplier.Defence[defenceLevel]
+	damageMultiplier := costs.UpgradeMultiplier.Damage[damageLevel]
+	attackMultiplier := costs.UpgradeMultiplier.Attack[attackLevel]
+	healthMultiplier := costs.UpgradeMultiplier.Health[healthLevel]
+	defenceMultiplier := costs.UpgradeMultiplier.Defence[defenceLevel]
 
 	newDamageLow := baseDamageLow * damageMultiplier
 	newDamageHigh := baseDamageHigh * damageMultiplier
@@ -27,3 +27,40 @@ func ComputeChokepointsForGuild(guildTag string) (map[string]alg.ChokeReport, er
 
 	return alg.ComputeChokepoints(guildTag, territories, routes, opts.ChokepointEmeraldWeight, opts.ChokepointIncludeDownstream)
 }
+
+// ComputeRivalChokepoints runs the cross-guild chokepoint analysis between a guild and a rival on-demand.
+// With alliance set, both sides include their allies' claims.
+func ComputeRivalChokepoints(guildTag, rivalTag string, alliance bool) (*alg.RivalChokeReport, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	territories := make(map[string]*typedef.Territory, len(TerritoryMap))
+	routesTaken := make(map[string][]string, len(TerritoryMap))
+	for name, t := range TerritoryMap {
+		territories[name] = t
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		if !t.HQ && len(t.TradingRoutes) > 0 {
+			routesTaken[name] = routeToNames(t.TradingRoutes[0])
+		}
+		t.Mu.RUnlock()
+	}
+
+	routes := make(map[string][]string, len(TradingRoutesMap))
+	for name, adj := range TradingRoutesMap {
+		copied := make([]string, len(adj))
+		copy(copied, adj)
+		routes[name] = copied
+	}
+
+	return alg.ComputeRivalChokepoints(territories, routes, routesTaken, alg.RivalChokeConfig{
+		GuildTag:      guildTag,
+		RivalTag:      rivalTag,
+		Alliance:      alliance,
+		GuildAllies:   getGuildAllies(guildTag),
+		RivalAllies:   getGuildAllies(rivalTag),
+		EmeraldWeight: st.runtimeOptions.ChokepointEmeraldWeight,
+	})
+}
@@ -0,0 +1,223 @@
+package eruntime
+
+import (
+	"RueaES/typedef"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Tower bonus timings in seconds, indexed by bonus level
+var (
+	towerAuraIntervals   = []float64{0, 24, 18, 12}
+	towerVolleyIntervals = []float64{0, 20, 15, 10}
+)
+
+// towerVolleyHits is the number of projectiles in one volley, all aimed at the tower's primary target
+const towerVolleyHits = 3
+
+// TowerCombat is the outcome of a war party attacking a territory's tower.
+// Times are in seconds.
+type TowerCombat struct {
+	Territory string             `json:"territory"`
+	HQ        bool               `json:"hq"`
+	Stats     typedef.TowerStats `json:"stats"`
+
+	// Multipliers already included in Stats
+	LinkMultiplier     float64 `json:"linkMultiplier"`
+	ExternalMultiplier float64 `json:"externalMultiplier"`
+
+	EffectiveHealth float64 `json:"effectiveHealth"` // Health after defence
+
+	Targets         int     `json:"targets"`         // Players hit by each attack, from multi-attack
+	SingleTargetDPS float64 `json:"singleTargetDPS"` // Damage per second of regular attacks on one target
+	AuraDPS         float64 `json:"auraDPS"`         // Aura damage per second on each player
+	VolleyDPS       float64 `json:"volleyDPS"`       // Volley damage per second on the primary target
+	TowerDPS        float64 `json:"towerDPS"`        // Total damage per second dealt to the party
+
+	PartyDPS      float64 `json:"partyDPS"`
+	TimeToCapture float64 `json:"timeToCapture"` // Time for the party to take the tower down
+	PartySurvival float64 `json:"partySurvival"` // Time until the tower has killed every damage dealer, -1 if it never does
+	Capturable    bool    `json:"capturable"`    // Whether the party takes the tower before it is wiped
+}
+
+// CalculateTowerCombat estimates a war on a territory with its current upgrades.
+// A nil party uses the war party from the runtime options.
+func CalculateTowerCombat(territoryName string, party *typedef.WarParty) (*TowerCombat, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	territory := TerritoryMap[strings.TrimSpace(territoryName)]
+	if territory == nil {
+		return nil, fmt.Errorf("territory '%s' not found", territoryName)
+	}
+
+	result := towerCombatUnsafe(territory, combatParty(party))
+	return &result, nil
+}
+
+// CalculateGuildCombat estimates a war on every territory of a guild, easiest to capture first.
+// A nil party uses the war party from the runtime options.
+func CalculateGuildCombat(guildTag string, party *typedef.WarParty) ([]TowerCombat, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	if guildTag == "" || guildTag == "NONE" {
+		return nil, fmt.Errorf("guild tag cannot be empty")
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	warParty := combatParty(party)
+	var results []TowerCombat
+	for _, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		owner := territory.Guild.Tag
+		territory.Mu.RUnlock()
+		if owner == guildTag {
+			results = append(results, towerCombatUnsafe(territory, warParty))
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("guild %s owns no territories", guildTag)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Capturable != b.Capturable {
+			return a.Capturable
+		}
+		if a.TimeToCapture != b.TimeToCapture {
+			return a.TimeToCapture < b.TimeToCapture
+		}
+		return a.Territory < b.Territory
+	})
+	return results, nil
+}
+
+// combatParty returns a normalized copy of party, or the configured war party when nil
+func combatParty(party *typedef.WarParty) typedef.WarParty {
+	warParty := st.runtimeOptions.WarParty
+	if party != nil {
+		warParty = *party
+	}
+	normalizeWarParty(&warParty)
+	return warParty
+}
+
+// normalizeWarParty fills in missing war party values from the defaults
+func normalizeWarParty(party *typedef.WarParty) {
+	defaults := typedef.DefaultWarParty()
+	if party.Players <= 0 {
+		// A party without players comes from settings saved before the calculator existed
+		*party = defaults
+		return
+	}
+	if party.PlayerDPS <= 0 {
+		party.PlayerDPS = defaults.PlayerDPS
+	}
+	if party.PlayerHealth <= 0 {
+		party.PlayerHealth = defaults.PlayerHealth
+	}
+}
+
+// towerCombatUnsafe computes the combat outcome for one territory. Caller must hold st.mu.
+func towerCombatUnsafe(territory *typedef.Territory, party typedef.WarParty) TowerCombat {
+	territory.Mu.RLock()
+	// calculateTowerStats also updates the level fields, so it runs on a copy
+	forked := forkTerritory(territory)
+	territory.Mu.RUnlock()
+
+	return towerCombatWith(&st.costs, forked, party)
+}
+
+// towerCombatWith computes the combat outcome for a forked territory under the given cost table
+func towerCombatWith(costs *typedef.Costs, forked *typedef.Territory, party typedef.WarParty) TowerCombat {
+	stats := calculateTowerStatsWith(costs, forked)
+	bonus := forked.Options.Bonus.At
+
+	result := TowerCombat{
+		Territory:          forked.Name,
+		HQ:                 forked.HQ,
+		Stats:              stats,
+		LinkMultiplier:     calculateLinkBonus(&forked.Links),
+		ExternalMultiplier: calculateExternalBonus(&forked.Links, forked.HQ),
+		Targets:            min(1+max(bonus.TowerMultiAttack, 0), party.Players),
+	}
+	if stats.Defence < 1 {
+		result.EffectiveHealth = stats.Health / (1 - stats.Defence)
+	}
+
+	hit := (stats.Damage.Low + stats.Damage.High) / 2
+	result.SingleTargetDPS = hit * stats.Attack
+	if interval := bonusInterval(towerAuraIntervals, bonus.TowerAura); interval > 0 {
+		result.AuraDPS = hit / interval
+	}
+	if interval := bonusInterval(towerVolleyIntervals, bonus.TowerVolley); interval > 0 {
+		result.VolleyDPS = hit * towerVolleyHits / interval
+	}
+	result.TowerDPS = result.SingleTargetDPS*float64(result.Targets) + result.VolleyDPS + result.AuraDPS*float64(party.Players)
+
+	resolveCombat(&result, party)
+	return result
+}
+
+// resolveCombat works out the capture time and whether the party outlasts the tower.
+// With a tank, the tank soaks the primary target's damage until it dies, after which
+// the remaining players share the tower's damage between them.
+func resolveCombat(result *TowerCombat, party typedef.WarParty) {
+	players := float64(party.Players)
+	spread := result.SingleTargetDPS*float64(result.Targets) + result.VolleyDPS
+
+	// Damage each player takes per second when nobody tanks
+	untankedIntake := spread/players + result.AuraDPS
+
+	if !party.Tanked || party.Players == 1 {
+		result.PartyDPS = players * party.PlayerDPS
+		result.PartySurvival = party.PlayerHealth / untankedIntake
+		result.TimeToCapture = result.EffectiveHealth / result.PartyDPS
+		finishCombat(result)
+		return
+	}
+
+	dealers := players - 1
+	result.PartyDPS = dealers * party.PlayerDPS
+	result.TimeToCapture = result.EffectiveHealth / result.PartyDPS
+
+	// Phase one: the tank takes the primary target's attacks, extra targets fall on the dealers
+	tankIntake := result.SingleTargetDPS + result.VolleyDPS + result.AuraDPS
+	dealerIntake := result.SingleTargetDPS*float64(result.Targets-1)/dealers + result.AuraDPS
+	tankSurvival := party.PlayerHealth / tankIntake
+	dealerSurvival := party.PlayerHealth / dealerIntake
+
+	if dealerSurvival <= tankSurvival {
+		result.PartySurvival = dealerSurvival
+	} else {
+		// Phase two: the dealers spread the tower's damage after the tank falls
+		remaining := party.PlayerHealth - dealerIntake*tankSurvival
+		result.PartySurvival = tankSurvival + remaining/(spread/dealers+result.AuraDPS)
+	}
+	finishCombat(result)
+}
+
+// finishCombat decides the outcome once capture and survival times are known.
+// A tower that deals no damage leaves an infinite survival time, which JSON cannot hold.
+func finishCombat(result *TowerCombat) {
+	if math.IsInf(result.PartySurvival, 0) || math.IsNaN(result.PartySurvival) {
+		result.PartySurvival = -1
+		result.Capturable = true
+		return
+	}
+	result.Capturable = result.TimeToCapture <= result.PartySurvival
+}
+
+// bonusInterval returns the timing of a tower bonus level, 0 when inactive
+func bonusInterval(intervals []float64, level int) float64 {
+	if level <= 0 {
+		return 0
+	}
+	return intervals[min(level, len(intervals)-1)]
+}
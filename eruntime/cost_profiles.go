@@ -0,0 +1,438 @@
+package eruntime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RueaES/storage"
+	"RueaES/typedef"
+)
+
+const (
+	// costProfilesDirName is the data directory folder holding named cost profiles, one
+	// upgrades.json-format file per profile
+	costProfilesDirName = "costprofiles"
+
+	// costProfileSettingsFile records the profile in use, so it is applied again when the app
+	// starts or a state is loaded
+	costProfileSettingsFile = "cost_profile.json"
+)
+
+// activeCostProfile is the name of the profile the current costs came from, "" when they are the
+// built-in costs or came from a plugin. Guarded by st.mu like the costs.
+var activeCostProfile string
+
+// costProfileSettings is the cost profile selection saved in the data directory
+type costProfileSettings struct {
+	Name string `json:"name"` // Profile in use, empty for the costs of the map pack
+}
+
+// CostProfileInfo describes a stored cost profile
+type CostProfileInfo struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Active bool   `json:"active"`
+	Error  string `json:"error,omitempty"` // Set when the file no longer parses
+}
+
+// CostDiff is one value that differs between two cost tables. Level is -1 for values that apply
+// to the whole upgrade or bonus, such as its resource type or max level.
+type CostDiff struct {
+	Item  string `json:"item"`  // Upgrade or bonus name, e.g. "damage" or "towerMultiAttack"
+	Field string `json:"field"` // "cost", "multiplier", "value", "maxLevel" or "resourceType"
+	Level int    `json:"level"`
+	From  string `json:"from"` // "-" when the level does not exist in that table
+	To    string `json:"to"`
+}
+
+// GuildUpkeepChange is the hourly upgrade and bonus upkeep of a guild under two cost tables
+type GuildUpkeepChange struct {
+	Guild       string                 `json:"guild"`
+	Tag         string                 `json:"tag"`
+	Territories int                    `json:"territories"`
+	From        typedef.BasicResources `json:"from"`
+	To          typedef.BasicResources `json:"to"`
+}
+
+// Delta returns the upkeep change, negative where the guild pays less
+func (c GuildUpkeepChange) Delta() typedef.BasicResources {
+	return typedef.BasicResources{
+		Emeralds: c.To.Emeralds - c.From.Emeralds,
+		Ores:     c.To.Ores - c.From.Ores,
+		Wood:     c.To.Wood - c.From.Wood,
+		Fish:     c.To.Fish - c.From.Fish,
+		Crops:    c.To.Crops - c.From.Crops,
+	}
+}
+
+// CostProfilesDir returns the directory cost profiles are stored in
+func CostProfilesDir() string {
+	return storage.DataFile(costProfilesDirName)
+}
+
+// costProfilePath validates a profile name and returns its file
+func costProfilePath(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("profile name is empty")
+	}
+	if strings.ContainsAny(name, `/\:*?"<>|`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("profile name %q contains characters not allowed in file names", name)
+	}
+	return filepath.Join(CostProfilesDir(), name+".json"), nil
+}
+
+// ActiveCostProfile returns the name of the profile in use, "" for the built-in or plugin costs
+func ActiveCostProfile() string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return activeCostProfile
+}
+
+// savedCostProfile returns the profile recorded in the data directory, "" when there is none
+func savedCostProfile() string {
+	data, err := storage.ReadDataFile(costProfileSettingsFile)
+	if err != nil {
+		return ""
+	}
+	var settings costProfileSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return ""
+	}
+	return settings.Name
+}
+
+// saveCostProfileSetting records the profile in use in the data directory
+func saveCostProfileSetting(name string) {
+	data, err := json.MarshalIndent(costProfileSettings{Name: name}, "", "  ")
+	if err == nil {
+		err = storage.WriteDataFile(costProfileSettingsFile, data, 0o644)
+	}
+	if err != nil {
+		fmt.Printf("[COSTS] Failed to save the cost profile in use: %v\n", err)
+	}
+}
+
+// reloadCostsUnsafe applies the recorded cost profile, or the costs of the active map pack when
+// none is recorded or it no longer loads. Caller must hold st.mu.
+func reloadCostsUnsafe() error {
+	if name := savedCostProfile(); name != "" {
+		costs, err := LoadCostProfile(name)
+		if err == nil {
+			st.costs = costs
+			activeCostProfile = name
+			return nil
+		}
+		fmt.Printf("[COSTS] Failed to load cost profile %s, using the map pack costs: %v\n", name, err)
+	}
+
+	data, err := ActiveMapPack().ReadCosts()
+	if err != nil {
+		return err
+	}
+	costs, err := parseCostsJSON(data)
+	if err != nil {
+		return err
+	}
+	st.costs = costs
+	activeCostProfile = ""
+	return nil
+}
+
+// ListCostProfiles returns the stored profiles sorted by name
+func ListCostProfiles() []CostProfileInfo {
+	entries, err := os.ReadDir(CostProfilesDir())
+	if err != nil {
+		return nil
+	}
+	active := ActiveCostProfile()
+
+	var profiles []CostProfileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		info := CostProfileInfo{
+			Name:   name,
+			Path:   filepath.Join(CostProfilesDir(), entry.Name()),
+			Active: name == active,
+		}
+		if _, err := LoadCostProfile(name); err != nil {
+			info.Error = err.Error()
+		}
+		profiles = append(profiles, info)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+	return profiles
+}
+
+// LoadCostProfile reads a stored profile. The empty name is the built-in costs of the active map
+// pack.
+func LoadCostProfile(name string) (typedef.Costs, error) {
+	var data []byte
+	var err error
+	if name == "" {
+		data, err = ActiveMapPack().ReadCosts()
+	} else {
+		var path string
+		if path, err = costProfilePath(name); err != nil {
+			return typedef.Costs{}, err
+		}
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return typedef.Costs{}, err
+	}
+
+	costs, err := parseCostsJSON(data)
+	if err != nil {
+		return typedef.Costs{}, fmt.Errorf("invalid cost profile: %v", err)
+	}
+	return costs, nil
+}
+
+// ImportCostProfile validates costs in the upgrades.json format and stores them under name,
+// replacing a profile of the same name
+func ImportCostProfile(name string, data []byte) error {
+	path, err := costProfilePath(name)
+	if err != nil {
+		return err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("invalid cost profile: %v", err)
+	}
+	for _, key := range []string{"upgradesCost", "upgradeMultiplier", "bonuses"} {
+		if _, ok := sections[key]; !ok {
+			return fmt.Errorf("invalid cost profile: missing %q", key)
+		}
+	}
+	if _, err := parseCostsJSON(data); err != nil {
+		return fmt.Errorf("invalid cost profile: %v", err)
+	}
+
+	if err := os.MkdirAll(CostProfilesDir(), 0o755); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, data, 0o644)
+}
+
+// SaveCostProfile stores the costs currently in use under name
+func SaveCostProfile(name string) error {
+	st.mu.RLock()
+	data, err := json.MarshalIndent(st.costs, "", "  ")
+	st.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := ImportCostProfile(name, data); err != nil {
+		return err
+	}
+
+	name = strings.TrimSpace(name)
+	st.mu.Lock()
+	activeCostProfile = name
+	st.mu.Unlock()
+	saveCostProfileSetting(name)
+	return nil
+}
+
+// DeleteCostProfile removes a stored profile. The costs in use are kept.
+func DeleteCostProfile(name string) error {
+	path, err := costProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	cleared := activeCostProfile == strings.TrimSpace(name)
+	if cleared {
+		activeCostProfile = ""
+	}
+	st.mu.Unlock()
+	if cleared {
+		saveCostProfileSetting("")
+	}
+	return nil
+}
+
+// SwitchCostProfile replaces the costs in use with a stored profile, or the built-in costs for
+// the empty name, and reports how the upkeep of every guild changes
+func SwitchCostProfile(name string) ([]GuildUpkeepChange, error) {
+	costs, err := LoadCostProfile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	st.mu.Lock()
+	changes := compareGuildUpkeepUnsafe(&st.costs, &costs)
+	st.costs = costs
+	activeCostProfile = name
+	st.mu.Unlock()
+
+	saveCostProfileSetting(name)
+	if stateChangeCallback != nil {
+		stateChangeCallback()
+	}
+	return changes, nil
+}
+
+// CompareGuildUpkeep reports how the upkeep of every guild would change if the current
+// territories ran under costs instead of the costs in use. Nothing is changed. Guilds are sorted
+// by the largest change first.
+func CompareGuildUpkeep(costs *typedef.Costs) []GuildUpkeepChange {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return compareGuildUpkeepUnsafe(&st.costs, costs)
+}
+
+// compareGuildUpkeepUnsafe is CompareGuildUpkeep from current costs. Caller must hold st.mu.
+func compareGuildUpkeepUnsafe(current, costs *typedef.Costs) []GuildUpkeepChange {
+	byGuild := make(map[string]*GuildUpkeepChange)
+	for _, t := range st.territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		guild := typedef.Guild{Name: t.Guild.Name, Tag: t.Guild.Tag}
+		from := calculateTotalCostsWith(current, t)
+		to := calculateTotalCostsWith(costs, t)
+		t.Mu.RUnlock()
+
+		if guild.Name == "" || guild.Name == "No Guild" {
+			continue
+		}
+		change := byGuild[guild.Name]
+		if change == nil {
+			change = &GuildUpkeepChange{Guild: guild.Name, Tag: guild.Tag}
+			byGuild[guild.Name] = change
+		}
+		change.Territories++
+		change.From = change.From.Add(&from)
+		change.To = change.To.Add(&to)
+	}
+
+	changes := make([]GuildUpkeepChange, 0, len(byGuild))
+	for _, change := range byGuild {
+		changes = append(changes, *change)
+	}
+	size := func(c GuildUpkeepChange) float64 {
+		d := c.Delta()
+		return math.Abs(d.Emeralds) + math.Abs(d.Ores) + math.Abs(d.Wood) + math.Abs(d.Fish) + math.Abs(d.Crops)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		si, sj := size(changes[i]), size(changes[j])
+		if si != sj {
+			return si > sj
+		}
+		return changes[i].Guild < changes[j].Guild
+	})
+	return changes
+}
+
+// DiffCosts lists every upgrade cost, upgrade multiplier and bonus cost, value, max level and
+// resource type that differs between two cost tables
+func DiffCosts(from, to *typedef.Costs) []CostDiff {
+	var diffs []CostDiff
+
+	fromUpgrades, toUpgrades := upgradeCostTables(from), upgradeCostTables(to)
+	fromMultipliers, toMultipliers := upgradeMultiplierTables(from), upgradeMultiplierTables(to)
+	for i, name := range upgradeNames {
+		diffs = appendTextDiff(diffs, name, "resourceType", fromUpgrades[i].ResourceType, toUpgrades[i].ResourceType)
+		diffs = appendIntLevelDiffs(diffs, name, "cost", fromUpgrades[i].Value, toUpgrades[i].Value)
+		diffs = appendFloatLevelDiffs(diffs, name, "multiplier", fromMultipliers[i], toMultipliers[i])
+	}
+
+	fromBonuses, toBonuses := bonusCostTables(from), bonusCostTables(to)
+	for i, name := range bonusNames {
+		a, b := fromBonuses[i], toBonuses[i]
+		diffs = appendTextDiff(diffs, name, "resourceType", a.ResourceType, b.ResourceType)
+		diffs = appendTextDiff(diffs, name, "maxLevel", strconv.Itoa(a.MaxLevel), strconv.Itoa(b.MaxLevel))
+		diffs = appendIntLevelDiffs(diffs, name, "cost", a.Cost, b.Cost)
+		diffs = appendFloatLevelDiffs(diffs, name, "value", a.Value, b.Value)
+	}
+	return diffs
+}
+
+// upgradeNames and bonusNames follow the names GetUpgradeCost and GetBonusCost accept
+var (
+	upgradeNames = []string{"damage", "attack", "health", "defence"}
+	bonusNames   = []string{
+		"strongerMinions", "towerMultiAttack", "towerAura", "towerVolley", "gatheringExperience",
+		"mobExperience", "mobDamage", "pvpDamage", "xpSeeking", "tomeSeeking", "emeraldSeeking",
+		"largerResourceStorage", "largerEmeraldStorage", "efficientResource", "efficientEmerald",
+		"resourceRate", "emeraldRate",
+	}
+)
+
+func upgradeCostTables(c *typedef.Costs) []typedef.UpgradeCosts {
+	u := &c.UpgradesCost
+	return []typedef.UpgradeCosts{u.Damage, u.Attack, u.Health, u.Defence}
+}
+
+func upgradeMultiplierTables(c *typedef.Costs) [][]float64 {
+	m := &c.UpgradeMultiplier
+	return [][]float64{m.Damage, m.Attack, m.Health, m.Defence}
+}
+
+func bonusCostTables(c *typedef.Costs) []typedef.BonusCosts {
+	b := &c.Bonuses
+	return []typedef.BonusCosts{
+		b.StrongerMinions, b.TowerMultiAttack, b.TowerAura, b.TowerVolley, b.GatheringExperience,
+		b.MobExperience, b.MobDamage, b.PvPDamage, b.XPSeeking, b.TomeSeeking, b.EmeraldsSeeking,
+		b.LargerResourceStorage, b.LargerEmeraldsStorage, b.EfficientResource, b.EfficientEmeralds,
+		b.ResourceRate, b.EmeraldsRate,
+	}
+}
+
+func appendTextDiff(diffs []CostDiff, item, field, from, to string) []CostDiff {
+	if from == to {
+		return diffs
+	}
+	return append(diffs, CostDiff{Item: item, Field: field, Level: -1, From: from, To: to})
+}
+
+func appendIntLevelDiffs(diffs []CostDiff, item, field string, from, to []int) []CostDiff {
+	format := func(values []int, level int) string {
+		if level >= len(values) {
+			return "-"
+		}
+		return strconv.Itoa(values[level])
+	}
+	for level := 0; level < max(len(from), len(to)); level++ {
+		if a, b := format(from, level), format(to, level); a != b {
+			diffs = append(diffs, CostDiff{Item: item, Field: field, Level: level, From: a, To: b})
+		}
+	}
+	return diffs
+}
+
+func appendFloatLevelDiffs(diffs []CostDiff, item, field string, from, to []float64) []CostDiff {
+	format := func(values []float64, level int) string {
+		if level >= len(values) {
+			return "-"
+		}
+		return strconv.FormatFloat(values[level], 'f', -1, 64)
+	}
+	for level := 0; level < max(len(from), len(to)); level++ {
+		if a, b := format(from, level), format(to, level); a != b {
+			diffs = append(diffs, CostDiff{Item: item, Field: field, Level: level, From: a, To: b})
+		}
+	}
+	return diffs
+}
@@ -5,13 +5,13 @@ import (
 	"errors"
 	"fmt"
 
-	"RueaES/assets"
 	"RueaES/typedef"
 )
 
-// ReloadDefaultCosts loads the built-in costs from assets/upgrades.json.
+// ReloadDefaultCosts loads the costs of the active map pack, which are the built-in costs from
+// assets/upgrades.json unless the pack brings its own.
 func ReloadDefaultCosts() error {
-	data, err := assets.AssetFiles.ReadFile("upgrades.json")
+	data, err := ActiveMapPack().ReadCosts()
 	if err != nil {
 		return err
 	}
@@ -43,10 +43,25 @@ func SetCostsFromMap(payload map[string]any) error {
 	return applyCostsJSON(data)
 }
 
+// applyCostsJSON replaces the costs in use. The costs no longer come from a named profile.
 func applyCostsJSON(data []byte) error {
+	costs, err := parseCostsJSON(data)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	st.costs = costs
+	activeCostProfile = ""
+	st.mu.Unlock()
+	saveCostProfileSetting("")
+	return nil
+}
+
+// parseCostsJSON decodes costs in the upgrades.json format and pads missing tables
+func parseCostsJSON(data []byte) (typedef.Costs, error) {
 	var costs typedef.Costs
 	if err := json.Unmarshal(data, &costs); err != nil {
-		return err
+		return costs, err
 	}
 
 	// Basic validation to avoid empty slices causing panics.
@@ -97,6 +112,5 @@ func applyCostsJSON(data []byte) error {
 	padBonus(&costs.Bonuses.ResourceRate)
 	padBonus(&costs.Bonuses.EmeraldsRate)
 
-	st.costs = costs
-	return nil
+	return costs, nil
 }
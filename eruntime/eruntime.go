@@ -64,6 +64,11 @@ type state struct {
 	manualRouteToHQ   map[string]int
 	manualRouteFromHQ map[string]int
 
+	// Season rating: pending captures, points per guild tag and the formula they are earned with
+	scheduledCaptures []*typedef.ScheduledCapture
+	seasonScores      map[string]*typedef.SeasonScore
+	seasonFormula     typedef.SeasonRatingFormula
+
 	// No need, each territory has its own mutex
 	// mu sync.Mutex // mutex to protect state changes
 
@@ -106,6 +111,7 @@ func init() {
 		territoryMap:          make(map[string]*typedef.Territory),
 		manualRouteToHQ:       make(map[string]int),
 		manualRouteFromHQ:     make(map[string]int),
+		seasonScores:          make(map[string]*typedef.SeasonScore),
 		tickQueue:             make(chan struct{}, 50000),
 		useParallelProcessing: true, // Enable parallel processing by default for better performance
 	}
@@ -114,9 +120,14 @@ func init() {
 	go st.processQueuedTicks()
 
 	loadTerritories()
-	if err := ReloadDefaultCosts(); err != nil {
+	st.mu.Lock()
+	err := reloadCostsUnsafe()
+	st.mu.Unlock()
+	if err != nil {
 		panic("failed to load default costs: " + err.Error())
 	}
+	loadSeasonRatingFormula()
+	loadAutoSaveSettings()
 
 	// Start the timer for resource generation
 	st.start()
@@ -264,6 +275,27 @@ func CreateGuildToGuildTribute(fromGuildName, toGuildName string, amount typedef
 	return tribute.ID, nil
 }
 
+// CreateScheduledTribute creates a tribute with a non-recurring schedule.
+// Either guild name may be empty for spawned or sunk resources, as with CreateTribute.
+func CreateScheduledTribute(fromGuildName, toGuildName string, amount typedef.BasicResources, intervalMinutes uint32, schedule typedef.TributeSchedule) (string, error) {
+	if intervalMinutes == 0 && schedule.Kind == typedef.TributeOneShot {
+		intervalMinutes = 1 // One-shot tributes don't use the interval
+	}
+
+	tribute, err := CreateTribute(fromGuildName, toGuildName, amount, intervalMinutes)
+	if err != nil {
+		return "", err
+	}
+	tribute.Schedule = schedule
+
+	err = AddTribute(tribute)
+	if err != nil {
+		return "", err
+	}
+
+	return tribute.ID, nil
+}
+
 // GetAllActiveTributes returns all active tributes in the system
 func GetAllActiveTributes() []*typedef.ActiveTribute {
 	return GetActiveTributes()
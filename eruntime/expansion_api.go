@@ -0,0 +1,25 @@
+package eruntime
+
+import (
+	"RueaES/alg"
+	"RueaES/typedef"
+	"maps"
+)
+
+// PlanClaimExpansion searches contiguous expansions of a guild's claim on-demand.
+func PlanClaimExpansion(guildTag string, cfg alg.ExpansionConfig) ([]alg.ExpansionPlan, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	territories := make(map[string]*typedef.Territory, len(TerritoryMap))
+	maps.Copy(territories, TerritoryMap)
+
+	routes := make(map[string][]string, len(TradingRoutesMap))
+	for name, adj := range TradingRoutesMap {
+		copied := make([]string, len(adj))
+		copy(copied, adj)
+		routes[name] = copied
+	}
+
+	return alg.ComputeExpansionPlans(guildTag, territories, routes, getGuildAllies(guildTag), cfg)
+}
@@ -0,0 +1,473 @@
+package eruntime
+
+import (
+	"fmt"
+	"math"
+
+	"RueaES/typedef"
+)
+
+// forecastNever marks a forecast event that will not happen at the current rates.
+const forecastNever int64 = -1
+
+// forecastResourceNames lists resources in the order used by every forecast.
+var forecastResourceNames = []string{"emeralds", "ores", "wood", "fish", "crops"}
+
+// ResourceForecast projects when a single stored resource fills up or runs out.
+// Tick counts are relative to Tick of the parent forecast, -1 means never at the current rate.
+type ResourceForecast struct {
+	Resource     string  `json:"resource"`
+	Stored       float64 `json:"stored"`
+	Capacity     float64 `json:"capacity"`
+	NetPerHour   float64 `json:"net_per_hour"`
+	TicksToFull  int64   `json:"ticks_to_full"`
+	TicksToEmpty int64   `json:"ticks_to_empty"`
+}
+
+// AffordabilityForecast projects when a territory can run an upgrade or bonus level.
+// AffordableNow mirrors CheckUpgradeAffordabilityWithTolerance / CheckBonusAffordabilityWithTolerance.
+type AffordabilityForecast struct {
+	Territory     string  `json:"territory"`
+	Kind          string  `json:"kind"` // "upgrade" or "bonus"
+	Type          string  `json:"type"`
+	Level         int     `json:"level"`
+	Resource      string  `json:"resource"`
+	CostPerHour   float64 `json:"cost_per_hour"`
+	AffordableNow bool    `json:"affordable_now"`
+	TicksToAfford int64   `json:"ticks_to_afford"` // 0 when affordable now, -1 when never at the current rate
+}
+
+// StorageForecast is a point-in-time projection of a territory or guild HQ storage.
+// It is only valid for the tick it was computed at; callers should recompute as the simulation advances.
+type StorageForecast struct {
+	Territory string                  `json:"territory"`
+	GuildTag  string                  `json:"guild_tag"`
+	HQ        bool                    `json:"hq"`
+	GuildWide bool                    `json:"guild_wide"` // True when the net includes every guild territory and tributes
+	Tick      uint64                  `json:"tick"`
+	Resources []ResourceForecast      `json:"resources"`
+	Pending   []AffordabilityForecast `json:"pending,omitempty"` // Configured levels that are not currently running
+}
+
+// IsStale reports whether the forecast was computed on an earlier tick than the current one.
+func (f *StorageForecast) IsStale() bool {
+	return f == nil || f.Tick != Tick()
+}
+
+// Resource returns the forecast entry for the given resource name.
+func (f *StorageForecast) Resource(name string) (ResourceForecast, bool) {
+	if f == nil {
+		return ResourceForecast{}, false
+	}
+	for _, r := range f.Resources {
+		if r.Resource == name {
+			return r, true
+		}
+	}
+	return ResourceForecast{}, false
+}
+
+// ForecastTerritory projects time-to-full and time-to-empty for a territory's storage,
+// along with when each configured but currently unaffordable upgrade or bonus becomes affordable.
+// For an HQ the projection includes the whole guild's net flow (see ForecastGuildHQ).
+func ForecastTerritory(territoryName string) (*StorageForecast, error) {
+	st.mu.RLock()
+	territory := getTerritoryUnsafe(territoryName)
+	if territory == nil {
+		st.mu.RUnlock()
+		return nil, fmt.Errorf("territory not found: %s", territoryName)
+	}
+	forecast := forecastTerritoryUnsafe(territory)
+	st.mu.RUnlock()
+
+	// Affordability checks take the state lock themselves
+	forecast.Pending = pendingAffordability(forecast)
+	return forecast, nil
+}
+
+// ForecastGuildHQ projects the guild HQ storage using the guild-wide net flow:
+// HQ production and upkeep, surplus shipped in from other territories after route tax,
+// deficits shipped out to them, and tributes in and out.
+func ForecastGuildHQ(guildTag string) (*StorageForecast, error) {
+	st.mu.RLock()
+	hq := getHQFromMap(guildTag)
+	if hq == nil {
+		st.mu.RUnlock()
+		return nil, fmt.Errorf("guild %s has no HQ", guildTag)
+	}
+	forecast := forecastGuildHQUnsafe(hq)
+	st.mu.RUnlock()
+
+	forecast.Pending = pendingAffordability(forecast)
+	return forecast, nil
+}
+
+// ForecastUpgradeAffordability projects when a territory can afford the given upgrade level.
+func ForecastUpgradeAffordability(territoryName, upgradeType string, level int) (AffordabilityForecast, error) {
+	cost, resource := GetUpgradeCost(upgradeType, level)
+	if resource == "" {
+		return AffordabilityForecast{}, fmt.Errorf("unknown upgrade %s level %d", upgradeType, level)
+	}
+
+	result := AffordabilityForecast{
+		Territory:     territoryName,
+		Kind:          "upgrade",
+		Type:          upgradeType,
+		Level:         level,
+		Resource:      normalizeForecastResource(resource),
+		CostPerHour:   float64(cost),
+		AffordableNow: CheckUpgradeAffordabilityWithTolerance(territoryName, upgradeType, level),
+	}
+	return finishAffordabilityForecast(result)
+}
+
+// ForecastBonusAffordability projects when a territory can afford the given bonus level.
+// Bonus types use the same camelCase keys as SetTerritoryBonus.
+func ForecastBonusAffordability(territoryName, bonusType string, level int) (AffordabilityForecast, error) {
+	cost, resource := GetBonusCost(bonusType, level)
+	if resource == "" {
+		return AffordabilityForecast{}, fmt.Errorf("unknown bonus %s", bonusType)
+	}
+
+	result := AffordabilityForecast{
+		Territory:     territoryName,
+		Kind:          "bonus",
+		Type:          bonusType,
+		Level:         level,
+		Resource:      normalizeForecastResource(resource),
+		CostPerHour:   float64(cost),
+		AffordableNow: CheckBonusAffordabilityWithTolerance(territoryName, bonusType, level),
+	}
+	return finishAffordabilityForecast(result)
+}
+
+// finishAffordabilityForecast fills TicksToAfford once AffordableNow is known.
+func finishAffordabilityForecast(result AffordabilityForecast) (AffordabilityForecast, error) {
+	if result.AffordableNow || result.CostPerHour <= 0 {
+		result.AffordableNow = true
+		return result, nil
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	territory := getTerritoryUnsafe(result.Territory)
+	if territory == nil {
+		return result, fmt.Errorf("territory not found: %s", result.Territory)
+	}
+
+	result.TicksToAfford = ticksUntilStoredUnsafe(territory, result.Resource, result.CostPerHour*COST_PER_HOUR_TO_PER_SECOND)
+	return result, nil
+}
+
+// forecastTerritoryUnsafe builds a storage forecast for a single territory. Caller must hold st.mu.
+func forecastTerritoryUnsafe(territory *typedef.Territory) *StorageForecast {
+	if territory.HQ {
+		return forecastGuildHQUnsafe(territory)
+	}
+
+	territory.Mu.RLock()
+	stored := territory.Storage.At
+	capacity := territory.Storage.Capacity
+	net := territoryNetPerHour(territory)
+	routed := territory.Destination != nil && len(territory.TradingRoutes) > 0
+	guildTag := territory.Guild.Tag
+	territory.Mu.RUnlock()
+
+	forecast := &StorageForecast{
+		Territory: territory.Name,
+		GuildTag:  guildTag,
+		Tick:      st.tick,
+	}
+
+	var hqForecast *StorageForecast
+	if routed {
+		if hq := getHQFromMap(guildTag); hq != nil {
+			hqForecast = forecastGuildHQUnsafe(hq)
+		}
+	}
+
+	// Surplus leaves a routed territory on every minute boundary, so it can only fill up before the next shipment.
+	window := int64(60 - st.tick%60)
+
+	for _, name := range forecastResourceNames {
+		rf := forecastResource(name, resourceValue(stored, name), resourceValue(capacity, name), resourceValue(net, name))
+		if routed {
+			if rf.TicksToFull > window {
+				rf.TicksToFull = forecastNever
+			}
+			// Deficits are topped up from the HQ, so the territory runs dry when the HQ does.
+			if rf.NetPerHour < 0 {
+				rf.TicksToEmpty = forecastNever
+				if hqRes, ok := hqForecast.Resource(name); ok {
+					rf.TicksToEmpty = hqRes.TicksToEmpty
+				}
+			}
+		}
+		forecast.Resources = append(forecast.Resources, rf)
+	}
+
+	return forecast
+}
+
+// forecastGuildHQUnsafe builds a guild-wide forecast for an HQ territory. Caller must hold st.mu.
+func forecastGuildHQUnsafe(hq *typedef.Territory) *StorageForecast {
+	hq.Mu.RLock()
+	stored := hq.Storage.At
+	capacity := hq.Storage.Capacity
+	net := territoryNetPerHour(hq)
+	guildTag := hq.Guild.Tag
+	guildName := hq.Guild.Name
+	hq.Mu.RUnlock()
+
+	for _, t := range st.territories {
+		if t == nil || t == hq || t.Guild.Tag != guildTag {
+			continue
+		}
+
+		t.Mu.RLock()
+		surplus := territoryNetPerHour(t)
+		routeTax := t.RouteTax
+		routed := t.Destination == hq && len(t.TradingRoutes) > 0
+		t.Mu.RUnlock()
+
+		// Territories without a route to this HQ neither ship to it nor draw from it
+		if !routed {
+			continue
+		}
+		if routeTax < 0 {
+			routeTax = 0
+		}
+
+		for _, name := range forecastResourceNames {
+			v := resourceValue(surplus, name)
+			if v > 0 {
+				v *= 1 - routeTax
+			}
+			setResourceValue(&net, name, resourceValue(net, name)+v)
+		}
+	}
+
+	// Guild tribute totals are per minute
+	if guild := GetGuildByNameUnsafe(guildName); guild != nil {
+		tributeIn := guild.TributeIn.MulFloat(60)
+		tributeOut := guild.TributeOut.MulFloat(60)
+		net = net.Add(&tributeIn)
+		net = net.Sub(&tributeOut)
+	}
+
+	forecast := &StorageForecast{
+		Territory: hq.Name,
+		GuildTag:  guildTag,
+		HQ:        true,
+		GuildWide: true,
+		Tick:      st.tick,
+	}
+	for _, name := range forecastResourceNames {
+		forecast.Resources = append(forecast.Resources, forecastResource(name, resourceValue(stored, name), resourceValue(capacity, name), resourceValue(net, name)))
+	}
+	return forecast
+}
+
+// pendingAffordability lists configured upgrades and bonuses whose running level is below the set level.
+func pendingAffordability(forecast *StorageForecast) []AffordabilityForecast {
+	territory := GetTerritory(forecast.Territory)
+	if territory == nil {
+		return nil
+	}
+
+	territory.Mu.RLock()
+	upgradeSet := territory.Options.Upgrade.Set
+	upgradeAt := territory.Options.Upgrade.At
+	bonusSet := territory.Options.Bonus.Set
+	bonusAt := territory.Options.Bonus.At
+	territory.Mu.RUnlock()
+
+	var pending []AffordabilityForecast
+
+	upgrades := []struct {
+		name    string
+		set, at int
+	}{
+		{"damage", upgradeSet.Damage, upgradeAt.Damage},
+		{"attack", upgradeSet.Attack, upgradeAt.Attack},
+		{"health", upgradeSet.Health, upgradeAt.Health},
+		{"defence", upgradeSet.Defence, upgradeAt.Defence},
+	}
+	for _, u := range upgrades {
+		if u.set <= u.at {
+			continue
+		}
+		if f, err := ForecastUpgradeAffordability(forecast.Territory, u.name, u.set); err == nil {
+			pending = append(pending, f)
+		}
+	}
+
+	bonuses := []struct {
+		name    string
+		set, at int
+	}{
+		{"strongerMinions", bonusSet.StrongerMinions, bonusAt.StrongerMinions},
+		{"towerMultiAttack", bonusSet.TowerMultiAttack, bonusAt.TowerMultiAttack},
+		{"towerAura", bonusSet.TowerAura, bonusAt.TowerAura},
+		{"towerVolley", bonusSet.TowerVolley, bonusAt.TowerVolley},
+		{"gatheringExperience", bonusSet.GatheringExperience, bonusAt.GatheringExperience},
+		{"mobExperience", bonusSet.MobExperience, bonusAt.MobExperience},
+		{"mobDamage", bonusSet.MobDamage, bonusAt.MobDamage},
+		{"pvpDamage", bonusSet.PvPDamage, bonusAt.PvPDamage},
+		{"xpSeeking", bonusSet.XPSeeking, bonusAt.XPSeeking},
+		{"tomeSeeking", bonusSet.TomeSeeking, bonusAt.TomeSeeking},
+		{"emeraldSeeking", bonusSet.EmeraldSeeking, bonusAt.EmeraldSeeking},
+		{"largerResourceStorage", bonusSet.LargerResourceStorage, bonusAt.LargerResourceStorage},
+		{"largerEmeraldStorage", bonusSet.LargerEmeraldStorage, bonusAt.LargerEmeraldStorage},
+		{"efficientResource", bonusSet.EfficientResource, bonusAt.EfficientResource},
+		{"efficientEmerald", bonusSet.EfficientEmerald, bonusAt.EfficientEmerald},
+		{"resourceRate", bonusSet.ResourceRate, bonusAt.ResourceRate},
+		{"emeraldRate", bonusSet.EmeraldRate, bonusAt.EmeraldRate},
+	}
+	for _, b := range bonuses {
+		if b.set <= b.at {
+			continue
+		}
+		if f, err := ForecastBonusAffordability(forecast.Territory, b.name, b.set); err == nil {
+			pending = append(pending, f)
+		}
+	}
+
+	return pending
+}
+
+// ticksUntilStoredUnsafe estimates how many ticks until a territory holds the given amount of a resource.
+// Routed territories receive resources from the HQ on minute boundaries, one hop per minute.
+// Caller must hold st.mu.
+func ticksUntilStoredUnsafe(territory *typedef.Territory, resource string, amount float64) int64 {
+	territory.Mu.RLock()
+	stored := resourceValue(territory.Storage.At, resource)
+	net := resourceValue(territoryNetPerHour(territory), resource)
+	hops := 0
+	if len(territory.TradingRoutes) > 0 {
+		hops = len(territory.TradingRoutes[0]) - 1
+	}
+	isHQ := territory.HQ
+	guildTag := territory.Guild.Tag
+	territory.Mu.RUnlock()
+
+	if stored >= amount {
+		return 0
+	}
+
+	var hq *typedef.Territory
+	if !isHQ && hops > 0 {
+		hq = getHQFromMap(guildTag)
+	}
+	if hq == nil {
+		if isHQ {
+			if hqRes, ok := forecastGuildHQUnsafe(territory).Resource(resource); ok {
+				net = hqRes.NetPerHour
+			}
+		}
+		return ticksToReach(amount-stored, net)
+	}
+
+	// Shipments leave the HQ on the next minute boundary and advance one hop per minute
+	delivery := int64(60-st.tick%60) + int64(hops-1)*60
+
+	hqForecast := forecastGuildHQUnsafe(hq)
+	hqRes, _ := hqForecast.Resource(resource)
+	if hqRes.Stored >= amount {
+		return delivery
+	}
+	wait := ticksToReach(amount-hqRes.Stored, hqRes.NetPerHour)
+	if wait == forecastNever {
+		return forecastNever
+	}
+	// Round the HQ wait up to the next shipment boundary
+	if rem := wait % 60; rem != 0 {
+		wait += 60 - rem
+	}
+	return wait + delivery
+}
+
+// forecastResource projects a single resource from its current amount, capacity and net hourly rate.
+func forecastResource(name string, stored, capacity, netPerHour float64) ResourceForecast {
+	rf := ResourceForecast{
+		Resource:     name,
+		Stored:       stored,
+		Capacity:     capacity,
+		NetPerHour:   netPerHour,
+		TicksToFull:  forecastNever,
+		TicksToEmpty: forecastNever,
+	}
+
+	switch {
+	case capacity > 0 && stored >= capacity:
+		rf.TicksToFull = 0
+	case netPerHour > 0 && capacity > 0:
+		rf.TicksToFull = ticksToReach(capacity-stored, netPerHour)
+	}
+
+	switch {
+	case stored <= 0 && netPerHour <= 0:
+		rf.TicksToEmpty = 0
+	case netPerHour < 0:
+		rf.TicksToEmpty = ticksToReach(stored, -netPerHour)
+	}
+
+	return rf
+}
+
+// ticksToReach returns the ticks needed to accumulate amount at a per-hour rate.
+func ticksToReach(amount, perHour float64) int64 {
+	if amount <= 0 {
+		return 0
+	}
+	if perHour <= 0 {
+		return forecastNever
+	}
+	return int64(math.Ceil(amount / (perHour * COST_PER_HOUR_TO_PER_SECOND)))
+}
+
+// territoryNetPerHour returns production minus configured upkeep per hour. Caller must hold territory.Mu.
+func territoryNetPerHour(territory *typedef.Territory) typedef.BasicResources {
+	return territory.ResourceGeneration.At.Sub(&territory.Costs)
+}
+
+// normalizeForecastResource maps cost resource types to forecast resource names.
+func normalizeForecastResource(resource string) string {
+	if resource == "ore" {
+		return "ores"
+	}
+	return resource
+}
+
+func resourceValue(res typedef.BasicResources, name string) float64 {
+	switch name {
+	case "emeralds":
+		return res.Emeralds
+	case "ores", "ore":
+		return res.Ores
+	case "wood":
+		return res.Wood
+	case "fish":
+		return res.Fish
+	case "crops":
+		return res.Crops
+	default:
+		return 0
+	}
+}
+
+func setResourceValue(res *typedef.BasicResources, name string, value float64) {
+	switch name {
+	case "emeralds":
+		res.Emeralds = value
+	case "ores", "ore":
+		res.Ores = value
+	case "wood":
+		res.Wood = value
+	case "fish":
+		res.Fish = value
+	case "crops":
+		res.Crops = value
+	}
+}
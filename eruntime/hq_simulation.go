@@ -0,0 +1,267 @@
+package eruntime
+
+import (
+	"RueaES/alg"
+	"RueaES/typedef"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultHQSimulationCandidates = 10
+	defaultHQSimulationMinutes    = 60
+)
+
+// HQSimulation is the outcome of moving a guild's HQ to a candidate territory in a forked state.
+type HQSimulation struct {
+	Name string `json:"name"`
+
+	// ConnectionScore is the connection-driven score from alg.ComputeHQCandidates
+	ConnectionScore float64 `json:"connectionScore"`
+
+	// Tower stats of the HQ at its new location
+	EffectiveHealth float64 `json:"effectiveHealth"`
+	DPS             float64 `json:"dps"`
+
+	NetPerHour      typedef.BasicResources `json:"netPerHour"`      // Guild-wide net income after route tax
+	RouteTaxPerHour typedef.BasicResources `json:"routeTaxPerHour"` // Resources lost to route tax on the way to the HQ
+
+	Unreachable []string `json:"unreachable"` // Territories without a route to the new HQ
+	Deficits    []string `json:"deficits"`    // Territories whose upkeep went unpaid during the simulation
+
+	// TreasuryLowered lists territories whose treasury bonus drops with the HQ at this candidate,
+	// and TreasuryLoss is the generation per hour they give up because of it
+	TreasuryLowered []string               `json:"treasuryLowered"`
+	TreasuryLoss    typedef.BasicResources `json:"treasuryLoss"`
+
+	// Score combines the above into a single ranking value, higher is better
+	Score float64 `json:"score"`
+}
+
+// SimulateHQPlacements ranks HQ candidates for a guild by forking its territories, moving the HQ,
+// recomputing routes and simulating a short period of resource flow. Live state is not modified.
+// Only the best candidates by connection score are simulated; zero values use the defaults.
+func SimulateHQPlacements(guildTag string, candidates, minutes int) ([]HQSimulation, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	if candidates <= 0 {
+		candidates = defaultHQSimulationCandidates
+	}
+	if minutes <= 0 {
+		minutes = defaultHQSimulationMinutes
+	}
+
+	// Everything the candidates need, including the costs, options and routes, is forked under the
+	// lock, which is released before they are simulated so the tick loop isn't held up
+	st.mu.RLock()
+	territories := make(map[string]*typedef.Territory, len(TerritoryMap))
+	maps.Copy(territories, TerritoryMap)
+	ranked, err := alg.ComputeHQCandidates(guildTag, territories)
+	if err != nil {
+		st.mu.RUnlock()
+		return nil, err
+	}
+	if len(ranked) > candidates {
+		ranked = ranked[:candidates]
+	}
+
+	forked := make(map[string]*typedef.Territory, len(TerritoryMap))
+	var guildNames []string
+	for name, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		forked[name] = forkTerritory(territory)
+		territory.Mu.RUnlock()
+		if forked[name].Guild.Tag == guildTag {
+			guildNames = append(guildNames, name)
+		}
+	}
+	sort.Strings(guildNames)
+
+	// The simulation draws on what the current HQ holds
+	var pool typedef.BasicResources
+	if hq := getHQFromMap(guildTag); hq != nil {
+		hq.Mu.RLock()
+		pool = hq.Storage.At
+		hq.Mu.RUnlock()
+	}
+	allies := getGuildAllies(guildTag)
+	env := snapshotSimulationEnvUnsafe()
+	st.mu.RUnlock()
+
+	// Candidates share the fork, so treasury losses are measured against the bonuses before any move
+	bonuses := generationBonuses(forked, guildNames)
+	results := make([]HQSimulation, 0, len(ranked))
+	for _, candidate := range ranked {
+		result := simulateHQCandidate(env, forked, guildNames, bonuses, forked[candidate.Name], guildTag, allies, pool, minutes)
+		result.ConnectionScore = candidate.Score
+		results = append(results, result)
+	}
+
+	scoreHQSimulations(results)
+	return results, nil
+}
+
+// generationBonuses records the generation bonus of each named territory
+func generationBonuses(forked map[string]*typedef.Territory, names []string) map[string]float64 {
+	bonuses := make(map[string]float64, len(names))
+	for _, name := range names {
+		bonuses[name] = forked[name].GenerationBonus
+	}
+	return bonuses
+}
+
+// simulateHQCandidate moves the forked HQ to candidate and runs the simulation for it. bonuses holds
+// the generation bonuses with the HQ where it is now, from generationBonuses, and env the tables
+// copied with the fork.
+func simulateHQCandidate(env *simulationEnv, forked map[string]*typedef.Territory, guildNames []string, bonuses map[string]float64, candidate *typedef.Territory, guildTag string, allies []string, pool typedef.BasicResources, minutes int) HQSimulation {
+	result := HQSimulation{Name: candidate.Name}
+
+	for _, name := range guildNames {
+		forked[name].HQ = false
+	}
+	candidate.HQ = true
+
+	type flow struct {
+		name     string
+		net      typedef.BasicResources
+		routeTax float64
+		routed   bool
+	}
+	flows := make([]flow, 0, len(guildNames))
+
+	for _, name := range guildNames {
+		t := forked[name]
+		t.TradingRoutes = nil
+		routeTax, routed := 0.0, true
+		if t != candidate {
+			var route []*typedef.Territory
+			route, routeTax, routed = whatIfRouteWith(env, t, candidate, forked, guildTag, allies)
+			if routed {
+				t.TradingRoutes = [][]*typedef.Territory{route}
+			} else {
+				result.Unreachable = append(result.Unreachable, name)
+			}
+		}
+
+		// Treasury depends on the distance to the HQ, so generation is recomputed from the new routes
+		bonusBefore := bonuses[name]
+		updateGenerationBonusWith(&env.options, t)
+		generation, _ := calculateResourceGenerationWith(&env.costs, t, t.Options.Bonus.At.ResourceRate, t.Options.Bonus.At.EmeraldRate,
+			t.Options.Bonus.At.EfficientResource, t.Options.Bonus.At.EfficientEmerald)
+		if t.GenerationBonus < bonusBefore {
+			result.TreasuryLowered = append(result.TreasuryLowered, name)
+			lost := generation.MulFloat((100+bonusBefore)/(100+t.GenerationBonus) - 1)
+			result.TreasuryLoss = result.TreasuryLoss.Add(&lost)
+		}
+		flows = append(flows, flow{name: name, net: generation.Sub(&t.Costs), routeTax: routeTax, routed: routed})
+	}
+
+	stats := calculateTowerStatsWith(&env.costs, candidate)
+	if stats.Defence < 1 {
+		result.EffectiveHealth = stats.Health / (1 - stats.Defence)
+	}
+	result.DPS = (stats.Damage.Low + stats.Damage.High) / 2 * stats.Attack
+
+	for _, f := range flows {
+		if !f.routed {
+			continue
+		}
+		result.NetPerHour = addRoutedNet(result.NetPerHour, f.net, f.routeTax)
+		for _, name := range forecastResourceNames {
+			if v := resourceValue(f.net, name); v > 0 {
+				setResourceValue(&result.RouteTaxPerHour, name, resourceValue(result.RouteTaxPerHour, name)+v*f.routeTax)
+			}
+		}
+	}
+
+	// Step minute by minute: surpluses flow into the HQ pool and upkeep is drawn from it
+	deficit := make(map[string]bool)
+	for minute := 0; minute < minutes; minute++ {
+		for _, f := range flows {
+			for _, name := range forecastResourceNames {
+				v := resourceValue(f.net, name) / 60
+				switch {
+				case v > 0 && f.routed:
+					setResourceValue(&pool, name, resourceValue(pool, name)+v*(1-f.routeTax))
+				case v < 0 && !f.routed:
+					deficit[f.name] = true
+				case v < 0:
+					stored := resourceValue(pool, name)
+					if stored < -v {
+						deficit[f.name] = true
+						stored = -v
+					}
+					setResourceValue(&pool, name, stored+v)
+				}
+			}
+		}
+	}
+	for name := range deficit {
+		result.Deficits = append(result.Deficits, name)
+	}
+	sort.Strings(result.Deficits)
+
+	return result
+}
+
+// scoreHQSimulations scores and sorts simulated candidates. Tower strength and net income
+// count in favour of a candidate, route tax and unpaid upkeep against it.
+func scoreHQSimulations(results []HQSimulation) {
+	var maxTower, maxNet, maxTax, maxTrouble float64
+	for _, r := range results {
+		maxTower = math.Max(maxTower, r.EffectiveHealth*r.DPS)
+		maxNet = math.Max(maxNet, math.Abs(basicResourcesTotal(r.NetPerHour)))
+		maxTax = math.Max(maxTax, basicResourcesTotal(r.RouteTaxPerHour))
+		maxTrouble = math.Max(maxTrouble, float64(len(r.Deficits)+len(r.Unreachable)))
+	}
+
+	ratio := func(value, max float64) float64 {
+		if max == 0 {
+			return 0
+		}
+		return value / max
+	}
+
+	for i := range results {
+		r := &results[i]
+		r.Score = 0.35*ratio(r.EffectiveHealth*r.DPS, maxTower) +
+			0.35*ratio(basicResourcesTotal(r.NetPerHour), maxNet) -
+			0.15*ratio(basicResourcesTotal(r.RouteTaxPerHour), maxTax) -
+			0.15*ratio(float64(len(r.Deficits)+len(r.Unreachable)), maxTrouble)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Score > results[j].Score
+	})
+}
+
+// forkTerritory copies the parts of a territory that routing, generation and tower stats read.
+// The copy has its own mutex and no channels. Caller must hold territory.Mu.
+func forkTerritory(territory *typedef.Territory) *typedef.Territory {
+	return &typedef.Territory{
+		ID:                 territory.ID,
+		Name:               territory.Name,
+		Guild:              territory.Guild,
+		Options:            territory.Options,
+		Costs:              territory.Costs,
+		Links:              territory.Links,
+		ResourceGeneration: territory.ResourceGeneration,
+		Treasury:           territory.Treasury,
+		TreasuryOverride:   territory.TreasuryOverride,
+		GenerationBonus:    territory.GenerationBonus,
+		CapturedAt:         territory.CapturedAt,
+		HeldBefore:         territory.HeldBefore,
+		RouteTax:           territory.RouteTax,
+		RoutingMode:        territory.RoutingMode,
+		Border:             territory.Border,
+		Tax:                territory.Tax,
+		HQ:                 territory.HQ,
+	}
+}
@@ -1,7 +1,6 @@
 package eruntime
 
 import (
-	"RueaES/assets"
 	"RueaES/storage"
 	"RueaES/typedef"
 	"encoding/json"
@@ -14,17 +13,48 @@ var TerritoryMap map[string]*typedef.Territory
 var TerritoryClaimsMap map[string]*typedef.Guild // territory name -> guild
 
 func loadTerritories() {
-	var rawTerritories typedef.TerritoriesFileJSON
-	f, err := assets.AssetFiles.ReadFile("territories.json")
-	if err != nil {
-		panic("failed to read territories.json: " + err.Error())
+	pack := loadSelectedMapPack()
+	setActiveMapPack(pack)
+	buildMapTerritories(pack)
+
+	// Now load guilds from guilds.json, skip if running in WASM
+	var f []byte
+	if runtime.GOARCH != "wasm" {
+		var err error
+		f, err = storage.ReadDataFile("guilds.json")
+		if err != nil {
+			// Create an empty guild list if file doesn't exist
+			st.guilds = []*typedef.Guild{}
+			if err := storage.WriteDataFile("guilds.json", []byte("[]"), 0o644); err != nil {
+				panic("failed to create guilds.json: " + err.Error())
+			}
+			return
+		}
+	} else {
+		// When running in WASM, initialize empty guilds list
+		st.guilds = []*typedef.Guild{}
+		return
 	}
 
-	err = json.Unmarshal(f, &rawTerritories)
-	if err != nil {
-		panic("failed to unmarshal territories.json: " + err.Error())
+	var rawGuilds typedef.GuildsFileJSON
+
+	json.Unmarshal(f, &rawGuilds)
+	for _, g := range rawGuilds {
+		st.guilds = append(st.guilds, &typedef.Guild{
+			Name: g.Name,
+			Tag:  g.Tag,
+			// Not implemented yet
+			Allies: []*typedef.Guild{},
+		})
 	}
 
+	// After all territories and guilds are loaded, rebuild the HQ map for fast lookups
+	rebuildHQMap()
+}
+
+// buildMapTerritories creates the territories and trading links of a map pack. The caller must
+// hold the state lock or run before the simulation starts.
+func buildMapTerritories(pack *MapPack) {
 	// Initialize maps
 	TradingRoutesMap = make(map[string][]string)
 	TerritoryMap = make(map[string]*typedef.Territory)
@@ -34,7 +64,7 @@ func loadTerritories() {
 	loadTerritoryClaims()
 
 	// Now initialize the territories
-	for name, t := range rawTerritories {
+	for name, t := range pack.territories {
 		territory, err := initializeTerritory(name, t)
 		if err != nil {
 			panic("failed to initialize territory " + name + ": " + err.Error())
@@ -64,38 +94,6 @@ func loadTerritories() {
 
 		st.territories = append(st.territories, territory)
 	}
-
-	// Now load guilds from guilds.json, skip if running in WASM
-	if runtime.GOARCH != "wasm" {
-		f, err = storage.ReadDataFile("guilds.json")
-		if err != nil {
-			// Create an empty guild list if file doesn't exist
-			st.guilds = []*typedef.Guild{}
-			if err := storage.WriteDataFile("guilds.json", []byte("[]"), 0o644); err != nil {
-				panic("failed to create guilds.json: " + err.Error())
-			}
-			return
-		}
-	} else {
-		// When running in WASM, initialize empty guilds list
-		st.guilds = []*typedef.Guild{}
-		return
-	}
-
-	var rawGuilds typedef.GuildsFileJSON
-
-	json.Unmarshal(f, &rawGuilds)
-	for _, g := range rawGuilds {
-		st.guilds = append(st.guilds, &typedef.Guild{
-			Name: g.Name,
-			Tag:  g.Tag,
-			// Not implemented yet
-			Allies: []*typedef.Guild{},
-		})
-	}
-
-	// After all territories and guilds are loaded, rebuild the HQ map for fast lookups
-	rebuildHQMap()
 }
 
 func loadTerritoryClaims() {
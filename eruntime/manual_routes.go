@@ -0,0 +1,95 @@
+package eruntime
+
+import (
+	"RueaES/typedef"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ManualRouteData identifies a hand-picked trading route by the names of the territories along it.
+// Route IDs are only positions in a sorted list of alternatives, so they are not stable across map changes.
+type ManualRouteData struct {
+	Territory string   `json:"territory"`
+	Route     []string `json:"route"`
+}
+
+// exportManualRoutesUnsafe converts the manual route selections into name-based routes.
+// Caller must hold st.mu. Selections that are not currently applied to a route are skipped.
+func exportManualRoutesUnsafe() (toHQ, fromHQ []ManualRouteData) {
+	for name := range st.manualRouteToHQ {
+		territory := TerritoryMap[name]
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		if len(territory.TradingRoutes) > 0 {
+			toHQ = append(toHQ, ManualRouteData{Territory: name, Route: routeToNames(territory.TradingRoutes[0])})
+		}
+		territory.Mu.RUnlock()
+	}
+
+	for name := range st.manualRouteFromHQ {
+		territory := TerritoryMap[name]
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		guildTag := territory.Guild.Tag
+		territory.Mu.RUnlock()
+
+		hq := getHQFromMap(guildTag)
+		if hq == nil {
+			continue
+		}
+		hq.Mu.RLock()
+		for _, route := range hq.TradingRoutes {
+			if len(route) > 0 && route[len(route)-1] != nil && route[len(route)-1].Name == name {
+				fromHQ = append(fromHQ, ManualRouteData{Territory: name, Route: routeToNames(route)})
+				break
+			}
+		}
+		hq.Mu.RUnlock()
+	}
+
+	// Map iteration order is random; keep saves deterministic
+	sort.Slice(toHQ, func(i, j int) bool { return toHQ[i].Territory < toHQ[j].Territory })
+	sort.Slice(fromHQ, func(i, j int) bool { return fromHQ[i].Territory < fromHQ[j].Territory })
+	return toHQ, fromHQ
+}
+
+// restoreManualRoutesUnsafe replaces the manual route selections with saved name-based routes,
+// resolving each one to its current route ID. It returns a description of every saved route
+// that no longer exists among the territory's alternatives. Caller must hold st.mu.
+func restoreManualRoutesUnsafe(toHQ, fromHQ []ManualRouteData) []string {
+	st.manualRouteToHQ = make(map[string]int)
+	st.manualRouteFromHQ = make(map[string]int)
+
+	var missing []string
+	for _, saved := range toHQ {
+		if id, ok := findRouteIDByNames(alternativeRoutesToHQUnsafe(saved.Territory), saved.Route); ok {
+			st.manualRouteToHQ[saved.Territory] = id
+		} else {
+			missing = append(missing, fmt.Sprintf("%s to HQ via %s", saved.Territory, strings.Join(saved.Route, " > ")))
+		}
+	}
+	for _, saved := range fromHQ {
+		if id, ok := findRouteIDByNames(alternativeRoutesFromHQUnsafe(saved.Territory), saved.Route); ok {
+			st.manualRouteFromHQ[saved.Territory] = id
+		} else {
+			missing = append(missing, fmt.Sprintf("HQ to %s via %s", saved.Territory, strings.Join(saved.Route, " > ")))
+		}
+	}
+	return missing
+}
+
+// findRouteIDByNames returns the ID of the alternative whose territory names match route exactly
+func findRouteIDByNames(routes map[int][]*typedef.Territory, route []string) (int, bool) {
+	key := strings.Join(route, "\x00")
+	for id, candidate := range routes {
+		if strings.Join(routeToNames(candidate), "\x00") == key {
+			return id, true
+		}
+	}
+	return -1, false
+}
@@ -0,0 +1,588 @@
+package eruntime
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"RueaES/assets"
+	"RueaES/storage"
+	"RueaES/typedef"
+)
+
+const (
+	// DefaultMapPackID identifies the map built into the binary
+	DefaultMapPackID = "default"
+
+	mapPackManifestFile = "manifest.json"
+	mapPacksDirName     = "mappacks"
+	mapPackSettingsFile = "map_pack.json"
+)
+
+// MapPack is a loaded map pack: its manifest, the territories it defines and the validation
+// issues found in them
+type MapPack struct {
+	Manifest    typedef.MapPackManifest
+	Path        string // Pack directory or archive, empty for the built-in pack
+	Issues      []typedef.MapPackIssue
+	Fingerprint string
+
+	files       fs.FS
+	territories typedef.TerritoriesFileJSON
+}
+
+// MapPackInfo describes a map pack found in the map packs directory
+type MapPackInfo struct {
+	Manifest typedef.MapPackManifest `json:"manifest"`
+	Path     string                  `json:"path"`
+	Selected bool                    `json:"selected"` // Used the next time the app starts
+	Active   bool                    `json:"active"`   // Used by the running simulation
+	Warnings int                     `json:"warnings"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// mapPackSettings is the map pack selection saved in the data directory
+type mapPackSettings struct {
+	Path string `json:"path"` // Pack directory or archive, empty for the built-in pack
+}
+
+// builtinImageOffset is where game coordinate 0,0 is on the built-in map image
+var builtinImageOffset = typedef.MapPackOffset{X: 2382, Y: 6572}
+
+var (
+	activeMapPackMu sync.RWMutex // Guards activeMapPack, which the UI reads while packs switch
+	activeMapPack   *MapPack
+
+	builtinMapPackOnce sync.Once
+	builtinMapPack     *MapPack
+	builtinMapPackErr  error
+)
+
+// Ref returns the reference saved with states to identify this pack
+func (p *MapPack) Ref() typedef.MapPackRef {
+	return typedef.MapPackRef{
+		ID:          p.Manifest.ID,
+		Name:        p.Manifest.Name,
+		Version:     p.Manifest.Version,
+		Fingerprint: p.Fingerprint,
+	}
+}
+
+// IsBuiltin reports whether this is the map built into the binary
+func (p *MapPack) IsBuiltin() bool {
+	return p.Path == ""
+}
+
+// Err returns the first validation error of the pack, nil if it can be used
+func (p *MapPack) Err() error {
+	for _, issue := range p.Issues {
+		if issue.Error {
+			if issue.Territory != "" {
+				return fmt.Errorf("%s: %s", issue.Territory, issue.Message)
+			}
+			return errors.New(issue.Message)
+		}
+	}
+	return nil
+}
+
+// ReadFile reads a file of the pack by its path relative to the pack root
+func (p *MapPack) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(p.files, name)
+}
+
+// ReadImage reads the background map image of the pack
+func (p *MapPack) ReadImage() ([]byte, error) {
+	return p.ReadFile(p.Manifest.Files.Image)
+}
+
+// ImageOffset returns the pixel position of game coordinate 0,0 on the pack's map image
+func (p *MapPack) ImageOffset() (float64, float64) {
+	if p.Manifest.ImageOffset == nil {
+		return builtinImageOffset.X, builtinImageOffset.Y
+	}
+	return p.Manifest.ImageOffset.X, p.Manifest.ImageOffset.Y
+}
+
+// ReadCosts reads the costs of the pack, falling back to the built-in costs
+func (p *MapPack) ReadCosts() ([]byte, error) {
+	if p.Manifest.Files.Costs == "" {
+		return assets.AssetFiles.ReadFile("upgrades.json")
+	}
+	return p.ReadFile(p.Manifest.Files.Costs)
+}
+
+// ActiveMapPack returns the map pack the simulation runs on
+func ActiveMapPack() *MapPack {
+	activeMapPackMu.RLock()
+	pack := activeMapPack
+	activeMapPackMu.RUnlock()
+	if pack == nil {
+		return BuiltinMapPack()
+	}
+	return pack
+}
+
+// setActiveMapPack replaces the map pack the simulation runs on
+func setActiveMapPack(pack *MapPack) {
+	activeMapPackMu.Lock()
+	activeMapPack = pack
+	activeMapPackMu.Unlock()
+}
+
+// BuiltinMapPack returns the map built into the binary
+func BuiltinMapPack() *MapPack {
+	builtinMapPackOnce.Do(func() {
+		offset := builtinImageOffset
+		manifest := typedef.MapPackManifest{
+			ID:      DefaultMapPackID,
+			Name:    "Wynncraft (built-in)",
+			Version: "builtin",
+			Files: typedef.MapPackFiles{
+				Territories: "territories.json",
+				Image:       "main-map.png",
+			},
+			ImageOffset: &offset,
+		}
+		builtinMapPack, builtinMapPackErr = loadMapPack(assets.AssetFiles, "", manifest)
+	})
+	if builtinMapPackErr != nil {
+		panic("failed to load the built-in map: " + builtinMapPackErr.Error())
+	}
+	return builtinMapPack
+}
+
+// MapPacksDir returns the directory map packs are installed in
+func MapPacksDir() string {
+	return storage.DataFile(mapPacksDirName)
+}
+
+// OpenMapPack loads a map pack from a directory or a .zip archive and validates it. The pack is
+// returned with its issues even when validation fails, so they can be shown to the user.
+func OpenMapPack(packPath string) (*MapPack, error) {
+	files, err := openMapPackFiles(packPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(files, mapPackManifestFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", mapPackManifestFile, err)
+	}
+	var manifest typedef.MapPackManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", mapPackManifestFile, err)
+	}
+	if manifest.ID == "" || manifest.ID == DefaultMapPackID {
+		return nil, fmt.Errorf("%s needs an id other than %q", mapPackManifestFile, DefaultMapPackID)
+	}
+	if manifest.Name == "" {
+		manifest.Name = manifest.ID
+	}
+	if manifest.Files.Territories == "" {
+		manifest.Files.Territories = "territories.json"
+	}
+	if manifest.Files.Image == "" {
+		return nil, fmt.Errorf("%s does not name a map image", mapPackManifestFile)
+	}
+
+	pack, err := loadMapPack(files, packPath, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return pack, pack.Err()
+}
+
+// openMapPackFiles opens a pack directory, or reads a .zip archive into memory. Archives made by
+// zipping the pack directory itself are accepted too.
+func openMapPackFiles(packPath string) (fs.FS, error) {
+	info, err := os.Stat(packPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(packPath), nil
+	}
+	if !strings.EqualFold(filepath.Ext(packPath), ".zip") {
+		return nil, fmt.Errorf("%s is neither a directory nor a .zip archive", filepath.Base(packPath))
+	}
+
+	data, err := os.ReadFile(packPath)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	if _, err := fs.Stat(archive, mapPackManifestFile); err == nil {
+		return archive, nil
+	}
+	entries, err := fs.ReadDir(archive, ".")
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		if _, err := fs.Stat(archive, path.Join(entries[0].Name(), mapPackManifestFile)); err == nil {
+			return fs.Sub(archive, entries[0].Name())
+		}
+	}
+	return nil, fmt.Errorf("archive has no %s", mapPackManifestFile)
+}
+
+// loadMapPack reads the territories of a pack, applies the optional link and location files and
+// validates the result
+func loadMapPack(files fs.FS, packPath string, manifest typedef.MapPackManifest) (*MapPack, error) {
+	pack := &MapPack{Manifest: manifest, Path: packPath, files: files}
+
+	data, err := fs.ReadFile(files, manifest.Files.Territories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read territories: %v", err)
+	}
+	if err := json.Unmarshal(data, &pack.territories); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", manifest.Files.Territories, err)
+	}
+	if len(pack.territories) == 0 {
+		return nil, fmt.Errorf("%s has no territories", manifest.Files.Territories)
+	}
+
+	if manifest.Files.Links != "" {
+		var links map[string][]string
+		if err := readMapPackJSON(files, manifest.Files.Links, &links); err != nil {
+			return nil, err
+		}
+		for name, t := range pack.territories {
+			t.TradingRoutes = links[name]
+			pack.territories[name] = t
+		}
+		for name := range links {
+			if _, ok := pack.territories[name]; !ok {
+				pack.Issues = append(pack.Issues, typedef.MapPackIssue{Error: true, Territory: name,
+					Message: "has trading links but is not a territory"})
+			}
+		}
+	}
+
+	if manifest.Files.Locations != "" {
+		var locations map[string]typedef.LocationObject
+		if err := readMapPackJSON(files, manifest.Files.Locations, &locations); err != nil {
+			return nil, err
+		}
+		for name, location := range locations {
+			t, ok := pack.territories[name]
+			if !ok {
+				pack.Issues = append(pack.Issues, typedef.MapPackIssue{Error: true, Territory: name,
+					Message: "has a location but is not a territory"})
+				continue
+			}
+			t.Location = location
+			pack.territories[name] = t
+		}
+	}
+
+	if _, err := fs.Stat(files, manifest.Files.Image); err != nil {
+		pack.Issues = append(pack.Issues, typedef.MapPackIssue{Error: true, Message: "map image " + manifest.Files.Image + " is missing"})
+	}
+	if manifest.Files.Costs != "" {
+		if _, err := fs.Stat(files, manifest.Files.Costs); err != nil {
+			pack.Issues = append(pack.Issues, typedef.MapPackIssue{Error: true, Message: "costs file " + manifest.Files.Costs + " is missing"})
+		}
+	}
+
+	pack.Issues = append(pack.Issues, validateMapTerritories(pack.territories)...)
+	pack.Fingerprint = mapPackFingerprint(pack.territories)
+	return pack, nil
+}
+
+func readMapPackJSON(files fs.FS, name string, out any) error {
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", name, err)
+	}
+	return nil
+}
+
+// validateMapTerritories checks trading links and locations. Links to territories that don't
+// exist and links to the territory itself are errors; one-way links and overlapping locations
+// are warnings, as the Wynncraft map has a few of both.
+func validateMapTerritories(territories typedef.TerritoriesFileJSON) []typedef.MapPackIssue {
+	names := make([]string, 0, len(territories))
+	for name := range territories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []typedef.MapPackIssue
+	for _, name := range names {
+		seen := make(map[string]bool)
+		for _, link := range territories[name].TradingRoutes {
+			other, exists := territories[link]
+			switch {
+			case link == name:
+				issues = append(issues, typedef.MapPackIssue{Error: true, Territory: name, Message: "has a trading route to itself"})
+			case !exists:
+				issues = append(issues, typedef.MapPackIssue{Error: true, Territory: name, Message: fmt.Sprintf("has a trading route to unknown territory %q", link)})
+			case seen[link]:
+				issues = append(issues, typedef.MapPackIssue{Territory: name, Message: fmt.Sprintf("lists the trading route to %s twice", link)})
+			case !containsString(other.TradingRoutes, name):
+				issues = append(issues, typedef.MapPackIssue{Territory: name, Message: fmt.Sprintf("has a one-way trading route to %s", link)})
+			}
+			seen[link] = true
+		}
+	}
+
+	type rect struct {
+		name                   string
+		minX, minY, maxX, maxY int
+	}
+	rects := make([]rect, 0, len(names))
+	for _, name := range names {
+		loc := territories[name].Location
+		r := rect{name, min(loc.Start[0], loc.End[0]), min(loc.Start[1], loc.End[1]), max(loc.Start[0], loc.End[0]), max(loc.Start[1], loc.End[1])}
+		if r.minX == r.maxX || r.minY == r.maxY {
+			issues = append(issues, typedef.MapPackIssue{Error: true, Territory: name, Message: "has an empty location"})
+			continue
+		}
+		rects = append(rects, r)
+	}
+	for i, a := range rects {
+		for _, b := range rects[i+1:] {
+			if a.minX < b.maxX && b.minX < a.maxX && a.minY < b.maxY && b.minY < a.maxY {
+				issues = append(issues, typedef.MapPackIssue{Territory: a.name, Message: "overlaps " + b.name})
+			}
+		}
+	}
+	return issues
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// mapPackFingerprint hashes the territory names, links and locations, the parts of a map a
+// saved state depends on
+func mapPackFingerprint(territories typedef.TerritoriesFileJSON) string {
+	names := make([]string, 0, len(territories))
+	for name := range territories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		t := territories[name]
+		links := append([]string(nil), t.TradingRoutes...)
+		sort.Strings(links)
+		fmt.Fprintf(hash, "%s\x00%v\x00%v\x00%s\n", name, t.Location.Start, t.Location.End, strings.Join(links, "\x00"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// CheckMapPackCompatible returns an error if a state saved with ref can't be loaded on the active
+// map. States saved before map packs existed were made with the built-in map.
+func CheckMapPackCompatible(ref *typedef.MapPackRef) error {
+	active := ActiveMapPack()
+	if ref == nil {
+		ref = &typedef.MapPackRef{ID: DefaultMapPackID, Name: BuiltinMapPack().Manifest.Name, Fingerprint: BuiltinMapPack().Fingerprint}
+	}
+	if ref.Fingerprint == active.Fingerprint {
+		return nil
+	}
+	saved := ref.Name
+	if ref.Version != "" {
+		saved += " " + ref.Version
+	}
+	return fmt.Errorf("the state was saved on map %s, which does not match the current map %s %s; switch map packs to load it",
+		saved, active.Manifest.Name, active.Manifest.Version)
+}
+
+// ListMapPacks returns the built-in map followed by the packs installed in the map packs
+// directory, directories and .zip archives alike
+func ListMapPacks() []MapPackInfo {
+	selected := GetSelectedMapPack()
+	active := ActiveMapPack()
+
+	builtin := BuiltinMapPack()
+	packs := []MapPackInfo{{
+		Manifest: builtin.Manifest,
+		Selected: selected == "",
+		Active:   active.IsBuiltin(),
+		Warnings: len(builtin.Issues),
+	}}
+
+	entries, err := os.ReadDir(MapPacksDir())
+	if err != nil {
+		return packs
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			continue
+		}
+		packPath := filepath.Join(MapPacksDir(), entry.Name())
+		info := MapPackInfo{
+			Manifest: typedef.MapPackManifest{ID: entry.Name(), Name: entry.Name()},
+			Path:     packPath,
+			Selected: samePath(selected, packPath),
+			Active:   samePath(active.Path, packPath),
+		}
+		pack, err := OpenMapPack(packPath)
+		if pack != nil {
+			info.Manifest = pack.Manifest
+			for _, issue := range pack.Issues {
+				if !issue.Error {
+					info.Warnings++
+				}
+			}
+		}
+		if err != nil {
+			info.Error = err.Error()
+		}
+		packs = append(packs, info)
+	}
+	return packs
+}
+
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// ResolveMapPack turns a pack path or the id of an installed pack into a pack path. "" and
+// "default" select the built-in map and resolve to "".
+func ResolveMapPack(nameOrPath string) (string, error) {
+	if nameOrPath == "" || nameOrPath == DefaultMapPackID {
+		return "", nil
+	}
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return filepath.Abs(nameOrPath)
+	}
+	for _, info := range ListMapPacks() {
+		if info.Path != "" && info.Manifest.ID == nameOrPath {
+			return info.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no map pack %q in %s", nameOrPath, MapPacksDir())
+}
+
+// GetSelectedMapPack returns the path of the pack used when the app starts, "" for the built-in map
+func GetSelectedMapPack() string {
+	data, err := storage.ReadDataFile(mapPackSettingsFile)
+	if err != nil {
+		return ""
+	}
+	var settings mapPackSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return ""
+	}
+	return settings.Path
+}
+
+// SelectMapPack validates a pack and saves it as the pack to use when the app starts. An empty
+// path selects the built-in map.
+func SelectMapPack(packPath string) error {
+	if packPath != "" {
+		if _, err := OpenMapPack(packPath); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(mapPackSettings{Path: packPath}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteDataFile(mapPackSettingsFile, data, 0o644)
+}
+
+// loadSelectedMapPack opens the selected pack, falling back to the built-in map if it is gone or
+// no longer valid
+func loadSelectedMapPack() *MapPack {
+	selected := GetSelectedMapPack()
+	if selected == "" {
+		return BuiltinMapPack()
+	}
+	pack, err := OpenMapPack(selected)
+	if err != nil {
+		fmt.Printf("[MAPPACK] Failed to load map pack %s, using the built-in map: %v\n", selected, err)
+		return BuiltinMapPack()
+	}
+	return pack
+}
+
+// ActivateMapPack switches the simulation to another map, discarding the territories, routes,
+// transits and season progress of the current one. Guilds are kept. It is meant to run before
+// the UI loads the map, as with the -map-pack flag.
+func ActivateMapPack(packPath string) error {
+	pack := BuiltinMapPack()
+	if packPath != "" {
+		var err error
+		if pack, err = OpenMapPack(packPath); err != nil {
+			return err
+		}
+	}
+	if pack.Fingerprint == ActiveMapPack().Fingerprint && pack.Path == ActiveMapPack().Path {
+		return nil
+	}
+
+	wasHalted := st.halted
+	if !wasHalted {
+		st.halt()
+	}
+
+	st.mu.Lock()
+	st.stateLoading = true
+	for _, territory := range st.territories {
+		if territory != nil && territory.CloseCh != nil {
+			territory.CloseCh()
+		}
+	}
+	if st.transitManager != nil {
+		st.transitManager.ClearAllTransits()
+	}
+	st.territories = make([]*typedef.Territory, 0, len(pack.territories))
+	st.territoryMap = make(map[string]*typedef.Territory)
+	st.hqMap = make(map[string]*typedef.Territory)
+	st.manualRouteToHQ = make(map[string]int)
+	st.manualRouteFromHQ = make(map[string]int)
+	st.scheduledCaptures = nil
+	st.seasonScores = make(map[string]*typedef.SeasonScore)
+	st.tick = 0
+
+	// Annotations are placed in map coordinates of the old map
+	ClearAnnotations()
+
+	setActiveMapPack(pack)
+	buildMapTerritories(pack)
+	rebuildHQMap()
+	st.updateRoute()
+	st.stateLoading = false
+	st.mu.Unlock()
+
+	if err := ReloadDefaultCosts(); err != nil {
+		fmt.Printf("[MAPPACK] Failed to load the costs of %s: %v\n", pack.Manifest.Name, err)
+	}
+
+	if !wasHalted {
+		st.start()
+	}
+	if stateChangeCallback != nil {
+		stateChangeCallback()
+	}
+	return nil
+}
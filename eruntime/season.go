@@ -0,0 +1,522 @@
+package eruntime
+
+import (
+	"RueaES/storage"
+	"RueaES/typedef"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	seasonFormulaFile     = "season_rating.json"
+	captureCooldownTicks  = 600
+	maxSeasonCompareHours = 24 * 30
+)
+
+// SeasonStrategy is a named set of captures to score on top of the current claims.
+// Capture ticks are absolute, like those of live scheduled captures.
+type SeasonStrategy struct {
+	Name     string                     `json:"name"`
+	Captures []typedef.ScheduledCapture `json:"captures"`
+}
+
+// SeasonStrategyResult is the season rating every guild earns over the compared period under a strategy.
+type SeasonStrategyResult struct {
+	Name     string                `json:"name"`
+	Scores   []typedef.SeasonScore `json:"scores"`   // Highest first
+	Applied  int                   `json:"applied"`  // Captures that took place
+	Deferred []string              `json:"deferred"` // Territories whose capture was still waiting on the cooldown at the end
+}
+
+// seasonHolding is the ownership of one territory as far as season scoring is concerned
+type seasonHolding struct {
+	guildTag   string
+	capturedAt uint64
+	heldBefore uint64
+	hq         bool
+}
+
+// heldFor returns the seconds the holding has been held at tick
+func (h seasonHolding) heldFor(tick uint64) uint64 {
+	return tick - h.capturedAt + h.heldBefore
+}
+
+// DefaultSeasonRatingFormula returns the formula used when no season_rating.json exists.
+// Hold tiers follow the treasury thresholds.
+func DefaultSeasonRatingFormula() typedef.SeasonRatingFormula {
+	return typedef.SeasonRatingFormula{
+		TerritoryPointsPerHour: 1,
+		HQPointsPerHour:        5,
+		CapturePoints:          2,
+		HoldTiers: []typedef.SeasonHoldTier{
+			{AfterHours: 1, Multiplier: 1.1},
+			{AfterHours: 24, Multiplier: 1.2},
+			{AfterHours: 120, Multiplier: 1.25},
+			{AfterHours: 288, Multiplier: 1.3},
+		},
+	}
+}
+
+// loadSeasonRatingFormula reads the formula from the data directory, falling back to the default
+func loadSeasonRatingFormula() {
+	st.seasonFormula = DefaultSeasonRatingFormula()
+
+	data, err := storage.ReadDataFile(seasonFormulaFile)
+	if err != nil {
+		return
+	}
+	var formula typedef.SeasonRatingFormula
+	if err := json.Unmarshal(data, &formula); err != nil {
+		fmt.Printf("[SEASON] Ignoring %s: %v\n", seasonFormulaFile, err)
+		return
+	}
+	if err := validateSeasonFormula(&formula); err != nil {
+		fmt.Printf("[SEASON] Ignoring %s: %v\n", seasonFormulaFile, err)
+		return
+	}
+	st.seasonFormula = formula
+}
+
+// validateSeasonFormula rejects negative rates and sorts the hold tiers
+func validateSeasonFormula(formula *typedef.SeasonRatingFormula) error {
+	if formula.TerritoryPointsPerHour < 0 || formula.HQPointsPerHour < 0 || formula.CapturePoints < 0 {
+		return fmt.Errorf("season points cannot be negative")
+	}
+	for name, rate := range formula.Territories {
+		if rate < 0 {
+			return fmt.Errorf("season points for %s cannot be negative", name)
+		}
+	}
+	for _, tier := range formula.HoldTiers {
+		if tier.AfterHours < 0 || tier.Multiplier < 0 {
+			return fmt.Errorf("hold tiers cannot be negative")
+		}
+	}
+	sort.Slice(formula.HoldTiers, func(i, j int) bool {
+		return formula.HoldTiers[i].AfterHours < formula.HoldTiers[j].AfterHours
+	})
+	return nil
+}
+
+// GetSeasonRatingFormula returns the formula season points are currently earned with
+func GetSeasonRatingFormula() typedef.SeasonRatingFormula {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.seasonFormula
+}
+
+// SetSeasonRatingFormula replaces the formula and stores it in the data directory.
+// Points already accumulated are kept.
+func SetSeasonRatingFormula(formula typedef.SeasonRatingFormula) error {
+	if err := validateSeasonFormula(&formula); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(formula, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteDataFile(seasonFormulaFile, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save season formula: %v", err)
+	}
+
+	st.mu.Lock()
+	st.seasonFormula = formula
+	st.mu.Unlock()
+	return nil
+}
+
+// LoadSeasonRatingFormula reads a formula from a JSON file and makes it the current one
+func LoadSeasonRatingFormula(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read season formula: %v", err)
+	}
+	var formula typedef.SeasonRatingFormula
+	if err := json.Unmarshal(data, &formula); err != nil {
+		return fmt.Errorf("failed to parse season formula: %v", err)
+	}
+	return SetSeasonRatingFormula(formula)
+}
+
+// seasonMinutePoints returns the territory and HQ points a holding earns over one minute
+func seasonMinutePoints(formula *typedef.SeasonRatingFormula, name string, held uint64, hq bool) (float64, float64) {
+	rate := formula.TerritoryPointsPerHour
+	if custom, ok := formula.Territories[name]; ok {
+		rate = custom
+	}
+
+	multiplier := 1.0
+	hours := float64(held) / 3600
+	for _, tier := range formula.HoldTiers {
+		if hours >= tier.AfterHours {
+			multiplier = tier.Multiplier
+		}
+	}
+
+	var hqPoints float64
+	if hq {
+		hqPoints = formula.HQPointsPerHour / 60
+	}
+	return rate * multiplier / 60, hqPoints
+}
+
+// seasonScoreFor returns the guild's score entry, creating it if needed
+func seasonScoreFor(scores map[string]*typedef.SeasonScore, guildTag string) *typedef.SeasonScore {
+	score := scores[guildTag]
+	if score == nil {
+		score = &typedef.SeasonScore{GuildTag: guildTag}
+		scores[guildTag] = score
+	}
+	return score
+}
+
+// accrueSeasonMinute adds one minute of holding a territory to the guild's score
+func accrueSeasonMinute(scores map[string]*typedef.SeasonScore, formula *typedef.SeasonRatingFormula, name, guildTag string, held uint64, hq bool) {
+	territoryPoints, hqPoints := seasonMinutePoints(formula, name, held, hq)
+	score := seasonScoreFor(scores, guildTag)
+	score.TerritoryPoints += territoryPoints
+	score.HQPoints += hqPoints
+	score.TerritoryHours += 1.0 / 60
+	score.Points = score.TerritoryPoints + score.HQPoints + score.CapturePoints
+}
+
+// recordSeasonCapture credits a capture to the guild's score
+func recordSeasonCapture(scores map[string]*typedef.SeasonScore, formula *typedef.SeasonRatingFormula, guildTag string) {
+	if guildTag == "" {
+		return
+	}
+	score := seasonScoreFor(scores, guildTag)
+	score.Captures++
+	score.CapturePoints += formula.CapturePoints
+	score.Points = score.TerritoryPoints + score.HQPoints + score.CapturePoints
+}
+
+// sortedSeasonScores copies scores out of the map, highest first
+func sortedSeasonScores(scores map[string]*typedef.SeasonScore) []typedef.SeasonScore {
+	result := make([]typedef.SeasonScore, 0, len(scores))
+	for _, score := range scores {
+		result = append(result, *score)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Points != result[j].Points {
+			return result[i].Points > result[j].Points
+		}
+		return result[i].GuildTag < result[j].GuildTag
+	})
+	return result
+}
+
+// updateSeason applies scheduled captures that are due and accrues season points on minute boundaries.
+// Caller must hold the st.mu write lock.
+func (s *state) updateSeason() {
+	if len(s.scheduledCaptures) > 0 {
+		s.applyDueCapturesUnsafe()
+	}
+
+	if s.tick%60 != 0 {
+		return
+	}
+	for _, t := range s.territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		name, guildName, guildTag := t.Name, t.Guild.Name, t.Guild.Tag
+		held, hq := territoryHeldFor(t, s.tick), t.HQ
+		t.Mu.RUnlock()
+
+		if guildName == "" || guildName == "No Guild" {
+			continue
+		}
+		accrueSeasonMinute(s.seasonScores, &s.seasonFormula, name, guildTag, held, hq)
+	}
+}
+
+// applyDueCapturesUnsafe hands over the territories of due captures. Captures blocked by the
+// capture cooldown stay scheduled until it runs out; ones that can never apply are dropped.
+func (s *state) applyDueCapturesUnsafe() {
+	remaining := s.scheduledCaptures[:0]
+	captured := false
+	for _, capture := range s.scheduledCaptures {
+		if capture.Tick > s.tick {
+			remaining = append(remaining, capture)
+			continue
+		}
+
+		t := getTerritoryUnsafe(capture.Territory)
+		guild := s.guildByTagUnsafe(capture.GuildTag)
+		if t == nil || guild == nil {
+			debugf("Dropping scheduled capture %s: territory or guild no longer exists\n", capture.ID)
+			continue
+		}
+
+		t.Mu.Lock()
+		if t.Guild.Tag != guild.Tag {
+			if s.runtimeOptions.ImposeCooldown && territoryHeldFor(t, s.tick) < captureCooldownTicks {
+				t.Mu.Unlock()
+				remaining = append(remaining, capture)
+				continue
+			}
+
+			t.Guild = typedef.Guild{Name: guild.Name, Tag: guild.Tag, AllyTags: guild.AllyTags}
+			t.CapturedAt = s.tick
+			t.HeldBefore = 0
+			t.Treasury = typedef.TreasuryLevelVeryLow
+			if t.HQ {
+				setHQInMap(t, false)
+			}
+			t.HQ = false
+			recordSeasonCapture(s.seasonScores, &s.seasonFormula, guild.Tag)
+		}
+		t.Mu.Unlock()
+
+		s.updateRoute()
+		if capture.SetHQ {
+			sethqUnsafe(t)
+		}
+		captured = true
+	}
+	s.scheduledCaptures = remaining
+
+	if captured {
+		go NotifyTerritoryColorsUpdate()
+	}
+}
+
+// guildByTagUnsafe finds a guild by tag. Caller must hold st.mu.
+func (s *state) guildByTagUnsafe(tag string) *typedef.Guild {
+	for _, guild := range s.guilds {
+		if guild != nil && guild.Tag == tag {
+			return guild
+		}
+	}
+	return nil
+}
+
+// ScheduleCapture queues a capture of the territory by the guild at the given tick and returns its ID
+func ScheduleCapture(territory, guildTag string, tick uint64, setHQ bool) (string, error) {
+	territory = strings.TrimSpace(territory)
+	guildTag = strings.TrimSpace(guildTag)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if getTerritoryUnsafe(territory) == nil {
+		return "", fmt.Errorf("territory %s not found", territory)
+	}
+	if st.guildByTagUnsafe(guildTag) == nil {
+		return "", fmt.Errorf("guild with tag '%s' not found", guildTag)
+	}
+	if tick <= st.tick {
+		return "", fmt.Errorf("capture tick %d is not after the current tick %d", tick, st.tick)
+	}
+
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	capture := &typedef.ScheduledCapture{
+		ID:        "capture_" + hex.EncodeToString(bytes),
+		Tick:      tick,
+		Territory: territory,
+		GuildTag:  guildTag,
+		SetHQ:     setHQ,
+	}
+	st.scheduledCaptures = append(st.scheduledCaptures, capture)
+	sort.SliceStable(st.scheduledCaptures, func(i, j int) bool {
+		return st.scheduledCaptures[i].Tick < st.scheduledCaptures[j].Tick
+	})
+	return capture.ID, nil
+}
+
+// CancelScheduledCapture removes a capture that has not happened yet
+func CancelScheduledCapture(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for i, capture := range st.scheduledCaptures {
+		if capture.ID == id {
+			st.scheduledCaptures = append(st.scheduledCaptures[:i], st.scheduledCaptures[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("scheduled capture %s not found", id)
+}
+
+// GetScheduledCaptures returns the pending captures in the order they are due
+func GetScheduledCaptures() []typedef.ScheduledCapture {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return copyScheduledCapturesUnsafe()
+}
+
+func copyScheduledCapturesUnsafe() []typedef.ScheduledCapture {
+	result := make([]typedef.ScheduledCapture, 0, len(st.scheduledCaptures))
+	for _, capture := range st.scheduledCaptures {
+		result = append(result, *capture)
+	}
+	return result
+}
+
+// GetSeasonScores returns the season rating accumulated by each guild, highest first
+func GetSeasonScores() []typedef.SeasonScore {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return sortedSeasonScores(st.seasonScores)
+}
+
+// ResetSeasonScores starts the season over. Scheduled captures are kept.
+func ResetSeasonScores() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.seasonScores = make(map[string]*typedef.SeasonScore)
+}
+
+// restoreSeasonUnsafe replaces the season state with saved data. Caller must hold the st.mu write lock.
+func restoreSeasonUnsafe(captures []typedef.ScheduledCapture, scores []typedef.SeasonScore, formula *typedef.SeasonRatingFormula) {
+	st.scheduledCaptures = make([]*typedef.ScheduledCapture, 0, len(captures))
+	for i := range captures {
+		capture := captures[i]
+		st.scheduledCaptures = append(st.scheduledCaptures, &capture)
+	}
+
+	st.seasonScores = make(map[string]*typedef.SeasonScore, len(scores))
+	for i := range scores {
+		score := scores[i]
+		st.seasonScores[score.GuildTag] = &score
+	}
+
+	if formula != nil && validateSeasonFormula(formula) == nil {
+		st.seasonFormula = *formula
+	}
+}
+
+// CompareSeasonStrategies scores each strategy over the next hours, starting from the current claims
+// and including captures already scheduled. Scores only count points earned within the period, and
+// nothing in the live state is modified.
+func CompareSeasonStrategies(hours int, strategies []SeasonStrategy) ([]SeasonStrategyResult, error) {
+	if hours <= 0 || hours > maxSeasonCompareHours {
+		return nil, fmt.Errorf("hours must be between 1 and %d", maxSeasonCompareHours)
+	}
+	if len(strategies) == 0 {
+		return nil, fmt.Errorf("no strategies to compare")
+	}
+
+	st.mu.RLock()
+	start := st.tick
+	formula := st.seasonFormula
+	cooldown := st.runtimeOptions.ImposeCooldown
+	scheduled := copyScheduledCapturesUnsafe()
+	holdings := make(map[string]seasonHolding, len(st.territories))
+	names := make([]string, 0, len(st.territories))
+	for _, t := range st.territories {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		if t.Guild.Name != "" && t.Guild.Name != "No Guild" {
+			holdings[t.Name] = seasonHolding{guildTag: t.Guild.Tag, capturedAt: t.CapturedAt, heldBefore: t.HeldBefore, hq: t.HQ}
+		} else {
+			holdings[t.Name] = seasonHolding{}
+		}
+		t.Mu.RUnlock()
+		names = append(names, t.Name)
+	}
+	guildTags := make(map[string]bool, len(st.guilds))
+	for _, guild := range st.guilds {
+		if guild != nil {
+			guildTags[guild.Tag] = true
+		}
+	}
+	st.mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]SeasonStrategyResult, 0, len(strategies))
+	for _, strategy := range strategies {
+		captures := append(append([]typedef.ScheduledCapture{}, scheduled...), strategy.Captures...)
+		for _, capture := range strategy.Captures {
+			if _, ok := holdings[capture.Territory]; !ok {
+				return nil, fmt.Errorf("strategy %s: territory %s not found", strategy.Name, capture.Territory)
+			}
+			if !guildTags[capture.GuildTag] {
+				return nil, fmt.Errorf("strategy %s: guild with tag '%s' not found", strategy.Name, capture.GuildTag)
+			}
+		}
+		sort.SliceStable(captures, func(i, j int) bool {
+			return captures[i].Tick < captures[j].Tick
+		})
+
+		result := simulateSeasonStrategy(holdings, names, captures, &formula, start, uint64(hours)*3600, cooldown)
+		result.Name = strategy.Name
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// simulateSeasonStrategy steps a copy of the holdings tick by tick, applying captures and accruing points
+func simulateSeasonStrategy(base map[string]seasonHolding, names []string, captures []typedef.ScheduledCapture, formula *typedef.SeasonRatingFormula, start, duration uint64, cooldown bool) SeasonStrategyResult {
+	holdings := make(map[string]seasonHolding, len(base))
+	for name, holding := range base {
+		holdings[name] = holding
+	}
+	scores := make(map[string]*typedef.SeasonScore)
+	result := SeasonStrategyResult{}
+
+	pending := captures
+	for tick := start + 1; tick <= start+duration; tick++ {
+		if len(pending) > 0 && pending[0].Tick <= tick {
+			waiting := pending[:0:0]
+			for _, capture := range pending {
+				holding := holdings[capture.Territory]
+				switch {
+				case capture.Tick > tick:
+					waiting = append(waiting, capture)
+					continue
+				case holding.guildTag == capture.GuildTag:
+				case cooldown && holding.heldFor(tick) < captureCooldownTicks:
+					waiting = append(waiting, capture)
+					continue
+				default:
+					holding = seasonHolding{guildTag: capture.GuildTag, capturedAt: tick}
+					recordSeasonCapture(scores, formula, capture.GuildTag)
+				}
+
+				if capture.SetHQ && !holding.hq {
+					for name, other := range holdings {
+						if other.guildTag == capture.GuildTag && other.hq {
+							other.hq = false
+							holdings[name] = other
+						}
+					}
+					holding.hq = true
+				}
+				holdings[capture.Territory] = holding
+				result.Applied++
+			}
+			pending = waiting
+		}
+
+		if tick%60 != 0 {
+			continue
+		}
+		for _, name := range names {
+			holding := holdings[name]
+			if holding.guildTag == "" {
+				continue
+			}
+			accrueSeasonMinute(scores, formula, name, holding.guildTag, holding.heldFor(tick), holding.hq)
+		}
+	}
+
+	for _, capture := range pending {
+		if capture.Tick <= start+duration {
+			result.Deferred = append(result.Deferred, capture.Territory)
+		}
+	}
+	result.Scores = sortedSeasonScores(scores)
+	return result
+}
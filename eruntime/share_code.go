@@ -0,0 +1,574 @@
+package eruntime
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"RueaES/typedef"
+)
+
+// Share codes are short text codes for pasting tower setups in chat. The payload is a compact
+// varint encoding rather than ETF, which stores field names and would make codes several times
+// longer. Layout: version, kind, flags, body (deflated when that is smaller), CRC32 of the rest.
+const (
+	shareCodePrefix        = "RES-"
+	shareCodeVersion       = 1
+	shareCodeFlagDeflated  = 1 << 0
+	maxShareCodeLength     = 64 * 1024
+	maxShareCodeBody       = 256 * 1024
+	maxShareCodeString     = 256
+	maxShareCodeTerritory  = 2000
+	shareCodeTaxResolution = 1000 // Taxes are stored in tenths of a percent
+)
+
+// ShareCodeKind is what a share code carries
+type ShareCodeKind uint8
+
+const (
+	ShareCodeLoadout     ShareCodeKind = 1 // A single loadout
+	ShareCodeTerritories ShareCodeKind = 2 // Options for a set of territories
+	ShareCodeClaim       ShareCodeKind = 3 // A guild claim, territories with owner and HQ
+)
+
+func (k ShareCodeKind) String() string {
+	switch k {
+	case ShareCodeLoadout:
+		return "Loadout"
+	case ShareCodeTerritories:
+		return "Territory Setup"
+	case ShareCodeClaim:
+		return "Guild Claim"
+	}
+	return fmt.Sprintf("Unknown (%d)", uint8(k))
+}
+
+// SharedTerritory is the setup of one territory in a share code
+type SharedTerritory struct {
+	Name    string                   `json:"name"`
+	Options typedef.TerritoryOptions `json:"options"`
+}
+
+// ShareCode is the decoded content of a share code. Loadout is set for loadout codes, Territories
+// for territory and claim codes, GuildName and GuildTag for claim codes.
+type ShareCode struct {
+	Kind        ShareCodeKind     `json:"kind"`
+	Loadout     *typedef.Loadout  `json:"loadout,omitempty"`
+	Territories []SharedTerritory `json:"territories,omitempty"`
+	GuildName   string            `json:"guildName,omitempty"`
+	GuildTag    string            `json:"guildTag,omitempty"`
+}
+
+// IsShareCode reports whether text looks like a share code, without validating it
+func IsShareCode(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), shareCodePrefix)
+}
+
+// LoadoutShareCode encodes a loadout as a share code
+func LoadoutShareCode(loadout typedef.Loadout) (string, error) {
+	return EncodeShareCode(&ShareCode{Kind: ShareCodeLoadout, Loadout: &loadout})
+}
+
+// TerritoriesShareCode encodes the current options of the named territories
+func TerritoriesShareCode(names []string) (string, error) {
+	code := &ShareCode{Kind: ShareCodeTerritories}
+	for _, name := range names {
+		t := GetTerritory(name)
+		if t == nil {
+			return "", fmt.Errorf("territory %s not found", name)
+		}
+		code.Territories = append(code.Territories, sharedTerritoryOf(t))
+	}
+	if len(code.Territories) == 0 {
+		return "", fmt.Errorf("no territories to share")
+	}
+	return EncodeShareCode(code)
+}
+
+// ClaimShareCode encodes every territory the guild with the given tag owns, with its options
+func ClaimShareCode(guildTag string) (string, error) {
+	code := &ShareCode{Kind: ShareCodeClaim, GuildTag: guildTag}
+	for _, t := range GetTerritories() {
+		if t == nil {
+			continue
+		}
+		t.Mu.RLock()
+		owned := strings.EqualFold(t.Guild.Tag, guildTag)
+		if owned {
+			code.GuildName = t.Guild.Name
+			code.GuildTag = t.Guild.Tag
+		}
+		t.Mu.RUnlock()
+		if owned {
+			code.Territories = append(code.Territories, sharedTerritoryOf(t))
+		}
+	}
+	if len(code.Territories) == 0 {
+		return "", fmt.Errorf("guild [%s] owns no territories", guildTag)
+	}
+	sort.Slice(code.Territories, func(i, j int) bool { return code.Territories[i].Name < code.Territories[j].Name })
+	return EncodeShareCode(code)
+}
+
+func sharedTerritoryOf(t *typedef.Territory) SharedTerritory {
+	t.Mu.RLock()
+	defer t.Mu.RUnlock()
+	return SharedTerritory{
+		Name: t.Name,
+		Options: typedef.TerritoryOptions{
+			Upgrades:    t.Options.Upgrade.Set,
+			Bonuses:     t.Options.Bonus.Set,
+			Tax:         t.Tax,
+			RoutingMode: t.RoutingMode,
+			Border:      t.Border,
+			HQ:          t.HQ,
+		},
+	}
+}
+
+// EncodeShareCode encodes share code content as text
+func EncodeShareCode(code *ShareCode) (string, error) {
+	var body bytes.Buffer
+	w := shareWriter{buf: &body}
+	switch code.Kind {
+	case ShareCodeLoadout:
+		if code.Loadout == nil {
+			return "", fmt.Errorf("loadout share code has no loadout")
+		}
+		w.string(code.Loadout.Name)
+		w.options(code.Loadout.TerritoryOptions)
+	case ShareCodeTerritories, ShareCodeClaim:
+		if code.Kind == ShareCodeClaim {
+			w.string(code.GuildName)
+			w.string(code.GuildTag)
+		}
+		w.uvarint(uint64(len(code.Territories)))
+		for _, t := range code.Territories {
+			w.string(t.Name)
+			w.options(t.Options)
+		}
+	default:
+		return "", fmt.Errorf("unknown share code kind %d", code.Kind)
+	}
+
+	payload := []byte{shareCodeVersion, byte(code.Kind), 0}
+	if deflated, err := deflateShareCode(body.Bytes()); err == nil && len(deflated) < body.Len() {
+		payload[2] |= shareCodeFlagDeflated
+		payload = append(payload, deflated...)
+	} else {
+		payload = append(payload, body.Bytes()...)
+	}
+	payload = binary.LittleEndian.AppendUint32(payload, crc32.ChecksumIEEE(payload))
+
+	return shareCodePrefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// DecodeShareCode decodes and validates a share code. Whitespace, such as line breaks added by
+// chat clients, is ignored.
+func DecodeShareCode(text string) (*ShareCode, error) {
+	text = strings.Join(strings.Fields(text), "")
+	if !strings.HasPrefix(text, shareCodePrefix) {
+		return nil, fmt.Errorf("not a share code, share codes start with %s", shareCodePrefix)
+	}
+	if len(text) > maxShareCodeLength {
+		return nil, fmt.Errorf("share code is too long")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(text[len(shareCodePrefix):], "="))
+	if err != nil {
+		return nil, fmt.Errorf("share code is damaged or incomplete")
+	}
+	if len(payload) < 7 {
+		return nil, fmt.Errorf("share code is too short")
+	}
+
+	checksum := binary.LittleEndian.Uint32(payload[len(payload)-4:])
+	payload = payload[:len(payload)-4]
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, fmt.Errorf("share code checksum mismatch, it was probably cut off or edited")
+	}
+
+	version, kind, flags := payload[0], ShareCodeKind(payload[1]), payload[2]
+	if version > shareCodeVersion {
+		return nil, fmt.Errorf("share code version %d was made by a newer RueaES, this build reads up to version %d", version, shareCodeVersion)
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("invalid share code version 0")
+	}
+
+	body := payload[3:]
+	if flags&shareCodeFlagDeflated != 0 {
+		if body, err = inflateShareCode(body); err != nil {
+			return nil, fmt.Errorf("share code body is damaged: %v", err)
+		}
+	}
+
+	r := shareReader{data: body}
+	code := &ShareCode{Kind: kind}
+	switch kind {
+	case ShareCodeLoadout:
+		loadout := &typedef.Loadout{Name: r.string()}
+		loadout.TerritoryOptions = r.options()
+		code.Loadout = loadout
+	case ShareCodeTerritories, ShareCodeClaim:
+		if kind == ShareCodeClaim {
+			code.GuildName = r.string()
+			code.GuildTag = r.string()
+		}
+		count := r.uvarint()
+		if count > maxShareCodeTerritory {
+			return nil, fmt.Errorf("share code lists %d territories, at most %d are allowed", count, maxShareCodeTerritory)
+		}
+		seen := make(map[string]bool, count)
+		for i := uint64(0); i < count && r.err == nil; i++ {
+			t := SharedTerritory{Name: r.string(), Options: r.options()}
+			if r.err == nil && seen[t.Name] {
+				return nil, fmt.Errorf("share code lists %s twice", t.Name)
+			}
+			seen[t.Name] = true
+			code.Territories = append(code.Territories, t)
+		}
+	default:
+		return nil, fmt.Errorf("unknown share code kind %d", kind)
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("share code is damaged: %v", r.err)
+	}
+	if len(r.data) != 0 {
+		return nil, fmt.Errorf("share code has %d unexpected trailing bytes", len(r.data))
+	}
+	if err := code.validate(); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// validate checks decoded values against the ranges the game allows
+func (code *ShareCode) validate() error {
+	check := func(label string, opts typedef.TerritoryOptions) error {
+		for _, level := range []int{opts.Upgrades.Damage, opts.Upgrades.Attack, opts.Upgrades.Health, opts.Upgrades.Defence} {
+			if level > 11 {
+				return fmt.Errorf("%s has an upgrade at level %d, the maximum is 11", label, level)
+			}
+		}
+		for _, tax := range []float64{opts.Tax.Tax, opts.Tax.Ally} {
+			if tax > 1 {
+				return fmt.Errorf("%s has a tax of %.1f%%, taxes cannot exceed 100%%", label, tax*100)
+			}
+		}
+		return nil
+	}
+
+	if code.Loadout != nil {
+		if strings.TrimSpace(code.Loadout.Name) == "" {
+			return fmt.Errorf("shared loadout has no name")
+		}
+		return check("loadout "+code.Loadout.Name, code.Loadout.TerritoryOptions)
+	}
+
+	hqs := 0
+	for _, t := range code.Territories {
+		if err := check(t.Name, t.Options); err != nil {
+			return err
+		}
+		if t.Options.HQ {
+			hqs++
+		}
+	}
+	if code.Kind == ShareCodeClaim && (code.GuildName == "" || code.GuildTag == "") {
+		return fmt.Errorf("shared claim has no guild")
+	}
+	if hqs > 1 && code.Kind == ShareCodeClaim {
+		return fmt.Errorf("shared claim has %d HQs", hqs)
+	}
+	return nil
+}
+
+// ShareCodePreview summarizes what applying a share code would change
+type ShareCodePreview struct {
+	Unchanged    []string `json:"unchanged"`    // Territories that already have the shared setup
+	Changed      []string `json:"changed"`      // Territories whose options would change
+	Missing      []string `json:"missing"`      // Territories not on the current map, skipped when applying
+	OwnerChanges []string `json:"ownerChanges"` // Claim territories that would change owner
+	GuildMissing bool     `json:"guildMissing"` // Claim guild is not in the guild list
+}
+
+// PreviewShareCode compares a territory or claim share code with the current state
+func PreviewShareCode(code *ShareCode) *ShareCodePreview {
+	preview := &ShareCodePreview{}
+	if code.Kind == ShareCodeClaim {
+		preview.GuildMissing = findGuildByTag(code.GuildTag) == nil
+	}
+	for _, shared := range code.Territories {
+		t := GetTerritory(shared.Name)
+		if t == nil {
+			preview.Missing = append(preview.Missing, shared.Name)
+			continue
+		}
+		current := sharedTerritoryOf(t)
+		t.Mu.RLock()
+		owner := t.Guild.Tag
+		t.Mu.RUnlock()
+
+		if code.Kind == ShareCodeClaim && owner != code.GuildTag {
+			preview.OwnerChanges = append(preview.OwnerChanges, shared.Name)
+		}
+		if sharedOptionsEqual(current.Options, shared.Options) {
+			preview.Unchanged = append(preview.Unchanged, shared.Name)
+		} else {
+			preview.Changed = append(preview.Changed, shared.Name)
+		}
+	}
+	return preview
+}
+
+// sharedOptionsEqual compares options the way they round-trip through a share code
+func sharedOptionsEqual(a, b typedef.TerritoryOptions) bool {
+	a.Tax.Tax, a.Tax.Ally = roundShareTax(a.Tax.Tax), roundShareTax(a.Tax.Ally)
+	b.Tax.Tax, b.Tax.Ally = roundShareTax(b.Tax.Tax), roundShareTax(b.Tax.Ally)
+	return a == b
+}
+
+func roundShareTax(tax float64) float64 {
+	return math.Round(tax*shareCodeTaxResolution) / shareCodeTaxResolution
+}
+
+// ApplyShareCode applies a territory or claim share code. Claims first move the territories to
+// the claim guild, which must be in the guild list, then set options, then the HQ. Territories
+// missing from the map are skipped. It returns the number of territories updated.
+func ApplyShareCode(code *ShareCode) (int, error) {
+	if code.Kind != ShareCodeTerritories && code.Kind != ShareCodeClaim {
+		return 0, fmt.Errorf("%s share codes are not applied to territories", code.Kind)
+	}
+
+	if code.Kind == ShareCodeClaim {
+		guild := findGuildByTag(code.GuildTag)
+		if guild == nil {
+			return 0, fmt.Errorf("guild %s [%s] is not in the guild list, add it before applying the claim", code.GuildName, code.GuildTag)
+		}
+		owners := make(map[string]*typedef.Guild)
+		for _, shared := range code.Territories {
+			if t := GetTerritory(shared.Name); t != nil {
+				owners[shared.Name] = &typedef.Guild{Name: guild.Name, Tag: guild.Tag}
+			}
+		}
+		SetGuildBatch(owners)
+	}
+
+	applied := 0
+	hq := ""
+	for _, shared := range code.Territories {
+		if GetTerritory(shared.Name) == nil {
+			continue
+		}
+		opts := shared.Options
+		if opts.HQ {
+			hq = shared.Name
+			opts.HQ = false
+		}
+		if Set(shared.Name, opts) != nil {
+			applied++
+		}
+	}
+	if hq != "" {
+		if t := GetTerritory(hq); t != nil {
+			opts := sharedTerritoryOf(t).Options
+			opts.HQ = true
+			Set(hq, opts)
+		}
+	}
+	return applied, nil
+}
+
+// findGuildByTag returns the guild with the given tag from the guild list
+func findGuildByTag(tag string) *typedef.Guild {
+	for _, guild := range GetGuildsInternal() {
+		if guild != nil && guild.Tag == tag {
+			return guild
+		}
+	}
+	return nil
+}
+
+func deflateShareCode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func inflateShareCode(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	inflated, err := io.ReadAll(io.LimitReader(r, maxShareCodeBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(inflated) > maxShareCodeBody {
+		return nil, fmt.Errorf("body is larger than %d bytes", maxShareCodeBody)
+	}
+	return inflated, nil
+}
+
+// shareBonusLevels lists the bonus levels in share code order. New bonuses must be appended,
+// the count written before them lets older codes decode with the missing bonuses at 0.
+func shareBonusLevels(b *typedef.Bonus) []*int {
+	return []*int{
+		&b.StrongerMinions, &b.TowerMultiAttack, &b.TowerAura, &b.TowerVolley,
+		&b.GatheringExperience, &b.MobExperience, &b.MobDamage, &b.PvPDamage,
+		&b.XPSeeking, &b.TomeSeeking, &b.EmeraldSeeking,
+		&b.LargerResourceStorage, &b.LargerEmeraldStorage,
+		&b.EfficientResource, &b.EfficientEmerald,
+		&b.ResourceRate, &b.EmeraldRate,
+	}
+}
+
+const (
+	shareOptionFastest = 1 << 0
+	shareOptionOpen    = 1 << 1
+	shareOptionHQ      = 1 << 2
+)
+
+type shareWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w shareWriter) uvarint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w shareWriter) string(s string) {
+	if len(s) > maxShareCodeString {
+		s = s[:maxShareCodeString]
+	}
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w shareWriter) options(opts typedef.TerritoryOptions) {
+	for _, level := range []int{opts.Upgrades.Damage, opts.Upgrades.Attack, opts.Upgrades.Health, opts.Upgrades.Defence} {
+		w.uvarint(uint64(max(level, 0)))
+	}
+	bonuses := shareBonusLevels(&opts.Bonuses)
+	w.uvarint(uint64(len(bonuses)))
+	for _, level := range bonuses {
+		w.uvarint(uint64(max(*level, 0)))
+	}
+	w.uvarint(uint64(math.Round(max(opts.Tax.Tax, 0) * shareCodeTaxResolution)))
+	w.uvarint(uint64(math.Round(max(opts.Tax.Ally, 0) * shareCodeTaxResolution)))
+
+	var flags byte
+	if opts.RoutingMode == typedef.RoutingFastest {
+		flags |= shareOptionFastest
+	}
+	if opts.Border == typedef.BorderOpen {
+		flags |= shareOptionOpen
+	}
+	if opts.HQ {
+		flags |= shareOptionHQ
+	}
+	w.buf.WriteByte(flags)
+}
+
+// shareReader decodes a share code body, keeping the first error so callers check once at the end
+type shareReader struct {
+	data []byte
+	err  error
+}
+
+var errShareCodeTruncated = errors.New("unexpected end of data")
+
+func (r *shareReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errShareCodeTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// level reads a level and rejects values that could not have come from a real setup
+func (r *shareReader) level() int {
+	v := r.uvarint()
+	if v > 255 && r.err == nil {
+		r.err = fmt.Errorf("level %d is out of range", v)
+	}
+	return int(v)
+}
+
+func (r *shareReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > maxShareCodeString || n > uint64(len(r.data)) {
+		r.err = errShareCodeTruncated
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+func (r *shareReader) options() typedef.TerritoryOptions {
+	var opts typedef.TerritoryOptions
+	opts.Upgrades.Damage = r.level()
+	opts.Upgrades.Attack = r.level()
+	opts.Upgrades.Health = r.level()
+	opts.Upgrades.Defence = r.level()
+
+	bonuses := shareBonusLevels(&opts.Bonuses)
+	count := r.uvarint()
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		level := r.level()
+		// Bonuses added by newer versions are dropped
+		if i < uint64(len(bonuses)) {
+			*bonuses[i] = level
+		}
+	}
+
+	opts.Tax.Tax = float64(r.uvarint()) / shareCodeTaxResolution
+	opts.Tax.Ally = float64(r.uvarint()) / shareCodeTaxResolution
+
+	if r.err != nil {
+		return opts
+	}
+	if len(r.data) == 0 {
+		r.err = errShareCodeTruncated
+		return opts
+	}
+	flags := r.data[0]
+	r.data = r.data[1:]
+	if flags&shareOptionFastest != 0 {
+		opts.RoutingMode = typedef.RoutingFastest
+	} else {
+		opts.RoutingMode = typedef.RoutingCheapest
+	}
+	if flags&shareOptionOpen != 0 {
+		opts.Border = typedef.BorderOpen
+	} else {
+		opts.Border = typedef.BorderClosed
+	}
+	opts.HQ = flags&shareOptionHQ != 0
+	return opts
+}
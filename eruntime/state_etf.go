@@ -0,0 +1,80 @@
+package eruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"RueaES/parser"
+)
+
+// stateETFExtension selects the binary ETF format when saving. Loading detects the format
+// from the file contents regardless of extension.
+const stateETFExtension = ".etf"
+
+// isETFStatePath reports whether a save path selects the binary ETF format
+func isETFStatePath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), stateETFExtension)
+}
+
+// newStateParser returns the parser used for .etf state files. Field type names are left out
+// because fields are matched by name and carry their own length, so they only add size.
+func newStateParser() *parser.Parser {
+	config := parser.DefaultConfig()
+	config.IncludeFieldTypeInfo = false
+	return parser.NewWithConfig(config)
+}
+
+// encodeStateData serializes state data as LZ4-compressed JSON, or as ETF when etf is set
+func encodeStateData(stateData *StateData, etf bool) ([]byte, error) {
+	if etf {
+		data, err := newStateParser().Encode(stateData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode state data: %v", err)
+		}
+		return data, nil
+	}
+
+	// Marshal to JSON
+	jsonData, err := json.Marshal(stateData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state data: %v", err)
+	}
+
+	// Compress with LZ4
+	compressedData, err := compressLZ4(jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress data: %v", err)
+	}
+	return compressedData, nil
+}
+
+// decodeStateData reads state file contents in either format into out. ETF files are recognised
+// by their magic number and every struct is checked against its checksum.
+func decodeStateData(data []byte, out interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("file is empty")
+	}
+
+	if parser.IsETF(data) {
+		if err := newStateParser().Decode(data, out); err != nil {
+			return fmt.Errorf("failed to decode ETF data (corrupted state file): %v", err)
+		}
+		return nil
+	}
+
+	// Decompress LZ4
+	jsonData, err := decompressLZ4(data)
+	if err != nil {
+		return fmt.Errorf("failed to decompress data (corrupted or not a valid LZ4 file): %v", err)
+	}
+	if len(jsonData) == 0 {
+		return fmt.Errorf("decompressed data is empty")
+	}
+
+	if err := json.Unmarshal(jsonData, out); err != nil {
+		return fmt.Errorf("failed to parse JSON data (corrupted state file): %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package eruntime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"RueaES/parser"
+	"RueaES/typedef"
+)
+
+// populatedStateData returns a state with every kind of saved data. With runtime set, the fields
+// tagged etf:"-" are filled in as well, the way they are on a live state.
+func populatedStateData(runtime bool) *StateData {
+	guild := typedef.Guild{
+		Name:      "Avicia",
+		Tag:       "AVO",
+		AllyTags:  []string{"ESI"},
+		TributeIn: typedef.BasicResources{Emeralds: 500},
+	}
+	hq := &typedef.Territory{
+		ID:       "ragni",
+		Name:     "Ragni",
+		Guild:    guild,
+		Location: typedef.LocationObject{Start: [2]int{-955, -1415}, End: [2]int{-756, -1519}},
+		Options: typedef.TerritoryTower{
+			Upgrade: typedef.TerritoryUpgrade{
+				Set: typedef.Upgrade{Damage: 6, Attack: 5, Health: 8, Defence: 7},
+				At:  typedef.Upgrade{Damage: 6, Attack: 5, Health: 8, Defence: 7},
+			},
+			Bonus: typedef.TerritoryBonus{
+				Set: typedef.Bonus{TowerAura: 2, TowerVolley: 1},
+				At:  typedef.Bonus{TowerAura: 2},
+			},
+		},
+		SetLevelInt: 42,
+		SetLevel:    typedef.DefenceLevelHigh,
+		Links: typedef.Links{
+			Direct:    map[string]struct{}{"Maltic": {}},
+			Externals: map[string]struct{}{"Maltic": {}, "Nivla Woods": {}},
+		},
+		ResourceGeneration: typedef.ResourceGeneration{
+			Base:              typedef.BasicResources{Emeralds: 9000, Ores: 3600},
+			At:                typedef.BasicResources{Emeralds: 10800, Ores: 4320},
+			ResourceDeltaTime: 4,
+			EmeraldDeltaTime:  2,
+		},
+		Treasury:             typedef.TreasuryLevelHigh,
+		TreasuryOverride:     typedef.TreasuryOverrideVeryHigh,
+		GenerationBonus:      20,
+		CapturedAt:           3600,
+		HeldBefore:           86400,
+		ConnectedTerritories: []string{"Maltic", "Ragni Plains"},
+		TradingRoutesJSON:    [][]string{{"Maltic", "Ragni"}},
+		RouteTax:             -1,
+		RoutingMode:          typedef.RoutingFastest,
+		Border:               typedef.BorderOpen,
+		Tax:                  typedef.TerritoryTax{Tax: 0.3, Ally: 0.05},
+		HQ:                   true,
+		Storage: typedef.TerritoryStorage{
+			Capacity: typedef.BasicResources{Emeralds: 120000, Ores: 24000},
+			At:       typedef.BasicResources{Emeralds: 45000.5, Ores: 1200},
+		},
+		TransitResource: []typedef.InTransitResources{{
+			BasicResources: typedef.BasicResources{Wood: 300},
+			Route2:         []string{"Maltic", "Ragni"},
+			RouteIndex:     1,
+		}},
+		Warning: typedef.WarningOverflowEmerald,
+	}
+	maltic := &typedef.Territory{ID: "maltic", Name: "Maltic", Guild: guild, ConnectedTerritories: []string{"Ragni"}}
+
+	allied := &typedef.Guild{Name: "Empire of Sindria", Tag: "ESI"}
+	tribute := &typedef.ActiveTribute{
+		ID:              "tribute-1",
+		FromGuildName:   guild.Name,
+		ToGuildName:     allied.Name,
+		AmountPerHour:   typedef.BasicResources{Emeralds: 6000},
+		AmountPerMinute: typedef.BasicResources{Emeralds: 100},
+		IntervalMinutes: 10,
+		LastTransfer:    7200,
+		IsActive:        true,
+		CreatedAt:       600,
+		Schedule: typedef.TributeSchedule{
+			Kind:      typedef.TributeConditional,
+			Condition: typedef.TributeReceiverBelow,
+			Resource:  "emeralds",
+			Threshold: 20000,
+		},
+		TransferCount: 12,
+		TotalSent:     typedef.BasicResources{Emeralds: 72000},
+	}
+
+	state := &StateData{
+		Type:           "state_save",
+		Version:        CurrentStateVersion,
+		Timestamp:      time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Tick:           9007199254740993,
+		Territories:    []*typedef.Territory{hq, maltic},
+		Guilds:         []*typedef.Guild{&guild, allied},
+		ActiveTributes: []*typedef.ActiveTribute{tribute},
+		RuntimeOptions: typedef.RuntimeOptions{TreasuryEnabled: true, MapOpacityPercent: 60},
+		Transits: []*Transit{{
+			ID:             "transit-1",
+			BasicResources: typedef.BasicResources{Emeralds: 100},
+			OriginID:       "maltic",
+			DestinationID:  "ragni",
+			Route:          []string{"maltic", "ragni"},
+			NextTax:        0.05,
+			CreatedAt:      7000,
+			TributeID:      "tribute-1",
+		}},
+		GuildColors:       map[string]map[string]string{"Avicia": {"tag": "AVO", "color": "#22ccff"}},
+		ScheduledCaptures: []typedef.ScheduledCapture{{ID: "capture-1", Tick: 10800, Territory: "Maltic", GuildTag: "ESI", SetHQ: true}},
+		MapPack:           &typedef.MapPackRef{ID: "default", Name: "Wynncraft (built-in)", Version: "builtin", Fingerprint: "0123456789abcdef"},
+		Annotations: []typedef.AnnotationLayer{{
+			Name:    "Attack plan",
+			Color:   typedef.RGBAColor{R: 255, A: 255},
+			Visible: true,
+			Annotations: []typedef.Annotation{
+				{ID: "a1", Kind: typedef.AnnotationPolyline, Points: []typedef.AnnotationPoint{{X: 1.5, Y: -2}, {X: 30, Y: 40}}, Size: 3, Visible: true},
+				{ID: "a2", Kind: typedef.AnnotationLabel, Points: []typedef.AnnotationPoint{{X: 10, Y: 10}}, Text: "Rally", Color: &typedef.RGBAColor{G: 200, A: 255}},
+			},
+		}},
+	}
+	state.Costs.UpgradesCost.Damage = typedef.UpgradeCosts{Value: []int{0, 100, 300}, ResourceType: "ores"}
+	state.Costs.UpgradeMultiplier.Health = []float64{1, 1.5, 2.25}
+	state.Costs.Bonuses.TowerAura = typedef.BonusCosts{MaxLevel: 3, Cost: []int{0, 800, 1600}, ResourceType: "crops", Value: []float64{0, 24, 18}}
+
+	if runtime {
+		hq.Costs = typedef.BasicResources{Ores: 900}
+		hq.Net = typedef.BasicResources{Emeralds: 9900}
+		hq.TowerStats = typedef.TowerStats{Attack: 1.5, Health: 900000}
+		hq.Level = typedef.DefenceLevelVeryHigh
+		hq.LevelInt = 40
+		hq.TradingRoutes = [][]*typedef.Territory{{maltic, hq}}
+		hq.NextTerritory = maltic
+		hq.ResourceGeneration.EmeraldAccumulator = 12.5
+		hq.ResourceGeneration.LastResourceTick = 7000
+		hq.TransitResource[0].Origin = maltic
+		hq.TransitResource[0].Destination = hq
+		hq.WarningExpiration = map[typedef.Warning]uint64{typedef.WarningOverflowEmerald: 7260}
+		maltic.Destination = hq
+		state.Guilds[0].Allies = []*typedef.Guild{allied}
+		tribute.From = state.Guilds[0]
+		tribute.To = allied
+	}
+	return state
+}
+
+func TestStateDataRoundTrip(t *testing.T) {
+	config := parser.DefaultConfig()
+	config.IncludeFieldTypeInfo = false
+	config.CompressionLevel = parser.CompressionNone
+	uncompressed := parser.NewWithConfig(config)
+
+	cases := []struct {
+		name   string
+		encode func(*StateData) ([]byte, error)
+	}{
+		{"json", func(state *StateData) ([]byte, error) { return encodeStateData(state, false) }},
+		{"etf", func(state *StateData) ([]byte, error) { return encodeStateData(state, true) }},
+		{"etf uncompressed", func(state *StateData) ([]byte, error) { return uncompressed.Encode(state) }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.encode(populatedStateData(true))
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			var got StateData
+			if err := decodeStateData(data, &got); err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+
+			// Runtime-only fields are left out of both formats
+			want := populatedStateData(false)
+			if !reflect.DeepEqual(&got, want) {
+				t.Errorf("round trip changed the state:\ngot  %+v\nwant %+v", got, *want)
+			}
+		})
+	}
+}
+
+func TestStateDataETFRejectsCorruption(t *testing.T) {
+	data, err := encodeStateData(populatedStateData(false), true)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+
+	var got StateData
+	err = decodeStateData(corrupted, &got)
+	if err == nil {
+		t.Fatal("decode of corrupted data succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "corrupted state file") {
+		t.Errorf("error %q does not report a corrupted state file", err)
+	}
+}
@@ -13,12 +13,9 @@ import (
 	"RueaES/storage"
 	"RueaES/typedef"
 
-	"github.com/gookit/goutil/arrutil"
 	"github.com/pierrec/lz4"
 )
 
-var supportedStateVersion = []string{"1.0", "1.1", "1.2", "1.3", "1.4", "1.5", "1.6", "1.7", "1.8", "1.9"}
-
 // Callback functions for user data that persists through resets
 var (
 	getLoadoutsCallback      func() []typedef.Loadout
@@ -59,6 +56,8 @@ func normalizeRuntimeOptions(opts *typedef.RuntimeOptions) {
 	// Normalize plugin keybind overrides if present.
 	typedef.NormalizePluginKeybinds(&opts.PluginKeybinds)
 
+	normalizeWarParty(&opts.WarParty)
+
 	// Normalize computation source.
 	switch opts.ComputationSource {
 	case typedef.ComputationCPU, typedef.ComputationGPU:
@@ -67,6 +66,11 @@ func normalizeRuntimeOptions(opts *typedef.RuntimeOptions) {
 		opts.ComputationSource = typedef.ComputationCPU
 	}
 
+	// Normalize chokepoint mode. Defaults for saves without it are set by the state migrations.
+	if opts.ChokepointMode != "Cardinal" && opts.ChokepointMode != "Ordinal" {
+		opts.ChokepointMode = "Cardinal"
+	}
+
 	// Normalize pathfinding algorithm.
 	switch opts.PathfindingAlgorithm {
 	case typedef.PathfindingDijkstra,
@@ -247,6 +251,21 @@ type StateData struct {
 
 	// Plugins (version 1.9+)
 	Plugins []typedef.PluginState `json:"plugins,omitempty"`
+
+	// Manual route selections, identified by territory names so they survive route ID changes
+	ManualRoutesToHQ   []ManualRouteData `json:"manualRoutesToHQ,omitempty"`
+	ManualRoutesFromHQ []ManualRouteData `json:"manualRoutesFromHQ,omitempty"`
+
+	// Season rating state
+	ScheduledCaptures []typedef.ScheduledCapture   `json:"scheduledCaptures,omitempty"`
+	SeasonScores      []typedef.SeasonScore        `json:"seasonScores,omitempty"`
+	SeasonFormula     *typedef.SeasonRatingFormula `json:"seasonFormula,omitempty"`
+
+	// Map pack the territories belong to (version 2.1+)
+	MapPack *typedef.MapPackRef `json:"mapPack,omitempty"`
+
+	// Map annotation layers (version 2.2+)
+	Annotations []typedef.AnnotationLayer `json:"annotations,omitempty"`
 }
 
 // compressedTransitResource stores transit packets using either a pooled reference or inline resources.
@@ -275,6 +294,7 @@ type compressedTransit struct {
 	NextTax        float64                 `json:"nextTax,omitempty"`
 	CreatedAt      uint64                  `json:"createdAt,omitempty"`
 	Moved          bool                    `json:"moved,omitempty"`
+	TributeID      string                  `json:"tributeId,omitempty"`
 }
 
 // resourceKey produces a stable string key for a BasicResources value (used for interning).
@@ -282,8 +302,15 @@ func resourceKey(br typedef.BasicResources) string {
 	return fmt.Sprintf("%.4f|%.4f|%.4f|%.4f|%.4f", br.Emeralds, br.Ores, br.Wood, br.Fish, br.Crops)
 }
 
-// SaveStateToFile saves the current state to a file with LZ4 compression
+// SaveStateToFile saves the current state to a file with LZ4 compression.
+// Paths ending in .etf are written in the binary ETF format instead of JSON.
 func SaveStateToFile(filepath string) error {
+	_, err := saveStateToFile(filepath)
+	return err
+}
+
+// saveStateToFile implements SaveStateToFile and returns the tick that was saved
+func saveStateToFile(filepath string) (uint64, error) {
 	// Capture current state under read lock - minimize lock time for better performance
 	var stateData StateData
 
@@ -291,13 +318,16 @@ func SaveStateToFile(filepath string) error {
 
 	// Quick copy of basic state data (no deep copying yet)
 	stateData.Type = "state_save"
-	stateData.Version = "1.9"
+	stateData.Version = CurrentStateVersion
 	stateData.Timestamp = time.Now()
 	stateData.Tick = st.tick
 	stateData.RuntimeOptions = st.runtimeOptions
 	stateData.Costs = st.costs
 	stateData.TotalTerritories = len(st.territories)
 	stateData.TotalGuilds = len(st.guilds)
+	mapPack := ActiveMapPack().Ref()
+	stateData.MapPack = &mapPack
+	stateData.Annotations = GetAnnotationLayers()
 
 	// Create slices with the right capacity but don't copy data yet
 	territoryRefs := make([]*typedef.Territory, len(st.territories))
@@ -309,6 +339,13 @@ func SaveStateToFile(filepath string) error {
 	tributeRefs := make([]*typedef.ActiveTribute, len(st.activeTributes))
 	copy(tributeRefs, st.activeTributes)
 
+	stateData.ManualRoutesToHQ, stateData.ManualRoutesFromHQ = exportManualRoutesUnsafe()
+
+	stateData.ScheduledCaptures = copyScheduledCapturesUnsafe()
+	stateData.SeasonScores = sortedSeasonScores(st.seasonScores)
+	seasonFormula := st.seasonFormula
+	stateData.SeasonFormula = &seasonFormula
+
 	st.mu.RUnlock()
 
 	// Now do the expensive deep copying WITHOUT holding any locks
@@ -341,6 +378,7 @@ func SaveStateToFile(filepath string) error {
 				TreasuryOverride:     territory.TreasuryOverride,
 				GenerationBonus:      territory.GenerationBonus,
 				CapturedAt:           territory.CapturedAt,
+				HeldBefore:           territory.HeldBefore,
 				ConnectedTerritories: territory.ConnectedTerritories,
 				TradingRoutes:        territory.TradingRoutes,
 				TradingRoutesJSON:    territory.TradingRoutesJSON,
@@ -460,25 +498,19 @@ func SaveStateToFile(filepath string) error {
 	// Compress transit payloads by interning duplicate resource packets (version 1.8+)
 	compressTransitPayloads(&stateData)
 
-	// Marshal to JSON
-	jsonData, err := json.Marshal(stateData)
+	// Serialize as JSON or ETF depending on the extension
+	compressedData, err := encodeStateData(&stateData, isETFStatePath(filepath))
 	if err != nil {
-		return fmt.Errorf("failed to marshal state data: %v", err)
+		return 0, err
 	}
 
-	// Compress with LZ4
-	compressedData, err := compressLZ4(jsonData)
+	// Write to a temporary file and rename it, so a crash mid-write keeps the previous save
+	err = storage.WriteFileAtomic(filepath, compressedData, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to compress data: %v", err)
+		return 0, fmt.Errorf("failed to write file: %v", err)
 	}
 
-	// Write to file
-	err = os.WriteFile(filepath, compressedData, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write file: %v", err)
-	}
-
-	return nil
+	return stateData.Tick, nil
 }
 
 // expandCompressedTransitPayloads restores transit payloads from the compact representation.
@@ -551,6 +583,7 @@ func expandCompressedTransitPayloads(state *StateData) {
 				NextTax:        c.NextTax,
 				CreatedAt:      c.CreatedAt,
 				Moved:          c.Moved,
+				TributeID:      c.TributeID,
 			}
 			rebuilt = append(rebuilt, transit)
 		}
@@ -672,6 +705,7 @@ func compressTransitPayloads(state *StateData) {
 				NextTax:       tr.NextTax,
 				CreatedAt:     tr.CreatedAt,
 				Moved:         tr.Moved,
+				TributeID:     tr.TributeID,
 			}
 
 			if idx, ok := keyToIndex[key]; ok {
@@ -702,36 +736,13 @@ func ValidateStateFile(filepath string) error {
 		return fmt.Errorf("failed to read file: %v", err)
 	}
 
-	// Check if file is empty
-	if len(compressedData) == 0 {
-		return fmt.Errorf("file is empty")
-	}
-
-	// Decompress LZ4
-	jsonData, err := decompressLZ4(compressedData)
-	if err != nil {
-		return fmt.Errorf("failed to decompress data (corrupted or not a valid LZ4 file): %v", err)
-	}
-
-	// Check if decompressed data is empty
-	if len(jsonData) == 0 {
-		return fmt.Errorf("decompressed data is empty")
-	}
-
-	// Try to unmarshal JSON
-	var stateData StateData
-	err = json.Unmarshal(jsonData, &stateData)
+	// Decode JSON or ETF and migrate it, which also verifies ETF checksums and the version
+	stateData, _, err := loadStateData(compressedData)
 	if err != nil {
-		return fmt.Errorf("failed to parse JSON data (corrupted state file): %v", err)
+		return err
 	}
-
-	// Validate state data
-	if stateData.Type != "state_save" {
-		return fmt.Errorf("invalid file type: expected 'state_save', got '%s'", stateData.Type)
-	}
-
-	if !arrutil.Contains(supportedStateVersion, stateData.Version) {
-		return fmt.Errorf("unsupported state version '%s'. Supported versions: %v", stateData.Version, supportedStateVersion)
+	if err := CheckMapPackCompatible(stateData.MapPack); err != nil {
+		return err
 	}
 
 	// File is valid
@@ -740,10 +751,10 @@ func ValidateStateFile(filepath string) error {
 
 // LoadStateFromFileSelective loads state from a file with selective import options
 func LoadStateFromFileSelective(filepath string, importOptions map[string]bool) error {
-	return loadStateFromFileInternal(filepath, importOptions)
+	return loadStateFromFileInternal(filepath, importOptions, false)
 }
 
-// LoadStateFromFile loads state from a file with LZ4 decompression (imports everything)
+// LoadStateFromFile loads state from an LZ4 JSON or ETF file (imports everything)
 func LoadStateFromFile(filepath string) error {
 	// Default: import everything
 	importOptions := map[string]bool{
@@ -756,12 +767,16 @@ func LoadStateFromFile(filepath string) error {
 		"tributes":         true,
 		"loadouts":         true,
 		"plugins":          true,
+		"manual_routes":    true,
+		"season":           true,
+		"annotations":      true,
 	}
-	return loadStateFromFileInternal(filepath, importOptions)
+	return loadStateFromFileInternal(filepath, importOptions, true)
 }
 
-// loadStateFromFileInternal is the internal implementation that handles selective loading
-func loadStateFromFileInternal(filepath string, importOptions map[string]bool) error {
+// loadStateFromFileInternal is the internal implementation that handles selective loading. A full
+// load replaces the annotation layers, a selective one merges them by name.
+func loadStateFromFileInternal(filepath string, importOptions map[string]bool, full bool) error {
 	if importOptions == nil {
 		importOptions = make(map[string]bool)
 	}
@@ -787,42 +802,23 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		return fmt.Errorf("failed to read file: %v", err)
 	}
 
-	// Decompress LZ4
-	jsonData, err := decompressLZ4(compressedData)
+	// Decode JSON or ETF, detected from the file contents, and migrate older versions
+	loaded, _, err := loadStateData(compressedData)
 	if err != nil {
 		// Restore runtime state if we halted it
 		if !wasHalted {
 			st.start()
 		}
-		return fmt.Errorf("failed to decompress data: %v", err)
+		return fmt.Errorf("failed to load state data: %v", err)
 	}
+	stateData := *loaded
 
-	// Unmarshal JSON
-	var stateData StateData
-	err = json.Unmarshal(jsonData, &stateData)
-	if err != nil {
-		// Restore runtime state if we halted it
-		if !wasHalted {
-			st.start()
-		}
-		return fmt.Errorf("failed to unmarshal state data: %v", err)
-	}
-
-	// Validate state data
-	if stateData.Type != "state_save" {
-		// Restore runtime state if we halted it
-		if !wasHalted {
-			st.start()
-		}
-		return fmt.Errorf("invalid file type: expected 'state_save', got '%s'", stateData.Type)
-	}
-
-	if !arrutil.Contains(supportedStateVersion, stateData.Version) {
-		// Restore runtime state if we halted it
+	// Territories, routes and transits only make sense on the map they were saved on
+	if err := CheckMapPackCompatible(stateData.MapPack); err != nil {
 		if !wasHalted {
 			st.start()
 		}
-		return fmt.Errorf("unsupported version: %s", stateData.Version)
+		return err
 	}
 
 	// Sanitize loaded names to strip banned guild/territory strings while preserving other content.
@@ -836,20 +832,6 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		st.mu.Lock()
 		st.tick = stateData.Tick
 		st.runtimeOptions = stateData.RuntimeOptions
-		if stateData.Version != "1.5" && st.runtimeOptions.MapOpacityPercent == 0 {
-			st.runtimeOptions.MapOpacityPercent = 100 // Backward compatibility for older saves
-		}
-		// Default new chokepoint options for older saves
-		if st.runtimeOptions.ChokepointEmeraldWeight == 0 {
-			st.runtimeOptions.ChokepointEmeraldWeight = 1
-		}
-		if st.runtimeOptions.ChokepointMode == "" {
-			st.runtimeOptions.ChokepointMode = "Cardinal"
-		}
-		if st.runtimeOptions.ChokepointMode == "Cardinal" && !st.runtimeOptions.ChokepointIncludeDownstream {
-			// For legacy saves that lacked the field, default to true unless explicitly stored
-			st.runtimeOptions.ChokepointIncludeDownstream = true
-		}
 		normalizeRuntimeOptions(&st.runtimeOptions)
 		st.mu.Unlock()
 	}
@@ -967,11 +949,26 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		st.activeTributes = stateData.ActiveTributes
 		// Rebuild guild pointers in tributes
 		rebuildTributeGuildPointers()
+		disableInvalidTributeSchedules()
 	} else {
 		// Still need to rebuild pointers for existing tributes
 		rebuildTributeGuildPointers()
 	}
 
+	// Restore manual route selections before routes are recalculated so they are applied
+	var missingManualRoutes []string
+	if importOptions["manual_routes"] {
+		missingManualRoutes = restoreManualRoutesUnsafe(stateData.ManualRoutesToHQ, stateData.ManualRoutesFromHQ)
+	} else if importOptions["territories"] {
+		// Selections made for the previous territories don't apply to the imported ones
+		st.manualRouteToHQ = make(map[string]int)
+		st.manualRouteFromHQ = make(map[string]int)
+	}
+
+	if importOptions["season"] {
+		restoreSeasonUnsafe(stateData.ScheduledCaptures, stateData.SeasonScores, stateData.SeasonFormula)
+	}
+
 	// Only recalculate routes if new fields are missing
 	if !hasTransitFields {
 		st.updateRoute()
@@ -980,7 +977,9 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		RestorePointersFromIDs(st.territories)
 	}
 
-	ReloadDefaultCosts()
+	if err := reloadCostsUnsafe(); err != nil {
+		fmt.Printf("[STATE] Failed to reload costs: %v\n", err)
+	}
 
 	// st.mu.Unlock()
 
@@ -997,6 +996,10 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		NotifyTerritoryColorsUpdate()
 	}()
 
+	if len(missingManualRoutes) > 0 && manualRoutesMissingCallback != nil {
+		go manualRoutesMissingCallback(missingManualRoutes)
+	}
+
 	// Load persistent user data (loadouts) - version 1.3+ (if requested)
 	if importOptions["loadouts"] && mergeLoadoutsCallback != nil && stateData.Loadouts != nil {
 		mergeLoadoutsCallback(stateData.Loadouts)
@@ -1007,6 +1010,13 @@ func loadStateFromFileInternal(filepath string, importOptions map[string]bool) e
 		mergeGuildColorsCallback(stateData.GuildColors)
 	}
 
+	// Load annotation layers - version 2.2+
+	if importOptions["annotations"] && full {
+		replaceAnnotationLayers(stateData.Annotations)
+	} else if importOptions["annotations"] {
+		mergeAnnotationLayers(stateData.Annotations)
+	}
+
 	// Load plugins - version 1.9+
 	if importOptions["plugins"] && setPluginsCallback != nil {
 		setPluginsCallback(stateData.Plugins)
@@ -0,0 +1,49 @@
+package eruntime
+
+import (
+	"testing"
+
+	"RueaES/typedef"
+)
+
+func TestTributeTransitSurvivesSaveLoad(t *testing.T) {
+	for _, etf := range []bool{false, true} {
+		name := "json"
+		if etf {
+			name = "etf"
+		}
+		t.Run(name, func(t *testing.T) {
+			shared := typedef.BasicResources{Emeralds: 100, Ores: 20}
+			state := StateData{
+				Type:    "state_save",
+				Version: CurrentStateVersion,
+				Transits: []*Transit{
+					{ID: "tribute", BasicResources: shared, OriginID: "a", DestinationID: "c", Route: []string{"a", "b", "c"}, RouteIndex: 1, TributeID: "tribute-1"},
+					{ID: "pooled", BasicResources: shared, OriginID: "a", DestinationID: "c", Route: []string{"a", "b", "c"}, TributeID: "tribute-2"},
+					{ID: "plain", BasicResources: typedef.BasicResources{Wood: 5}, OriginID: "b", DestinationID: "c", Route: []string{"b", "c"}},
+				},
+			}
+
+			compressTransitPayloads(&state)
+			data, err := encodeStateData(&state, etf)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			var loaded StateData
+			if err := decodeStateData(data, &loaded); err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			expandCompressedTransitPayloads(&loaded)
+
+			want := map[string]string{"tribute": "tribute-1", "pooled": "tribute-2", "plain": ""}
+			if len(loaded.Transits) != len(want) {
+				t.Fatalf("loaded %d transits, want %d", len(loaded.Transits), len(want))
+			}
+			for _, transit := range loaded.Transits {
+				if transit.TributeID != want[transit.ID] {
+					t.Errorf("transit %s has tribute %q, want %q", transit.ID, transit.TributeID, want[transit.ID])
+				}
+			}
+		})
+	}
+}
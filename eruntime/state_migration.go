@@ -0,0 +1,321 @@
+package eruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"RueaES/parser"
+	"RueaES/storage"
+)
+
+// CurrentStateVersion is the state schema version written by SaveStateToFile
+const CurrentStateVersion = "2.2"
+
+// stateMigration upgrades a raw state document from one schema version to the next. Documents are
+// the decoded JSON objects of the save, so a migration can tell a missing field from a zero value.
+type stateMigration struct {
+	from    string
+	to      string
+	migrate func(doc map[string]interface{}) error
+}
+
+// stateMigrations holds the registered migrations keyed by the version they upgrade from
+var stateMigrations = map[string]stateMigration{}
+
+// registerStateMigration adds the migration from one version to the next
+func registerStateMigration(from, to string, migrate func(doc map[string]interface{}) error) {
+	if _, exists := stateMigrations[from]; exists {
+		panic("duplicate state migration from version " + from)
+	}
+	stateMigrations[from] = stateMigration{from: from, to: to, migrate: migrate}
+}
+
+// noStateChanges is the migration for versions that only added optional fields
+func noStateChanges(map[string]interface{}) error {
+	return nil
+}
+
+func init() {
+	registerStateMigration("1.0", "1.1", noStateChanges)
+	registerStateMigration("1.1", "1.2", noStateChanges)
+	// Loadouts and guild colors are optional
+	registerStateMigration("1.2", "1.3", noStateChanges)
+	// TransitManager transits are optional, territories keep their own transit resources
+	registerStateMigration("1.3", "1.4", noStateChanges)
+	registerStateMigration("1.4", "1.5", migrateState14To15)
+	registerStateMigration("1.5", "1.6", noStateChanges)
+	registerStateMigration("1.6", "1.7", noStateChanges)
+	// Interned transit payloads are optional and expanded on load
+	registerStateMigration("1.7", "1.8", noStateChanges)
+	// Plugins are optional
+	registerStateMigration("1.8", "1.9", noStateChanges)
+	registerStateMigration("1.9", "2.0", migrateState19To20)
+	registerStateMigration("2.0", "2.1", migrateState20To21)
+	// Annotation layers are optional
+	registerStateMigration("2.1", "2.2", noStateChanges)
+}
+
+// migrateState14To15 adds the map opacity introduced in 1.5
+func migrateState14To15(doc map[string]interface{}) error {
+	options, err := runtimeOptionsDocument(doc)
+	if err != nil {
+		return err
+	}
+	setMissing(options, "MapOpacityPercent", 100)
+	return nil
+}
+
+// migrateState19To20 bakes in the runtime option defaults that loaders before 2.0 applied to zero
+// values, so 2.0 saves can store zero emerald weight and excluded downstream production as real
+// choices. Map opacity is only set when missing, so a saved zero opacity stays zero.
+func migrateState19To20(doc map[string]interface{}) error {
+	options, err := runtimeOptionsDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	setMissing(options, "MapOpacityPercent", 100)
+	if isZeroNumber(options["ChokepointEmeraldWeight"]) {
+		options["ChokepointEmeraldWeight"] = 1
+	}
+	if mode, _ := options["ChokepointMode"].(string); mode == "" || mode == "Cardinal" {
+		options["ChokepointMode"] = "Cardinal"
+		options["ChokepointIncludeDownstream"] = true
+	}
+	return nil
+}
+
+// migrateState20To21 records the built-in map as the map pack of saves made before map packs
+func migrateState20To21(doc map[string]interface{}) error {
+	ref := BuiltinMapPack().Ref()
+	setMissing(doc, "mapPack", map[string]interface{}{
+		"id":          ref.ID,
+		"name":        ref.Name,
+		"version":     ref.Version,
+		"fingerprint": ref.Fingerprint,
+	})
+	return nil
+}
+
+// runtimeOptionsDocument returns the runtime options object of a document, creating it if missing
+func runtimeOptionsDocument(doc map[string]interface{}) (map[string]interface{}, error) {
+	switch options := doc["runtimeOptions"].(type) {
+	case map[string]interface{}:
+		return options, nil
+	case nil:
+		created := map[string]interface{}{}
+		doc["runtimeOptions"] = created
+		return created, nil
+	default:
+		return nil, fmt.Errorf("runtimeOptions is a %T, expected an object", options)
+	}
+}
+
+// setMissing sets key to value unless the document already has it
+func setMissing(doc map[string]interface{}, key string, value interface{}) {
+	if _, ok := doc[key]; !ok {
+		doc[key] = value
+	}
+}
+
+// isZeroNumber reports whether a document value is missing, null or the number zero
+func isZeroNumber(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case float64:
+		return v == 0
+	case int:
+		return v == 0
+	}
+	return false
+}
+
+// parseStateVersion splits a "major.minor" version into its numbers
+func parseStateVersion(version string) (major, minor int, ok bool) {
+	majorText, minorText, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(minorText)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// CheckStateVersion reports whether a state file of the given version can be loaded,
+// either directly or by migrating it to CurrentStateVersion
+func CheckStateVersion(version string) error {
+	if version == CurrentStateVersion {
+		return nil
+	}
+	if _, ok := stateMigrations[version]; ok {
+		return nil
+	}
+
+	major, minor, ok := parseStateVersion(version)
+	currentMajor, currentMinor, _ := parseStateVersion(CurrentStateVersion)
+	if ok && (major > currentMajor || major == currentMajor && minor > currentMinor) {
+		return fmt.Errorf("state version %s was saved by a newer RueaES, this build reads versions up to %s; update RueaES to load it", version, CurrentStateVersion)
+	}
+	return fmt.Errorf("unsupported state version '%s', supported versions are 1.0 to %s", version, CurrentStateVersion)
+}
+
+// migrateStateDocument upgrades a raw state document to CurrentStateVersion one version at a time
+func migrateStateDocument(doc map[string]interface{}) error {
+	version, _ := doc["version"].(string)
+	if err := CheckStateVersion(version); err != nil {
+		return err
+	}
+
+	for version != CurrentStateVersion {
+		migration, ok := stateMigrations[version]
+		if !ok {
+			return fmt.Errorf("no state migration registered from version %s", version)
+		}
+		if err := migration.migrate(doc); err != nil {
+			return fmt.Errorf("failed to migrate state from %s to %s: %v", migration.from, migration.to, err)
+		}
+		version = migration.to
+		doc["version"] = version
+	}
+	return nil
+}
+
+// loadStateData decodes state file contents in either format, validates them and migrates older
+// versions to CurrentStateVersion. It returns the version the file was saved with. ETF files decode
+// straight into StateData, which hides the fields a file didn't store from the migrations, so only
+// JSON files are migrated.
+func loadStateData(data []byte) (*StateData, string, error) {
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("file is empty")
+	}
+
+	var stateData StateData
+	if parser.IsETF(data) {
+		if err := decodeStateData(data, &stateData); err != nil {
+			return nil, "", err
+		}
+		if stateData.Version == CurrentStateVersion {
+			return checkedStateData(&stateData, stateData.Version)
+		}
+		if err := CheckStateVersion(stateData.Version); err != nil {
+			return nil, stateData.Version, err
+		}
+		return nil, stateData.Version, fmt.Errorf("ETF state files of version %s can't be migrated, this build reads ETF version %s only; open the file in the RueaES that saved it and save it as .lz4 to migrate it", stateData.Version, CurrentStateVersion)
+	}
+
+	jsonData, err := decompressLZ4(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decompress data (corrupted or not a valid LZ4 file): %v", err)
+	}
+	if len(jsonData) == 0 {
+		return nil, "", fmt.Errorf("decompressed data is empty")
+	}
+
+	var header struct {
+		Type    string `json:"type"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(jsonData, &header); err != nil {
+		return nil, "", fmt.Errorf("failed to parse JSON data (corrupted state file): %v", err)
+	}
+	if header.Type != "state_save" {
+		return nil, header.Version, fmt.Errorf("invalid file type: expected 'state_save', got '%s'", header.Type)
+	}
+
+	if header.Version != CurrentStateVersion {
+		migrated, err := migrateStateJSON(jsonData)
+		if err != nil {
+			return nil, header.Version, err
+		}
+		jsonData = migrated
+	}
+
+	stateData = StateData{}
+	if err := json.Unmarshal(jsonData, &stateData); err != nil {
+		return nil, header.Version, fmt.Errorf("failed to parse JSON data (corrupted state file): %v", err)
+	}
+	return checkedStateData(&stateData, header.Version)
+}
+
+// migrateStateJSON migrates an encoded state document and re-encodes it
+func migrateStateJSON(jsonData []byte) ([]byte, error) {
+	// Numbers are kept as written, float64 would round large ticks
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON data (corrupted state file): %v", err)
+	}
+
+	if err := migrateStateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migrated state: %v", err)
+	}
+	return migrated, nil
+}
+
+// checkedStateData validates the type of decoded state data
+func checkedStateData(stateData *StateData, savedVersion string) (*StateData, string, error) {
+	if stateData.Type != "state_save" {
+		return nil, savedVersion, fmt.Errorf("invalid file type: expected 'state_save', got '%s'", stateData.Type)
+	}
+	return stateData, savedVersion, nil
+}
+
+// UpgradeStateFile migrates a state file to CurrentStateVersion in place, keeping the original
+// next to it as a backup. Only JSON files can be migrated, see loadStateData. It returns the
+// version the file had and the backup path, which is empty when the file was already current.
+func UpgradeStateFile(path string) (from string, backupPath string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %v", err)
+	}
+
+	stateData, from, err := loadStateData(data)
+	if err != nil {
+		return from, "", err
+	}
+	if from == CurrentStateVersion {
+		return from, "", nil
+	}
+
+	upgraded, err := encodeStateData(stateData, false)
+	if err != nil {
+		return from, "", err
+	}
+
+	// Never overwrite an earlier backup
+	backupPath = fmt.Sprintf("%s.v%s.bak", path, from)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			break
+		}
+		backupPath = fmt.Sprintf("%s.v%s.%d.bak", path, from, i)
+	}
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return from, "", fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	if err := storage.WriteFileAtomic(path, upgraded, 0644); err != nil {
+		return from, backupPath, fmt.Errorf("failed to write upgraded file: %v", err)
+	}
+	return from, backupPath, nil
+}
@@ -0,0 +1,221 @@
+package eruntime
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadMigrationFixture decodes a saved state document from testdata/state_migrations the way
+// migrateStateJSON does
+func loadMigrationFixture(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "state_migrations", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatalf("failed to decode fixture %s: %v", name, err)
+	}
+	return doc
+}
+
+// migrationOptions returns the runtime options object of a migrated document
+func migrationOptions(t *testing.T, doc map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	options, ok := doc["runtimeOptions"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("runtimeOptions is a %T, expected an object", doc["runtimeOptions"])
+	}
+	return options
+}
+
+func expectOption(t *testing.T, options map[string]interface{}, key string, want interface{}) {
+	t.Helper()
+	got, ok := options[key]
+	if !ok {
+		t.Fatalf("%s is missing", key)
+	}
+	if n, isNumber := got.(json.Number); isNumber {
+		f, err := n.Float64()
+		if err != nil {
+			t.Fatalf("%s is not a number: %v", key, err)
+		}
+		got = f
+	}
+	if wantInt, isInt := want.(int); isInt {
+		want = float64(wantInt)
+	}
+	if gotInt, isInt := got.(int); isInt {
+		got = float64(gotInt)
+	}
+	if got != want {
+		t.Errorf("%s = %v, want %v", key, got, want)
+	}
+}
+
+// unchanged checks that a migration only adding optional fields left the document as it was
+func unchanged(t *testing.T, before, after map[string]interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("document changed:\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+// stateMigrationCases has at least one fixture for every registered migration step
+var stateMigrationCases = []struct {
+	name    string
+	fixture string
+	from    string
+	check   func(t *testing.T, before, after map[string]interface{})
+}{
+	{"1.0 to 1.1", "1.0.json", "1.0", unchanged},
+	{"1.1 to 1.2", "1.1.json", "1.1", unchanged},
+	{"1.2 to 1.3", "1.2.json", "1.2", unchanged},
+	{"1.3 to 1.4", "1.3.json", "1.3", unchanged},
+	{"1.4 to 1.5 adds map opacity", "1.4.json", "1.4", func(t *testing.T, _, after map[string]interface{}) {
+		expectOption(t, migrationOptions(t, after), "MapOpacityPercent", 100)
+	}},
+	{"1.5 to 1.6", "1.5.json", "1.5", unchanged},
+	{"1.6 to 1.7", "1.6.json", "1.6", unchanged},
+	{"1.7 to 1.8", "1.7.json", "1.7", unchanged},
+	{"1.8 to 1.9", "1.8.json", "1.8", unchanged},
+	{"1.9 to 2.0 keeps zero map opacity", "1.9.json", "1.9", func(t *testing.T, _, after map[string]interface{}) {
+		options := migrationOptions(t, after)
+		expectOption(t, options, "MapOpacityPercent", 0)
+		expectOption(t, options, "ChokepointEmeraldWeight", 1)
+		expectOption(t, options, "ChokepointMode", "Cardinal")
+		expectOption(t, options, "ChokepointIncludeDownstream", true)
+	}},
+	{"1.9 to 2.0 defaults missing map opacity", "1.9-no-opacity.json", "1.9", func(t *testing.T, _, after map[string]interface{}) {
+		options := migrationOptions(t, after)
+		expectOption(t, options, "MapOpacityPercent", 100)
+		expectOption(t, options, "ChokepointEmeraldWeight", 2.5)
+		expectOption(t, options, "ChokepointMode", "Radial")
+		expectOption(t, options, "ChokepointIncludeDownstream", false)
+	}},
+	{"2.0 to 2.1 records the built-in map pack", "2.0.json", "2.0", func(t *testing.T, _, after map[string]interface{}) {
+		ref, ok := after["mapPack"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("mapPack is a %T, expected an object", after["mapPack"])
+		}
+		if ref["id"] != DefaultMapPackID {
+			t.Errorf("mapPack id = %v, want %s", ref["id"], DefaultMapPackID)
+		}
+	}},
+	{"2.1 to 2.2", "2.1.json", "2.1", unchanged},
+}
+
+func TestStateMigrationSteps(t *testing.T) {
+	for _, tc := range stateMigrationCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := loadMigrationFixture(t, tc.fixture)
+			if version, _ := doc["version"].(string); version != tc.from {
+				t.Fatalf("fixture %s has version %s, want %s", tc.fixture, version, tc.from)
+			}
+			migration, ok := stateMigrations[tc.from]
+			if !ok {
+				t.Fatalf("no migration registered from %s", tc.from)
+			}
+			before := loadMigrationFixture(t, tc.fixture)
+			if err := migration.migrate(doc); err != nil {
+				t.Fatalf("migration failed: %v", err)
+			}
+			tc.check(t, before, doc)
+		})
+	}
+}
+
+func TestStateMigrationsHaveFixtures(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tc := range stateMigrationCases {
+		covered[tc.from] = true
+	}
+	for from, migration := range stateMigrations {
+		if !covered[from] {
+			t.Errorf("migration %s to %s has no fixture case", from, migration.to)
+		}
+	}
+}
+
+func TestStateFixturesMigrateToCurrent(t *testing.T) {
+	for _, tc := range stateMigrationCases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "state_migrations", tc.fixture))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			migrated, err := migrateStateJSON(data)
+			if err != nil {
+				t.Fatalf("migration failed: %v", err)
+			}
+
+			var stateData StateData
+			if err := json.Unmarshal(migrated, &stateData); err != nil {
+				t.Fatalf("migrated state does not decode: %v", err)
+			}
+			if stateData.Version != CurrentStateVersion {
+				t.Errorf("version = %s, want %s", stateData.Version, CurrentStateVersion)
+			}
+			// Ticks above 2^53 must survive the document round trip
+			if stateData.Tick != 9007199254740993 {
+				t.Errorf("tick = %d, want 9007199254740993", stateData.Tick)
+			}
+			if stateData.MapPack == nil {
+				t.Error("migrated state has no map pack")
+			}
+		})
+	}
+}
+
+// stateETFCases are ETF saves, which load at CurrentStateVersion only. A decoded ETF file can't
+// tell the migrations which fields it didn't store.
+var stateETFCases = []struct {
+	fixture string
+	from    string
+}{
+	{"2.1.etf", "2.1"},
+	{"2.2.etf", "2.2"},
+}
+
+func TestStateETFFixtures(t *testing.T) {
+	for _, tc := range stateETFCases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "state_migrations", tc.fixture))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+
+			stateData, from, err := loadStateData(data)
+			if from != tc.from {
+				t.Errorf("saved version = %s, want %s", from, tc.from)
+			}
+			if tc.from != CurrentStateVersion {
+				if err == nil {
+					t.Fatal("old ETF state loaded, want an error")
+				}
+				if !strings.Contains(err.Error(), "can't be migrated") {
+					t.Errorf("error %q does not explain that ETF states can't be migrated", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("load failed: %v", err)
+			}
+			if stateData.Tick != 9007199254740993 {
+				t.Errorf("tick = %d, want 9007199254740993", stateData.Tick)
+			}
+			if stateData.RuntimeOptions.MapOpacityPercent != 60 {
+				t.Errorf("map opacity = %v, want 60", stateData.RuntimeOptions.MapOpacityPercent)
+			}
+		})
+	}
+}
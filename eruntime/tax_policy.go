@@ -0,0 +1,461 @@
+package eruntime
+
+import (
+	"RueaES/eruntime/pathfinder"
+	"RueaES/typedef"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TaxPolicyObjective decides what the tax policy optimizer maximises.
+type TaxPolicyObjective string
+
+const (
+	TaxPolicyRevenue TaxPolicyObjective = "revenue" // Tax collected from foreign routes
+	TaxPolicyDeny    TaxPolicyObjective = "deny"    // Rival resources lost to tax or rerouting before reaching their HQ
+)
+
+const defaultTaxPolicyPasses = 2
+
+// defaultTaxPolicyLevels are the tax rates tried on every open territory
+var defaultTaxPolicyLevels = []float64{0.05, 0.1, 0.15, 0.2, 0.3, 0.4, 0.5, 0.6}
+
+// TaxPolicyConfig configures the tax policy optimizer. Zero values use the defaults.
+type TaxPolicyConfig struct {
+	GuildTag  string             `json:"guildTag"`
+	Objective TaxPolicyObjective `json:"objective"`
+	Rivals    []string           `json:"rivals,omitempty"` // Guilds to deny, every non-allied guild when empty
+	TaxLevels []float64          `json:"taxLevels,omitempty"`
+	Passes    int                `json:"passes"` // Rounds of per-territory improvement
+}
+
+// TaxPolicyOutcome is the hourly traffic through a guild's claim under one set of tax and border settings.
+type TaxPolicyOutcome struct {
+	Revenue         typedef.BasicResources `json:"revenue"`         // Tax collected per hour
+	RivalThroughput float64                `json:"rivalThroughput"` // Rival resources per hour reaching their HQ
+	CutOffRoutes    int                    `json:"cutOffRoutes"`    // Rival territories left without a route to their HQ
+	Transiting      int                    `json:"transiting"`      // Foreign territories routing through the claim
+}
+
+// TaxPolicySetting is the recommendation for one territory.
+type TaxPolicySetting struct {
+	Territory     string                 `json:"territory"`
+	Border        typedef.Border         `json:"border"`
+	Tax           float64                `json:"tax"`
+	CurrentBorder typedef.Border         `json:"currentBorder"`
+	CurrentTax    float64                `json:"currentTax"`
+	Revenue       typedef.BasicResources `json:"revenue"`    // Tax collected per hour under the recommendation
+	Throughput    float64                `json:"throughput"` // Foreign resources per hour passing through
+	Changed       bool                   `json:"changed"`
+}
+
+// TaxPolicyReport compares the current settings with the recommended ones.
+type TaxPolicyReport struct {
+	Config      TaxPolicyConfig    `json:"config"`
+	Current     TaxPolicyOutcome   `json:"current"`
+	Recommended TaxPolicyOutcome   `json:"recommended"`
+	Settings    []TaxPolicySetting `json:"settings"`
+	Rerouted    []string           `json:"rerouted"` // Foreign territories whose route changes under the recommendation
+}
+
+// taxPolicyFlow is a foreign territory shipping its surplus to its HQ
+type taxPolicyFlow struct {
+	territory *typedef.Territory
+	hq        *typedef.Territory
+	guild     string
+	allies    []string
+	surplus   typedef.BasicResources
+	rival     bool
+}
+
+// taxPolicyOption is one candidate setting for a territory
+type taxPolicyOption struct {
+	border typedef.Border
+	tax    float64
+}
+
+// taxPolicyResult is the evaluation of one set of settings
+type taxPolicyResult struct {
+	outcome    TaxPolicyOutcome
+	revenue    map[string]typedef.BasicResources
+	throughput map[string]float64
+	routes     map[string]string
+	touched    map[int]struct{} // Flows routed through the claim
+}
+
+// taxPolicy holds the forked map the optimizer reroutes on
+type taxPolicy struct {
+	cfg     TaxPolicyConfig
+	forked  map[string]*typedef.Territory
+	env     *simulationEnv // Routes and pathfinding options copied with the fork
+	flows   []taxPolicyFlow
+	active  []int // Flows that can reach the claim, the only ones rerouted during the search
+	fixed   TaxPolicyOutcome
+	options []taxPolicyOption
+}
+
+// OptimizeTaxPolicy estimates the tax each territory of a guild collects from foreign trading routes
+// and searches tax and border settings that maximise revenue or deny rivals throughput. Foreign guilds
+// are rerouted with the pathfinder for every candidate, so cheaper detours are taken into account.
+// Live state is not modified.
+func OptimizeTaxPolicy(cfg TaxPolicyConfig) (*TaxPolicyReport, error) {
+	cfg.GuildTag = strings.TrimSpace(cfg.GuildTag)
+	if cfg.GuildTag == "" || cfg.GuildTag == "NONE" {
+		return nil, fmt.Errorf("guild tag cannot be empty")
+	}
+	switch cfg.Objective {
+	case TaxPolicyRevenue, TaxPolicyDeny:
+	case "":
+		cfg.Objective = TaxPolicyRevenue
+	default:
+		return nil, fmt.Errorf("unknown objective '%s'", cfg.Objective)
+	}
+	if cfg.Passes <= 0 {
+		cfg.Passes = defaultTaxPolicyPasses
+	}
+	levels := make([]float64, 0, len(cfg.TaxLevels))
+	for _, level := range cfg.TaxLevels {
+		if level >= 0 && level <= 1 {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		levels = append(levels, defaultTaxPolicyLevels...)
+	}
+	sort.Float64s(levels)
+	cfg.TaxLevels = levels
+
+	// Fork the map, routes and options and collect the foreign flows under the lock, then search
+	// on the fork so the pathfinding doesn't hold up the tick loop
+	st.mu.RLock()
+	policy := &taxPolicy{
+		cfg:     cfg,
+		forked:  make(map[string]*typedef.Territory, len(TerritoryMap)),
+		env:     snapshotSimulationEnvUnsafe(),
+		options: []taxPolicyOption{{border: typedef.BorderClosed}},
+	}
+	for _, level := range levels {
+		policy.options = append(policy.options, taxPolicyOption{border: typedef.BorderOpen, tax: level})
+	}
+
+	var owned []string
+	for name, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		policy.forked[name] = forkTerritory(territory)
+		territory.Mu.RUnlock()
+		if policy.forked[name].Guild.Tag == cfg.GuildTag {
+			owned = append(owned, name)
+		}
+	}
+	if len(owned) == 0 {
+		st.mu.RUnlock()
+		return nil, fmt.Errorf("guild %s owns no territories", cfg.GuildTag)
+	}
+	sort.Strings(owned)
+	policy.collectFlows()
+	st.mu.RUnlock()
+
+	current := make(map[string]taxPolicyOption, len(owned))
+	for _, name := range owned {
+		t := policy.forked[name]
+		current[name] = taxPolicyOption{border: t.Border, tax: t.Tax.Tax}
+	}
+	for i := range policy.flows {
+		policy.active = append(policy.active, i)
+	}
+	baseline := policy.evaluate()
+
+	// Only territories a foreign route uses today, or would use if the whole claim were open at the
+	// lowest tax, can change anything
+	candidates := policy.candidates(owned, baseline)
+
+	best := baseline
+	bestScore := policy.score(best)
+	for pass := 0; pass < cfg.Passes; pass++ {
+		improved := false
+		for _, name := range candidates {
+			t := policy.forked[name]
+			kept := taxPolicyOption{border: t.Border, tax: t.Tax.Tax}
+			for _, option := range policy.options {
+				if option == kept {
+					continue
+				}
+				policy.apply(name, option)
+				result := policy.evaluate()
+				if score := policy.score(result); score > bestScore+1e-9 {
+					best, bestScore, kept = result, score, option
+					improved = true
+				}
+			}
+			policy.apply(name, kept)
+		}
+		if !improved {
+			break
+		}
+	}
+
+	report := &TaxPolicyReport{
+		Config:      cfg,
+		Current:     baseline.outcome,
+		Recommended: best.outcome,
+	}
+	for _, name := range candidates {
+		t := policy.forked[name]
+		was := current[name]
+		setting := TaxPolicySetting{
+			Territory:     name,
+			Border:        t.Border,
+			Tax:           t.Tax.Tax,
+			CurrentBorder: was.border,
+			CurrentTax:    was.tax,
+			Revenue:       best.revenue[name],
+			Throughput:    best.throughput[name],
+		}
+		if setting.Border == typedef.BorderClosed {
+			setting.Tax = was.tax // Closed borders take no tax, whatever the rate
+		}
+		setting.Changed = setting.Border != was.border || setting.Tax != was.tax
+		report.Settings = append(report.Settings, setting)
+	}
+	sort.Slice(report.Settings, func(i, j int) bool {
+		a, b := report.Settings[i], report.Settings[j]
+		if a.Changed != b.Changed {
+			return a.Changed
+		}
+		if a.Throughput != b.Throughput {
+			return a.Throughput > b.Throughput
+		}
+		return a.Territory < b.Territory
+	})
+
+	for name, route := range baseline.routes {
+		if best.routes[name] != route {
+			report.Rerouted = append(report.Rerouted, name)
+		}
+	}
+	for name := range best.routes {
+		if _, ok := baseline.routes[name]; !ok {
+			report.Rerouted = append(report.Rerouted, name)
+		}
+	}
+	sort.Strings(report.Rerouted)
+
+	return report, nil
+}
+
+// ApplyTaxPolicy sets the tax and border of every changed territory in a recommendation.
+// Ally tax, upgrades and routing are kept. Returns the number of territories changed.
+func ApplyTaxPolicy(settings []TaxPolicySetting) int {
+	applied := 0
+	for _, setting := range settings {
+		if !setting.Changed {
+			continue
+		}
+		territory := GetTerritory(setting.Territory)
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		tax := territory.Tax
+		if setting.Border == typedef.BorderOpen {
+			tax.Tax = setting.Tax
+		}
+		options := typedef.TerritoryOptions{
+			Upgrades:    territory.Options.Upgrade.Set,
+			Bonuses:     territory.Options.Bonus.Set,
+			Tax:         tax,
+			RoutingMode: territory.RoutingMode,
+			Border:      setting.Border,
+			HQ:          territory.HQ,
+		}
+		territory.Mu.RUnlock()
+		if Set(setting.Territory, options) != nil {
+			applied++
+		}
+	}
+	return applied
+}
+
+// collectFlows lists every foreign territory with a surplus and an HQ to ship it to
+func (p *taxPolicy) collectFlows() {
+	allies := make(map[string][]string)
+	rivals := make(map[string]struct{}, len(p.cfg.Rivals))
+	for _, tag := range p.cfg.Rivals {
+		rivals[strings.TrimSpace(tag)] = struct{}{}
+	}
+	ownAllies := make(map[string]struct{})
+	for _, tag := range getGuildAllies(p.cfg.GuildTag) {
+		ownAllies[tag] = struct{}{}
+	}
+
+	names := make([]string, 0, len(p.forked))
+	for name := range p.forked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := p.forked[name]
+		guild := t.Guild.Tag
+		if guild == "" || guild == "NONE" || guild == p.cfg.GuildTag || t.HQ {
+			continue
+		}
+		hq := getHQFromMap(guild)
+		if hq == nil || p.forked[hq.Name] == nil {
+			continue
+		}
+
+		var surplus typedef.BasicResources
+		net := territoryNetPerHour(t)
+		for _, resource := range forecastResourceNames {
+			if v := resourceValue(net, resource); v > 0 {
+				setResourceValue(&surplus, resource, v)
+			}
+		}
+		if basicResourcesTotal(surplus) <= 0 {
+			continue
+		}
+
+		if _, ok := allies[guild]; !ok {
+			allies[guild] = getGuildAllies(guild)
+		}
+		_, allied := ownAllies[guild]
+		rival := !allied
+		if len(rivals) > 0 {
+			_, rival = rivals[guild]
+		}
+		p.flows = append(p.flows, taxPolicyFlow{
+			territory: t,
+			hq:        p.forked[hq.Name],
+			guild:     guild,
+			allies:    allies[guild],
+			surplus:   surplus,
+			rival:     rival,
+		})
+	}
+}
+
+// candidates returns the owned territories foreign routes use now or would use with the lowest open tax.
+// Flows that reach the claim in neither case are dropped from the search and kept as a fixed outcome.
+func (p *taxPolicy) candidates(owned []string, baseline taxPolicyResult) []string {
+	used := make(map[string]struct{})
+	for name := range baseline.throughput {
+		used[name] = struct{}{}
+	}
+	touched := make(map[int]struct{}, len(baseline.touched))
+	for i := range baseline.touched {
+		touched[i] = struct{}{}
+	}
+
+	saved := make(map[string]taxPolicyOption, len(owned))
+	for _, name := range owned {
+		t := p.forked[name]
+		saved[name] = taxPolicyOption{border: t.Border, tax: t.Tax.Tax}
+		p.apply(name, p.options[1])
+	}
+	open := p.evaluate()
+	for name := range open.throughput {
+		used[name] = struct{}{}
+	}
+	for i := range open.touched {
+		touched[i] = struct{}{}
+	}
+	for name, option := range saved {
+		p.apply(name, option)
+	}
+
+	// The remaining flows never see a changed setting, so their baseline result holds throughout
+	var active []int
+	others := make([]int, 0, len(p.flows))
+	for _, i := range p.active {
+		if _, ok := touched[i]; ok {
+			active = append(active, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+	p.active = others
+	p.fixed = p.evaluate().outcome
+	p.active = active
+
+	var names []string
+	for _, name := range owned {
+		if _, ok := used[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// apply sets a candidate option on a forked territory
+func (p *taxPolicy) apply(name string, option taxPolicyOption) {
+	t := p.forked[name]
+	t.Border = option.border
+	if option.border == typedef.BorderOpen {
+		t.Tax.Tax = option.tax
+	}
+}
+
+// evaluate reroutes every foreign flow and collects the tax taken by the guild's territories
+func (p *taxPolicy) evaluate() taxPolicyResult {
+	result := taxPolicyResult{
+		outcome:    p.fixed,
+		revenue:    make(map[string]typedef.BasicResources),
+		throughput: make(map[string]float64),
+		routes:     make(map[string]string),
+		touched:    make(map[int]struct{}),
+	}
+
+	for _, i := range p.active {
+		flow := p.flows[i]
+		route, _, ok := whatIfRouteWith(p.env, flow.territory, flow.hq, p.forked, flow.guild, flow.allies)
+		if !ok {
+			if flow.rival {
+				result.outcome.CutOffRoutes++
+			}
+			continue
+		}
+
+		carried := flow.surplus
+		transits := false
+		for _, hop := range route[1 : len(route)-1] {
+			tax := pathfinder.GetTaxForTerritory(hop, flow.guild, flow.allies)
+			if hop.Guild.Tag == p.cfg.GuildTag {
+				transits = true
+				collected := carried.MulFloat(tax)
+				revenue := result.revenue[hop.Name]
+				result.revenue[hop.Name] = revenue.Add(&collected)
+				result.outcome.Revenue = result.outcome.Revenue.Add(&collected)
+				result.throughput[hop.Name] += basicResourcesTotal(carried)
+			}
+			carried = carried.MulFloat(1 - tax)
+		}
+		if transits {
+			result.touched[i] = struct{}{}
+			result.outcome.Transiting++
+			names := make([]string, len(route))
+			for i, hop := range route {
+				names[i] = hop.Name
+			}
+			result.routes[flow.territory.Name] = strings.Join(names, ">")
+		}
+		if flow.rival {
+			result.outcome.RivalThroughput += basicResourcesTotal(carried)
+		}
+	}
+	return result
+}
+
+// score ranks an evaluation by the configured objective, higher is better
+func (p *taxPolicy) score(result taxPolicyResult) float64 {
+	revenue := basicResourcesTotal(result.outcome.Revenue)
+	if p.cfg.Objective == TaxPolicyDeny {
+		// Revenue only breaks ties between settings that deny equally
+		return -result.outcome.RivalThroughput + revenue*1e-6
+	}
+	return revenue
+}
@@ -0,0 +1,809 @@
+package eruntime
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"RueaES/typedef"
+)
+
+// Territory CSV columns. Exports write every column in this order; imports only touch the
+// columns present in the file, and an empty cell leaves that value unchanged.
+const (
+	csvColumnTerritory = "territory"
+	csvColumnGuild     = "guild"
+	csvColumnGuildTag  = "guild_tag"
+	csvColumnHQ        = "hq"
+	csvColumnTax       = "tax"
+	csvColumnAllyTax   = "ally_tax"
+	csvColumnBorder    = "border"
+	csvColumnRouting   = "routing"
+	csvColumnTreasury  = "treasury_override"
+)
+
+// csvUpgradeColumns maps upgrade columns to their level in typedef.Upgrade
+var csvUpgradeColumns = []struct {
+	name  string
+	level func(u *typedef.Upgrade) *int
+}{
+	{"damage", func(u *typedef.Upgrade) *int { return &u.Damage }},
+	{"attack", func(u *typedef.Upgrade) *int { return &u.Attack }},
+	{"health", func(u *typedef.Upgrade) *int { return &u.Health }},
+	{"defence", func(u *typedef.Upgrade) *int { return &u.Defence }},
+}
+
+// csvBonusColumns maps bonus columns to their level in typedef.Bonus and their cost table key
+var csvBonusColumns = []struct {
+	name    string
+	costKey string
+	level   func(b *typedef.Bonus) *int
+}{
+	{"stronger_minions", "strongerMinions", func(b *typedef.Bonus) *int { return &b.StrongerMinions }},
+	{"tower_multi_attack", "towerMultiAttack", func(b *typedef.Bonus) *int { return &b.TowerMultiAttack }},
+	{"tower_aura", "towerAura", func(b *typedef.Bonus) *int { return &b.TowerAura }},
+	{"tower_volley", "towerVolley", func(b *typedef.Bonus) *int { return &b.TowerVolley }},
+	{"gathering_experience", "gatheringExperience", func(b *typedef.Bonus) *int { return &b.GatheringExperience }},
+	{"mob_experience", "mobExperience", func(b *typedef.Bonus) *int { return &b.MobExperience }},
+	{"mob_damage", "mobDamage", func(b *typedef.Bonus) *int { return &b.MobDamage }},
+	{"pvp_damage", "pvpDamage", func(b *typedef.Bonus) *int { return &b.PvPDamage }},
+	{"xp_seeking", "xpSeeking", func(b *typedef.Bonus) *int { return &b.XPSeeking }},
+	{"tome_seeking", "tomeSeeking", func(b *typedef.Bonus) *int { return &b.TomeSeeking }},
+	{"emerald_seeking", "emeraldSeeking", func(b *typedef.Bonus) *int { return &b.EmeraldSeeking }},
+	{"larger_resource_storage", "largerResourceStorage", func(b *typedef.Bonus) *int { return &b.LargerResourceStorage }},
+	{"larger_emerald_storage", "largerEmeraldStorage", func(b *typedef.Bonus) *int { return &b.LargerEmeraldStorage }},
+	{"efficient_resource", "efficientResource", func(b *typedef.Bonus) *int { return &b.EfficientResource }},
+	{"efficient_emerald", "efficientEmerald", func(b *typedef.Bonus) *int { return &b.EfficientEmerald }},
+	{"resource_rate", "resourceRate", func(b *typedef.Bonus) *int { return &b.ResourceRate }},
+	{"emerald_rate", "emeraldRate", func(b *typedef.Bonus) *int { return &b.EmeraldRate }},
+}
+
+// csvStorageColumns maps storage columns to the resource amount they hold
+var csvStorageColumns = []struct {
+	name   string
+	amount func(r *typedef.BasicResources) *float64
+}{
+	{"storage_emeralds", func(r *typedef.BasicResources) *float64 { return &r.Emeralds }},
+	{"storage_ores", func(r *typedef.BasicResources) *float64 { return &r.Ores }},
+	{"storage_wood", func(r *typedef.BasicResources) *float64 { return &r.Wood }},
+	{"storage_fish", func(r *typedef.BasicResources) *float64 { return &r.Fish }},
+	{"storage_crops", func(r *typedef.BasicResources) *float64 { return &r.Crops }},
+}
+
+// csvBonusGuildLimits are the per guild limits on territories with a bonus enabled, the same
+// limits SetTerritoryBonus enforces
+var csvBonusGuildLimits = map[string]int{
+	"tower_multi_attack": 5,
+	"xp_seeking":         8,
+	"tome_seeking":       8,
+	"emerald_seeking":    8,
+}
+
+var csvTreasuryNames = []string{"none", "very low", "low", "medium", "high", "very high"}
+
+// TerritoryCSVColumns returns the column names in export order
+func TerritoryCSVColumns() []string {
+	columns := []string{csvColumnTerritory, csvColumnGuild, csvColumnGuildTag, csvColumnHQ}
+	for _, c := range csvUpgradeColumns {
+		columns = append(columns, c.name)
+	}
+	for _, c := range csvBonusColumns {
+		columns = append(columns, c.name)
+	}
+	columns = append(columns, csvColumnTax, csvColumnAllyTax, csvColumnBorder, csvColumnRouting, csvColumnTreasury)
+	for _, c := range csvStorageColumns {
+		columns = append(columns, c.name)
+	}
+	return columns
+}
+
+// csvTerritoryState is the part of a territory the CSV import and export work with
+type csvTerritoryState struct {
+	guild    typedef.Guild
+	options  typedef.TerritoryOptions
+	treasury typedef.TreasuryOverride
+	storage  typedef.BasicResources
+}
+
+func csvStateOf(t *typedef.Territory) csvTerritoryState {
+	t.Mu.RLock()
+	defer t.Mu.RUnlock()
+	return csvTerritoryState{
+		guild: typedef.Guild{Name: t.Guild.Name, Tag: t.Guild.Tag},
+		options: typedef.TerritoryOptions{
+			Upgrades:    t.Options.Upgrade.Set,
+			Bonuses:     t.Options.Bonus.Set,
+			Tax:         t.Tax,
+			RoutingMode: t.RoutingMode,
+			Border:      t.Border,
+			HQ:          t.HQ,
+		},
+		treasury: t.TreasuryOverride,
+		storage:  t.Storage.At,
+	}
+}
+
+// csvValues formats a territory state as a CSV record in TerritoryCSVColumns order
+func (s csvTerritoryState) csvValues(name string) []string {
+	record := []string{name, s.guild.Name, s.guild.Tag, strconv.FormatBool(s.options.HQ)}
+	for _, c := range csvUpgradeColumns {
+		record = append(record, strconv.Itoa(*c.level(&s.options.Upgrades)))
+	}
+	for _, c := range csvBonusColumns {
+		record = append(record, strconv.Itoa(*c.level(&s.options.Bonuses)))
+	}
+	record = append(record,
+		formatCSVPercent(s.options.Tax.Tax),
+		formatCSVPercent(s.options.Tax.Ally),
+		formatCSVBorder(s.options.Border),
+		formatCSVRouting(s.options.RoutingMode),
+		formatCSVTreasury(s.treasury),
+	)
+	for _, c := range csvStorageColumns {
+		record = append(record, strconv.FormatFloat(math.Round(*c.amount(&s.storage)), 'f', -1, 64))
+	}
+	return record
+}
+
+// ExportTerritoriesCSV writes every territory to w as CSV, sorted by name. Taxes are written as
+// percentages so they read naturally in a spreadsheet.
+func ExportTerritoriesCSV(w io.Writer) error {
+	territories := GetTerritories()
+	sort.Slice(territories, func(i, j int) bool { return territories[i].Name < territories[j].Name })
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(TerritoryCSVColumns()); err != nil {
+		return err
+	}
+	for _, t := range territories {
+		if t == nil {
+			continue
+		}
+		if err := writer.Write(csvStateOf(t).csvValues(t.Name)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// TerritoryCSVRowError is a problem with one row of an imported CSV. Rows with errors are left
+// out of the import entirely.
+type TerritoryCSVRowError struct {
+	Row     int    `json:"row"` // 1-based line number, the header is row 1
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e TerritoryCSVRowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("row %d, %s: %s", e.Row, e.Column, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// TerritoryCSVChange is one value an import changes
+type TerritoryCSVChange struct {
+	Column string `json:"column"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// TerritoryCSVRow is a validated row that changes a territory
+type TerritoryCSVRow struct {
+	Row       int                  `json:"row"`
+	Territory string               `json:"territory"`
+	Changes   []TerritoryCSVChange `json:"changes"`
+
+	target   csvTerritoryState
+	current  csvTerritoryState
+	columns  map[string]bool
+	hqWanted bool
+}
+
+// ownerUnchanged reports whether the row keeps the territory with its current guild
+func (r *TerritoryCSVRow) ownerUnchanged() bool {
+	return r.target.guild.Name == r.current.guild.Name && r.target.guild.Tag == r.current.guild.Tag
+}
+
+// TerritoryCSVUnknown is a territory name that is not on the map, with the closest names that are
+type TerritoryCSVUnknown struct {
+	Row         int      `json:"row"`
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// TerritoryCSVImport is the dry run of a CSV import: the resolved column mapping, the per row
+// changes it would make and everything that was rejected. Nothing is changed until Apply.
+type TerritoryCSVImport struct {
+	Mapping   map[string]string      `json:"mapping"` // CSV header to column name
+	Ignored   []string               `json:"ignored,omitempty"`
+	Rows      []*TerritoryCSVRow     `json:"rows"`
+	Unchanged int                    `json:"unchanged"`
+	Errors    []TerritoryCSVRowError `json:"errors,omitempty"`
+	Unknown   []TerritoryCSVUnknown  `json:"unknown,omitempty"`
+
+	valid []*TerritoryCSVRow // every row without errors, including unchanged ones
+}
+
+// ParseTerritoryCSVMapping parses a column mapping written as "Header=column" pairs separated by
+// commas, semicolons or new lines. The mapping is only needed for headers that do not already
+// match a column name.
+func ParseTerritoryCSVMapping(text string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	known := make(map[string]bool)
+	for _, c := range TerritoryCSVColumns() {
+		known[c] = true
+	}
+	pairs := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '\n' })
+	for _, pair := range pairs {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		header, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("mapping %q must have the form Header=column", strings.TrimSpace(pair))
+		}
+		header = strings.TrimSpace(header)
+		column = normalizeCSVHeader(column)
+		if !known[column] && column != "" {
+			return nil, fmt.Errorf("mapping %q: unknown column %q", strings.TrimSpace(pair), column)
+		}
+		mapping[header] = column
+	}
+	return mapping, nil
+}
+
+// normalizeCSVHeader turns a header like "Ally Tax" into its column name form "ally_tax"
+func normalizeCSVHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	header = strings.NewReplacer(" ", "_", "-", "_", "%", "").Replace(header)
+	return strings.Trim(header, "_")
+}
+
+// resolveCSVColumns maps each header to a column, preferring the explicit mapping. An explicit
+// mapping to "" ignores the header.
+func resolveCSVColumns(headers []string, mapping map[string]string) ([]string, *TerritoryCSVImport, error) {
+	known := make(map[string]bool)
+	for _, c := range TerritoryCSVColumns() {
+		known[c] = true
+	}
+	aliases := map[string]string{
+		"name": csvColumnTerritory, "owner": csvColumnGuild, "tag": csvColumnGuildTag,
+		"border_style": csvColumnBorder, "routing_mode": csvColumnRouting, "treasury": csvColumnTreasury,
+		"defense": "defence", "allytax": csvColumnAllyTax,
+	}
+
+	result := &TerritoryCSVImport{Mapping: make(map[string]string)}
+	columns := make([]string, len(headers))
+	used := make(map[string]string)
+	for i, header := range headers {
+		column, mapped := mapping[strings.TrimSpace(header)]
+		if !mapped {
+			column = normalizeCSVHeader(header)
+			if alias, ok := aliases[column]; ok {
+				column = alias
+			}
+		}
+		if column == "" || !known[column] {
+			result.Ignored = append(result.Ignored, header)
+			continue
+		}
+		if other, ok := used[column]; ok {
+			return nil, nil, fmt.Errorf("headers %q and %q both map to column %s", other, header, column)
+		}
+		used[column] = header
+		columns[i] = column
+		result.Mapping[header] = column
+	}
+	if _, ok := used[csvColumnTerritory]; !ok {
+		return nil, nil, fmt.Errorf("no territory column, map one of the headers to %q", csvColumnTerritory)
+	}
+	return columns, result, nil
+}
+
+// PlanTerritoryCSV reads a territory CSV and works out what importing it would change, without
+// changing anything. Headers are matched to columns case-insensitively, with spaces treated as
+// underscores, and mapping can rename the rest.
+func PlanTerritoryCSV(r io.Reader, mapping map[string]string) (*TerritoryCSVImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff") // spreadsheet exports often add a BOM
+	}
+	columns, result, err := resolveCSVColumns(headers, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	guilds := GetGuildsInternal()
+	var names []string
+	byLowerName := make(map[string]*typedef.Territory)
+	for _, t := range GetTerritories() {
+		if t != nil {
+			names = append(names, t.Name)
+			byLowerName[strings.ToLower(t.Name)] = t
+		}
+	}
+
+	seen := make(map[string]int)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Errors = append(result.Errors, TerritoryCSVRowError{Row: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+
+		cells := make(map[string]string)
+		for i, value := range record {
+			if i < len(columns) && columns[i] != "" {
+				cells[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		name := cells[csvColumnTerritory]
+		if name == "" {
+			if strings.TrimSpace(strings.Join(record, "")) != "" {
+				result.Errors = append(result.Errors, TerritoryCSVRowError{Row: row, Column: csvColumnTerritory, Message: "territory name is empty"})
+			}
+			continue
+		}
+
+		t := byLowerName[strings.ToLower(name)]
+		if t == nil {
+			result.Unknown = append(result.Unknown, TerritoryCSVUnknown{Row: row, Name: name, Suggestions: suggestTerritoryNames(name, names)})
+			continue
+		}
+		if first, ok := seen[t.Name]; ok {
+			result.Errors = append(result.Errors, TerritoryCSVRowError{Row: row, Column: csvColumnTerritory,
+				Message: fmt.Sprintf("%s is already listed on row %d", t.Name, first)})
+			continue
+		}
+		seen[t.Name] = row
+
+		planned, rowErrors := planTerritoryCSVRow(row, t, cells, guilds)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		result.valid = append(result.valid, planned)
+		if len(planned.Changes) > 0 {
+			result.Rows = append(result.Rows, planned)
+		}
+	}
+
+	result.checkGuildLimits()
+	result.Unchanged = len(result.valid) - len(result.Rows)
+	return result, nil
+}
+
+// planTerritoryCSVRow validates one row and returns the changes it makes to the territory
+func planTerritoryCSVRow(row int, t *typedef.Territory, cells map[string]string, guilds []*typedef.Guild) (*TerritoryCSVRow, []TerritoryCSVRowError) {
+	current := csvStateOf(t)
+	planned := &TerritoryCSVRow{Row: row, Territory: t.Name, target: current, current: current, columns: make(map[string]bool)}
+	var rowErrors []TerritoryCSVRowError
+	fail := func(column string, format string, args ...any) {
+		rowErrors = append(rowErrors, TerritoryCSVRowError{Row: row, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+	cell := func(column string) (string, bool) {
+		value, ok := cells[column]
+		if ok && value != "" {
+			planned.columns[column] = true
+			return value, true
+		}
+		return "", false
+	}
+	level := func(column, value string, maxLevel int) (int, bool) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxLevel {
+			fail(column, "level must be a whole number from 0 to %d, got %q", maxLevel, value)
+			return 0, false
+		}
+		return n, true
+	}
+
+	guildName, hasName := cell(csvColumnGuild)
+	guildTag, hasTag := cell(csvColumnGuildTag)
+	if hasName || hasTag {
+		if guild, err := resolveCSVGuild(guildName, guildTag, guilds); err != nil {
+			column := csvColumnGuild
+			if hasTag {
+				column = csvColumnGuildTag
+			}
+			fail(column, "%v", err)
+		} else {
+			planned.target.guild = guild
+		}
+	}
+
+	if value, ok := cell(csvColumnHQ); ok {
+		hq, err := parseCSVBool(value)
+		if err != nil {
+			fail(csvColumnHQ, "%v", err)
+		} else {
+			planned.hqWanted = hq
+			planned.target.options.HQ = hq
+		}
+	}
+
+	for _, c := range csvUpgradeColumns {
+		if value, ok := cell(c.name); ok {
+			if n, ok := level(c.name, value, 11); ok {
+				*c.level(&planned.target.options.Upgrades) = n
+			}
+		}
+	}
+	costs := GetCost()
+	for _, c := range csvBonusColumns {
+		if value, ok := cell(c.name); ok {
+			if n, ok := level(c.name, value, getBonusMaxLevelFromCosts(costs, c.costKey)); ok {
+				*c.level(&planned.target.options.Bonuses) = n
+			}
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		target *float64
+	}{{csvColumnTax, &planned.target.options.Tax.Tax}, {csvColumnAllyTax, &planned.target.options.Tax.Ally}} {
+		if value, ok := cell(c.name); ok {
+			tax, err := parseCSVPercent(value)
+			if err != nil {
+				fail(c.name, "%v", err)
+			} else if tax < 0.05 || tax > 0.7 {
+				fail(c.name, "tax must be between 5%% and 70%%, got %s", value)
+			} else {
+				*c.target = tax
+			}
+		}
+	}
+
+	if value, ok := cell(csvColumnBorder); ok {
+		switch strings.ToLower(value) {
+		case "open":
+			planned.target.options.Border = typedef.BorderOpen
+		case "closed":
+			planned.target.options.Border = typedef.BorderClosed
+		default:
+			fail(csvColumnBorder, "border must be open or closed, got %q", value)
+		}
+	}
+	if value, ok := cell(csvColumnRouting); ok {
+		switch strings.ToLower(value) {
+		case "cheapest":
+			planned.target.options.RoutingMode = typedef.RoutingCheapest
+		case "fastest":
+			planned.target.options.RoutingMode = typedef.RoutingFastest
+		default:
+			fail(csvColumnRouting, "routing must be cheapest or fastest, got %q", value)
+		}
+	}
+	if value, ok := cell(csvColumnTreasury); ok {
+		override, err := parseCSVTreasury(value)
+		if err != nil {
+			fail(csvColumnTreasury, "%v", err)
+		} else {
+			planned.target.treasury = override
+		}
+	}
+
+	for _, c := range csvStorageColumns {
+		if value, ok := cell(c.name); ok {
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+			if err != nil || amount < 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+				fail(c.name, "storage must be a number of at least 0, got %q", value)
+			} else if math.Round(amount) != math.Round(*c.amount(&current.storage)) {
+				*c.amount(&planned.target.storage) = amount
+			}
+		}
+	}
+
+	// A guild change drops the HQ, so a row that moves the territory keeps it only if asked to
+	if !planned.ownerUnchanged() && !planned.hqWanted {
+		planned.target.options.HQ = false
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+	planned.Changes = diffCSVStates(planned.current, planned.target, t.Name)
+	return planned, nil
+}
+
+// diffCSVStates lists the columns whose formatted value differs between two states
+func diffCSVStates(from, to csvTerritoryState, name string) []TerritoryCSVChange {
+	columns := TerritoryCSVColumns()
+	before := from.csvValues(name)
+	after := to.csvValues(name)
+	var changes []TerritoryCSVChange
+	for i := range columns {
+		if before[i] != after[i] {
+			changes = append(changes, TerritoryCSVChange{Column: columns[i], From: before[i], To: after[i]})
+		}
+	}
+	return changes
+}
+
+// checkGuildLimits rejects rows that would break a per guild rule once the whole import is
+// applied: more than one HQ per guild, clearing an HQ without marking a new one, and the bonus
+// limits
+func (imp *TerritoryCSVImport) checkGuildLimits() {
+	planned := make(map[string]*TerritoryCSVRow, len(imp.valid))
+	for _, row := range imp.valid {
+		planned[row.Territory] = row
+	}
+
+	// Project the owner, HQ and bonuses of every territory after the import
+	hqs := make(map[string][]string)
+	bonusCounts := make(map[string]map[string]int)
+	for _, t := range GetTerritories() {
+		if t == nil {
+			continue
+		}
+		state := csvStateOf(t)
+		if row, ok := planned[t.Name]; ok {
+			state = row.target
+		}
+		if state.options.HQ {
+			hqs[state.guild.Name] = append(hqs[state.guild.Name], t.Name)
+		}
+		for _, c := range csvBonusColumns {
+			if _, limited := csvBonusGuildLimits[c.name]; limited && *c.level(&state.options.Bonuses) > 0 {
+				if bonusCounts[state.guild.Name] == nil {
+					bonusCounts[state.guild.Name] = make(map[string]int)
+				}
+				bonusCounts[state.guild.Name][c.name]++
+			}
+		}
+	}
+
+	var kept, rows []*TerritoryCSVRow
+	for _, row := range imp.valid {
+		var message, column string
+		guild := row.target.guild.Name
+		switch {
+		case row.hqWanted && countCSVHQRows(imp.valid, guild) > 1:
+			column, message = csvColumnHQ, fmt.Sprintf("more than one territory of %s is marked as HQ", guild)
+		case row.columns[csvColumnHQ] && !row.hqWanted && row.current.options.HQ && row.ownerUnchanged() && len(hqs[guild]) == 0:
+			column, message = csvColumnHQ, "an HQ can only be cleared by marking another territory of the guild as HQ"
+		default:
+			for _, c := range csvBonusColumns {
+				limit, limited := csvBonusGuildLimits[c.name]
+				enabling := *c.level(&row.target.options.Bonuses) > 0 &&
+					(*c.level(&row.current.options.Bonuses) == 0 || !row.ownerUnchanged())
+				if limited && enabling && bonusCounts[guild][c.name] > limit {
+					column, message = c.name, fmt.Sprintf("%s would have %d territories with %s, the limit is %d", guild, bonusCounts[guild][c.name], c.name, limit)
+					break
+				}
+			}
+		}
+		if message != "" {
+			imp.Errors = append(imp.Errors, TerritoryCSVRowError{Row: row.Row, Column: column, Message: message})
+			continue
+		}
+		kept = append(kept, row)
+		if len(row.Changes) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	imp.valid, imp.Rows = kept, rows
+	sort.Slice(imp.Errors, func(i, j int) bool { return imp.Errors[i].Row < imp.Errors[j].Row })
+}
+
+func countCSVHQRows(rows []*TerritoryCSVRow, guild string) int {
+	count := 0
+	for _, row := range rows {
+		if row.hqWanted && row.target.guild.Name == guild {
+			count++
+		}
+	}
+	return count
+}
+
+// Apply makes the planned changes through the normal runtime paths: owners are set in one batch,
+// then options, treasury overrides and storage per territory, and HQs last so an HQ moved onto a
+// territory that also changes owner survives the ownership change. It returns the number of
+// territories updated.
+func (imp *TerritoryCSVImport) Apply() (int, error) {
+	if len(imp.Rows) == 0 {
+		return 0, errors.New("the import has no changes to apply")
+	}
+
+	owners := make(map[string]*typedef.Guild)
+	for _, row := range imp.Rows {
+		if !row.ownerUnchanged() {
+			guild := row.target.guild
+			owners[row.Territory] = &guild
+		}
+	}
+	if len(owners) > 0 {
+		SetGuildBatch(owners)
+	}
+
+	applied := 0
+	var hqs []string
+	for _, row := range imp.Rows {
+		opts := row.target.options
+		// Set never clears an HQ, a cleared HQ goes away when its guild's new HQ is set below
+		if row.hqWanted && (!row.current.options.HQ || owners[row.Territory] != nil) {
+			hqs = append(hqs, row.Territory)
+		}
+		opts.HQ = false
+		if Set(row.Territory, opts) == nil {
+			continue
+		}
+		if row.target.treasury != row.current.treasury {
+			SetTreasuryOverride(GetTerritory(row.Territory), row.target.treasury)
+		}
+		if row.target.storage != row.current.storage {
+			// Storage keeps moving after the dry run, only overwrite the resources the file lists
+			storage := csvStateOf(GetTerritory(row.Territory)).storage
+			for _, c := range csvStorageColumns {
+				if row.columns[c.name] {
+					*c.amount(&storage) = *c.amount(&row.target.storage)
+				}
+			}
+			ModifyStorageState(row.Territory, &storage)
+		}
+		applied++
+	}
+
+	for _, name := range hqs {
+		if t := GetTerritory(name); t != nil {
+			opts := csvStateOf(t).options
+			opts.HQ = true
+			Set(name, opts)
+		}
+	}
+	return applied, nil
+}
+
+// resolveCSVGuild finds the guild a row names, by tag first and then by name or tag
+func resolveCSVGuild(name, tag string, guilds []*typedef.Guild) (typedef.Guild, error) {
+	if strings.EqualFold(name, "No Guild") || strings.EqualFold(tag, "NONE") || strings.EqualFold(name, "none") {
+		return typedef.Guild{Name: "No Guild", Tag: "NONE"}, nil
+	}
+	for _, guild := range guilds {
+		if guild == nil {
+			continue
+		}
+		if tag != "" && guild.Tag == tag && (name == "" || strings.EqualFold(guild.Name, name)) {
+			return typedef.Guild{Name: guild.Name, Tag: guild.Tag}, nil
+		}
+		if tag == "" && (strings.EqualFold(guild.Name, name) || guild.Tag == name) {
+			return typedef.Guild{Name: guild.Name, Tag: guild.Tag}, nil
+		}
+	}
+	if tag != "" {
+		return typedef.Guild{}, fmt.Errorf("guild [%s] is not in the guild list", tag)
+	}
+	return typedef.Guild{}, fmt.Errorf("guild %q is not in the guild list", name)
+}
+
+// suggestTerritoryNames returns up to three territory names close to an unknown name
+func suggestTerritoryNames(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	lower := strings.ToLower(name)
+	limit := max(2, len(lower)/3)
+	var candidates []candidate
+	for _, n := range names {
+		candidateLower := strings.ToLower(n)
+		distance := levenshtein(lower, candidateLower)
+		if strings.Contains(candidateLower, lower) || strings.Contains(lower, candidateLower) {
+			distance = min(distance, 1)
+		}
+		if distance <= limit {
+			candidates = append(candidates, candidate{n, distance})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	suggestions := make([]string, 0, 3)
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func formatCSVPercent(tax float64) string {
+	return strconv.FormatFloat(math.Round(tax*1000)/10, 'f', -1, 64)
+}
+
+// parseCSVPercent reads a tax as a percentage, "5" and "5%" both mean 5%
+func parseCSVPercent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil {
+		return 0, fmt.Errorf("tax must be a percentage, got %q", value)
+	}
+	return percent / 100, nil
+}
+
+func parseCSVBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1", "x":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true or false, got %q", value)
+}
+
+func formatCSVBorder(border typedef.Border) string {
+	if border == typedef.BorderOpen {
+		return "open"
+	}
+	return "closed"
+}
+
+func formatCSVRouting(routing typedef.Routing) string {
+	if routing == typedef.RoutingFastest {
+		return "fastest"
+	}
+	return "cheapest"
+}
+
+func formatCSVTreasury(override typedef.TreasuryOverride) string {
+	if int(override) >= 0 && int(override) < len(csvTreasuryNames) {
+		return csvTreasuryNames[override]
+	}
+	return csvTreasuryNames[0]
+}
+
+func parseCSVTreasury(value string) (typedef.TreasuryOverride, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(value, "_", " "))), " ")
+	for i, name := range csvTreasuryNames {
+		if normalized == name || normalized == strings.ReplaceAll(name, " ", "") {
+			return typedef.TreasuryOverride(i), nil
+		}
+	}
+	return 0, fmt.Errorf("treasury override must be one of %s, got %q", strings.Join(csvTreasuryNames, ", "), value)
+}
@@ -109,6 +109,7 @@ func (s *state) processQueuedTicks() {
 			s.update2()
 		}
 		s.update()
+		s.updateSeason()
 
 		// Trigger auto-save every minute (60 ticks)
 		if s.tick%60 == 0 {
@@ -8,15 +8,16 @@ import (
 
 // Transit represents a single resource movement between territories
 type Transit struct {
-	ID             string                 `json:"id"`            // Unique identifier for this transit
-	BasicResources typedef.BasicResources `json:"resources"`     // Resources being moved
-	OriginID       string                 `json:"originId"`      // ID of origin territory
-	DestinationID  string                 `json:"destinationId"` // ID of destination territory
-	Route          []string               `json:"route"`         // Territory IDs along the route
-	RouteIndex     int                    `json:"routeIndex"`    // Current position in route
-	NextTax        float64                `json:"nextTax"`       // Tax for next territory
-	CreatedAt      uint64                 `json:"createdAt"`     // Tick when transit was created
-	Moved          bool                   `json:"moved"`         // Whether this transit has moved this tick
+	ID             string                 `json:"id"`                  // Unique identifier for this transit
+	BasicResources typedef.BasicResources `json:"resources"`           // Resources being moved
+	OriginID       string                 `json:"originId"`            // ID of origin territory
+	DestinationID  string                 `json:"destinationId"`       // ID of destination territory
+	Route          []string               `json:"route"`               // Territory IDs along the route
+	RouteIndex     int                    `json:"routeIndex"`          // Current position in route
+	NextTax        float64                `json:"nextTax"`             // Tax for next territory
+	CreatedAt      uint64                 `json:"createdAt"`           // Tick when transit was created
+	Moved          bool                   `json:"moved"`               // Whether this transit has moved this tick
+	TributeID      string                 `json:"tributeId,omitempty"` // Set when the transit carries a guild-to-guild tribute
 }
 
 // TransitManager manages all resource transits in the system
@@ -103,6 +104,41 @@ func (tm *TransitManager) StartTransit(resources typedef.BasicResources, originI
 	return transitID
 }
 
+// StartTributeTransit creates a transit carrying a guild-to-guild tribute.
+// Tribute transits pay the ally tax rate in territories of the sender's allies and are delivered
+// as soon as they reach the destination HQ, so they arrive after one minute per hop.
+func (tm *TransitManager) StartTributeTransit(tributeID string, resources typedef.BasicResources, originID, destID string, route []string) string {
+	transitID := tm.StartTransit(resources, originID, destID, route)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if transit, ok := tm.transits[transitID]; ok {
+		transit.TributeID = tributeID
+		if len(route) > 1 {
+			if nextTerritory := st.getTerritoryByID(route[1]); nextTerritory != nil {
+				transit.NextTax = tributeTaxRate(originID, nextTerritory)
+			}
+		}
+	}
+
+	return transitID
+}
+
+// tributeTaxRate returns the tax a territory charges a tribute sent from the given origin HQ
+func tributeTaxRate(originID string, territory *typedef.Territory) float64 {
+	origin := st.getTerritoryByID(originID)
+	if origin == nil {
+		return territory.Tax.Tax
+	}
+	for _, ally := range getGuildAllies(origin.Guild.Tag) {
+		if ally == territory.Guild.Tag {
+			return territory.Tax.Ally
+		}
+	}
+	return territory.Tax.Tax
+}
+
 // ProcessAllTransits moves all transits forward one step
 func (tm *TransitManager) ProcessAllTransits() {
 	tm.mu.Lock()
@@ -164,12 +200,18 @@ func (tm *TransitManager) moveTransitForward(transit *Transit) bool {
 	nextTerritory := st.getTerritoryByID(nextTerritoryID)
 
 	if nextTerritory == nil {
+		if transit.TributeID != "" {
+			st.recordTributeErrorByID(transit.TributeID, fmt.Sprintf("tribute voided: territory %s on route no longer exists", nextTerritoryID))
+		}
 		return true // Next territory doesn't exist, remove transit
 	}
 
 	// Check border status
 	if nextTerritory.Border == typedef.BorderClosed &&
 		currentTerritory.Guild.Tag != nextTerritory.Guild.Tag {
+		if transit.TributeID != "" {
+			st.recordTributeErrorByID(transit.TributeID, fmt.Sprintf("tribute voided: border closed at %s", nextTerritory.Name))
+		}
 		// Border closed to different guild, void resources
 		return true
 	}
@@ -211,7 +253,9 @@ func (tm *TransitManager) moveTransitForward(transit *Transit) bool {
 		followingTerritoryID := transit.Route[transit.RouteIndex+1]
 		followingTerritory := st.getTerritoryByID(followingTerritoryID)
 		if followingTerritory != nil {
-			if nextTerritory.Guild.Tag != followingTerritory.Guild.Tag {
+			if transit.TributeID != "" {
+				transit.NextTax = tributeTaxRate(transit.OriginID, followingTerritory)
+			} else if nextTerritory.Guild.Tag != followingTerritory.Guild.Tag {
 				transit.NextTax = followingTerritory.Tax.Tax
 			} else {
 				transit.NextTax = followingTerritory.Tax.Ally
@@ -219,6 +263,13 @@ func (tm *TransitManager) moveTransitForward(transit *Transit) bool {
 		}
 	}
 
+	// Tributes are handed over as soon as they reach the destination HQ
+	if transit.TributeID != "" && transit.RouteIndex == len(transit.Route)-1 {
+		tm.removeTransitFromTerritory(currentTerritoryID, transit)
+		tm.deliverResources(transit, nextTerritory)
+		return true
+	}
+
 	// Remove from current territory's list
 	tm.removeTransitFromTerritory(currentTerritoryID, transit)
 
@@ -5,8 +5,13 @@ import (
 )
 
 func calculateTreasury(territory *typedef.Territory) float64 {
+	return calculateTreasuryWith(&st.runtimeOptions, territory)
+}
+
+// calculateTreasuryWith returns the treasury generation multiplier of a territory under the given options
+func calculateTreasuryWith(options *typedef.RuntimeOptions, territory *typedef.Territory) float64 {
 	bonusMultiplier := 1.0
-	if !st.runtimeOptions.TreasuryEnabled {
+	if !options.TreasuryEnabled {
 		return bonusMultiplier
 	}
 
@@ -40,7 +45,11 @@ func calculateTreasury(territory *typedef.Territory) float64 {
 		level = territory.Treasury
 	}
 
-	// Calculate treasury bonus based on distance and level
+	return 1.0 + treasuryBonusFor(distance, level)
+}
+
+// treasuryBonusFor returns the generation bonus fraction for a treasury level at a distance from the HQ
+func treasuryBonusFor(distance int, level typedef.TreasuryLevel) float64 {
 	var treasuryBonus float64
 
 	switch distance {
@@ -112,14 +121,25 @@ func calculateTreasury(territory *typedef.Territory) float64 {
 		}
 	}
 
-	return 1.0 + treasuryBonus
+	return treasuryBonus
 }
 
 // calculateTreasuryLevel calculates the treasury level based on time since captured
-func calculateTreasuryLevel(capturedAt uint64) typedef.TreasuryLevel {
-	timeSinceCaptured := st.tick - capturedAt
+func calculateTreasuryLevel(territory *typedef.Territory) typedef.TreasuryLevel {
+	return treasuryLevelFor(territoryHeldFor(territory, st.tick))
+}
 
-	secondsSinceCaptured := timeSinceCaptured
+// territoryHeldFor returns the seconds a territory has been held at tick, including the time it was
+// held before tick 0
+func territoryHeldFor(territory *typedef.Territory, tick uint64) uint64 {
+	if tick < territory.CapturedAt {
+		return territory.HeldBefore
+	}
+	return tick - territory.CapturedAt + territory.HeldBefore
+}
+
+// treasuryLevelFor calculates the treasury level of a territory held for the given seconds
+func treasuryLevelFor(secondsSinceCaptured uint64) typedef.TreasuryLevel {
 
 	// Calculate time thresholds:
 	// Very Low: 0 to 59 minutes 59 seconds (3599 seconds)
@@ -158,6 +178,7 @@ func updateTreasuryLevel(territory *typedef.Territory) {
 	if territory.Guild.Name == "" || territory.Guild.Name == "No Guild" {
 		territory.Treasury = typedef.TreasuryLevelVeryLow
 		territory.CapturedAt = 0
+		territory.HeldBefore = 0
 		return
 	}
 
@@ -166,11 +187,16 @@ func updateTreasuryLevel(territory *typedef.Territory) {
 	}
 
 	// Calculate and update treasury level
-	territory.Treasury = calculateTreasuryLevel(territory.CapturedAt)
+	territory.Treasury = calculateTreasuryLevel(territory)
 }
 
 // updateGenerationBonus calculates and updates the territory's GenerationBonus based on treasury
 func updateGenerationBonus(territory *typedef.Territory) {
-	treasuryMultiplier := calculateTreasury(territory)
+	updateGenerationBonusWith(&st.runtimeOptions, territory)
+}
+
+// updateGenerationBonusWith updates the territory's GenerationBonus under the given options
+func updateGenerationBonusWith(options *typedef.RuntimeOptions, territory *typedef.Territory) {
+	treasuryMultiplier := calculateTreasuryWith(options, territory)
 	territory.GenerationBonus = (treasuryMultiplier - 1.0) * 100.0
 }
@@ -0,0 +1,269 @@
+package eruntime
+
+import (
+	"RueaES/typedef"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// treasuryThresholds are the held times in seconds at which each treasury level is reached
+var treasuryThresholds = []struct {
+	Level   typedef.TreasuryLevel
+	Seconds uint64
+}{
+	{typedef.TreasuryLevelLow, 3600},
+	{typedef.TreasuryLevelMedium, 86400},
+	{typedef.TreasuryLevelHigh, 432000},
+	{typedef.TreasuryLevelVeryHigh, 1036800},
+}
+
+var treasuryLevelNames = []string{"Very Low", "Low", "Medium", "High", "Very High"}
+
+// TreasuryLevelName returns the display name of a treasury level
+func TreasuryLevelName(level typedef.TreasuryLevel) string {
+	if int(level) < len(treasuryLevelNames) {
+		return treasuryLevelNames[level]
+	}
+	return "Unknown"
+}
+
+// TreasuryMilestone is the point at which a territory reaches the next treasury level.
+type TreasuryMilestone struct {
+	Level     typedef.TreasuryLevel  `json:"level"`
+	Name      string                 `json:"name"`
+	Tick      uint64                 `json:"tick"`
+	Remaining uint64                 `json:"remaining"` // Seconds until the level is reached
+	Bonus     float64                `json:"bonus"`     // Generation bonus in percent at this level
+	Gain      typedef.BasicResources `json:"gain"`      // Extra generation per hour over the current bonus
+}
+
+// TreasuryProjection is the treasury outlook of a single territory.
+type TreasuryProjection struct {
+	Territory  string                `json:"territory"`
+	HeldFor    uint64                `json:"heldFor"` // Seconds since capture
+	Level      typedef.TreasuryLevel `json:"level"`
+	Distance   int                   `json:"distance"` // Route length to the HQ used for the bonus
+	Bonus      float64               `json:"bonus"`    // Current generation bonus in percent
+	Overridden bool                  `json:"overridden"`
+	Milestones []TreasuryMilestone   `json:"milestones"` // Levels still to be reached, in order
+}
+
+// SetTerritoriesHeldFor backdates the capture time of the given territories so they count as held for
+// the given duration, one tick being one second, and refreshes their treasury. Unowned territories are
+// skipped. It returns the number of territories updated.
+func SetTerritoriesHeldFor(names []string, held time.Duration) int {
+	if held < 0 {
+		held = 0
+	}
+	heldSeconds := uint64(held / time.Second)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	updated := 0
+	for _, name := range names {
+		if setTerritoryHeldForUnsafe(name, "", heldSeconds) {
+			updated++
+		}
+	}
+
+	return updated
+}
+
+// SetTerritoriesHeldSince is SetTerritoriesHeldFor with a real-world capture date.
+// Dates in the future count as captured now.
+func SetTerritoriesHeldSince(names []string, since time.Time) int {
+	return SetTerritoriesHeldFor(names, time.Since(since))
+}
+
+// TerritoryAcquisition is when a guild took a territory in the real world
+type TerritoryAcquisition struct {
+	GuildName string    // Guild the date belongs to
+	At        time.Time // When the guild took the territory
+}
+
+// SetTerritoriesAcquired backdates each territory to its own real-world capture date, such as
+// the acquired times of the Wynncraft territory list. asOf is the real-world time matching the
+// current tick, usually when the list was fetched, so a saved list keeps the holds it recorded.
+// Dates after asOf count as captured at the current tick. Territories no longer owned by the
+// guild of their date, such as captures refused by the cooldown, are skipped. It returns the
+// number of territories updated.
+func SetTerritoriesAcquired(acquired map[string]TerritoryAcquisition, asOf time.Time) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	updated := 0
+	for name, acquisition := range acquired {
+		held := asOf.Sub(acquisition.At)
+		if held < 0 {
+			held = 0
+		}
+		if setTerritoryHeldForUnsafe(name, acquisition.GuildName, uint64(held/time.Second)) {
+			updated++
+		}
+	}
+
+	return updated
+}
+
+// setTerritoryHeldForUnsafe backdates one owned territory. A non-empty guildName skips the
+// territory unless that guild owns it. The caller holds st.mu.
+func setTerritoryHeldForUnsafe(name, guildName string, heldSeconds uint64) bool {
+	t := getTerritoryUnsafe(strings.TrimSpace(name))
+	if t == nil {
+		return false
+	}
+
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	if t.Guild.Name == "" || t.Guild.Name == "No Guild" {
+		return false
+	}
+	if guildName != "" && !strings.EqualFold(t.Guild.Name, guildName) {
+		return false
+	}
+
+	// Holds longer than the simulation has run start at tick 0 with the rest kept in HeldBefore
+	if heldSeconds <= st.tick {
+		t.CapturedAt = st.tick - heldSeconds
+		t.HeldBefore = 0
+	} else {
+		t.CapturedAt = 0
+		t.HeldBefore = heldSeconds - st.tick
+	}
+	if t.TreasuryOverride == typedef.TreasuryOverrideNone {
+		t.Treasury = calculateTreasuryLevel(t)
+	}
+	updateGenerationBonus(t)
+	return true
+}
+
+// ProjectTreasury projects when each of the guild's territories reaches the remaining treasury levels
+// and how much generation each level adds, assuming the territory is held and its route stays as it is.
+func ProjectTreasury(guildTag string) ([]TreasuryProjection, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	if guildTag == "" {
+		return nil, fmt.Errorf("guild tag is empty")
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	if !st.runtimeOptions.TreasuryEnabled {
+		return nil, fmt.Errorf("treasury is disabled in the runtime options")
+	}
+
+	var projections []TreasuryProjection
+	for _, t := range TerritoryMap {
+		if t == nil {
+			continue
+		}
+
+		t.Mu.RLock()
+		if t.Guild.Tag != guildTag {
+			t.Mu.RUnlock()
+			continue
+		}
+
+		held := territoryHeldFor(t, st.tick)
+		p := TreasuryProjection{
+			Territory:  t.Name,
+			HeldFor:    held,
+			Level:      treasuryLevelFor(held),
+			Bonus:      t.GenerationBonus,
+			Overridden: t.TreasuryOverride != typedef.TreasuryOverrideNone,
+		}
+		if p.Overridden {
+			p.Level = t.Treasury
+		}
+		routed := len(t.TradingRoutes) > 0
+		if routed {
+			p.Distance = len(t.TradingRoutes[0])
+		}
+		if t.HQ {
+			p.Distance = 0
+		}
+		if p.Distance > 7 {
+			p.Distance = 7
+		}
+
+		// Generation without the treasury bonus, so each level's gain scales from it
+		base := t.ResourceGeneration.At.DivFloat(1 + t.GenerationBonus/100)
+
+		// An override pins the level, and a territory without a route to its HQ gets no bonus
+		if !p.Overridden && (routed || t.HQ) {
+			for _, threshold := range treasuryThresholds {
+				if threshold.Level <= p.Level {
+					continue
+				}
+				bonus := treasuryBonusFor(p.Distance, threshold.Level) * 100
+				m := TreasuryMilestone{
+					Level:     threshold.Level,
+					Name:      TreasuryLevelName(threshold.Level),
+					Tick:      st.tick + threshold.Seconds - p.HeldFor,
+					Remaining: threshold.Seconds - p.HeldFor,
+					Bonus:     bonus,
+					Gain:      base.MulFloat((bonus - p.Bonus) / 100),
+				}
+				p.Milestones = append(p.Milestones, m)
+			}
+		}
+		t.Mu.RUnlock()
+
+		projections = append(projections, p)
+	}
+
+	if len(projections) == 0 {
+		return nil, fmt.Errorf("guild %s owns no territories", guildTag)
+	}
+
+	sort.Slice(projections, func(i, j int) bool {
+		return projections[i].Territory < projections[j].Territory
+	})
+	return projections, nil
+}
+
+// TreasuryHQMoveImpact checks what moving the guild's HQ to newHQ does to its treasury bonuses.
+// The move is simulated on a fork, so live state is not modified. An empty TreasuryLowered in the
+// result means no territory loses treasury bonus.
+func TreasuryHQMoveImpact(guildTag, newHQ string) (*HQSimulation, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	newHQ = strings.TrimSpace(newHQ)
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	target := getTerritoryUnsafe(newHQ)
+	if target == nil {
+		return nil, fmt.Errorf("territory %s not found", newHQ)
+	}
+	target.Mu.RLock()
+	owner := target.Guild.Tag
+	target.Mu.RUnlock()
+	if guildTag == "" {
+		guildTag = owner
+	}
+	if owner != guildTag {
+		return nil, fmt.Errorf("territory %s is not owned by %s", newHQ, guildTag)
+	}
+
+	forked := make(map[string]*typedef.Territory, len(TerritoryMap))
+	var guildNames []string
+	for name, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		forked[name] = forkTerritory(territory)
+		territory.Mu.RUnlock()
+		if forked[name].Guild.Tag == guildTag {
+			guildNames = append(guildNames, name)
+		}
+	}
+	sort.Strings(guildNames)
+
+	result := simulateHQCandidate(snapshotSimulationEnvUnsafe(), forked, guildNames, generationBonuses(forked, guildNames), forked[newHQ], guildTag, getGuildAllies(guildTag), typedef.BasicResources{}, 0)
+	return &result, nil
+}
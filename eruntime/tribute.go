@@ -134,6 +134,10 @@ func AddTribute(tribute *typedef.ActiveTribute) error {
 	st.mu.Lock()
 	defer st.mu.Unlock()
 
+	if err := validateTributeSchedule(tribute, &tribute.Schedule); err != nil {
+		return err
+	}
+
 	st.activeTributes = append(st.activeTributes, tribute)
 	debugf("Added tribute %s from %s to %s\n", tribute.ID, tribute.FromGuildName, tribute.ToGuildName)
 
@@ -217,6 +221,11 @@ func EnableTribute(tributeID string) error {
 		if tribute.ID == tributeID {
 			tribute.IsActive = true
 			tribute.LastTransfer = 0 // Reset transfer history when re-enabling
+			if tribute.Schedule.Kind == typedef.TributeOneShot || tribute.Schedule.Kind == typedef.TributeLimited {
+				// Finished schedules start over, otherwise they would disable again immediately
+				tribute.TransferCount = 0
+				tribute.TotalSent = typedef.BasicResources{}
+			}
 			debugf("Enabled tribute %s\n", tributeID)
 
 			// Recalculate guild tribute totals
@@ -235,45 +244,7 @@ func UpdateTribute(tributeID string, amount *typedef.BasicResources, interval *u
 	if amount == nil && interval == nil {
 		return fmt.Errorf("no update payload provided")
 	}
-
-	st.mu.Lock()
-	defer st.mu.Unlock()
-
-	var tribute *typedef.ActiveTribute
-	for _, t := range st.activeTributes {
-		if t != nil && t.ID == tributeID {
-			tribute = t
-			break
-		}
-	}
-
-	if tribute == nil {
-		return fmt.Errorf("tribute with ID '%s' not found", tributeID)
-	}
-
-	if amount != nil {
-		if amount.Crops < 0 || amount.Fish < 0 || amount.Ores < 0 || amount.Wood < 0 || amount.Emeralds < 0 {
-			return fmt.Errorf("tribute amounts cannot be negative")
-		}
-		tribute.AmountPerHour = *amount
-		tribute.AmountPerMinute = typedef.BasicResources{
-			Emeralds: amount.Emeralds / 60.0,
-			Ores:     amount.Ores / 60.0,
-			Wood:     amount.Wood / 60.0,
-			Fish:     amount.Fish / 60.0,
-			Crops:    amount.Crops / 60.0,
-		}
-	}
-
-	if interval != nil {
-		if *interval == 0 {
-			return fmt.Errorf("interval minutes must be greater than 0")
-		}
-		tribute.IntervalMinutes = *interval
-	}
-
-	recalculateGuildTributes()
-	return nil
+	return EditTribute(tributeID, amount, interval, nil)
 }
 
 // processTributes handles tribute transfers on each 60-tick cycle (called from update2)
@@ -305,87 +276,97 @@ func (s *state) processTributes() {
 			continue
 		}
 
-		// Check if enough time has passed since last transfer
-		var shouldTransfer bool
-		if tribute.LastTransfer == 0 {
-			// First transfer - use creation time
-			minutesSinceCreation := currentMinute - (tribute.CreatedAt / 60)
-			shouldTransfer = minutesSinceCreation >= uint64(tribute.IntervalMinutes)
-		} else {
-			// Subsequent transfers - use last transfer time
-			minutesSinceLastTransfer := currentMinute - (tribute.LastTransfer / 60)
-			shouldTransfer = minutesSinceLastTransfer >= uint64(tribute.IntervalMinutes)
+		// Windows that have closed disable their tribute
+		if s.tributeFinished(tribute) {
+			s.finishTribute(tribute)
+			continue
 		}
 
-		if !shouldTransfer {
+		// Check if enough time has passed since last transfer (or the one-shot tick is reached)
+		if !s.tributeDue(tribute, currentMinute) {
 			debugf("Tribute %s not ready yet (interval: %d minutes), skipping\n",
 				tribute.ID, tribute.IntervalMinutes)
 			continue
 		}
 
+		// Conditional tributes wait for their storage condition and retry every minute
+		if !s.tributeConditionMet(tribute) {
+			debugf("Tribute %s condition not met, skipping\n", tribute.ID)
+			continue
+		}
+
 		debugf("Processing tribute %s at tick %d (minute %d)\n", tribute.ID, s.tick, currentMinute)
 
 		// Process this tribute transfer
-		err := s.processTributeTransfer(tribute)
+		amount := tributeTransferAmount(tribute)
+		sent, err := s.processTributeTransfer(tribute, amount)
 		if err != nil {
 			debugf("Error processing tribute %s: %v\n", tribute.ID, err)
+			s.recordTributeError(tribute, err.Error())
 			continue
 		}
+		tribute.LastError = ""
 
 		// Update last transfer time
 		tribute.LastTransfer = s.tick
 		debugf("Updated LastTransfer for tribute %s to tick %d\n", tribute.ID, tribute.LastTransfer)
+
+		if sent {
+			recordTributeTransfer(tribute, amount)
+			if s.tributeFinished(tribute) {
+				s.finishTribute(tribute)
+			}
+		}
 	}
 }
 
-// processTributeTransfer handles a single tribute transfer
-func (s *state) processTributeTransfer(tribute *typedef.ActiveTribute) error {
+// finishTribute disables a tribute whose schedule has completed.
+// Caller must hold st.mu.
+func (s *state) finishTribute(tribute *typedef.ActiveTribute) {
+	tribute.IsActive = false
+	debugf("Tribute %s finished its schedule after %d transfers\n", tribute.ID, tribute.TransferCount)
+	recalculateGuildTributes()
+}
+
+// processTributeTransfer handles a single tribute transfer of the given amount.
+// It reports whether resources were actually moved; a sender short on resources skips the transfer without an error.
+func (s *state) processTributeTransfer(tribute *typedef.ActiveTribute, amount typedef.BasicResources) (bool, error) {
 	debugf("Processing tribute %s: %s -> %s\n", tribute.ID, tribute.FromGuildName, tribute.ToGuildName)
 	debugf("Tribute pointers: From=%v, To=%v\n", tribute.From != nil, tribute.To != nil)
 
 	// Case 1: Resource spawn (From is nil, To is specified)
 	if tribute.From == nil && tribute.To != nil {
 		debugf("Processing as resource spawn (case 1)\n")
-		return s.spawnResourcesToGuild(tribute)
+		return s.spawnResourcesToGuild(tribute, amount)
 	}
 
 	// Case 2: Resource sink (From is specified, To is nil)
 	if tribute.From != nil && tribute.To == nil {
 		debugf("Processing as resource sink (case 2)\n")
-		return s.removeResourcesFromGuild(tribute)
+		return s.removeResourcesFromGuild(tribute, amount)
 	}
 
 	// Case 3: Guild to guild transfer (both specified)
 	if tribute.From != nil && tribute.To != nil {
 		debugf("Processing as guild-to-guild transfer (case 3)\n")
-		return s.transferResourcesBetweenGuilds(tribute)
+		return s.transferResourcesBetweenGuilds(tribute, amount)
 	}
 
 	debugf("Invalid tribute configuration: From=%v, To=%v\n", tribute.From != nil, tribute.To != nil)
-	return fmt.Errorf("invalid tribute configuration: both guilds cannot be nil")
+	return false, fmt.Errorf("invalid tribute configuration: both guilds cannot be nil")
 }
 
 // spawnResourcesToGuild adds resources directly to a guild's HQ
-func (s *state) spawnResourcesToGuild(tribute *typedef.ActiveTribute) error {
+func (s *state) spawnResourcesToGuild(tribute *typedef.ActiveTribute, actualAmount typedef.BasicResources) (bool, error) {
 	toHQ := s.findGuildHQ(tribute.To)
 	if toHQ == nil {
 		debugf("Guild %s has no HQ, cannot spawn resources\n", tribute.To.Name)
-		return fmt.Errorf("guild %s has no HQ", tribute.To.Name)
+		return false, fmt.Errorf("guild %s has no HQ", tribute.To.Name)
 	}
 
 	toHQ.Mu.Lock()
 	defer toHQ.Mu.Unlock()
 
-	// Calculate actual amount to transfer based on interval
-	// This gives us the proper amount for the time interval
-	actualAmount := typedef.BasicResources{
-		Emeralds: tribute.AmountPerMinute.Emeralds * float64(tribute.IntervalMinutes),
-		Ores:     tribute.AmountPerMinute.Ores * float64(tribute.IntervalMinutes),
-		Wood:     tribute.AmountPerMinute.Wood * float64(tribute.IntervalMinutes),
-		Fish:     tribute.AmountPerMinute.Fish * float64(tribute.IntervalMinutes),
-		Crops:    tribute.AmountPerMinute.Crops * float64(tribute.IntervalMinutes),
-	}
-
 	// Add resources to HQ storage
 	toHQ.Storage.At.Emeralds += actualAmount.Emeralds
 	toHQ.Storage.At.Ores += actualAmount.Ores
@@ -394,29 +375,20 @@ func (s *state) spawnResourcesToGuild(tribute *typedef.ActiveTribute) error {
 	toHQ.Storage.At.Crops += actualAmount.Crops
 
 	debugf("Spawned %+v resources to %s HQ (%s)\n", actualAmount, tribute.To.Name, toHQ.Name)
-	return nil
+	return true, nil
 }
 
 // removeResourcesFromGuild removes resources from a guild's HQ
-func (s *state) removeResourcesFromGuild(tribute *typedef.ActiveTribute) error {
+func (s *state) removeResourcesFromGuild(tribute *typedef.ActiveTribute, actualAmount typedef.BasicResources) (bool, error) {
 	fromHQ := s.findGuildHQ(tribute.From)
 	if fromHQ == nil {
 		debugf("Guild %s has no HQ, cannot remove resources\n", tribute.From.Name)
-		return fmt.Errorf("guild %s has no HQ", tribute.From.Name)
+		return false, fmt.Errorf("guild %s has no HQ", tribute.From.Name)
 	}
 
 	fromHQ.Mu.Lock()
 	defer fromHQ.Mu.Unlock()
 
-	// Calculate actual amount to remove based on interval
-	actualAmount := typedef.BasicResources{
-		Emeralds: tribute.AmountPerMinute.Emeralds * float64(tribute.IntervalMinutes),
-		Ores:     tribute.AmountPerMinute.Ores * float64(tribute.IntervalMinutes),
-		Wood:     tribute.AmountPerMinute.Wood * float64(tribute.IntervalMinutes),
-		Fish:     tribute.AmountPerMinute.Fish * float64(tribute.IntervalMinutes),
-		Crops:    tribute.AmountPerMinute.Crops * float64(tribute.IntervalMinutes),
-	}
-
 	// Check if guild has enough resources
 	if fromHQ.Storage.At.Emeralds < actualAmount.Emeralds ||
 		fromHQ.Storage.At.Ores < actualAmount.Ores ||
@@ -424,7 +396,7 @@ func (s *state) removeResourcesFromGuild(tribute *typedef.ActiveTribute) error {
 		fromHQ.Storage.At.Fish < actualAmount.Fish ||
 		fromHQ.Storage.At.Crops < actualAmount.Crops {
 		debugf("Guild %s HQ (%s) doesn't have enough resources for tribute %s\n", tribute.From.Name, fromHQ.Name, tribute.ID)
-		return nil // Don't error, just skip this transfer
+		return false, nil // Don't error, just skip this transfer
 	}
 
 	// Remove resources from HQ storage
@@ -435,21 +407,21 @@ func (s *state) removeResourcesFromGuild(tribute *typedef.ActiveTribute) error {
 	fromHQ.Storage.At.Crops -= actualAmount.Crops
 
 	debugf("Removed %+v resources from %s HQ (%s)\n", actualAmount, tribute.From.Name, fromHQ.Name)
-	return nil
+	return true, nil
 }
 
 // transferResourcesBetweenGuilds handles guild-to-guild resource transfer
-func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute) error {
+func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute, actualAmount typedef.BasicResources) (bool, error) {
 	debugf("transferResourcesBetweenGuilds: Processing tribute %s from guild %s to guild %s\n",
 		tribute.ID, tribute.FromGuildName, tribute.ToGuildName)
 
 	if tribute.From == nil {
 		debugf("transferResourcesBetweenGuilds: tribute.From is nil for guild name %s\n", tribute.FromGuildName)
-		return fmt.Errorf("source guild pointer is nil for guild %s", tribute.FromGuildName)
+		return false, fmt.Errorf("source guild pointer is nil for guild %s", tribute.FromGuildName)
 	}
 	if tribute.To == nil {
 		debugf("transferResourcesBetweenGuilds: tribute.To is nil for guild name %s\n", tribute.ToGuildName)
-		return fmt.Errorf("destination guild pointer is nil for guild %s", tribute.ToGuildName)
+		return false, fmt.Errorf("destination guild pointer is nil for guild %s", tribute.ToGuildName)
 	}
 
 	fromHQ := s.findGuildHQ(tribute.From)
@@ -458,26 +430,17 @@ func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute) e
 	if fromHQ == nil {
 		debugf("transferResourcesBetweenGuilds: No HQ found for source guild %s (tag: %s)\n",
 			tribute.From.Name, tribute.From.Tag)
-		return fmt.Errorf("source guild %s has no HQ", tribute.From.Name)
+		return false, fmt.Errorf("source guild %s has no HQ", tribute.From.Name)
 	}
 	if toHQ == nil {
 		debugf("transferResourcesBetweenGuilds: No HQ found for destination guild %s (tag: %s)\n",
 			tribute.To.Name, tribute.To.Tag)
-		return fmt.Errorf("destination guild %s has no HQ", tribute.To.Name)
+		return false, fmt.Errorf("destination guild %s has no HQ", tribute.To.Name)
 	}
 
 	debugf("transferResourcesBetweenGuilds: Found HQs - From: %s (%s), To: %s (%s)\n",
 		fromHQ.Name, fromHQ.Guild.Name, toHQ.Name, toHQ.Guild.Name)
 
-	// Calculate actual amount to transfer based on interval
-	actualAmount := typedef.BasicResources{
-		Emeralds: tribute.AmountPerMinute.Emeralds * float64(tribute.IntervalMinutes),
-		Ores:     tribute.AmountPerMinute.Ores * float64(tribute.IntervalMinutes),
-		Wood:     tribute.AmountPerMinute.Wood * float64(tribute.IntervalMinutes),
-		Fish:     tribute.AmountPerMinute.Fish * float64(tribute.IntervalMinutes),
-		Crops:    tribute.AmountPerMinute.Crops * float64(tribute.IntervalMinutes),
-	}
-
 	// Check if source guild has enough resources
 	fromHQ.Mu.RLock()
 	hasEnoughResources := fromHQ.Storage.At.Emeralds >= actualAmount.Emeralds &&
@@ -490,7 +453,11 @@ func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute) e
 	if !hasEnoughResources {
 		debugf("Guild %s HQ (%s) doesn't have enough resources for tribute to %s\n",
 			tribute.From.Name, fromHQ.Name, tribute.To.Name)
-		return nil // Don't error, just skip this transfer
+		return false, nil // Don't error, just skip this transfer
+	}
+
+	if s.runtimeOptions.DirectTributes {
+		return true, s.deliverTributeDirect(tribute, fromHQ, toHQ, actualAmount)
 	}
 
 	// Find route from source HQ to destination HQ
@@ -498,7 +465,8 @@ func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute) e
 	if route == nil {
 		debugf("No route found from %s HQ to %s HQ for tribute %s\n",
 			tribute.From.Name, tribute.To.Name, tribute.ID)
-		return fmt.Errorf("no route found between guild HQs")
+		return false, fmt.Errorf("no route found from %s HQ (%s) to %s HQ (%s)",
+			tribute.From.Name, fromHQ.Name, tribute.To.Name, toHQ.Name)
 	}
 
 	// Remove resources from source HQ
@@ -510,15 +478,75 @@ func (s *state) transferResourcesBetweenGuilds(tribute *typedef.ActiveTribute) e
 	fromHQ.Storage.At.Crops -= actualAmount.Crops
 	fromHQ.Mu.Unlock()
 
-	// Create transit using existing transit system
-	transitID := s.transitManager.StartTransit(actualAmount, fromHQ.ID, toHQ.ID, route)
+	// Create a tribute transit; it pays taxes along the route and arrives after one minute per hop
+	transitID := s.transitManager.StartTributeTransit(tribute.ID, actualAmount, fromHQ.ID, toHQ.ID, route)
 
 	debugf("Created tribute transit %s from %s to %s with %+v resources\n",
 		transitID, tribute.From.Name, tribute.To.Name, actualAmount)
 
+	return true, nil
+}
+
+// deliverTributeDirect moves tribute resources straight from one HQ to another without travelling
+func (s *state) deliverTributeDirect(tribute *typedef.ActiveTribute, fromHQ, toHQ *typedef.Territory, amount typedef.BasicResources) error {
+	fromHQ.Mu.Lock()
+	fromHQ.Storage.At.Emeralds -= amount.Emeralds
+	fromHQ.Storage.At.Ores -= amount.Ores
+	fromHQ.Storage.At.Wood -= amount.Wood
+	fromHQ.Storage.At.Fish -= amount.Fish
+	fromHQ.Storage.At.Crops -= amount.Crops
+	fromHQ.Mu.Unlock()
+
+	toHQ.Mu.Lock()
+	toHQ.Storage.At.Emeralds += amount.Emeralds
+	toHQ.Storage.At.Ores += amount.Ores
+	toHQ.Storage.At.Wood += amount.Wood
+	toHQ.Storage.At.Fish += amount.Fish
+	toHQ.Storage.At.Crops += amount.Crops
+	toHQ.Mu.Unlock()
+
+	debugf("Delivered tribute %s directly from %s to %s with %+v resources\n",
+		tribute.ID, tribute.From.Name, tribute.To.Name, amount)
 	return nil
 }
 
+// recordTributeError marks a tribute as undeliverable and notifies listeners when the reason changes.
+// Caller must hold st.mu.
+func (s *state) recordTributeError(tribute *typedef.ActiveTribute, message string) {
+	changed := tribute.LastError != message
+	tribute.LastError = message
+	tribute.LastErrorTick = s.tick
+
+	if changed && tributeErrorCallback != nil {
+		go tributeErrorCallback(tribute.ID, message)
+	}
+}
+
+// recordTributeErrorByID is recordTributeError for callers that only know the tribute ID (e.g. transits).
+// Caller must hold st.mu.
+func (s *state) recordTributeErrorByID(tributeID, message string) {
+	for _, tribute := range s.activeTributes {
+		if tribute != nil && tribute.ID == tributeID {
+			s.recordTributeError(tribute, message)
+			return
+		}
+	}
+}
+
+// GetUndeliverableTributes returns tributes whose last transfer could not be delivered
+func GetUndeliverableTributes() []*typedef.ActiveTribute {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	var result []*typedef.ActiveTribute
+	for _, tribute := range st.activeTributes {
+		if tribute != nil && tribute.LastError != "" {
+			result = append(result, tribute)
+		}
+	}
+	return result
+}
+
 // findGuildHQ finds the HQ territory for a guild
 func (s *state) findGuildHQ(guild *typedef.Guild) *typedef.Territory {
 	if guild == nil {
@@ -665,6 +693,11 @@ func recalculateGuildTributes() {
 			continue
 		}
 
+		// One-shot tributes are a lump sum, not a rate
+		if tribute.Schedule.Kind == typedef.TributeOneShot {
+			continue
+		}
+
 		// For UI display, we want to show the per-minute rate
 		// This is already calculated and stored in AmountPerMinute
 		minuteAmount := tribute.AmountPerMinute
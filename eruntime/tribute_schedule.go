@@ -0,0 +1,256 @@
+package eruntime
+
+import (
+	"RueaES/typedef"
+	"fmt"
+)
+
+// SetTributeSchedule replaces the schedule of an existing tribute.
+// Transfer counters are reset so limited and one-shot tributes start over.
+func SetTributeSchedule(tributeID string, schedule typedef.TributeSchedule) error {
+	return EditTribute(tributeID, nil, nil, &schedule)
+}
+
+// EditTribute changes the amount, interval and schedule of an existing tribute at once. Nil fields
+// are left unchanged, and nothing changes unless every given field is valid. A new schedule resets
+// the transfer counters like SetTributeSchedule.
+func EditTribute(tributeID string, amount *typedef.BasicResources, interval *uint32, schedule *typedef.TributeSchedule) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var tribute *typedef.ActiveTribute
+	for _, t := range st.activeTributes {
+		if t != nil && t.ID == tributeID {
+			tribute = t
+			break
+		}
+	}
+	if tribute == nil {
+		return fmt.Errorf("tribute with ID '%s' not found", tributeID)
+	}
+
+	if amount != nil && (amount.Crops < 0 || amount.Fish < 0 || amount.Ores < 0 || amount.Wood < 0 || amount.Emeralds < 0) {
+		return fmt.Errorf("tribute amounts cannot be negative")
+	}
+	if interval != nil && *interval == 0 {
+		return fmt.Errorf("interval minutes must be greater than 0")
+	}
+	if schedule != nil {
+		if err := validateTributeSchedule(tribute, schedule); err != nil {
+			return err
+		}
+	}
+
+	if amount != nil {
+		tribute.AmountPerHour = *amount
+		tribute.AmountPerMinute = typedef.BasicResources{
+			Emeralds: amount.Emeralds / 60.0,
+			Ores:     amount.Ores / 60.0,
+			Wood:     amount.Wood / 60.0,
+			Fish:     amount.Fish / 60.0,
+			Crops:    amount.Crops / 60.0,
+		}
+	}
+	if interval != nil {
+		tribute.IntervalMinutes = *interval
+	}
+	if schedule != nil {
+		tribute.Schedule = *schedule
+		tribute.TransferCount = 0
+		tribute.TotalSent = typedef.BasicResources{}
+		tribute.LastError = ""
+		debugf("Set schedule of tribute %s to %+v\n", tributeID, *schedule)
+	}
+	recalculateGuildTributes()
+	return nil
+}
+
+// disableInvalidTributeSchedules disables loaded tributes whose schedule doesn't validate and
+// resets the schedule, so a hand-edited or damaged save can't reach the transfer logic or the
+// UI with an unknown kind or condition. Caller must hold st.mu.
+func disableInvalidTributeSchedules() {
+	for _, tribute := range st.activeTributes {
+		if tribute == nil {
+			continue
+		}
+		if err := validateTributeSchedule(tribute, &tribute.Schedule); err != nil {
+			fmt.Printf("[STATE] Disabling tribute %s: %v\n", tribute.ID, err)
+			tribute.Schedule = typedef.TributeSchedule{}
+			tribute.IsActive = false
+			tribute.LastError = "Invalid schedule: " + err.Error()
+		}
+	}
+}
+
+// validateTributeSchedule checks a schedule against the tribute it will be attached to
+// and normalizes the resource name of conditional schedules.
+func validateTributeSchedule(tribute *typedef.ActiveTribute, schedule *typedef.TributeSchedule) error {
+	switch schedule.Kind {
+	case typedef.TributeRecurring, typedef.TributeOneShot:
+		return nil
+
+	case typedef.TributeLimited:
+		if schedule.MaxTotal < 0 {
+			return fmt.Errorf("limited tribute total cannot be negative")
+		}
+		if schedule.MaxTransfers == 0 && schedule.MaxTotal == 0 {
+			return fmt.Errorf("limited tribute needs a transfer count or a total amount")
+		}
+		return nil
+
+	case typedef.TributeWindowed:
+		if schedule.WindowEnd != 0 && schedule.WindowEnd <= schedule.WindowStart {
+			return fmt.Errorf("tribute window must end after it starts")
+		}
+		return nil
+
+	case typedef.TributeConditional:
+		schedule.Resource = normalizeForecastResource(schedule.Resource)
+		switch schedule.Resource {
+		case "emeralds", "ores", "wood", "fish", "crops":
+		default:
+			return fmt.Errorf("unknown resource '%s' for tribute condition", schedule.Resource)
+		}
+		if schedule.Threshold < 0 {
+			return fmt.Errorf("tribute condition threshold cannot be negative")
+		}
+		switch schedule.Condition {
+		case typedef.TributeSenderAbove:
+			if tribute.FromGuildName == "" {
+				return fmt.Errorf("sender condition needs a tribute with a source guild")
+			}
+		case typedef.TributeReceiverBelow:
+			if tribute.ToGuildName == "" {
+				return fmt.Errorf("receiver condition needs a tribute with a destination guild")
+			}
+		default:
+			return fmt.Errorf("unknown tribute condition %d", schedule.Condition)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown tribute kind %d", schedule.Kind)
+	}
+}
+
+// tributeDue reports whether a tribute should transfer at the current minute.
+// Caller must hold st.mu.
+func (s *state) tributeDue(tribute *typedef.ActiveTribute, currentMinute uint64) bool {
+	schedule := &tribute.Schedule
+
+	switch schedule.Kind {
+	case typedef.TributeOneShot:
+		// Retries every minute until the transfer goes through
+		return s.tick >= schedule.FireAt
+
+	case typedef.TributeWindowed:
+		if s.tick < schedule.WindowStart {
+			return false
+		}
+	}
+
+	if tribute.LastTransfer == 0 {
+		// First transfer - use creation time
+		return currentMinute-(tribute.CreatedAt/60) >= uint64(tribute.IntervalMinutes)
+	}
+	// Subsequent transfers - use last transfer time
+	return currentMinute-(tribute.LastTransfer/60) >= uint64(tribute.IntervalMinutes)
+}
+
+// tributeFinished reports whether a tribute's schedule has run its course.
+func (s *state) tributeFinished(tribute *typedef.ActiveTribute) bool {
+	schedule := &tribute.Schedule
+
+	switch schedule.Kind {
+	case typedef.TributeOneShot:
+		return tribute.TransferCount > 0
+
+	case typedef.TributeLimited:
+		if schedule.MaxTransfers > 0 && tribute.TransferCount >= schedule.MaxTransfers {
+			return true
+		}
+		return schedule.MaxTotal > 0 && basicResourcesTotal(tribute.TotalSent) >= schedule.MaxTotal
+
+	case typedef.TributeWindowed:
+		return schedule.WindowEnd != 0 && s.tick >= schedule.WindowEnd
+	}
+	return false
+}
+
+// tributeConditionMet checks the storage condition of a conditional tribute.
+// Non-conditional tributes always pass. Caller must hold st.mu.
+func (s *state) tributeConditionMet(tribute *typedef.ActiveTribute) bool {
+	schedule := &tribute.Schedule
+	if schedule.Kind != typedef.TributeConditional {
+		return true
+	}
+
+	var guild *typedef.Guild
+	if schedule.Condition == typedef.TributeSenderAbove {
+		guild = tribute.From
+	} else {
+		guild = tribute.To
+	}
+	hq := s.findGuildHQ(guild)
+	if hq == nil {
+		return false
+	}
+
+	hq.Mu.RLock()
+	stored := resourceValue(hq.Storage.At, schedule.Resource)
+	hq.Mu.RUnlock()
+
+	if schedule.Condition == typedef.TributeSenderAbove {
+		return stored > schedule.Threshold
+	}
+	return stored < schedule.Threshold
+}
+
+// tributeTransferAmount returns the resources a tribute sends in its next transfer.
+// One-shot tributes send AmountPerHour as a lump sum; limited tributes are trimmed to their remaining total.
+func tributeTransferAmount(tribute *typedef.ActiveTribute) typedef.BasicResources {
+	if tribute.Schedule.Kind == typedef.TributeOneShot {
+		return tribute.AmountPerHour
+	}
+
+	interval := float64(tribute.IntervalMinutes)
+	amount := typedef.BasicResources{
+		Emeralds: tribute.AmountPerMinute.Emeralds * interval,
+		Ores:     tribute.AmountPerMinute.Ores * interval,
+		Wood:     tribute.AmountPerMinute.Wood * interval,
+		Fish:     tribute.AmountPerMinute.Fish * interval,
+		Crops:    tribute.AmountPerMinute.Crops * interval,
+	}
+
+	if tribute.Schedule.Kind == typedef.TributeLimited && tribute.Schedule.MaxTotal > 0 {
+		remaining := tribute.Schedule.MaxTotal - basicResourcesTotal(tribute.TotalSent)
+		total := basicResourcesTotal(amount)
+		if remaining <= 0 {
+			return typedef.BasicResources{}
+		}
+		if total > remaining {
+			scale := remaining / total
+			amount.Emeralds *= scale
+			amount.Ores *= scale
+			amount.Wood *= scale
+			amount.Fish *= scale
+			amount.Crops *= scale
+		}
+	}
+
+	return amount
+}
+
+// recordTributeTransfer updates a tribute's counters after a transfer went through
+func recordTributeTransfer(tribute *typedef.ActiveTribute, amount typedef.BasicResources) {
+	tribute.TransferCount++
+	tribute.TotalSent.Emeralds += amount.Emeralds
+	tribute.TotalSent.Ores += amount.Ores
+	tribute.TotalSent.Wood += amount.Wood
+	tribute.TotalSent.Fish += amount.Fish
+	tribute.TotalSent.Crops += amount.Crops
+}
+
+func basicResourcesTotal(res typedef.BasicResources) float64 {
+	return res.Emeralds + res.Ores + res.Wood + res.Fish + res.Crops
+}
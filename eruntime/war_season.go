@@ -0,0 +1,468 @@
+package eruntime
+
+import (
+	"RueaES/typedef"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// AttackModel decides which territory an attack event targets.
+type AttackModel string
+
+const (
+	AttackUniform AttackModel = "uniform" // Every held territory is equally likely
+	AttackBorder  AttackModel = "border"  // Weighted by the number of hostile neighbours
+	AttackValue   AttackModel = "value"   // Weighted by base production
+)
+
+const (
+	defaultWarSeasonHours   = 72
+	defaultWarSeasonRuns    = 200
+	defaultAttacksPerHour   = 2
+	maxWarSeasonHours       = 24 * 30
+	maxWarSeasonRuns        = 5000
+	warSeasonMostLostLength = 10
+)
+
+// WarSeasonConfig configures a Monte Carlo war season. Zero values use the defaults.
+type WarSeasonConfig struct {
+	GuildTag       string            `json:"guildTag"`
+	Hours          int               `json:"hours"`
+	Runs           int               `json:"runs"`
+	Seed           int64             `json:"seed"`
+	Model          AttackModel       `json:"model"`
+	AttacksPerHour float64           `json:"attacksPerHour"` // Mean number of attacks on the guild each hour
+	RetakeHours    int               `json:"retakeHours"`    // Hours until a lost territory is retaken, 0 to never retake
+	Party          *typedef.WarParty `json:"party,omitempty"`
+}
+
+// WarSeasonReport aggregates the outcome of every simulated season.
+// Income is the net that reaches the HQ after route tax.
+type WarSeasonReport struct {
+	Config WarSeasonConfig `json:"config"`
+
+	BaselineIncome        typedef.BasicResources `json:"baselineIncome"` // Income over the period without attacks
+	ExpectedIncome        typedef.BasicResources `json:"expectedIncome"` // Mean income over the period
+	ExpectedIncomePerHour typedef.BasicResources `json:"expectedIncomePerHour"`
+	EmeraldsP10           float64                `json:"emeraldsP10"` // Emerald income of the worst tenth of seasons
+	EmeraldsP90           float64                `json:"emeraldsP90"`
+
+	HQSurvival      float64 `json:"hqSurvival"`      // Share of seasons in which the original HQ was never captured
+	MeanHQLossHour  float64 `json:"meanHQLossHour"`  // Mean hour of the HQ loss in seasons where it fell, -1 if it never did
+	ExpectedAttacks float64 `json:"expectedAttacks"` // Mean attacks per season
+	ExpectedLosses  float64 `json:"expectedLosses"`  // Mean captures per season
+
+	MostLost []WarSeasonLoss `json:"mostLost"`
+}
+
+// WarSeasonLoss is how often a territory fell across the simulated seasons.
+type WarSeasonLoss struct {
+	Territory      string  `json:"territory"`
+	LossRate       float64 `json:"lossRate"`       // Share of seasons in which it was captured at least once
+	ExpectedLosses float64 `json:"expectedLosses"` // Mean captures per season
+}
+
+// warSeason holds the snapshot shared by every simulated season
+type warSeason struct {
+	cfg        WarSeasonConfig
+	base       map[string]*typedef.Territory
+	guildNames []string
+	hq         string
+	allies     []string
+	party      typedef.WarParty
+	startTick  uint64
+	env        *simulationEnv
+	chance     map[captureKey]float64
+}
+
+// captureKey is what a capture chance depends on during a season. The tower bonuses follow the
+// HQ and the held neighbours, which change as the claim is captured and retaken.
+type captureKey struct {
+	name      string
+	hq        bool
+	direct    int
+	externals int
+}
+
+// warSeasonRun is the outcome of one simulated season
+type warSeasonRun struct {
+	income     typedef.BasicResources
+	attacks    int
+	captures   map[string]int
+	hqLostHour int
+}
+
+// SimulateWarSeason replays the guild's economy over a period of random attacks, many times over,
+// and aggregates the results. Each capture resets the territory's treasury when it is retaken and
+// reroutes the rest of the claim. Live state is not modified.
+func SimulateWarSeason(cfg WarSeasonConfig) (*WarSeasonReport, error) {
+	cfg.GuildTag = strings.TrimSpace(cfg.GuildTag)
+	if cfg.GuildTag == "" || cfg.GuildTag == "NONE" {
+		return nil, fmt.Errorf("guild tag cannot be empty")
+	}
+	if cfg.Hours <= 0 {
+		cfg.Hours = defaultWarSeasonHours
+	}
+	if cfg.Runs <= 0 {
+		cfg.Runs = defaultWarSeasonRuns
+	}
+	cfg.Hours = min(cfg.Hours, maxWarSeasonHours)
+	cfg.Runs = min(cfg.Runs, maxWarSeasonRuns)
+	if cfg.Seed == 0 {
+		cfg.Seed = 1
+	}
+	if cfg.AttacksPerHour <= 0 {
+		cfg.AttacksPerHour = defaultAttacksPerHour
+	}
+	switch cfg.Model {
+	case AttackUniform, AttackBorder, AttackValue:
+	case "":
+		cfg.Model = AttackBorder
+	default:
+		return nil, fmt.Errorf("unknown attack model '%s'", cfg.Model)
+	}
+	if cfg.RetakeHours < 0 {
+		cfg.RetakeHours = 0
+	}
+
+	// Fork the claim, costs, options and routes under the lock and run the season on the fork, so
+	// the runs don't hold up the tick loop
+	st.mu.RLock()
+	hq := getHQFromMap(cfg.GuildTag)
+	if hq == nil {
+		st.mu.RUnlock()
+		return nil, fmt.Errorf("guild %s has no HQ", cfg.GuildTag)
+	}
+
+	season := &warSeason{
+		cfg:       cfg,
+		base:      make(map[string]*typedef.Territory, len(TerritoryMap)),
+		hq:        hq.Name,
+		allies:    getGuildAllies(cfg.GuildTag),
+		party:     combatParty(cfg.Party),
+		startTick: st.tick,
+		env:       snapshotSimulationEnvUnsafe(),
+		chance:    make(map[captureKey]float64),
+	}
+	for name, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		season.base[name] = forkTerritory(territory)
+		territory.Mu.RUnlock()
+		if season.base[name].Guild.Tag == cfg.GuildTag {
+			season.guildNames = append(season.guildNames, name)
+		}
+	}
+	st.mu.RUnlock()
+	sort.Strings(season.guildNames)
+
+	report := &WarSeasonReport{Config: cfg, MeanHQLossHour: -1}
+	report.BaselineIncome = season.run(nil).income
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	lostIn := make(map[string]int)
+	captures := make(map[string]int)
+	emeralds := make([]float64, 0, cfg.Runs)
+	hqSurvived, hqLossHours, hqLosses := 0, 0, 0
+
+	for i := 0; i < cfg.Runs; i++ {
+		result := season.run(rng)
+		report.ExpectedIncome = report.ExpectedIncome.Add(&result.income)
+		emeralds = append(emeralds, result.income.Emeralds)
+		report.ExpectedAttacks += float64(result.attacks)
+		if result.hqLostHour < 0 {
+			hqSurvived++
+		} else {
+			hqLossHours += result.hqLostHour
+			hqLosses++
+		}
+		for name, count := range result.captures {
+			lostIn[name]++
+			captures[name] += count
+			report.ExpectedLosses += float64(count)
+		}
+	}
+
+	runs := float64(cfg.Runs)
+	report.ExpectedIncome = report.ExpectedIncome.MulFloat(1 / runs)
+	report.ExpectedIncomePerHour = report.ExpectedIncome.MulFloat(1 / float64(cfg.Hours))
+	report.ExpectedAttacks /= runs
+	report.ExpectedLosses /= runs
+	report.HQSurvival = float64(hqSurvived) / runs
+	if hqLosses > 0 {
+		report.MeanHQLossHour = float64(hqLossHours) / float64(hqLosses)
+	}
+
+	sort.Float64s(emeralds)
+	report.EmeraldsP10 = emeralds[int(float64(len(emeralds)-1)*0.1)]
+	report.EmeraldsP90 = emeralds[int(float64(len(emeralds)-1)*0.9)]
+
+	for name, count := range lostIn {
+		report.MostLost = append(report.MostLost, WarSeasonLoss{
+			Territory:      name,
+			LossRate:       float64(count) / runs,
+			ExpectedLosses: float64(captures[name]) / runs,
+		})
+	}
+	sort.Slice(report.MostLost, func(i, j int) bool {
+		a, b := report.MostLost[i], report.MostLost[j]
+		if a.LossRate != b.LossRate {
+			return a.LossRate > b.LossRate
+		}
+		if a.ExpectedLosses != b.ExpectedLosses {
+			return a.ExpectedLosses > b.ExpectedLosses
+		}
+		return a.Territory < b.Territory
+	})
+	if len(report.MostLost) > warSeasonMostLostLength {
+		report.MostLost = report.MostLost[:warSeasonMostLostLength]
+	}
+
+	return report, nil
+}
+
+// run simulates one season hour by hour. A nil rng runs the season without attacks.
+func (ws *warSeason) run(rng *rand.Rand) warSeasonRun {
+	result := warSeasonRun{captures: make(map[string]int), hqLostHour: -1}
+
+	territories := make(map[string]*typedef.Territory, len(ws.base))
+	for name, territory := range ws.base {
+		territories[name] = forkTerritory(territory)
+	}
+	owned := make(map[string]bool, len(ws.guildNames))
+	for _, name := range ws.guildNames {
+		owned[name] = true
+	}
+	lostAt := make(map[string]int)
+	routeTax := make(map[string]float64)
+	hq := ws.hq
+	reroute := true
+
+	for hour := 0; hour < ws.cfg.Hours; hour++ {
+		tick := ws.startTick + uint64(hour)*3600
+
+		// Lost territories are taken back with a fresh treasury
+		if ws.cfg.RetakeHours > 0 {
+			for _, name := range ws.guildNames {
+				at, lost := lostAt[name]
+				if !lost || hour-at < ws.cfg.RetakeHours {
+					continue
+				}
+				t := territories[name]
+				t.Guild = ws.base[name].Guild
+				t.Border = ws.base[name].Border
+				t.CapturedAt = tick
+				t.HeldBefore = 0
+				owned[name] = true
+				delete(lostAt, name)
+				if hq == "" {
+					hq = name
+					t.HQ = true
+				}
+				reroute = true
+			}
+		}
+
+		if rng != nil {
+			attacks := poisson(rng, ws.cfg.AttacksPerHour)
+			result.attacks += attacks
+			for i := 0; i < attacks; i++ {
+				target := ws.pickTarget(rng, territories, owned)
+				if target == "" {
+					break
+				}
+				if rng.Float64() >= ws.captureChance(territories[target], owned) {
+					continue
+				}
+
+				t := territories[target]
+				t.Guild = typedef.Guild{Name: "No Guild", Tag: "NONE"}
+				t.Border = typedef.BorderClosed
+				owned[target] = false
+				lostAt[target] = hour
+				result.captures[target]++
+				if target == hq {
+					t.HQ = false
+					if target == ws.hq && result.hqLostHour < 0 {
+						result.hqLostHour = hour
+					}
+					hq = ws.relocateHQ(territories, owned)
+				}
+				reroute = true
+			}
+		}
+
+		if reroute {
+			ws.reroute(territories, owned, hq, routeTax)
+			reroute = false
+		}
+
+		for _, name := range ws.guildNames {
+			if !owned[name] {
+				continue
+			}
+			t := territories[name]
+			if t.TreasuryOverride == typedef.TreasuryOverrideNone {
+				t.Treasury = treasuryLevelFor(territoryHeldFor(t, tick))
+			}
+			updateGenerationBonusWith(&ws.env.options, t)
+			generation, _ := calculateResourceGenerationWith(&ws.env.costs, t, t.Options.Bonus.At.ResourceRate, t.Options.Bonus.At.EmeraldRate,
+				t.Options.Bonus.At.EfficientResource, t.Options.Bonus.At.EfficientEmerald)
+			net := generation.Sub(&t.Costs)
+
+			switch {
+			case name == hq:
+				result.income = result.income.Add(&net)
+			case len(t.TradingRoutes) > 0:
+				result.income = addRoutedNet(result.income, net, routeTax[name])
+			}
+		}
+	}
+
+	return result
+}
+
+// reroute recomputes the route of every held territory to the current HQ
+func (ws *warSeason) reroute(territories map[string]*typedef.Territory, owned map[string]bool, hq string, routeTax map[string]float64) {
+	hqTerritory := territories[hq]
+	for _, name := range ws.guildNames {
+		t := territories[name]
+		t.TradingRoutes = nil
+		if !owned[name] || name == hq {
+			continue
+		}
+		if route, tax, ok := whatIfRouteWith(ws.env, t, hqTerritory, territories, ws.cfg.GuildTag, ws.allies); ok {
+			t.TradingRoutes = [][]*typedef.Territory{route}
+			routeTax[name] = tax
+		}
+	}
+}
+
+// relocateHQ moves the HQ to the held territory with the most held neighbours
+func (ws *warSeason) relocateHQ(territories map[string]*typedef.Territory, owned map[string]bool) string {
+	best, bestLinks := "", -1
+	for _, name := range ws.guildNames {
+		if !owned[name] {
+			continue
+		}
+		links := 0
+		for neighbour := range territories[name].Links.Direct {
+			if owned[neighbour] {
+				links++
+			}
+		}
+		if links > bestLinks {
+			best, bestLinks = name, links
+		}
+	}
+	if best != "" {
+		territories[best].HQ = true
+	}
+	return best
+}
+
+// pickTarget chooses the territory an attack goes for according to the attack model
+func (ws *warSeason) pickTarget(rng *rand.Rand, territories map[string]*typedef.Territory, owned map[string]bool) string {
+	var names []string
+	var weights []float64
+	total := 0.0
+	for _, name := range ws.guildNames {
+		if !owned[name] {
+			continue
+		}
+		weight := 1.0
+		switch ws.cfg.Model {
+		case AttackBorder:
+			hostile := 0
+			for neighbour := range territories[name].Links.Direct {
+				if !owned[neighbour] {
+					hostile++
+				}
+			}
+			weight += float64(hostile)
+		case AttackValue:
+			base := territories[name].ResourceGeneration.Base
+			weight += basicResourcesTotal(base) / 3600
+		}
+		names = append(names, name)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	roll := rng.Float64() * total
+	for i, weight := range weights {
+		if roll < weight {
+			return names[i]
+		}
+		roll -= weight
+	}
+	return names[len(names)-1]
+}
+
+// captureChance turns the war party's odds against a tower into a capture probability.
+// The tower's links are rebuilt from the held territories, and results are cached by captureKey.
+func (ws *warSeason) captureChance(territory *typedef.Territory, owned map[string]bool) float64 {
+	links := ws.links(territory.Name, owned)
+	key := captureKey{name: territory.Name, hq: territory.HQ, direct: len(links.Direct), externals: len(links.Externals)}
+	if chance, ok := ws.chance[key]; ok {
+		return chance
+	}
+
+	// Tower stats update the level fields, so they are computed on a copy
+	forked := forkTerritory(territory)
+	forked.Links = links
+	combat := towerCombatWith(&ws.env.costs, forked, ws.party)
+	chance := 1.0
+	if combat.PartySurvival >= 0 && combat.TimeToCapture > 0 {
+		// Even odds when the party would wipe at the exact moment the tower falls
+		ratio := combat.PartySurvival / combat.TimeToCapture
+		chance = ratio / (1 + ratio)
+	}
+	ws.chance[key] = chance
+	return chance
+}
+
+// links returns the links of a held territory to the rest of the held claim, built the way
+// populateTerritoryLinks builds them on the live map
+func (ws *warSeason) links(name string, owned map[string]bool) typedef.Links {
+	links := typedef.Links{Direct: make(map[string]struct{}), Externals: make(map[string]struct{})}
+	visited := map[string]bool{name: true}
+	frontier := []string{name}
+	for distance := 1; distance <= 3 && len(frontier) > 0; distance++ {
+		var next []string
+		for _, current := range frontier {
+			for _, neighbour := range ws.env.routes[current] {
+				if visited[neighbour] || !owned[neighbour] {
+					continue
+				}
+				visited[neighbour] = true
+				if distance == 1 {
+					links.Direct[neighbour] = struct{}{}
+				}
+				links.Externals[neighbour] = struct{}{}
+				next = append(next, neighbour)
+			}
+		}
+		frontier = next
+	}
+	return links
+}
+
+// poisson draws the number of events in an interval with the given mean
+func poisson(rng *rand.Rand, mean float64) int {
+	limit := math.Exp(-mean)
+	count, product := 0, rng.Float64()
+	for product > limit {
+		count++
+		product *= rng.Float64()
+	}
+	return count
+}
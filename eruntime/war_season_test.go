@@ -0,0 +1,47 @@
+package eruntime
+
+import (
+	"testing"
+
+	"RueaES/typedef"
+)
+
+func TestWarSeasonCaptureChanceFollowsHeldLinks(t *testing.T) {
+	env := &simulationEnv{routes: map[string][]string{
+		"a": {"b"},
+		"b": {"a", "c"},
+		"c": {"b", "d"},
+		"d": {"c", "e"},
+		"e": {"d"},
+	}}
+	env.costs.UpgradeMultiplier.Damage = []float64{1}
+	env.costs.UpgradeMultiplier.Attack = []float64{1}
+	env.costs.UpgradeMultiplier.Health = []float64{1}
+	env.costs.UpgradeMultiplier.Defence = []float64{1}
+
+	ws := &warSeason{env: env, party: typedef.DefaultWarParty(), chance: make(map[captureKey]float64)}
+	owned := map[string]bool{"a": true, "b": true, "c": true, "d": true, "e": true}
+	tower := &typedef.Territory{Name: "c"}
+
+	links := ws.links("c", owned)
+	if len(links.Direct) != 2 || len(links.Externals) != 4 {
+		t.Fatalf("held claim gives %d direct and %d external links, want 2 and 4", len(links.Direct), len(links.Externals))
+	}
+	held := ws.captureChance(tower, owned)
+
+	// Losing b cuts c off from a as well
+	owned["b"] = false
+	links = ws.links("c", owned)
+	if len(links.Direct) != 1 || len(links.Externals) != 2 {
+		t.Fatalf("after losing b, %d direct and %d external links, want 1 and 2", len(links.Direct), len(links.Externals))
+	}
+	exposed := ws.captureChance(tower, owned)
+	if exposed <= held {
+		t.Errorf("capture chance %v after losing a neighbour, want more than %v", exposed, held)
+	}
+
+	owned["b"] = true
+	if again := ws.captureChance(tower, owned); again != held {
+		t.Errorf("capture chance %v once b is retaken, want %v", again, held)
+	}
+}
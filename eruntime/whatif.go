@@ -0,0 +1,260 @@
+package eruntime
+
+import (
+	"RueaES/eruntime/pathfinder"
+	"RueaES/typedef"
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TerritoryLossReport describes what would happen to a guild if it lost a set of territories.
+// Lost territories are assumed to be taken by a hostile guild with closed borders.
+type TerritoryLossReport struct {
+	GuildTag string   `json:"guildTag"`
+	Removed  []string `json:"removed"`
+	HQLost   bool     `json:"hqLost"`
+
+	// CutOff lists the remaining territories that would no longer have a route to the HQ
+	CutOff []string `json:"cutOff"`
+
+	// LostProduction is the hourly production of removed and cut off territories that no longer reaches the HQ
+	LostProduction typedef.BasicResources `json:"lostProduction"`
+
+	RouteTaxChanges []RouteTaxChange       `json:"routeTaxChanges"`
+	NewWarnings     []TerritoryLossWarning `json:"newWarnings"`
+}
+
+// RouteTaxChange is a territory whose route tax to the HQ would change
+type RouteTaxChange struct {
+	Territory string  `json:"territory"`
+	Before    float64 `json:"before"`
+	After     float64 `json:"after"`
+}
+
+// TerritoryLossWarning lists warnings a territory would gain after the loss
+type TerritoryLossWarning struct {
+	Territory string   `json:"territory"`
+	Warnings  []string `json:"warnings"`
+}
+
+// whatIfTerritory holds the live values needed to compare a territory before and after the loss
+type whatIfTerritory struct {
+	name       string
+	hq         bool
+	routed     bool
+	routeTax   float64
+	generation typedef.BasicResources
+	net        typedef.BasicResources
+	warning    typedef.Warning
+}
+
+// AnalyzeTerritoryLoss virtually removes territories from a guild's claim, recomputes the routes
+// of the remaining territories and reports the outcome. Live state is not modified.
+func AnalyzeTerritoryLoss(guildTag string, removed []string) (*TerritoryLossReport, error) {
+	guildTag = strings.TrimSpace(guildTag)
+	if guildTag == "" || guildTag == "NONE" {
+		return nil, fmt.Errorf("guild tag cannot be empty")
+	}
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("no territories to remove")
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	removedSet := make(map[string]bool, len(removed))
+	for _, name := range removed {
+		name = strings.TrimSpace(name)
+		territory := TerritoryMap[name]
+		if territory == nil {
+			return nil, fmt.Errorf("territory '%s' not found", name)
+		}
+		territory.Mu.RLock()
+		owner := territory.Guild.Tag
+		territory.Mu.RUnlock()
+		if owner != guildTag {
+			return nil, fmt.Errorf("territory '%s' is not owned by %s", name, guildTag)
+		}
+		removedSet[name] = true
+	}
+
+	// Build a virtual map with the removed territories handed over
+	virtual := make(map[string]*typedef.Territory, len(TerritoryMap))
+	var live []whatIfTerritory
+	for name, territory := range TerritoryMap {
+		if territory == nil {
+			continue
+		}
+		territory.Mu.RLock()
+		copied := forkTerritory(territory)
+		if territory.Guild.Tag == guildTag {
+			live = append(live, whatIfTerritory{
+				name:       name,
+				hq:         territory.HQ,
+				routed:     territory.HQ || len(territory.TradingRoutes) > 0,
+				routeTax:   territory.RouteTax,
+				generation: territory.ResourceGeneration.At,
+				net:        territoryNetPerHour(territory),
+				warning:    territory.Warning,
+			})
+		}
+		territory.Mu.RUnlock()
+
+		if removedSet[name] {
+			copied.Guild = typedef.Guild{Name: "No Guild", Tag: "NONE"}
+			copied.Border = typedef.BorderClosed
+			copied.HQ = false
+		}
+		virtual[name] = copied
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].name < live[j].name })
+
+	report := &TerritoryLossReport{GuildTag: guildTag}
+	for name := range removedSet {
+		report.Removed = append(report.Removed, name)
+	}
+	sort.Strings(report.Removed)
+
+	var hq *typedef.Territory
+	if liveHQ := getHQFromMap(guildTag); liveHQ != nil {
+		if removedSet[liveHQ.Name] {
+			report.HQLost = true
+		} else {
+			hq = virtual[liveHQ.Name]
+		}
+	}
+
+	allies := getGuildAllies(guildTag)
+	netBefore := typedef.BasicResources{}
+	netAfter := typedef.BasicResources{}
+	warnings := make(map[string][]string)
+	var hqWarning typedef.Warning
+
+	for _, t := range live {
+		if removedSet[t.name] {
+			if t.routed {
+				report.LostProduction = report.LostProduction.Add(&t.generation)
+				netBefore = addRoutedNet(netBefore, t.net, t.routeTax)
+			}
+			continue
+		}
+
+		if t.hq {
+			hqWarning = t.warning
+			netBefore = netBefore.Add(&t.net)
+			netAfter = netAfter.Add(&t.net)
+			continue
+		}
+
+		_, afterTax, routedAfter := whatIfRoute(virtual[t.name], hq, virtual, guildTag, allies)
+
+		if t.routed {
+			netBefore = addRoutedNet(netBefore, t.net, t.routeTax)
+		}
+		if routedAfter {
+			netAfter = addRoutedNet(netAfter, t.net, afterTax)
+		}
+
+		switch {
+		case t.routed && !routedAfter:
+			report.CutOff = append(report.CutOff, t.name)
+			report.LostProduction = report.LostProduction.Add(&t.generation)
+			// A territory without a route can neither ship its production nor be supplied
+			if t.generation.Emeralds > 0 && t.warning&typedef.WarningOverflowEmerald == 0 {
+				warnings[t.name] = append(warnings[t.name], "overflow_emerald")
+			}
+			if t.generation.Ores+t.generation.Wood+t.generation.Fish+t.generation.Crops > 0 && t.warning&typedef.WarningOverflowResources == 0 {
+				warnings[t.name] = append(warnings[t.name], "overflow_resources")
+			}
+		case t.routed && routedAfter && math.Abs(afterTax-math.Max(t.routeTax, 0)) > 1e-9:
+			report.RouteTaxChanges = append(report.RouteTaxChanges, RouteTaxChange{
+				Territory: t.name,
+				Before:    math.Max(t.routeTax, 0),
+				After:     afterTax,
+			})
+		}
+	}
+
+	// Guild-wide upkeep that production no longer covers shows up as usage warnings on the HQ
+	if hq != nil {
+		if netBefore.Emeralds >= 0 && netAfter.Emeralds < 0 && hqWarning&typedef.WarningUsageEmerald == 0 {
+			warnings[hq.Name] = append(warnings[hq.Name], "usage_emerald")
+		}
+		resourceDeficit := false
+		for _, name := range []string{"ores", "wood", "fish", "crops"} {
+			if resourceValue(netBefore, name) >= 0 && resourceValue(netAfter, name) < 0 {
+				resourceDeficit = true
+			}
+		}
+		if resourceDeficit && hqWarning&typedef.WarningUsageResources == 0 {
+			warnings[hq.Name] = append(warnings[hq.Name], "usage_resources")
+		}
+	}
+
+	for name, list := range warnings {
+		report.NewWarnings = append(report.NewWarnings, TerritoryLossWarning{Territory: name, Warnings: list})
+	}
+	sort.Slice(report.NewWarnings, func(i, j int) bool { return report.NewWarnings[i].Territory < report.NewWarnings[j].Territory })
+
+	return report, nil
+}
+
+// simulationEnv holds the global tables a simulation reads besides its forked territories. It is
+// copied under st.mu, so a simulation can keep using it after the lock is released.
+type simulationEnv struct {
+	costs   typedef.Costs
+	options typedef.RuntimeOptions
+	routes  map[string][]string
+}
+
+// snapshotSimulationEnvUnsafe copies the costs, runtime options and trading routes. Caller must hold st.mu.
+func snapshotSimulationEnvUnsafe() *simulationEnv {
+	routes := make(map[string][]string, len(TradingRoutesMap))
+	maps.Copy(routes, TradingRoutesMap)
+	return &simulationEnv{costs: st.costs, options: st.runtimeOptions, routes: routes}
+}
+
+// whatIfRoute finds a route from a virtual territory to the virtual HQ and returns it with its tax.
+// Caller must hold st.mu.
+func whatIfRoute(territory, hq *typedef.Territory, virtualMap map[string]*typedef.Territory, guildTag string, allies []string) ([]*typedef.Territory, float64, bool) {
+	env := &simulationEnv{options: st.runtimeOptions, routes: TradingRoutesMap}
+	return whatIfRouteWith(env, territory, hq, virtualMap, guildTag, allies)
+}
+
+// whatIfRouteWith finds a route like whatIfRoute, on the routes and options of env
+func whatIfRouteWith(env *simulationEnv, territory, hq *typedef.Territory, virtualMap map[string]*typedef.Territory, guildTag string, allies []string) ([]*typedef.Territory, float64, bool) {
+	if territory == nil || hq == nil {
+		return nil, 0, false
+	}
+
+	var route []*typedef.Territory
+	var err error
+	switch territory.RoutingMode {
+	case typedef.RoutingFastest:
+		route, err = pathfinder.FindPathFastest(territory, hq, virtualMap, env.routes, guildTag)
+	default:
+		route, err = pathfinder.FindPathCheapest(env.options.PathfindingAlgorithm, territory, hq, virtualMap, env.routes, guildTag, allies)
+	}
+	if err != nil || len(route) == 0 {
+		return nil, 0, false
+	}
+	return route, pathfinder.CalculateRouteTax(route, guildTag, allies), true
+}
+
+// addRoutedNet adds a territory's hourly net to a guild total, taxing the surplus shipped to the HQ
+func addRoutedNet(total, net typedef.BasicResources, routeTax float64) typedef.BasicResources {
+	if routeTax < 0 {
+		routeTax = 0
+	}
+	for _, name := range forecastResourceNames {
+		v := resourceValue(net, name)
+		if v > 0 {
+			v *= 1 - routeTax
+		}
+		setResourceValue(&total, name, resourceValue(total, name)+v)
+	}
+	return total
+}
@@ -1,7 +1,9 @@
 package javascript
 
 import (
+	"RueaES/alg"
 	"RueaES/eruntime"
+	"RueaES/tml"
 	"RueaES/typedef"
 	"bytes"
 	"context"
@@ -168,6 +170,284 @@ func (e *Eruntime) SetTreasuryOverride(t *typedef.Territory, level typedef.Treas
 	eruntime.SetTreasuryOverride(t, level)
 }
 
+func (e *Eruntime) ForecastTerritory(territory string) *eruntime.StorageForecast {
+	forecast, err := eruntime.ForecastTerritory(territory)
+	if err != nil {
+		return nil
+	}
+	return forecast
+}
+
+func (e *Eruntime) ForecastGuildHQ(guildTag string) *eruntime.StorageForecast {
+	forecast, err := eruntime.ForecastGuildHQ(guildTag)
+	if err != nil {
+		return nil
+	}
+	return forecast
+}
+
+func (e *Eruntime) ForecastUpgradeAffordability(territory string, upgradeType string, level int) *eruntime.AffordabilityForecast {
+	forecast, err := eruntime.ForecastUpgradeAffordability(territory, upgradeType, level)
+	if err != nil {
+		return nil
+	}
+	return &forecast
+}
+
+func (e *Eruntime) ForecastBonusAffordability(territory string, bonusType string, level int) *eruntime.AffordabilityForecast {
+	forecast, err := eruntime.ForecastBonusAffordability(territory, bonusType, level)
+	if err != nil {
+		return nil
+	}
+	return &forecast
+}
+
+func (e *Eruntime) AnalyzeTerritoryLoss(guildTag string, territories []string) *eruntime.TerritoryLossReport {
+	report, err := eruntime.AnalyzeTerritoryLoss(guildTag, territories)
+	if err != nil {
+		return nil
+	}
+	return report
+}
+
+func (e *Eruntime) CalculateTowerCombat(territoryName string) *eruntime.TowerCombat {
+	combat, err := eruntime.CalculateTowerCombat(territoryName, nil)
+	if err != nil {
+		return nil
+	}
+	return combat
+}
+
+func (e *Eruntime) CalculateGuildCombat(guildTag string) []eruntime.TowerCombat {
+	combat, err := eruntime.CalculateGuildCombat(guildTag, nil)
+	if err != nil {
+		return nil
+	}
+	return combat
+}
+
+func (e *Eruntime) SimulateWarSeason(cfg eruntime.WarSeasonConfig) *eruntime.WarSeasonReport {
+	report, err := eruntime.SimulateWarSeason(cfg)
+	if err != nil {
+		return nil
+	}
+	return report
+}
+
+func (e *Eruntime) PlanClaimExpansion(guildTag string, cfg alg.ExpansionConfig) []alg.ExpansionPlan {
+	plans, err := eruntime.PlanClaimExpansion(guildTag, cfg)
+	if err != nil {
+		return nil
+	}
+	return plans
+}
+
+func (e *Eruntime) ComputeRivalChokepoints(guildTag, rivalTag string, alliance bool) *alg.RivalChokeReport {
+	report, err := eruntime.ComputeRivalChokepoints(guildTag, rivalTag, alliance)
+	if err != nil {
+		return nil
+	}
+	return report
+}
+
+func (e *Eruntime) OptimizeTaxPolicy(cfg eruntime.TaxPolicyConfig) *eruntime.TaxPolicyReport {
+	report, err := eruntime.OptimizeTaxPolicy(cfg)
+	if err != nil {
+		return nil
+	}
+	return report
+}
+
+func (e *Eruntime) ApplyTaxPolicy(settings []eruntime.TaxPolicySetting) int {
+	return eruntime.ApplyTaxPolicy(settings)
+}
+
+func (e *Eruntime) SetTerritoriesHeldFor(names []string, seconds int64) int {
+	return eruntime.SetTerritoriesHeldFor(names, time.Duration(seconds)*time.Second)
+}
+
+// SetTerritoriesHeldSince takes an RFC 3339 date and returns -1 when it cannot be parsed
+func (e *Eruntime) SetTerritoriesHeldSince(names []string, since string) int {
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return -1
+	}
+	return eruntime.SetTerritoriesHeldSince(names, t)
+}
+
+func (e *Eruntime) ProjectTreasury(guildTag string) []eruntime.TreasuryProjection {
+	projections, err := eruntime.ProjectTreasury(guildTag)
+	if err != nil {
+		return nil
+	}
+	return projections
+}
+
+func (e *Eruntime) TreasuryHQMoveImpact(guildTag, territory string) *eruntime.HQSimulation {
+	impact, err := eruntime.TreasuryHQMoveImpact(guildTag, territory)
+	if err != nil {
+		return nil
+	}
+	return impact
+}
+
+// ScheduleCapture queues a capture inSeconds from now and returns its ID, or "" on error
+func (e *Eruntime) ScheduleCapture(territory, guildTag string, inSeconds int64, setHQ bool) string {
+	if inSeconds <= 0 {
+		return ""
+	}
+	id, err := eruntime.ScheduleCapture(territory, guildTag, eruntime.GetCurrentTick()+uint64(inSeconds), setHQ)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+func (e *Eruntime) CancelScheduledCapture(captureID string) string {
+	if err := eruntime.CancelScheduledCapture(captureID); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) GetScheduledCaptures() []typedef.ScheduledCapture {
+	return eruntime.GetScheduledCaptures()
+}
+
+func (e *Eruntime) GetSeasonScores() []typedef.SeasonScore {
+	return eruntime.GetSeasonScores()
+}
+
+func (e *Eruntime) ResetSeasonScores() {
+	eruntime.ResetSeasonScores()
+}
+
+func (e *Eruntime) GetSeasonRatingFormula() typedef.SeasonRatingFormula {
+	return eruntime.GetSeasonRatingFormula()
+}
+
+func (e *Eruntime) SetSeasonRatingFormula(formula typedef.SeasonRatingFormula) string {
+	if err := eruntime.SetSeasonRatingFormula(formula); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) CompareSeasonStrategies(hours int, strategies []eruntime.SeasonStrategy) []eruntime.SeasonStrategyResult {
+	results, err := eruntime.CompareSeasonStrategies(hours, strategies)
+	if err != nil {
+		return nil
+	}
+	return results
+}
+
+// Query runs a territory query like `select where guild = "ABC"`, returning nil when it is invalid
+func (e *Eruntime) Query(source string) *tml.Result {
+	result, err := tml.Run(source)
+	if err != nil {
+		return nil
+	}
+	return result
+}
+
+// CheckQuery returns the reason a territory query is invalid, or an empty string
+func (e *Eruntime) CheckQuery(source string) string {
+	if _, err := tml.Parse(source); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) GetTributes() []*typedef.ActiveTribute {
+	return eruntime.GetAllActiveTributes()
+}
+
+func (e *Eruntime) CreateScheduledTribute(fromGuild, toGuild string, amount typedef.BasicResources, intervalMinutes int, schedule typedef.TributeSchedule) string {
+	if intervalMinutes < 0 {
+		return ""
+	}
+	id, err := eruntime.CreateScheduledTribute(fromGuild, toGuild, amount, uint32(intervalMinutes), schedule)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+func (e *Eruntime) SetTributeSchedule(tributeID string, schedule typedef.TributeSchedule) string {
+	if err := eruntime.SetTributeSchedule(tributeID, schedule); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) GetAnnotationLayers() []typedef.AnnotationLayer {
+	return eruntime.GetAnnotationLayers()
+}
+
+func (e *Eruntime) SetAnnotationLayer(layer typedef.AnnotationLayer) string {
+	if err := eruntime.SetAnnotationLayer(layer); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) RemoveAnnotationLayer(name string) string {
+	if err := eruntime.RemoveAnnotationLayer(name); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) SetAnnotationLayerVisible(name string, visible bool) string {
+	if err := eruntime.SetAnnotationLayerVisible(name, visible); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// AddAnnotation adds an annotation to a layer, creating the layer if needed, and returns its ID,
+// or "" on error
+func (e *Eruntime) AddAnnotation(layer string, annotation typedef.Annotation) string {
+	id, err := eruntime.AddAnnotation(layer, annotation)
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+func (e *Eruntime) UpdateAnnotation(layer string, annotation typedef.Annotation) string {
+	if err := eruntime.UpdateAnnotation(layer, annotation); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *Eruntime) RemoveAnnotation(layer, id string) string {
+	if err := eruntime.RemoveAnnotation(layer, id); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// ExportAnnotationsJSON returns the named layers, or every layer, as JSON, or "" on error
+func (e *Eruntime) ExportAnnotationsJSON(layers ...string) string {
+	data, err := eruntime.ExportAnnotationsJSON(layers...)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ImportAnnotationsJSON adds the layers of an exported document and returns the number imported,
+// or -1 when the document is invalid
+func (e *Eruntime) ImportAnnotationsJSON(data string) int {
+	count, err := eruntime.ImportAnnotationsJSON([]byte(data))
+	if err != nil {
+		return -1
+	}
+	return count
+}
+
 func (u *Utils) Get(url string) (*http.Response, error) {
 	return http.Get(url)
 }
@@ -188,6 +468,18 @@ func (e *Eruntime) NewGuild() typedef.Guild {
 	return typedef.Guild{}
 }
 
+func (e *Eruntime) NewTributeSchedule() typedef.TributeSchedule {
+	return typedef.TributeSchedule{}
+}
+
+func (e *Eruntime) NewAnnotation() typedef.Annotation {
+	return typedef.Annotation{Visible: true}
+}
+
+func (e *Eruntime) NewAnnotationLayer() typedef.AnnotationLayer {
+	return typedef.AnnotationLayer{Color: typedef.RGBAColor{R: 255, A: 255}, Visible: true}
+}
+
 // Timeout after 60 seconds
 func Execute(src, scriptName string) (goja.Value, error) {
 	vm := goja.New()
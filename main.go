@@ -36,12 +36,31 @@ func main() {
 	// Parse command line flags
 	var headless bool
 	var stateFilePath string
+	var upgradePath string
+	var mapPack string
 	flag.BoolVar(&headless, "headless", false, "Run in headless mode without GUI")
 	flag.BoolVar(&headless, "h", false, "Run in headless mode without GUI (shorthand)")
-	flag.StringVar(&stateFilePath, "file", "", "State file (.lz4/.ruea) to import on launch")
-	flag.StringVar(&stateFilePath, "f", "", "State file (.lz4/.ruea) to import on launch (shorthand)")
+	flag.StringVar(&stateFilePath, "file", "", "State file (.lz4/.etf/.ruea) to import on launch")
+	flag.StringVar(&stateFilePath, "f", "", "State file (.lz4/.etf/.ruea) to import on launch (shorthand)")
+	flag.StringVar(&upgradePath, "upgrade", "", "Upgrade a state file to the current version in place, keeping a backup, then exit")
+	flag.StringVar(&mapPack, "map-pack", "", "Map pack name in the map packs directory or path to a pack directory/.zip to run this session on")
 	flag.Parse()
 
+	if upgradePath != "" {
+		os.Exit(upgradeStateFile(filepath.Clean(upgradePath)))
+	}
+
+	if mapPack != "" {
+		packPath, err := eruntime.ResolveMapPack(mapPack)
+		if err == nil {
+			err = eruntime.ActivateMapPack(packPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load map pack %s: %v\n", mapPack, err)
+			os.Exit(1)
+		}
+	}
+
 	// Support positional file argument so double-clicking a .lz4 passes the path through
 	if stateFilePath == "" {
 		if args := flag.Args(); len(args) > 0 {
@@ -94,6 +113,21 @@ func main() {
 	runWithGUI(lockOwned, cleanupLock)
 }
 
+// upgradeStateFile migrates a save to the current state version and returns the exit code
+func upgradeStateFile(path string) int {
+	from, backupPath, err := eruntime.UpgradeStateFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to upgrade %s: %v\n", path, err)
+		return 1
+	}
+	if backupPath == "" {
+		fmt.Printf("%s is already at state version %s\n", path, from)
+		return 0
+	}
+	fmt.Printf("Upgraded %s from state version %s to %s, backup saved to %s\n", path, from, eruntime.CurrentStateVersion, backupPath)
+	return 0
+}
+
 func prepareLock(lockPath string) (*os.File, bool, func(), error) {
 	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
 	owned := true
@@ -2,12 +2,17 @@ package parser
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"io"
+	"math"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/pierrec/lz4"
 )
@@ -24,6 +29,28 @@ const (
 // Magic number for file format identification - "can we honestly eco?"
 const MagicNumber uint32 = 0xC9E0EC0
 
+// Payload flags following the size in the header
+const (
+	payloadStored     uint8 = 0 // The encoded data follows as is
+	payloadCompressed uint8 = 1 // The encoded data follows as an LZ4 block
+)
+
+// maxLZ4Ratio bounds how much an LZ4 block can expand, a match token of one byte stands for at
+// most 255 bytes of output. It caps the size a header may claim for its compressed payload.
+const maxLZ4Ratio = 255
+
+// nilLength is written in place of a length for nil slices, maps and interfaces,
+// so nil and empty values decode back exactly as they were encoded
+const nilLength uint32 = 0xFFFFFFFF
+
+// ErrChecksumMismatch is returned when a struct's encoded fields do not match their CRC32 checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
 // Config holds configuration options for the parser
 type Config struct {
 	// Compression level to use (CompressionNone, CompressionFast, CompressionHigh)
@@ -59,7 +86,7 @@ type Config struct {
 	// Whether to attempt error correction on decode failures
 	EnableErrorCorrection bool
 
-	// Number of retry attempts for error correction
+	// Maximum number of fields per struct that error correction may reset before decoding fails
 	ErrorCorrectionRetries int
 
 	// Whether to include field version information for schema evolution
@@ -90,6 +117,11 @@ func DefaultConfig() *Config {
 type Parser struct {
 	config *Config
 	depth  int // Current recursion depth
+
+	scratch   [8]byte                         // Buffer for fixed size values
+	data      []byte                          // Decompressed payload of the current decode, used to verify checksums
+	fields    map[reflect.Type]map[string]int // Encoded field name -> field index, per decoded struct type
+	corrected int                             // Fields reset by error correction during the last decode
 }
 
 // New creates a new parser with default configuration
@@ -114,6 +146,11 @@ func NewWithConfig(config *Config) *Parser {
 	}
 }
 
+// IsETF reports whether data starts with the .etf magic number
+func IsETF(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == MagicNumber
+}
+
 func (p *Parser) Encode(data interface{}) ([]byte, error) {
 	val := reflect.ValueOf(data)
 	if val.Kind() == reflect.Ptr {
@@ -135,12 +172,7 @@ func (p *Parser) Encode(data interface{}) ([]byte, error) {
 		if typeName == "" {
 			typeName = val.Type().String()
 		}
-		if err := binary.Write(&buf, p.config.ByteOrder, uint32(len(typeName))); err != nil {
-			return nil, err
-		}
-		if _, err := buf.WriteString(typeName); err != nil {
-			return nil, err
-		}
+		p.writeString(&buf, typeName)
 	}
 
 	// Reset depth counter
@@ -150,51 +182,53 @@ func (p *Parser) Encode(data interface{}) ([]byte, error) {
 		return nil, err
 	}
 
-	// Handle compression based on configuration
+	var compressed []byte
 	switch p.config.CompressionLevel {
 	case CompressionNone:
-		// No compression, just return the data with magic number and size prefix
-		var result bytes.Buffer
-		// Write magic number first - "can we honestly eco?"
-		if err := binary.Write(&result, p.config.ByteOrder, MagicNumber); err != nil {
-			return nil, err
-		}
-		if err := binary.Write(&result, p.config.ByteOrder, uint32(len(buf.Bytes()))); err != nil {
-			return nil, err
-		}
-		result.Write(buf.Bytes())
-		return result.Bytes(), nil
-
 	case CompressionFast, CompressionHigh:
 		// Compress the encoded data with LZ4
-		compressed := make([]byte, lz4.CompressBlockBound(len(buf.Bytes())))
-		n, err := lz4.CompressBlock(buf.Bytes(), compressed, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		// Create final output with magic number, size prefix and compression flag
-		var result bytes.Buffer
-		// Write magic number first - "can we honestly eco?"
-		if err := binary.Write(&result, p.config.ByteOrder, MagicNumber); err != nil {
-			return nil, err
+		compressed = make([]byte, lz4.CompressBlockBound(buf.Len()))
+		var n int
+		var err error
+		if p.config.CompressionLevel == CompressionHigh {
+			n, err = lz4.CompressBlockHC(buf.Bytes(), compressed, 0)
+		} else {
+			n, err = lz4.CompressBlock(buf.Bytes(), compressed, nil)
 		}
-		if err := binary.Write(&result, p.config.ByteOrder, uint32(len(buf.Bytes()))); err != nil {
+		if err != nil {
 			return nil, err
 		}
-		if err := binary.Write(&result, p.config.ByteOrder, uint8(1)); err != nil { // compression flag
-			return nil, err
+		// LZ4 reports 0 for incompressible data, which is then stored as is like data that
+		// doesn't shrink
+		if n >= buf.Len() {
+			n = 0
 		}
-		result.Write(compressed[:n])
-		return result.Bytes(), nil
-
+		compressed = compressed[:n]
 	default:
 		return nil, errors.New("invalid compression level")
 	}
+
+	var result bytes.Buffer
+	// Write magic number first - "can we honestly eco?"
+	if err := binary.Write(&result, p.config.ByteOrder, MagicNumber); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&result, p.config.ByteOrder, uint32(buf.Len())); err != nil {
+		return nil, err
+	}
+	if len(compressed) == 0 {
+		// No compression, the data is stored as is
+		result.WriteByte(payloadStored)
+		result.Write(buf.Bytes())
+		return result.Bytes(), nil
+	}
+	result.WriteByte(payloadCompressed)
+	result.Write(compressed)
+	return result.Bytes(), nil
 }
 
 func (p *Parser) Decode(data []byte, out interface{}) error {
-	if len(data) < 8 { // magic number (4) + size (4) minimum
+	if len(data) < 9 { // magic number (4) + size (4) + payload flag (1) minimum
 		return errors.New("invalid data: too short")
 	}
 
@@ -215,58 +249,52 @@ func (p *Parser) Decode(data []byte, out interface{}) error {
 		return err
 	}
 
-	var decompressed []byte
-
-	// Check if there's a compression flag by reading the next byte
-	if buf.Len() > 0 {
-		// Peek at the next byte to see if it could be a compression flag
-		remainingAfterSize := buf.Len()
-
-		// If the remaining data is exactly the original size, it's uncompressed
-		// If the remaining data is different, check for compression flag
-		if remainingAfterSize == int(origSize) {
-			// Not compressed, read remaining data
-			decompressed = make([]byte, origSize)
-			if _, err := buf.Read(decompressed); err != nil {
-				return err
-			}
-		} else {
-			// Likely compressed, read compression flag
-			var compressed uint8
-			if err := binary.Read(buf, p.config.ByteOrder, &compressed); err != nil {
-				return err
-			}
+	flag, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
 
-			if compressed == 1 {
-				// Decompress
-				compressedData := make([]byte, buf.Len())
-				if _, err := buf.Read(compressedData); err != nil {
-					return err
-				}
+	// The size is checked against the payload before anything is allocated for it
+	var decompressed []byte
+	switch flag {
+	case payloadStored:
+		if buf.Len() != int(origSize) {
+			return fmt.Errorf("stored size mismatch: expected %d, got %d", origSize, buf.Len())
+		}
+		decompressed = make([]byte, origSize)
+		if _, err := io.ReadFull(buf, decompressed); err != nil {
+			return err
+		}
+	case payloadCompressed:
+		if buf.Len() == 0 {
+			return errors.New("no data after header")
+		}
+		if uint64(origSize) > uint64(buf.Len())*maxLZ4Ratio {
+			return fmt.Errorf("invalid data: %d bytes cannot decompress to %d", buf.Len(), origSize)
+		}
 
-				decompressed = make([]byte, origSize)
-				if _, err := lz4.UncompressBlock(compressedData, decompressed); err != nil {
-					return err
-				}
-			} else {
-				return errors.New("invalid compression flag")
-			}
+		compressedData := data[len(data)-buf.Len():]
+		decompressed = make([]byte, origSize)
+		n, err := lz4.UncompressBlock(compressedData, decompressed)
+		if err != nil {
+			return fmt.Errorf("failed to decompress data: %w", err)
 		}
-	} else {
-		return errors.New("no data after size header")
+		if n != int(origSize) {
+			return fmt.Errorf("decompressed size mismatch: expected %d, got %d", origSize, n)
+		}
+	default:
+		return fmt.Errorf("invalid payload flag %d", flag)
 	}
 
 	decodeBuf := bytes.NewReader(decompressed)
+	p.data = decompressed
+	p.corrected = 0
+	defer func() { p.data = nil }()
 
 	// Read type info if included
 	if p.config.IncludeTypeInfo {
-		var typeNameLen uint32
-		if err := binary.Read(decodeBuf, p.config.ByteOrder, &typeNameLen); err != nil {
-			return err
-		}
-
-		typeName := make([]byte, typeNameLen)
-		if _, err := decodeBuf.Read(typeName); err != nil {
+		typeName, err := p.readString(decodeBuf)
+		if err != nil {
 			return err
 		}
 
@@ -280,8 +308,8 @@ func (p *Parser) Decode(data []byte, out interface{}) error {
 			if expectedType == "" {
 				expectedType = val.Type().String()
 			}
-			if expectedType != string(typeName) {
-				return errors.New("type mismatch: expected " + expectedType + ", got " + string(typeName))
+			if expectedType != typeName {
+				return errors.New("type mismatch: expected " + expectedType + ", got " + typeName)
 			}
 		}
 	}
@@ -298,6 +326,98 @@ func (p *Parser) Decode(data []byte, out interface{}) error {
 	return p.decodeValue(decodeBuf, val.Elem())
 }
 
+// Helpers for fixed size and length prefixed values
+func (p *Parser) writeUint32(buf *bytes.Buffer, v uint32) {
+	p.config.ByteOrder.PutUint32(p.scratch[:4], v)
+	buf.Write(p.scratch[:4])
+}
+
+func (p *Parser) writeUint64(buf *bytes.Buffer, v uint64) {
+	p.config.ByteOrder.PutUint64(p.scratch[:8], v)
+	buf.Write(p.scratch[:8])
+}
+
+func (p *Parser) writeString(buf *bytes.Buffer, s string) {
+	p.writeUint32(buf, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func (p *Parser) readUint32(buf *bytes.Reader) (uint32, error) {
+	if _, err := io.ReadFull(buf, p.scratch[:4]); err != nil {
+		return 0, err
+	}
+	return p.config.ByteOrder.Uint32(p.scratch[:4]), nil
+}
+
+func (p *Parser) readUint64(buf *bytes.Reader) (uint64, error) {
+	if _, err := io.ReadFull(buf, p.scratch[:8]); err != nil {
+		return 0, err
+	}
+	return p.config.ByteOrder.Uint64(p.scratch[:8]), nil
+}
+
+// readN reads length bytes, refusing lengths longer than the remaining data so corrupted
+// lengths fail instead of allocating huge buffers
+func (p *Parser) readN(buf *bytes.Reader, length uint32) ([]byte, error) {
+	if int64(length) > int64(buf.Len()) {
+		return nil, fmt.Errorf("length %d exceeds remaining data (%d bytes)", length, buf.Len())
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(buf, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (p *Parser) readString(buf *bytes.Reader) (string, error) {
+	length, err := p.readUint32(buf)
+	if err != nil {
+		return "", err
+	}
+	data, err := p.readN(buf, length)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// readLength reads a slice or map length, returning -1 for nil
+func (p *Parser) readLength(buf *bytes.Reader) (int, error) {
+	length, err := p.readUint32(buf)
+	if err != nil {
+		return 0, err
+	}
+	if length == nilLength {
+		return -1, nil
+	}
+	// Every element takes at least one byte
+	if int64(length) > int64(buf.Len()) {
+		return 0, fmt.Errorf("length %d exceeds remaining data (%d bytes)", length, buf.Len())
+	}
+	return int(length), nil
+}
+
+// position returns the read offset of buf
+func position(buf *bytes.Reader) int64 {
+	return buf.Size() - int64(buf.Len())
+}
+
+// fieldName returns the name a field is encoded under: its tag name if set, otherwise its Go name
+func (p *Parser) fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get(p.config.TagName), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// usesBinaryMarshaler reports whether values of typ are encoded through their
+// encoding.BinaryMarshaler implementation, such as time.Time
+func usesBinaryMarshaler(typ reflect.Type) bool {
+	return typ.Kind() != reflect.Ptr && typ.Kind() != reflect.Interface &&
+		typ.Implements(binaryMarshalerType) && reflect.PointerTo(typ).Implements(binaryUnmarshalerType)
+}
+
 // Helper methods for encoding/decoding values
 func (p *Parser) encodeValue(buf *bytes.Buffer, val reflect.Value) error {
 	// Check recursion depth
@@ -307,9 +427,14 @@ func (p *Parser) encodeValue(buf *bytes.Buffer, val reflect.Value) error {
 	p.depth++
 	defer func() { p.depth-- }()
 
-	// Handle interface{} types by getting the underlying value
-	if val.Kind() == reflect.Interface && !val.IsNil() {
-		val = val.Elem()
+	if usesBinaryMarshaler(val.Type()) {
+		data, err := val.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		p.writeUint32(buf, uint32(len(data)))
+		buf.Write(data)
+		return nil
 	}
 
 	// Handle different value kinds
@@ -325,12 +450,7 @@ func (p *Parser) encodeValue(buf *bytes.Buffer, val reflect.Value) error {
 	case reflect.Map:
 		return p.encodeMap(buf, val)
 	case reflect.Interface:
-		// Handle nil interface
-		if val.IsNil() {
-			return binary.Write(buf, p.config.ByteOrder, uint8(0)) // nil marker
-		}
-		// This should not happen as we handle non-nil interfaces above
-		return errors.New("unexpected interface type")
+		return p.encodeInterface(buf, val)
 	default:
 		return p.encodePrimitive(buf, val)
 	}
@@ -361,14 +481,12 @@ func (p *Parser) encodeStruct(buf *bytes.Buffer, val reflect.Value) error {
 	// Sort fields if configured
 	if p.config.SortFields {
 		sort.Slice(fieldsToEncode, func(i, j int) bool {
-			return typ.Field(fieldsToEncode[i]).Name < typ.Field(fieldsToEncode[j]).Name
+			return p.fieldName(typ.Field(fieldsToEncode[i])) < p.fieldName(typ.Field(fieldsToEncode[j]))
 		})
 	}
 
 	// Write number of fields that will be encoded
-	if err := binary.Write(buf, p.config.ByteOrder, uint32(len(fieldsToEncode))); err != nil {
-		return err
-	}
+	p.writeUint32(buf, uint32(len(fieldsToEncode)))
 
 	// Mark data start for checksum if configured
 	var dataStart int
@@ -380,24 +498,13 @@ func (p *Parser) encodeStruct(buf *bytes.Buffer, val reflect.Value) error {
 		field := val.Field(i)
 		fieldType := typ.Field(i)
 
-		// Write field name for better debugging and validation
-		fieldName := fieldType.Name
-		if err := binary.Write(buf, p.config.ByteOrder, uint32(len(fieldName))); err != nil {
-			return err
-		}
-		if _, err := buf.WriteString(fieldName); err != nil {
-			return err
-		}
+		// Write field name, the etf tag name when set, for matching on decode
+		fieldName := p.fieldName(fieldType)
+		p.writeString(buf, fieldName)
 
 		// Include field type information if configured
 		if p.config.IncludeFieldTypeInfo {
-			fieldTypeName := fieldType.Type.String()
-			if err := binary.Write(buf, p.config.ByteOrder, uint32(len(fieldTypeName))); err != nil {
-				return err
-			}
-			if _, err := buf.WriteString(fieldTypeName); err != nil {
-				return err
-			}
+			p.writeString(buf, fieldType.Type.String())
 		}
 
 		// Include field version if configured
@@ -409,24 +516,25 @@ func (p *Parser) encodeStruct(buf *bytes.Buffer, val reflect.Value) error {
 					version = uint32(versionTag[0]) // Use first char as version
 				}
 			}
-			if err := binary.Write(buf, p.config.ByteOrder, version); err != nil {
-				return err
-			}
+			p.writeUint32(buf, version)
 		}
 
+		// Reserve the value length and fill it in once the value is encoded,
+		// so decoders can skip unknown fields and resynchronise after errors
+		lengthAt := buf.Len()
+		p.writeUint32(buf, 0)
+
 		// Encode the field value
 		if err := p.encodeValue(buf, field); err != nil {
 			return fmt.Errorf("failed to encode field %s: %w", fieldName, err)
 		}
+		p.config.ByteOrder.PutUint32(buf.Bytes()[lengthAt:], uint32(buf.Len()-lengthAt-4))
 	}
 
 	// Add checksum if configured
 	if p.config.IncludeChecksum {
 		data := buf.Bytes()[dataStart:]
-		checksum := crc32.ChecksumIEEE(data)
-		if err := binary.Write(buf, p.config.ByteOrder, checksum); err != nil {
-			return err
-		}
+		p.writeUint32(buf, crc32.ChecksumIEEE(data))
 	}
 
 	return nil
@@ -436,13 +544,13 @@ func (p *Parser) encodePointer(buf *bytes.Buffer, val reflect.Value) error {
 	if val.IsNil() {
 		if p.config.SkipNilPointers {
 			// Write nil marker
-			return binary.Write(buf, p.config.ByteOrder, uint8(0))
+			return buf.WriteByte(0)
 		}
 		return errors.New("cannot encode nil pointer")
 	}
 
 	// Write non-nil marker
-	if err := binary.Write(buf, p.config.ByteOrder, uint8(1)); err != nil {
+	if err := buf.WriteByte(1); err != nil {
 		return err
 	}
 
@@ -450,10 +558,20 @@ func (p *Parser) encodePointer(buf *bytes.Buffer, val reflect.Value) error {
 }
 
 func (p *Parser) encodeSlice(buf *bytes.Buffer, val reflect.Value) error {
-	if err := binary.Write(buf, p.config.ByteOrder, uint32(val.Len())); err != nil {
-		return err
+	if val.IsNil() {
+		p.writeUint32(buf, nilLength)
+		return nil
 	}
 
+	// Byte slices are written as is
+	if val.Type().Elem().Kind() == reflect.Uint8 {
+		data := val.Bytes()
+		p.writeUint32(buf, uint32(len(data)))
+		buf.Write(data)
+		return nil
+	}
+
+	p.writeUint32(buf, uint32(val.Len()))
 	for j := 0; j < val.Len(); j++ {
 		if err := p.encodeValue(buf, val.Index(j)); err != nil {
 			return err
@@ -464,9 +582,7 @@ func (p *Parser) encodeSlice(buf *bytes.Buffer, val reflect.Value) error {
 }
 
 func (p *Parser) encodeArray(buf *bytes.Buffer, val reflect.Value) error {
-	if err := binary.Write(buf, p.config.ByteOrder, uint32(val.Len())); err != nil {
-		return err
-	}
+	p.writeUint32(buf, uint32(val.Len()))
 
 	for j := 0; j < val.Len(); j++ {
 		if err := p.encodeValue(buf, val.Index(j)); err != nil {
@@ -478,9 +594,19 @@ func (p *Parser) encodeArray(buf *bytes.Buffer, val reflect.Value) error {
 }
 
 func (p *Parser) encodeMap(buf *bytes.Buffer, val reflect.Value) error {
+	if val.IsNil() {
+		p.writeUint32(buf, nilLength)
+		return nil
+	}
+
 	keys := val.MapKeys()
-	if err := binary.Write(buf, p.config.ByteOrder, uint32(len(keys))); err != nil {
-		return err
+	p.writeUint32(buf, uint32(len(keys)))
+
+	// String keys are sorted so the same map always encodes to the same bytes
+	if val.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
 	}
 
 	for _, key := range keys {
@@ -499,6 +625,23 @@ func (p *Parser) encodeMap(buf *bytes.Buffer, val reflect.Value) error {
 	return nil
 }
 
+// encodeInterface stores interface values as JSON, which decodes them to the same
+// dynamic types the JSON state format produces
+func (p *Parser) encodeInterface(buf *bytes.Buffer, val reflect.Value) error {
+	if val.IsNil() {
+		p.writeUint32(buf, nilLength)
+		return nil
+	}
+
+	data, err := json.Marshal(val.Interface())
+	if err != nil {
+		return fmt.Errorf("failed to encode interface value: %w", err)
+	}
+	p.writeUint32(buf, uint32(len(data)))
+	buf.Write(data)
+	return nil
+}
+
 func (p *Parser) encodePrimitive(buf *bytes.Buffer, val reflect.Value) error {
 	switch val.Kind() {
 	case reflect.Bool:
@@ -506,23 +649,19 @@ func (p *Parser) encodePrimitive(buf *bytes.Buffer, val reflect.Value) error {
 		if val.Bool() {
 			v = 1
 		}
-		return binary.Write(buf, p.config.ByteOrder, v)
+		return buf.WriteByte(v)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return binary.Write(buf, p.config.ByteOrder, val.Int())
+		p.writeUint64(buf, uint64(val.Int()))
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return binary.Write(buf, p.config.ByteOrder, val.Uint())
+		p.writeUint64(buf, val.Uint())
 	case reflect.Float32, reflect.Float64:
-		return binary.Write(buf, p.config.ByteOrder, val.Float())
+		p.writeUint64(buf, math.Float64bits(val.Float()))
 	case reflect.String:
-		str := val.String()
-		if err := binary.Write(buf, p.config.ByteOrder, uint32(len(str))); err != nil {
-			return err
-		}
-		_, err := buf.WriteString(str)
-		return err
+		p.writeString(buf, val.String())
 	default:
 		return errors.New("unsupported field type: " + val.Kind().String())
 	}
+	return nil
 }
 
 func (p *Parser) decodeValue(buf *bytes.Reader, val reflect.Value) error {
@@ -533,6 +672,18 @@ func (p *Parser) decodeValue(buf *bytes.Reader, val reflect.Value) error {
 	p.depth++
 	defer func() { p.depth-- }()
 
+	if usesBinaryMarshaler(val.Type()) {
+		length, err := p.readUint32(buf)
+		if err != nil {
+			return err
+		}
+		data, err := p.readN(buf, length)
+		if err != nil {
+			return err
+		}
+		return val.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
 	// Handle different value kinds
 	switch val.Kind() {
 	case reflect.Struct:
@@ -546,100 +697,90 @@ func (p *Parser) decodeValue(buf *bytes.Reader, val reflect.Value) error {
 	case reflect.Map:
 		return p.decodeMap(buf, val)
 	case reflect.Interface:
-		// For interface{}, we need to decode into a concrete type
-		// For simplicity, we'll decode as string first
-		var str string
-		strVal := reflect.ValueOf(&str).Elem()
-		if err := p.decodePrimitive(buf, strVal); err != nil {
-			return err
-		}
-		val.Set(reflect.ValueOf(str))
-		return nil
+		return p.decodeInterface(buf, val)
 	default:
 		return p.decodePrimitive(buf, val)
 	}
 }
 
+// structFields returns the field indices of typ by encoded name. Go names are included
+// so data written before a field gained an etf tag still decodes.
+func (p *Parser) structFields(typ reflect.Type) map[string]int {
+	if fields, ok := p.fields[typ]; ok {
+		return fields
+	}
+
+	fields := make(map[string]int)
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		if fieldType.IsExported() && fieldType.Tag.Get(p.config.TagName) != "-" {
+			fields[fieldType.Name] = i
+		}
+	}
+	// Tag names take precedence over Go names
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		if fieldType.IsExported() && fieldType.Tag.Get(p.config.TagName) != "-" {
+			fields[p.fieldName(fieldType)] = i
+		}
+	}
+
+	if p.fields == nil {
+		p.fields = make(map[reflect.Type]map[string]int)
+	}
+	p.fields[typ] = fields
+	return fields
+}
+
 func (p *Parser) decodeStruct(buf *bytes.Reader, val reflect.Value) error {
 	typ := val.Type()
 
 	// Read number of fields
-	var fieldCount uint32
-	if err := binary.Read(buf, p.config.ByteOrder, &fieldCount); err != nil {
+	fieldCount, err := p.readUint32(buf)
+	if err != nil {
 		return err
 	}
+	dataStart := position(buf)
 
-	// Create map of field names to indices for faster lookup
-	fieldMap := make(map[string]int)
-	fieldTypeMap := make(map[string]reflect.Type)
-	for i := 0; i < val.NumField(); i++ {
-		fieldType := typ.Field(i)
-		if fieldType.IsExported() {
-			fieldMap[fieldType.Name] = i
-			fieldTypeMap[fieldType.Name] = fieldType.Type
-		}
-	}
+	fieldMap := p.structFields(typ)
+	corrected := 0
 
 	for i := uint32(0); i < fieldCount; i++ {
-		// Read field name
-		var fieldNameLen uint32
-		if err := binary.Read(buf, p.config.ByteOrder, &fieldNameLen); err != nil {
-			if p.config.EnableErrorCorrection {
-				return p.attemptErrorCorrection(buf, val, err)
-			}
-			return err
-		}
-
-		fieldNameBytes := make([]byte, fieldNameLen)
-		if _, err := buf.Read(fieldNameBytes); err != nil {
-			if p.config.EnableErrorCorrection {
-				return p.attemptErrorCorrection(buf, val, err)
-			}
-			return err
+		// The field header has no length of its own, so errors here cannot be corrected
+		fieldName, err := p.readString(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read field name: %w", err)
 		}
-		fieldName := string(fieldNameBytes)
 
 		// Read field type information if included
 		var fieldTypeName string
 		if p.config.IncludeFieldTypeInfo {
-			var fieldTypeLen uint32
-			if err := binary.Read(buf, p.config.ByteOrder, &fieldTypeLen); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
-				}
-				return err
+			if fieldTypeName, err = p.readString(buf); err != nil {
+				return fmt.Errorf("failed to read type of field %s: %w", fieldName, err)
 			}
-
-			fieldTypeBytes := make([]byte, fieldTypeLen)
-			if _, err := buf.Read(fieldTypeBytes); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
-				}
-				return err
-			}
-			fieldTypeName = string(fieldTypeBytes)
 		}
 
 		// Read field version if included
 		if p.config.IncludeFieldVersions {
-			var version uint32
-			if err := binary.Read(buf, p.config.ByteOrder, &version); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
-				}
-				return err
+			if _, err := p.readUint32(buf); err != nil {
+				return fmt.Errorf("failed to read version of field %s: %w", fieldName, err)
 			}
 			// Version information can be used for schema evolution in the future
 		}
 
-		// Find corresponding field in struct
+		valueLength, err := p.readUint32(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read length of field %s: %w", fieldName, err)
+		}
+		if int64(valueLength) > int64(buf.Len()) {
+			return fmt.Errorf("field %s length %d exceeds remaining data (%d bytes)", fieldName, valueLength, buf.Len())
+		}
+		fieldEnd := position(buf) + int64(valueLength)
+
+		// Skip unknown fields, such as fields written by a newer schema
 		fieldIndex, exists := fieldMap[fieldName]
-		if !exists {
-			// Skip unknown field - need to read and discard the value
-			if err := p.skipValue(buf); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
-				}
+		if !exists || !val.Field(fieldIndex).CanSet() {
+			if _, err := buf.Seek(fieldEnd, io.SeekStart); err != nil {
 				return err
 			}
 			continue
@@ -648,66 +789,53 @@ func (p *Parser) decodeStruct(buf *bytes.Reader, val reflect.Value) error {
 		field := val.Field(fieldIndex)
 		fieldType := typ.Field(fieldIndex)
 
-		// Skip if field cannot be set
-		if !field.CanSet() {
-			if err := p.skipValue(buf); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
+		var decodeErr error
+		if p.config.IncludeFieldTypeInfo && p.config.StrictTypeChecking && fieldTypeName != "" && fieldType.Type.String() != fieldTypeName {
+			// Validate field type if type information is available
+			decodeErr = fmt.Errorf("type mismatch: expected %s, got %s", fieldType.Type.String(), fieldTypeName)
+			if p.config.EnableErrorCorrection {
+				// Try to convert types if possible
+				if err := p.attemptTypeConversion(buf, field, fieldTypeName); err == nil {
+					decodeErr = nil
 				}
-				return err
 			}
-			continue
+		} else {
+			decodeErr = p.decodeValue(buf, field)
 		}
 
-		tag := fieldType.Tag.Get(p.config.TagName)
-		if tag == "-" {
-			if err := p.skipValue(buf); err != nil {
-				if p.config.EnableErrorCorrection {
-					return p.attemptErrorCorrection(buf, val, err)
-				}
-				return err
-			}
-			continue
+		if decodeErr == nil && position(buf) != fieldEnd {
+			decodeErr = fmt.Errorf("decoded %d bytes, expected %d", position(buf)-(fieldEnd-int64(valueLength)), valueLength)
 		}
 
-		// Validate field type if type information is available
-		if p.config.IncludeFieldTypeInfo && p.config.StrictTypeChecking && fieldTypeName != "" {
-			expectedType := fieldType.Type.String()
-			if expectedType != fieldTypeName {
-				if p.config.EnableErrorCorrection {
-					// Try to convert types if possible
-					if err := p.attemptTypeConversion(buf, field, fieldTypeName); err != nil {
-						return fmt.Errorf("field %s type mismatch: expected %s, got %s", fieldName, expectedType, fieldTypeName)
-					}
-					continue
-				}
-				return fmt.Errorf("field %s type mismatch: expected %s, got %s", fieldName, expectedType, fieldTypeName)
+		if decodeErr != nil {
+			// Corrupted data is never corrected, checksum errors always fail the decode
+			if !p.config.EnableErrorCorrection || errors.Is(decodeErr, ErrChecksumMismatch) || corrected >= p.config.ErrorCorrectionRetries {
+				return fmt.Errorf("failed to decode field %s: %w", fieldName, decodeErr)
 			}
+			// Leave the field at its zero value and resume at the next field
+			field.Set(reflect.Zero(field.Type()))
+			corrected++
+			p.corrected++
 		}
 
-		// Decode the field value
-		if err := p.decodeValue(buf, field); err != nil {
-			if p.config.EnableErrorCorrection {
-				return p.attemptErrorCorrection(buf, val, err)
-			}
-			return fmt.Errorf("failed to decode field %s: %w", fieldName, err)
+		if _, err := buf.Seek(fieldEnd, io.SeekStart); err != nil {
+			return err
 		}
 	}
 
 	// Validate checksum if configured
 	if p.config.IncludeChecksum {
-		// Read expected checksum
-		var expectedChecksum uint32
-		if err := binary.Read(buf, p.config.ByteOrder, &expectedChecksum); err != nil {
-			if p.config.EnableErrorCorrection {
-				return p.attemptErrorCorrection(buf, val, err)
-			}
-			return err
+		dataEnd := position(buf)
+		expectedChecksum, err := p.readUint32(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read checksum of %s: %w", typ, err)
 		}
 
-		// Calculate actual checksum (this is simplified - in practice you'd need to track the data)
-		// For now, we'll skip the validation as implementing proper checksum tracking
-		// would require significant refactoring
+		if p.data != nil {
+			if checksum := crc32.ChecksumIEEE(p.data[dataStart:dataEnd]); checksum != expectedChecksum {
+				return fmt.Errorf("%w in %s: expected 0x%08X, got 0x%08X", ErrChecksumMismatch, typ, expectedChecksum, checksum)
+			}
+		}
 	}
 
 	return nil
@@ -715,8 +843,8 @@ func (p *Parser) decodeStruct(buf *bytes.Reader, val reflect.Value) error {
 
 func (p *Parser) decodePointer(buf *bytes.Reader, val reflect.Value) error {
 	// Read nil marker
-	var nilMarker uint8
-	if err := binary.Read(buf, p.config.ByteOrder, &nilMarker); err != nil {
+	nilMarker, err := buf.ReadByte()
+	if err != nil {
 		return err
 	}
 
@@ -737,13 +865,27 @@ func (p *Parser) decodePointer(buf *bytes.Reader, val reflect.Value) error {
 }
 
 func (p *Parser) decodeSlice(buf *bytes.Reader, val reflect.Value) error {
-	var length uint32
-	if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
+	length, err := p.readLength(buf)
+	if err != nil {
 		return err
 	}
+	if length < 0 {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
 
-	slice := reflect.MakeSlice(val.Type(), int(length), int(length))
-	for j := 0; j < int(length); j++ {
+	// Byte slices are read as is
+	if val.Type().Elem().Kind() == reflect.Uint8 {
+		data, err := p.readN(buf, uint32(length))
+		if err != nil {
+			return err
+		}
+		val.SetBytes(data)
+		return nil
+	}
+
+	slice := reflect.MakeSlice(val.Type(), length, length)
+	for j := 0; j < length; j++ {
 		if err := p.decodeValue(buf, slice.Index(j)); err != nil {
 			return err
 		}
@@ -754,8 +896,8 @@ func (p *Parser) decodeSlice(buf *bytes.Reader, val reflect.Value) error {
 }
 
 func (p *Parser) decodeArray(buf *bytes.Reader, val reflect.Value) error {
-	var length uint32
-	if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
+	length, err := p.readUint32(buf)
+	if err != nil {
 		return err
 	}
 
@@ -773,15 +915,19 @@ func (p *Parser) decodeArray(buf *bytes.Reader, val reflect.Value) error {
 }
 
 func (p *Parser) decodeMap(buf *bytes.Reader, val reflect.Value) error {
-	var length uint32
-	if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
+	length, err := p.readLength(buf)
+	if err != nil {
 		return err
 	}
+	if length < 0 {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
 
 	mapType := val.Type()
-	newMap := reflect.MakeMap(mapType)
+	newMap := reflect.MakeMapWithSize(mapType, length)
 
-	for i := uint32(0); i < length; i++ {
+	for i := 0; i < length; i++ {
 		key := reflect.New(mapType.Key()).Elem()
 		value := reflect.New(mapType.Elem()).Elem()
 
@@ -799,42 +945,68 @@ func (p *Parser) decodeMap(buf *bytes.Reader, val reflect.Value) error {
 	return nil
 }
 
+func (p *Parser) decodeInterface(buf *bytes.Reader, val reflect.Value) error {
+	length, err := p.readUint32(buf)
+	if err != nil {
+		return err
+	}
+	if length == nilLength {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
+
+	data, err := p.readN(buf, length)
+	if err != nil {
+		return err
+	}
+	if val.NumMethod() != 0 {
+		return errors.New("cannot decode into non-empty interface " + val.Type().String())
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("failed to decode interface value: %w", err)
+	}
+	if v == nil {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
+	val.Set(reflect.ValueOf(v))
+	return nil
+}
+
 func (p *Parser) decodePrimitive(buf *bytes.Reader, val reflect.Value) error {
 	switch val.Kind() {
 	case reflect.Bool:
-		var v uint8
-		if err := binary.Read(buf, p.config.ByteOrder, &v); err != nil {
+		v, err := buf.ReadByte()
+		if err != nil {
 			return err
 		}
 		val.SetBool(v != 0)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		var v int64
-		if err := binary.Read(buf, p.config.ByteOrder, &v); err != nil {
+		v, err := p.readUint64(buf)
+		if err != nil {
 			return err
 		}
-		val.SetInt(v)
+		val.SetInt(int64(v))
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		var v uint64
-		if err := binary.Read(buf, p.config.ByteOrder, &v); err != nil {
+		v, err := p.readUint64(buf)
+		if err != nil {
 			return err
 		}
 		val.SetUint(v)
 	case reflect.Float32, reflect.Float64:
-		var v float64
-		if err := binary.Read(buf, p.config.ByteOrder, &v); err != nil {
+		v, err := p.readUint64(buf)
+		if err != nil {
 			return err
 		}
-		val.SetFloat(v)
+		val.SetFloat(math.Float64frombits(v))
 	case reflect.String:
-		var length uint32
-		if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
-			return err
-		}
-		str := make([]byte, length)
-		if _, err := buf.Read(str); err != nil {
+		str, err := p.readString(buf)
+		if err != nil {
 			return err
 		}
-		val.SetString(string(str))
+		val.SetString(str)
 	default:
 		return errors.New("unsupported field type: " + val.Kind().String())
 	}
@@ -842,83 +1014,6 @@ func (p *Parser) decodePrimitive(buf *bytes.Reader, val reflect.Value) error {
 	return nil
 }
 
-// skipValue reads and discards a value from the buffer without decoding it
-func (p *Parser) skipValue(buf *bytes.Reader) error {
-	// Since we don't have explicit type markers, this is a simplified implementation
-	// that tries to skip common patterns. In practice, this would need type information.
-
-	// For our current encoding format, we'll try to skip by reading common patterns:
-	// 1. Check if it's a nil pointer (single byte 0)
-	// 2. Check if it's a string (length prefix + data)
-	// 3. Check if it's a number (8 bytes)
-	// 4. Otherwise, skip a reasonable amount
-
-	if buf.Len() == 0 {
-		return nil
-	}
-
-	// Try to read as a string (most complex structure we might need to skip)
-	var length uint32
-	if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
-		return err
-	}
-
-	// If length is reasonable for a string, skip that many bytes
-	if length <= uint32(buf.Len()) && length < 10000 { // reasonable string length
-		skipBytes := make([]byte, length)
-		_, err := buf.Read(skipBytes)
-		return err
-	}
-
-	// Otherwise, it might be a number, rewind and skip 8 bytes
-	// Reset the buffer position
-	current := int64(buf.Size()) - int64(buf.Len()) - 4 // -4 for the length we just read
-	buf.Seek(current, 0)
-
-	// Skip 8 bytes for a potential number
-	if buf.Len() >= 8 {
-		skipBytes := make([]byte, 8)
-		_, err := buf.Read(skipBytes)
-		return err
-	}
-
-	// If less than 8 bytes, skip what's left
-	remaining := make([]byte, buf.Len())
-	_, err := buf.Read(remaining)
-	return err
-}
-
-// attemptErrorCorrection tries to recover from decoding errors
-func (p *Parser) attemptErrorCorrection(buf *bytes.Reader, val reflect.Value, originalErr error) error {
-	if !p.config.EnableErrorCorrection {
-		return originalErr
-	}
-
-	// Prevent infinite recursion during error correction
-	if p.depth > p.config.MaxDepth/2 {
-		return originalErr
-	}
-
-	// Try to seek forward in the buffer to find a valid structure
-	if buf.Len() < 4 {
-		return originalErr
-	}
-
-	// Skip bytes and try to resynchronize (limited attempts)
-	maxAttempts := p.config.ErrorCorrectionRetries
-	for attempt := 0; attempt < maxAttempts && buf.Len() > 0; attempt++ {
-		var skipByte uint8
-		if err := binary.Read(buf, p.config.ByteOrder, &skipByte); err != nil {
-			return originalErr
-		}
-
-		// For now, we don't retry decoding to avoid infinite recursion
-		// This is a basic implementation that just skips data
-	}
-
-	return originalErr
-}
-
 // attemptTypeConversion tries to convert between compatible types
 func (p *Parser) attemptTypeConversion(buf *bytes.Reader, field reflect.Value, sourceTypeName string) error {
 	if !p.config.EnableErrorCorrection {
@@ -949,15 +1044,11 @@ func (p *Parser) attemptTypeConversion(buf *bytes.Reader, field reflect.Value, s
 		}
 		return p.convertAndSetValue(field, v, targetKind)
 	case "string":
-		var length uint32
-		if err := binary.Read(buf, p.config.ByteOrder, &length); err != nil {
-			return err
-		}
-		str := make([]byte, length)
-		if _, err := buf.Read(str); err != nil {
+		str, err := p.readString(buf)
+		if err != nil {
 			return err
 		}
-		return p.convertAndSetValue(field, string(str), targetKind)
+		return p.convertAndSetValue(field, str, targetKind)
 	default:
 		return errors.New("unsupported type conversion")
 	}
@@ -1049,6 +1140,10 @@ func (p *Parser) validateValue(val reflect.Value, depth int) error {
 		return errors.New("maximum recursion depth exceeded during validation")
 	}
 
+	if usesBinaryMarshaler(val.Type()) {
+		return nil
+	}
+
 	switch val.Kind() {
 	case reflect.Struct:
 		typ := val.Type()
@@ -1066,6 +1161,13 @@ func (p *Parser) validateValue(val reflect.Value, depth int) error {
 			}
 
 			if err := p.validateValue(field, depth+1); err != nil {
+				return fmt.Errorf("field %s: %w", fieldType.Name, err)
+			}
+		}
+	case reflect.Interface:
+		// Interface values are stored as JSON
+		if !val.IsNil() {
+			if _, err := json.Marshal(val.Interface()); err != nil {
 				return err
 			}
 		}
@@ -1145,6 +1247,14 @@ func (p *Parser) estimateValueSize(val reflect.Value, depth int) (int, error) {
 
 	size := 0
 
+	if usesBinaryMarshaler(val.Type()) {
+		data, err := val.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		return 4 + len(data), nil
+	}
+
 	switch val.Kind() {
 	case reflect.Struct:
 		typ := val.Type()
@@ -1164,7 +1274,10 @@ func (p *Parser) estimateValueSize(val reflect.Value, depth int) (int, error) {
 			}
 
 			// Field name size
-			size += 4 + len(fieldType.Name) // length prefix + field name
+			size += 4 + len(p.fieldName(fieldType)) // length prefix + field name
+
+			// Field value length
+			size += 4
 
 			// Field type info if configured
 			if p.config.IncludeFieldTypeInfo {
@@ -1198,6 +1311,10 @@ func (p *Parser) estimateValueSize(val reflect.Value, depth int) (int, error) {
 		}
 	case reflect.Slice, reflect.Array:
 		size += 4 // length
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			size += val.Len()
+			break
+		}
 		for j := 0; j < val.Len(); j++ {
 			elemSize, err := p.estimateValueSize(val.Index(j), depth+1)
 			if err != nil {
@@ -1218,6 +1335,15 @@ func (p *Parser) estimateValueSize(val reflect.Value, depth int) (int, error) {
 			}
 			size += keySize + valueSize
 		}
+	case reflect.Interface:
+		size += 4 // length
+		if !val.IsNil() {
+			data, err := json.Marshal(val.Interface())
+			if err != nil {
+				return 0, err
+			}
+			size += len(data)
+		}
 	case reflect.Bool:
 		size += 1
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -1265,5 +1391,6 @@ func (p *Parser) GetStats() map[string]interface{} {
 		"strict_type_checking":   p.config.StrictTypeChecking,
 		"include_field_versions": p.config.IncludeFieldVersions,
 		"tag_name":               p.config.TagName,
+		"corrected_fields":       p.corrected,
 	}
 }
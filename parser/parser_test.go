@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type roundTripInner struct {
+	Name   string
+	Values []float64
+}
+
+type roundTripRecord struct {
+	Bool   bool
+	Int    int
+	Int8   int8
+	Uint64 uint64
+	Float  float64
+	Text   string
+	Bytes  []byte
+	Nil    []int
+	Empty  []int
+	Array  [3]uint16
+	Map    map[string]int
+	NilMap map[string]int
+	Inner  roundTripInner
+	Ptr    *roundTripInner
+	Inners []roundTripInner
+}
+
+// newRoundTripRecord returns a record whose Text repeats enough to compress when long is set
+func newRoundTripRecord(long bool) roundTripRecord {
+	text := "can we honestly eco?"
+	if long {
+		text = strings.Repeat(text, 200)
+	}
+	return roundTripRecord{
+		Bool:   true,
+		Int:    -42,
+		Int8:   -8,
+		Uint64: 9007199254740993,
+		Float:  3.25,
+		Text:   text,
+		Bytes:  []byte{0, 1, 2, 255},
+		Empty:  []int{},
+		Array:  [3]uint16{1, 2, 3},
+		Map:    map[string]int{"emeralds": 1, "ores": 2},
+		Inner:  roundTripInner{Name: "inner", Values: []float64{0.5, -1}},
+		Ptr:    &roundTripInner{Name: "pointer"},
+		Inners: []roundTripInner{{Name: "a"}, {Name: "b", Values: []float64{}}},
+	}
+}
+
+var roundTripCases = []struct {
+	name        string
+	compression CompressionLevel
+	long        bool
+	flag        uint8
+}{
+	{"none", CompressionNone, true, payloadStored},
+	{"fast", CompressionFast, true, payloadCompressed},
+	{"high", CompressionHigh, true, payloadCompressed},
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, tc := range roundTripCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.CompressionLevel = tc.compression
+			want := newRoundTripRecord(tc.long)
+
+			data, err := NewWithConfig(config).Encode(&want)
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			if !IsETF(data) {
+				t.Fatal("encoded data has no magic number")
+			}
+			if data[8] != tc.flag {
+				t.Errorf("payload flag = %d, want %d", data[8], tc.flag)
+			}
+
+			var got roundTripRecord
+			if err := NewWithConfig(config).Decode(data, &got); err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip changed the record:\ngot  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeStoresIncompressibleData(t *testing.T) {
+	type noise struct {
+		Bytes []byte
+	}
+	want := noise{Bytes: make([]byte, 512)}
+	rng := rand.New(rand.NewSource(1))
+	rng.Read(want.Bytes)
+
+	data, err := New().Encode(&want)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	if data[8] != payloadStored {
+		t.Errorf("payload flag = %d, want %d", data[8], payloadStored)
+	}
+
+	var got noise
+	if err := New().Decode(data, &got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("round trip changed the stored data")
+	}
+}
+
+func TestDecodeRejectsBadHeaders(t *testing.T) {
+	record := newRoundTripRecord(true)
+	compressed, err := New().Encode(&record)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	config := DefaultConfig()
+	config.CompressionLevel = CompressionNone
+	stored, err := NewWithConfig(config).Encode(&record)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	withSize := func(data []byte, size uint32) []byte {
+		data = append([]byte(nil), data...)
+		binary.LittleEndian.PutUint32(data[4:], size)
+		return data
+	}
+	withFlag := func(data []byte, flag uint8) []byte {
+		data = append([]byte(nil), data...)
+		data[8] = flag
+		return data
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", compressed[:8]},
+		{"compressed size beyond the expansion bound", withSize(compressed, 0xFFFFFFF0)},
+		{"stored size mismatch", withSize(stored, uint32(len(stored)))},
+		{"truncated stored payload", stored[:len(stored)-1]},
+		{"unknown payload flag", withFlag(compressed, 7)},
+		{"stored flag on compressed payload", withFlag(compressed, payloadStored)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out roundTripRecord
+			if err := New().Decode(tc.data, &out); err == nil {
+				t.Error("decode succeeded, want an error")
+			}
+		})
+	}
+}
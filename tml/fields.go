@@ -0,0 +1,387 @@
+package tml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"RueaES/typedef"
+)
+
+// Type is the type of a territory field, literals are checked against it when a query is parsed
+type Type int
+
+const (
+	TypeNumber   Type = iota
+	TypeString        // Compared case-insensitively
+	TypeBool          // true/false, yes/no or on/off
+	TypeLevel         // very_low, low, medium, high or very_high, ordered
+	TypeBorder        // open or closed
+	TypeRouting       // cheapest or fastest
+	TypeResource      // emeralds, ores, wood, fish or crops
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	case TypeLevel:
+		return "level"
+	case TypeBorder:
+		return "border"
+	case TypeRouting:
+		return "routing"
+	case TypeResource:
+		return "resource"
+	}
+	return "unknown"
+}
+
+// Value is a typed field value. Levels, borders and routing modes are stored as their ordinal in
+// Number, resources as a bitmask of resourceBit values.
+type Value struct {
+	Type   Type
+	Number float64
+	Text   string
+	Bool   bool
+
+	alias string // Second string a TypeString value also matches, the guild tag for guild
+}
+
+var levelNames = []string{"very_low", "low", "medium", "high", "very_high"}
+
+var resourceNames = []string{"emeralds", "ores", "wood", "fish", "crops"}
+
+// resourceBit returns the bitmask bit of a resource index in resourceNames
+func resourceBit(index int) float64 {
+	return float64(uint(1) << index)
+}
+
+func (v Value) String() string {
+	switch v.Type {
+	case TypeNumber:
+		return fmt.Sprintf("%g", v.Number)
+	case TypeString:
+		return fmt.Sprintf("%q", v.Text)
+	case TypeBool:
+		return fmt.Sprintf("%t", v.Bool)
+	case TypeLevel:
+		if i := int(v.Number); i >= 0 && i < len(levelNames) {
+			return levelNames[i]
+		}
+	case TypeBorder:
+		if typedef.Border(v.Number) == typedef.BorderOpen {
+			return "open"
+		}
+		return "closed"
+	case TypeRouting:
+		if typedef.Routing(v.Number) == typedef.RoutingFastest {
+			return "fastest"
+		}
+		return "cheapest"
+	case TypeResource:
+		var names []string
+		for i, name := range resourceNames {
+			if uint(v.Number)&uint(resourceBit(i)) != 0 {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, "|")
+	}
+	return "?"
+}
+
+// field is a territory property a query can read and, when set is not nil, assign
+type field struct {
+	name  string
+	typ   Type
+	about string
+	get   func(s *Snapshot) Value
+	set   func(opts *typedef.TerritoryOptions, v Value) error
+}
+
+func number(v float64) Value { return Value{Type: TypeNumber, Number: v} }
+
+// assignTax sets a tax rate after checking it is within the range the game allows
+func assignTax(target *float64, v Value) error {
+	if v.Number < 0.05 || v.Number > 0.7 {
+		return fmt.Errorf("tax must be between 0.05 and 0.7 (5%% to 70%%), got %g", v.Number)
+	}
+	*target = v.Number
+	return nil
+}
+
+func upgradeField(name string, level func(u *typedef.Upgrade) *int) *field {
+	return &field{
+		name:  name,
+		typ:   TypeNumber,
+		about: "set " + name + " upgrade level, 0 to 11",
+		get: func(s *Snapshot) Value {
+			return number(float64(*level(&s.Upgrades)))
+		},
+		set: func(opts *typedef.TerritoryOptions, v Value) error {
+			if v.Number < 0 || v.Number > 11 || v.Number != math.Trunc(v.Number) {
+				return fmt.Errorf("%s level must be a whole number between 0 and 11, got %g", name, v.Number)
+			}
+			*level(&opts.Upgrades) = int(v.Number)
+			return nil
+		},
+	}
+}
+
+func bonusField(name string, level func(b *typedef.Bonus) int) *field {
+	return &field{
+		name:  name,
+		typ:   TypeNumber,
+		about: "set " + strings.ReplaceAll(name, "_", " ") + " bonus level",
+		get: func(s *Snapshot) Value {
+			return number(float64(level(&s.Bonuses)))
+		},
+	}
+}
+
+func productionField(name string, amount func(r *typedef.BasicResources) float64) *field {
+	return &field{
+		name:  name,
+		typ:   TypeNumber,
+		about: "base " + name + " production per hour",
+		get: func(s *Snapshot) Value {
+			return number(amount(&s.Production))
+		},
+	}
+}
+
+// fields holds every queryable territory property by name. Assignments build on the options the
+// territory currently has, so setting tax leaves ally tax, border and upgrades as they are.
+var fields = map[string]*field{}
+
+func init() {
+	list := []*field{
+		{name: "name", typ: TypeString, about: "territory name",
+			get: func(s *Snapshot) Value { return Value{Type: TypeString, Text: s.Name} }},
+		{name: "guild", typ: TypeString, about: "owning guild name or tag",
+			get: func(s *Snapshot) Value { return Value{Type: TypeString, Text: s.GuildName, alias: s.GuildTag} }},
+		{name: "tag", typ: TypeString, about: "owning guild tag",
+			get: func(s *Snapshot) Value { return Value{Type: TypeString, Text: s.GuildTag} }},
+		{name: "hq", typ: TypeBool, about: "whether the territory is its guild's HQ, can only be set to true on one territory",
+			get: func(s *Snapshot) Value { return Value{Type: TypeBool, Bool: s.HQ} },
+			set: func(opts *typedef.TerritoryOptions, v Value) error {
+				if !v.Bool {
+					return fmt.Errorf("hq can only be set to true, move the HQ to another territory instead")
+				}
+				opts.HQ = true
+				return nil
+			}},
+		{name: "level", typ: TypeLevel, about: "current defence level",
+			get: func(s *Snapshot) Value { return Value{Type: TypeLevel, Number: float64(s.Level)} }},
+		{name: "set_level", typ: TypeLevel, about: "defence level of the set upgrades and bonuses",
+			get: func(s *Snapshot) Value { return Value{Type: TypeLevel, Number: float64(s.SetLevel)} }},
+		{name: "treasury", typ: TypeLevel, about: "treasury level",
+			get: func(s *Snapshot) Value { return Value{Type: TypeLevel, Number: float64(s.Treasury)} }},
+		{name: "border", typ: TypeBorder, about: "border status, open or closed",
+			get: func(s *Snapshot) Value { return Value{Type: TypeBorder, Number: float64(s.Border)} },
+			set: func(opts *typedef.TerritoryOptions, v Value) error {
+				opts.Border = typedef.Border(v.Number)
+				return nil
+			}},
+		{name: "routing", typ: TypeRouting, about: "routing mode, cheapest or fastest",
+			get: func(s *Snapshot) Value { return Value{Type: TypeRouting, Number: float64(s.RoutingMode)} },
+			set: func(opts *typedef.TerritoryOptions, v Value) error {
+				opts.RoutingMode = typedef.Routing(v.Number)
+				return nil
+			}},
+		{name: "tax", typ: TypeNumber, about: "tax for non-allied guilds, 0.05 to 0.7 or 5% to 70%",
+			get: func(s *Snapshot) Value { return number(s.Tax.Tax) },
+			set: func(opts *typedef.TerritoryOptions, v Value) error { return assignTax(&opts.Tax.Tax, v) }},
+		{name: "ally", typ: TypeNumber, about: "tax for allied guilds, 0.05 to 0.7 or 5% to 70%",
+			get: func(s *Snapshot) Value { return number(s.Tax.Ally) },
+			set: func(opts *typedef.TerritoryOptions, v Value) error { return assignTax(&opts.Tax.Ally, v) }},
+		{name: "route_tax", typ: TypeNumber, about: "tax paid along the route to the HQ, -1 without a route",
+			get: func(s *Snapshot) Value { return number(s.RouteTax) }},
+		{name: "routes", typ: TypeNumber, about: "number of trading routes",
+			get: func(s *Snapshot) Value { return number(float64(s.Routes)) }},
+		{name: "connections", typ: TypeNumber, about: "number of connected territories",
+			get: func(s *Snapshot) Value { return number(float64(s.Connections)) }},
+		{name: "resource", typ: TypeResource, about: "resources the territory produces",
+			get: func(s *Snapshot) Value { return Value{Type: TypeResource, Number: s.producedResources()} }},
+
+		upgradeField("damage", func(u *typedef.Upgrade) *int { return &u.Damage }),
+		upgradeField("attack", func(u *typedef.Upgrade) *int { return &u.Attack }),
+		upgradeField("health", func(u *typedef.Upgrade) *int { return &u.Health }),
+		upgradeField("defence", func(u *typedef.Upgrade) *int { return &u.Defence }),
+
+		bonusField("stronger_minions", func(b *typedef.Bonus) int { return b.StrongerMinions }),
+		bonusField("tower_multi_attack", func(b *typedef.Bonus) int { return b.TowerMultiAttack }),
+		bonusField("tower_aura", func(b *typedef.Bonus) int { return b.TowerAura }),
+		bonusField("tower_volley", func(b *typedef.Bonus) int { return b.TowerVolley }),
+		bonusField("gathering_experience", func(b *typedef.Bonus) int { return b.GatheringExperience }),
+		bonusField("mob_experience", func(b *typedef.Bonus) int { return b.MobExperience }),
+		bonusField("mob_damage", func(b *typedef.Bonus) int { return b.MobDamage }),
+		bonusField("pvp_damage", func(b *typedef.Bonus) int { return b.PvPDamage }),
+		bonusField("xp_seeking", func(b *typedef.Bonus) int { return b.XPSeeking }),
+		bonusField("tome_seeking", func(b *typedef.Bonus) int { return b.TomeSeeking }),
+		bonusField("emerald_seeking", func(b *typedef.Bonus) int { return b.EmeraldSeeking }),
+		bonusField("larger_resource_storage", func(b *typedef.Bonus) int { return b.LargerResourceStorage }),
+		bonusField("larger_emerald_storage", func(b *typedef.Bonus) int { return b.LargerEmeraldStorage }),
+		bonusField("efficient_resource", func(b *typedef.Bonus) int { return b.EfficientResource }),
+		bonusField("efficient_emerald", func(b *typedef.Bonus) int { return b.EfficientEmerald }),
+		bonusField("resource_rate", func(b *typedef.Bonus) int { return b.ResourceRate }),
+		bonusField("emerald_rate", func(b *typedef.Bonus) int { return b.EmeraldRate }),
+
+		productionField("emeralds", func(r *typedef.BasicResources) float64 { return r.Emeralds }),
+		productionField("ores", func(r *typedef.BasicResources) float64 { return r.Ores }),
+		productionField("wood", func(r *typedef.BasicResources) float64 { return r.Wood }),
+		productionField("fish", func(r *typedef.BasicResources) float64 { return r.Fish }),
+		productionField("crops", func(r *typedef.BasicResources) float64 { return r.Crops }),
+	}
+	for _, f := range list {
+		fields[f.name] = f
+	}
+}
+
+// FieldInfo describes a queryable field for help text and API clients
+type FieldInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Settable bool   `json:"settable"`
+	About    string `json:"about"`
+}
+
+// Fields lists the fields queries can use, sorted by name
+func Fields() []FieldInfo {
+	infos := make([]FieldInfo, 0, len(fields))
+	for _, f := range fields {
+		infos = append(infos, FieldInfo{Name: f.name, Type: f.typ.String(), Settable: f.set != nil, About: f.about})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// literal converts a literal token to a value of the field's type
+func (f *field) literal(tok token) (Value, error) {
+	word := strings.ToLower(tok.text)
+	if tok.kind != tokenIdent && tok.kind != tokenString && tok.kind != tokenNumber {
+		return Value{}, errorAt(tok.pos, "expected a value for %s, got %s", f.name, tok.kind)
+	}
+
+	switch f.typ {
+	case TypeNumber:
+		if tok.kind == tokenNumber {
+			return number(tok.number), nil
+		}
+
+	case TypeString:
+		return Value{Type: TypeString, Text: tok.text}, nil
+
+	case TypeBool:
+		switch word {
+		case "true", "yes", "on":
+			return Value{Type: TypeBool, Bool: true}, nil
+		case "false", "no", "off":
+			return Value{Type: TypeBool, Bool: false}, nil
+		}
+
+	case TypeLevel:
+		normalized := strings.NewReplacer(" ", "_", "-", "_").Replace(word)
+		if normalized == "verylow" {
+			normalized = "very_low"
+		} else if normalized == "veryhigh" {
+			normalized = "very_high"
+		}
+		for i, name := range levelNames {
+			if normalized == name {
+				return Value{Type: TypeLevel, Number: float64(i)}, nil
+			}
+		}
+
+	case TypeBorder:
+		switch word {
+		case "open":
+			return Value{Type: TypeBorder, Number: float64(typedef.BorderOpen)}, nil
+		case "closed", "close":
+			return Value{Type: TypeBorder, Number: float64(typedef.BorderClosed)}, nil
+		}
+
+	case TypeRouting:
+		switch word {
+		case "cheapest":
+			return Value{Type: TypeRouting, Number: float64(typedef.RoutingCheapest)}, nil
+		case "fastest":
+			return Value{Type: TypeRouting, Number: float64(typedef.RoutingFastest)}, nil
+		}
+
+	case TypeResource:
+		for i, name := range resourceNames {
+			if word == name || word+"s" == name {
+				return Value{Type: TypeResource, Number: resourceBit(i)}, nil
+			}
+		}
+	}
+
+	return Value{}, errorAt(tok.pos, "%s is not a valid %s value for %s", tok.text, f.typ, f.name)
+}
+
+// operatorAllowed reports whether a comparison operator applies to values of type t
+func operatorAllowed(t Type, op string) bool {
+	switch op {
+	case "=", "!=":
+		return true
+	case "<", "<=", ">", ">=":
+		return t == TypeNumber || t == TypeLevel
+	case "~", "!~":
+		return t == TypeString
+	}
+	return false
+}
+
+// compare applies op to a territory value and a literal of the same type
+func compare(op string, left, right Value) bool {
+	switch left.Type {
+	case TypeString:
+		matches := func(text string) bool {
+			if op == "~" || op == "!~" {
+				return strings.Contains(strings.ToLower(text), strings.ToLower(right.Text))
+			}
+			return strings.EqualFold(text, right.Text)
+		}
+		matched := matches(left.Text) || left.alias != "" && matches(left.alias)
+		if op == "!=" || op == "!~" {
+			return !matched
+		}
+		return matched
+
+	case TypeBool:
+		if op == "!=" {
+			return left.Bool != right.Bool
+		}
+		return left.Bool == right.Bool
+
+	case TypeResource:
+		produced := uint(left.Number)&uint(right.Number) != 0
+		if op == "!=" {
+			return !produced
+		}
+		return produced
+	}
+
+	switch op {
+	case "=":
+		return left.Number == right.Number
+	case "!=":
+		return left.Number != right.Number
+	case "<":
+		return left.Number < right.Number
+	case "<=":
+		return left.Number <= right.Number
+	case ">":
+		return left.Number > right.Number
+	case ">=":
+		return left.Number >= right.Number
+	}
+	return false
+}
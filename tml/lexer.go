@@ -0,0 +1,187 @@
+package tml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of query"
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenOperator:
+		return "operator"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenComma:
+		return "','"
+	}
+	return "token"
+}
+
+type token struct {
+	kind   tokenKind
+	text   string  // Identifier or operator text, unquoted string contents
+	number float64 // Value of number tokens, percentages are already divided by 100
+	pos    int     // Byte offset in the query
+}
+
+// Error is a syntax or type error in a query, Pos is the byte offset it was found at
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("tml: %s (at position %d)", e.Msg, e.Pos+1)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// operators lists the comparison operators, longest first so "<=" wins over "<"
+var operators = []string{"==", "!=", "<>", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+// lex splits a query into tokens. The returned slice always ends with a tokenEOF.
+func lex(source string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(source) {
+		c := source[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			pos++
+
+		case c == '"' || c == '\'':
+			text, end, err := lexString(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos = end
+
+		case isDigit(c) || c == '.' && pos+1 < len(source) && isDigit(source[pos+1]) ||
+			c == '-' && pos+1 < len(source) && (isDigit(source[pos+1]) || source[pos+1] == '.'):
+			tok, end, err := lexNumber(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos = end
+
+		case isIdentStart(rune(c)):
+			end := pos
+			for end < len(source) && isIdentPart(rune(source[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[pos:end], pos: pos})
+			pos = end
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(source[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorAt(pos, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// lexString reads a quoted string starting at pos. Backslash escapes the next character.
+func lexString(source string, pos int) (string, int, error) {
+	quote := source[pos]
+	var sb strings.Builder
+	for i := pos + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 < len(source) {
+				i++
+				sb.WriteByte(source[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(source[i])
+		}
+	}
+	return "", 0, errorAt(pos, "unterminated string")
+}
+
+// lexNumber reads a number starting at pos. A trailing % divides the value by 100.
+func lexNumber(source string, pos int) (token, int, error) {
+	end := pos
+	if source[end] == '-' {
+		end++
+	}
+	for end < len(source) && (isDigit(source[end]) || source[end] == '.') {
+		end++
+	}
+	value, err := strconv.ParseFloat(source[pos:end], 64)
+	if err != nil {
+		return token{}, 0, errorAt(pos, "invalid number %q", source[pos:end])
+	}
+	if end < len(source) && source[end] == '%' {
+		value /= 100
+		end++
+	}
+	if end < len(source) && isIdentPart(rune(source[end])) {
+		return token{}, 0, errorAt(end, "unexpected %q after number", source[end])
+	}
+	return token{kind: tokenNumber, text: source[pos:end], number: value, pos: pos}, end, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,101 @@
+package tml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	cases := []struct {
+		name   string
+		source string
+		want   []token
+	}{
+		{"empty", "", []token{{kind: tokenEOF, pos: 0}}},
+		{"keywords and fields", "select where route_tax", []token{
+			{kind: tokenIdent, text: "select", pos: 0},
+			{kind: tokenIdent, text: "where", pos: 7},
+			{kind: tokenIdent, text: "route_tax", pos: 13},
+			{kind: tokenEOF, pos: 22},
+		}},
+		{"operators take the longest match", "<= < == = <> != !~ ~ >= >", []token{
+			{kind: tokenOperator, text: "<=", pos: 0},
+			{kind: tokenOperator, text: "<", pos: 3},
+			{kind: tokenOperator, text: "==", pos: 5},
+			{kind: tokenOperator, text: "=", pos: 8},
+			{kind: tokenOperator, text: "<>", pos: 10},
+			{kind: tokenOperator, text: "!=", pos: 13},
+			{kind: tokenOperator, text: "!~", pos: 16},
+			{kind: tokenOperator, text: "~", pos: 19},
+			{kind: tokenOperator, text: ">=", pos: 21},
+			{kind: tokenOperator, text: ">", pos: 24},
+			{kind: tokenEOF, pos: 25},
+		}},
+		{"numbers", "3 -2.5 .5 30%", []token{
+			{kind: tokenNumber, text: "3", number: 3, pos: 0},
+			{kind: tokenNumber, text: "-2.5", number: -2.5, pos: 2},
+			{kind: tokenNumber, text: ".5", number: 0.5, pos: 7},
+			{kind: tokenNumber, text: "30%", number: 0.3, pos: 10},
+			{kind: tokenEOF, pos: 13},
+		}},
+		{"strings with escapes", `"Ragni" 'it\'s' "a\"b"`, []token{
+			{kind: tokenString, text: "Ragni", pos: 0},
+			{kind: tokenString, text: "it's", pos: 8},
+			{kind: tokenString, text: `a"b`, pos: 16},
+			{kind: tokenEOF, pos: 22},
+		}},
+		{"punctuation without spaces", "(hq,tax=5%)", []token{
+			{kind: tokenLParen, text: "(", pos: 0},
+			{kind: tokenIdent, text: "hq", pos: 1},
+			{kind: tokenComma, text: ",", pos: 3},
+			{kind: tokenIdent, text: "tax", pos: 4},
+			{kind: tokenOperator, text: "=", pos: 7},
+			{kind: tokenNumber, text: "5%", number: 0.05, pos: 8},
+			{kind: tokenRParen, text: ")", pos: 10},
+			{kind: tokenEOF, pos: 11},
+		}},
+		{"dotted identifiers", "guild.name\tlevel\n", []token{
+			{kind: tokenIdent, text: "guild.name", pos: 0},
+			{kind: tokenIdent, text: "level", pos: 11},
+			{kind: tokenEOF, pos: 17},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := lex(tc.source)
+			if err != nil {
+				t.Fatalf("lex failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("lex(%q):\ngot  %+v\nwant %+v", tc.source, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	cases := []struct {
+		source string
+		pos    int
+		msg    string
+	}{
+		{`name = "Ragni`, 7, "unterminated string"},
+		{"tax = 1.2.3", 6, `invalid number "1.2.3"`},
+		{"tax = 5x", 7, `unexpected 'x' after number`},
+		{"hq & tax", 3, `unexpected character '&'`},
+		{"!", 0, `unexpected character '!'`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			_, err := lex(tc.source)
+			var tmlErr *Error
+			if !errors.As(err, &tmlErr) {
+				t.Fatalf("lex(%q) error = %v, want a *tml.Error", tc.source, err)
+			}
+			if tmlErr.Pos != tc.pos || tmlErr.Msg != tc.msg {
+				t.Errorf("lex(%q) error at %d %q, want at %d %q", tc.source, tmlErr.Pos, tmlErr.Msg, tc.pos, tc.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package tml
+
+import (
+	"strings"
+
+	"RueaES/typedef"
+)
+
+// QueryKind tells whether a query only selects territories or also changes them
+type QueryKind string
+
+const (
+	QuerySelect QueryKind = "select"
+	QuerySet    QueryKind = "set"
+)
+
+// expr is a condition of a where clause
+type expr interface {
+	eval(s *Snapshot) bool
+	String() string
+}
+
+type andExpr struct{ left, right expr }
+type orExpr struct{ left, right expr }
+type notExpr struct{ inner expr }
+
+type compareExpr struct {
+	field *field
+	op    string
+	value Value
+}
+
+func (e *andExpr) eval(s *Snapshot) bool { return e.left.eval(s) && e.right.eval(s) }
+func (e *orExpr) eval(s *Snapshot) bool  { return e.left.eval(s) || e.right.eval(s) }
+func (e *notExpr) eval(s *Snapshot) bool { return !e.inner.eval(s) }
+func (e *compareExpr) eval(s *Snapshot) bool {
+	return compare(e.op, e.field.get(s), e.value)
+}
+
+func (e *andExpr) String() string     { return "(" + e.left.String() + " and " + e.right.String() + ")" }
+func (e *orExpr) String() string      { return "(" + e.left.String() + " or " + e.right.String() + ")" }
+func (e *notExpr) String() string     { return "not " + e.inner.String() }
+func (e *compareExpr) String() string { return e.field.name + " " + e.op + " " + e.value.String() }
+
+// assignment is one "field value" pair of a set query
+type assignment struct {
+	field *field
+	value Value
+}
+
+// Query is a parsed query, ready to run against territory snapshots
+type Query struct {
+	Kind   QueryKind
+	Source string
+
+	where       expr // nil matches every territory
+	assignments []assignment
+}
+
+// String returns the query in normalized form
+func (q *Query) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(q.Kind))
+	for i, a := range q.assignments {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(" ")
+		sb.WriteString(a.field.name)
+		sb.WriteString(" ")
+		sb.WriteString(a.value.String())
+	}
+	if q.where != nil {
+		sb.WriteString(" where ")
+		sb.WriteString(q.where.String())
+	}
+	return sb.String()
+}
+
+// Match reports whether a territory snapshot satisfies the where clause
+func (q *Query) Match(s *Snapshot) bool {
+	return q.where == nil || q.where.eval(s)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query. Queries have the form
+//
+//	select [where <condition>]
+//	set <field> <value> [, <field> <value>...] [where <condition>]
+//
+// Conditions compare fields with =, !=, <, <=, >, >= or ~ (contains) and combine them with and,
+// or, not and parentheses. A bool field on its own, like "where hq", means "hq = true". Literals
+// are checked against the field type, so "level >= 0.3" is rejected while parsing.
+func Parse(source string) (*Query, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	query := &Query{Source: source}
+
+	head := p.next()
+	switch {
+	case p.isKeyword(head, "select"):
+		query.Kind = QuerySelect
+	case p.isKeyword(head, "set"):
+		query.Kind = QuerySet
+		if query.assignments, err = p.parseAssignments(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errorAt(head.pos, "query must start with select or set")
+	}
+
+	if tok := p.peek(); p.isKeyword(tok, "where") {
+		p.next()
+		if query.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorAt(tok.pos, "unexpected %q", tok.text)
+	}
+	return query, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(tok token, keyword string) bool {
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, keyword)
+}
+
+// lookupField resolves a field name token
+func (p *parser) lookupField(tok token) (*field, error) {
+	if tok.kind != tokenIdent {
+		return nil, errorAt(tok.pos, "expected a field name, got %s", tok.kind)
+	}
+	f, ok := fields[strings.ToLower(tok.text)]
+	if !ok {
+		return nil, errorAt(tok.pos, "unknown field %q", tok.text)
+	}
+	return f, nil
+}
+
+func (p *parser) parseAssignments() ([]assignment, error) {
+	var assignments []assignment
+	seen := map[string]bool{}
+	for {
+		tok := p.next()
+		f, err := p.lookupField(tok)
+		if err != nil {
+			return nil, err
+		}
+		if f.set == nil {
+			return nil, errorAt(tok.pos, "field %s cannot be set", f.name)
+		}
+		if seen[f.name] {
+			return nil, errorAt(tok.pos, "field %s is set twice", f.name)
+		}
+		seen[f.name] = true
+
+		valueTok := p.next()
+		if valueTok.kind == tokenOperator && valueTok.text == "=" {
+			valueTok = p.next()
+		}
+		value, err := f.literal(valueTok)
+		if err != nil {
+			return nil, err
+		}
+		// Catch out of range values while parsing rather than per territory
+		var scratch typedef.TerritoryOptions
+		if err := f.set(&scratch, value); err != nil {
+			return nil, errorAt(valueTok.pos, "%v", err)
+		}
+		assignments = append(assignments, assignment{field: f, value: value})
+
+		tok = p.peek()
+		if tok.kind == tokenComma {
+			p.next()
+			continue
+		}
+		if tok.kind == tokenEOF || p.isKeyword(tok, "where") {
+			return assignments, nil
+		}
+	}
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	tok := p.peek()
+	if p.isKeyword(tok, "not") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{inner: inner}, nil
+	}
+
+	if tok.kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorAt(closing.pos, "expected ')', got %s", closing.kind)
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	fieldTok := p.next()
+	f, err := p.lookupField(fieldTok)
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.peek()
+	if opTok.kind != tokenOperator {
+		if f.typ == TypeBool {
+			return &compareExpr{field: f, op: "=", value: Value{Type: TypeBool, Bool: true}}, nil
+		}
+		return nil, errorAt(opTok.pos, "expected an operator after %s", f.name)
+	}
+	p.next()
+
+	op := opTok.text
+	switch op {
+	case "==":
+		op = "="
+	case "<>":
+		op = "!="
+	}
+	if !operatorAllowed(f.typ, op) {
+		return nil, errorAt(opTok.pos, "operator %s cannot be used with %s, which is a %s", op, f.name, f.typ)
+	}
+
+	value, err := f.literal(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return &compareExpr{field: f, op: op, value: value}, nil
+}
@@ -0,0 +1,86 @@
+package tml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		source string
+		kind   QueryKind
+		want   string // Normalized query
+	}{
+		{"select", QuerySelect, "select"},
+		{"SELECT WHERE hq", QuerySelect, "select where hq = true"},
+		{`select where guild = "AVO" and level >= high`, QuerySelect, `select where (guild = "AVO" and level >= high)`},
+		{"select where tax <> 10% or not (hq or border = open)", QuerySelect, "select where (tax != 0.1 or not (hq = true or border = open))"},
+		{"select where resource = ore", QuerySelect, "select where resource = ores"},
+		{"set tax 30%, border closed where route_tax > 0.2", QuerySet, "set tax 0.3, border closed where route_tax > 0.2"},
+		{"set routing = fastest, damage 11", QuerySet, "set routing fastest, damage 11"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			query, err := Parse(tc.source)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if query.Kind != tc.kind {
+				t.Errorf("kind = %s, want %s", query.Kind, tc.kind)
+			}
+			if got := query.String(); got != tc.want {
+				t.Errorf("normalized to %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		source string
+		pos    int
+		msg    string // Expected start of the message
+	}{
+		{"", 0, "query must start with select or set"},
+		{"delete where hq", 0, "query must start with select or set"},
+		{"select x", 7, `unexpected "x"`},
+		{"select where foo = 1", 13, `unknown field "foo"`},
+		{"select where 5 = 1", 13, "expected a field name, got number"},
+		{"select where tax", 16, "expected an operator after tax"},
+		{"select where level >= 0.3", 22, "0.3 is not a valid level value for level"},
+		{`select where name < "a"`, 18, "operator < cannot be used with name, which is a string"},
+		{"select where border ~ open", 20, "operator ~ cannot be used with border, which is a border"},
+		{"select where (hq", 16, "expected ')', got end of query"},
+		{"select where hq and", 19, "expected a field name, got end of query"},
+		{"select where tax = )", 19, "expected a value for tax, got ')'"},
+		{`set name "x"`, 4, "field name cannot be set"},
+		{"set tax 0.3, tax 0.4", 13, "field tax is set twice"},
+		{"set tax 0.9", 8, "tax must be between 0.05 and 0.7"},
+		{"set damage 2.5", 11, "damage level must be a whole number"},
+		{"set hq false", 7, "hq can only be set to true"},
+		{"set border sideways", 11, "sideways is not a valid border value for border"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.source, func(t *testing.T) {
+			_, err := Parse(tc.source)
+			var tmlErr *Error
+			if !errors.As(err, &tmlErr) {
+				t.Fatalf("Parse(%q) error = %v, want a *tml.Error", tc.source, err)
+			}
+			if tmlErr.Pos != tc.pos || !strings.HasPrefix(tmlErr.Msg, tc.msg) {
+				t.Errorf("Parse(%q) error at %d %q, want at %d %q", tc.source, tmlErr.Pos, tmlErr.Msg, tc.pos, tc.msg)
+			}
+		})
+	}
+}
+
+func TestErrorReportsOneBasedPosition(t *testing.T) {
+	_, err := Parse("select where foo = 1")
+	if err == nil {
+		t.Fatal("parse succeeded, want an error")
+	}
+	if want := `tml: unknown field "foo" (at position 14)`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,169 @@
+package tml
+
+import (
+	"fmt"
+	"sort"
+
+	"RueaES/eruntime"
+	"RueaES/typedef"
+)
+
+// Snapshot is a copy of the territory data queries evaluate against, taken under the territory
+// lock so a query sees consistent values while the simulation keeps running
+type Snapshot struct {
+	Name        string
+	GuildName   string
+	GuildTag    string
+	HQ          bool
+	Level       typedef.DefenceLevel
+	SetLevel    typedef.DefenceLevel
+	Treasury    typedef.TreasuryLevel
+	Border      typedef.Border
+	RoutingMode typedef.Routing
+	Tax         typedef.TerritoryTax
+	RouteTax    float64
+	Routes      int
+	Connections int
+	Upgrades    typedef.Upgrade
+	Bonuses     typedef.Bonus
+	Production  typedef.BasicResources
+}
+
+// NewSnapshot copies the queryable data of a territory
+func NewSnapshot(t *typedef.Territory) *Snapshot {
+	t.Mu.RLock()
+	defer t.Mu.RUnlock()
+	return &Snapshot{
+		Name:        t.Name,
+		GuildName:   t.Guild.Name,
+		GuildTag:    t.Guild.Tag,
+		HQ:          t.HQ,
+		Level:       t.Level,
+		SetLevel:    t.SetLevel,
+		Treasury:    t.Treasury,
+		Border:      t.Border,
+		RoutingMode: t.RoutingMode,
+		Tax:         t.Tax,
+		RouteTax:    t.RouteTax,
+		Routes:      len(t.TradingRoutes),
+		Connections: len(t.Links.Direct),
+		Upgrades:    t.Options.Upgrade.Set,
+		Bonuses:     t.Options.Bonus.Set,
+		Production:  t.ResourceGeneration.Base,
+	}
+}
+
+// Snapshots copies every territory of the running simulation, sorted by name
+func Snapshots() []*Snapshot {
+	territories := eruntime.GetTerritories()
+	snapshots := make([]*Snapshot, 0, len(territories))
+	for _, t := range territories {
+		if t != nil {
+			snapshots = append(snapshots, NewSnapshot(t))
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
+}
+
+// producedResources returns the resourceBit mask of the resources with base production
+func (s *Snapshot) producedResources() float64 {
+	amounts := []float64{s.Production.Emeralds, s.Production.Ores, s.Production.Wood, s.Production.Fish, s.Production.Crops}
+	mask := 0.0
+	for i, amount := range amounts {
+		if amount > 0 {
+			mask += resourceBit(i)
+		}
+	}
+	return mask
+}
+
+// options returns the territory options Set would need to keep the territory as it is
+func (s *Snapshot) options() typedef.TerritoryOptions {
+	return typedef.TerritoryOptions{
+		Upgrades:    s.Upgrades,
+		Bonuses:     s.Bonuses,
+		Tax:         s.Tax,
+		RoutingMode: s.RoutingMode,
+		Border:      s.Border,
+		HQ:          s.HQ,
+	}
+}
+
+// Result is the outcome of running a query. Matched lists every territory the where clause
+// selected; for set queries Changed lists the territories that were updated and Failed the ones
+// the runtime refused, such as territories removed while the query ran.
+type Result struct {
+	Kind    QueryKind `json:"kind"`
+	Query   string    `json:"query"`
+	Matched []string  `json:"matched"`
+	Changed []string  `json:"changed,omitempty"`
+	Failed  []string  `json:"failed,omitempty"`
+}
+
+// TerritoryResults resolves the matched territories for display
+func (r *Result) TerritoryResults() []*TerritoryResult {
+	results := make([]*TerritoryResult, 0, len(r.Matched))
+	for _, name := range r.Matched {
+		t := eruntime.GetTerritory(name)
+		results = append(results, &TerritoryResult{Success: t != nil, Territory: t})
+	}
+	return results
+}
+
+// Filter returns the snapshots the query matches
+func (q *Query) Filter(snapshots []*Snapshot) []*Snapshot {
+	var matched []*Snapshot
+	for _, s := range snapshots {
+		if q.Match(s) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// Execute runs the query against the current territories. Set queries apply their assignments
+// to every matched territory through eruntime.Set.
+func (q *Query) Execute() (*Result, error) {
+	matched := q.Filter(Snapshots())
+	result := &Result{Kind: q.Kind, Query: q.String(), Matched: make([]string, 0, len(matched))}
+	for _, s := range matched {
+		result.Matched = append(result.Matched, s.Name)
+	}
+	if q.Kind != QuerySet {
+		return result, nil
+	}
+
+	for _, a := range q.assignments {
+		if a.field.name == "hq" && len(matched) > 1 {
+			return nil, fmt.Errorf("tml: set hq matched %d territories, narrow the where clause to one territory", len(matched))
+		}
+	}
+
+	for _, s := range matched {
+		opts := s.options()
+		for _, a := range q.assignments {
+			if err := a.field.set(&opts, a.value); err != nil {
+				return nil, fmt.Errorf("tml: %v", err)
+			}
+		}
+		if opts == s.options() {
+			continue
+		}
+		if eruntime.Set(s.Name, opts) == nil {
+			result.Failed = append(result.Failed, s.Name)
+			continue
+		}
+		result.Changed = append(result.Changed, s.Name)
+	}
+	return result, nil
+}
+
+// Run parses and executes a query
+func Run(source string) (*Result, error) {
+	query, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return query.Execute()
+}
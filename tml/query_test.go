@@ -0,0 +1,86 @@
+package tml
+
+import (
+	"reflect"
+	"testing"
+
+	"RueaES/typedef"
+)
+
+// testSnapshots builds territories by hand so queries run without the runtime
+func testSnapshots() []*Snapshot {
+	ragni := &Snapshot{
+		Name:        "Ragni",
+		GuildName:   "Avicia",
+		GuildTag:    "AVO",
+		HQ:          true,
+		Level:       typedef.DefenceLevelHigh,
+		Treasury:    typedef.TreasuryLevelMedium,
+		Border:      typedef.BorderOpen,
+		Tax:         typedef.TerritoryTax{Tax: 0.3, Ally: 0.1},
+		RouteTax:    -1,
+		Connections: 3,
+		Production:  typedef.BasicResources{Emeralds: 9000},
+	}
+	ragni.Upgrades.Damage = 6
+
+	maltic := &Snapshot{
+		Name:        "Maltic",
+		GuildName:   "Avicia",
+		GuildTag:    "AVO",
+		Level:       typedef.DefenceLevelLow,
+		Border:      typedef.BorderClosed,
+		RoutingMode: typedef.RoutingFastest,
+		Tax:         typedef.TerritoryTax{Tax: 0.1, Ally: 0.1},
+		RouteTax:    0.25,
+		Routes:      1,
+		Connections: 1,
+		Production:  typedef.BasicResources{Ores: 3600, Wood: 3600},
+	}
+	detlas := &Snapshot{
+		Name:       "Detlas",
+		GuildName:  "Empire of Sindria",
+		GuildTag:   "ESI",
+		Level:      typedef.DefenceLevelVeryHigh,
+		Tax:        typedef.TerritoryTax{Tax: 0.05, Ally: 0.05},
+		Production: typedef.BasicResources{Crops: 3600},
+	}
+	return []*Snapshot{ragni, maltic, detlas}
+}
+
+func TestQueryFilter(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"select", []string{"Ragni", "Maltic", "Detlas"}},
+		{`select where guild = "avo"`, []string{"Ragni", "Maltic"}},
+		{`select where guild ~ "sindria"`, []string{"Detlas"}},
+		{"select where hq", []string{"Ragni"}},
+		{"select where not hq and tag = AVO", []string{"Maltic"}},
+		{"select where level >= high", []string{"Ragni", "Detlas"}},
+		{"select where tax >= 30%", []string{"Ragni"}},
+		{"select where route_tax > 0.2 or connections = 3", []string{"Ragni", "Maltic"}},
+		{"select where resource = ores", []string{"Maltic"}},
+		{"select where resource != crops", []string{"Ragni", "Maltic"}},
+		{"select where border = closed and routing = fastest", []string{"Maltic"}},
+		{"select where damage >= 6", []string{"Ragni"}},
+		{"select where emeralds > 0", []string{"Ragni"}},
+		{"select where (guild = ESI or hq) and not treasury = medium", []string{"Detlas"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			query, err := Parse(tc.query)
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			got := []string{}
+			for _, s := range query.Filter(testSnapshots()) {
+				got = append(got, s.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("matched %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
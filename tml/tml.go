@@ -1,3 +1,5 @@
+// Package tml implements a small query and command language over territories, for example
+// `select where guild = "ABC" and level >= high` or `set tax 0.3 border closed where route_tax > 0.2`.
 package tml
 
 import "RueaES/typedef"
@@ -17,5 +19,3 @@ type TerritoryResult struct {
 	Success   bool
 	Territory *typedef.Territory
 }
-
-func Parse()
@@ -0,0 +1,60 @@
+package typedef
+
+import "encoding/json"
+
+// AnnotationKind is the shape of a map annotation
+type AnnotationKind string
+
+const (
+	AnnotationMarker   AnnotationKind = "marker"   // A dot at one point, with an optional label
+	AnnotationPolyline AnnotationKind = "polyline" // An open path such as a planned attack route
+	AnnotationPolygon  AnnotationKind = "polygon"  // A closed, filled area such as a zone
+	AnnotationLabel    AnnotationKind = "label"    // Text at one point
+)
+
+// AnnotationPoint is a position in map pixel coordinates, the same space as territory locations
+type AnnotationPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Annotation is a single shape drawn on the map. Markers and labels use the first point only.
+type Annotation struct {
+	ID      string            `json:"id"`
+	Kind    AnnotationKind    `json:"kind"`
+	Points  []AnnotationPoint `json:"points"`
+	Text    string            `json:"text,omitempty"`  // Label text, or the caption of a marker
+	Color   *RGBAColor        `json:"color,omitempty"` // The layer color when nil
+	Size    float64           `json:"size,omitempty"`  // Marker diameter or line width in map pixels, a default when 0
+	Visible bool              `json:"visible"`
+}
+
+// UnmarshalJSON decodes an annotation, making it visible when the document has no visible key
+func (a *Annotation) UnmarshalJSON(data []byte) error {
+	type plain Annotation
+	decoded := plain{Visible: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*a = Annotation(decoded)
+	return nil
+}
+
+// AnnotationLayer is a named group of annotations that is shown, hidden and shared together
+type AnnotationLayer struct {
+	Name        string       `json:"name"`
+	Color       RGBAColor    `json:"color"`
+	Visible     bool         `json:"visible"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// UnmarshalJSON decodes a layer, making it visible when the document has no visible key
+func (l *AnnotationLayer) UnmarshalJSON(data []byte) error {
+	type plain AnnotationLayer
+	decoded := plain{Visible: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*l = AnnotationLayer(decoded)
+	return nil
+}
@@ -0,0 +1,17 @@
+package typedef
+
+// AutoSaveSettings configures the autosave history and interval autosave.
+type AutoSaveSettings struct {
+	HistorySize     int  `json:"historySize"`     // Autosaves kept in the history, 0 disables the history
+	IntervalMinutes int  `json:"intervalMinutes"` // Autosave every this many minutes in addition to change-triggered autosaves, 0 disables it
+	PickerOnStartup bool `json:"pickerOnStartup"` // Offer to restore from the history when the app starts
+}
+
+// DefaultAutoSaveSettings returns the settings used when none are saved.
+func DefaultAutoSaveSettings() AutoSaveSettings {
+	return AutoSaveSettings{
+		HistorySize:     10,
+		IntervalMinutes: 0,
+		PickerOnStartup: true,
+	}
+}
@@ -0,0 +1,45 @@
+package typedef
+
+// MapPackManifest describes a map pack. It is read from manifest.json at the root of the pack
+// directory or archive; file paths are relative to that root.
+type MapPackManifest struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	Description string         `json:"description,omitempty"`
+	Author      string         `json:"author,omitempty"`
+	Files       MapPackFiles   `json:"files"`
+	ImageOffset *MapPackOffset `json:"imageOffset,omitempty"` // Where game coordinate 0,0 is on Image, the built-in offset when missing
+}
+
+// MapPackOffset is the pixel position of game coordinate 0,0 on a map image.
+type MapPackOffset struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// MapPackFiles lists the files of a map pack. Only Territories and Image are required.
+type MapPackFiles struct {
+	Territories string `json:"territories"`         // Territories in the territories.json format
+	Links       string `json:"links,omitempty"`     // Trading links by territory name, replacing the "Trading Routes" of Territories
+	Locations   string `json:"locations,omitempty"` // Locations by territory name, replacing the "Location" of Territories
+	Image       string `json:"image"`               // Background map image
+	Costs       string `json:"costs,omitempty"`     // Costs in the upgrades.json format, the built-in costs when empty
+}
+
+// MapPackRef identifies the map pack a state was saved with. The fingerprint covers the territory
+// names, links and locations, so packs that only differ in image or costs stay compatible.
+type MapPackRef struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// MapPackIssue is a problem found while validating a map pack. Errors stop the pack from being
+// used, warnings are reported but allowed.
+type MapPackIssue struct {
+	Error     bool   `json:"error"`
+	Territory string `json:"territory,omitempty"`
+	Message   string `json:"message"`
+}
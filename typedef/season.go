@@ -0,0 +1,36 @@
+package typedef
+
+// ScheduledCapture hands a territory to a guild once the simulation reaches Tick.
+type ScheduledCapture struct {
+	ID        string `json:"id"`
+	Tick      uint64 `json:"tick"`
+	Territory string `json:"territory"`
+	GuildTag  string `json:"guildTag"`
+	SetHQ     bool   `json:"setHQ,omitempty"` // Make the territory the guild's HQ once captured
+}
+
+// SeasonHoldTier multiplies a territory's points once it has been held for AfterHours.
+type SeasonHoldTier struct {
+	AfterHours float64 `json:"afterHours"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// SeasonRatingFormula defines how season points are earned.
+type SeasonRatingFormula struct {
+	TerritoryPointsPerHour float64            `json:"territoryPointsPerHour"` // Points per held territory per hour
+	HQPointsPerHour        float64            `json:"hqPointsPerHour"`        // Extra points per hour for the territory holding the HQ
+	CapturePoints          float64            `json:"capturePoints"`          // One-off points per territory taken by a scheduled capture
+	Territories            map[string]float64 `json:"territories,omitempty"`  // Per-territory points per hour replacing TerritoryPointsPerHour
+	HoldTiers              []SeasonHoldTier   `json:"holdTiers,omitempty"`    // Highest tier reached applies
+}
+
+// SeasonScore is the season rating a guild has accumulated.
+type SeasonScore struct {
+	GuildTag        string  `json:"guildTag"`
+	Points          float64 `json:"points"`
+	TerritoryPoints float64 `json:"territoryPoints"`
+	HQPoints        float64 `json:"hqPoints"`
+	CapturePoints   float64 `json:"capturePoints"`
+	Captures        int     `json:"captures"`
+	TerritoryHours  float64 `json:"territoryHours"` // Hours held summed over all territories
+}
@@ -149,10 +149,10 @@ type Guild struct {
 	Name string `json:"Name"` // Guild name
 	Tag  string `json:"Tag"`  // Guild tag
 	// Color      uint           `json:"Color"`      // Guild color
-	Allies     []*Guild       `json:"-"`          // List of allied guilds - excluded from JSON to prevent circular references
+	Allies     []*Guild       `json:"-" etf:"-"`  // List of allied guilds - excluded from JSON to prevent circular references
 	AllyTags   []string       `json:"AllyTags"`   // List of allied guild tags for safe JSON serialization
-	TributeIn  BasicResources `json:"TributeIn"`  // Resources received from tributes (per hour)
-	TributeOut BasicResources `json:"TributeOut"` // Resources sent as tributes (per hour)
+	TributeIn  BasicResources `json:"TributeIn"`  // Resources received from tributes (per minute)
+	TributeOut BasicResources `json:"TributeOut"` // Resources sent as tributes (per minute)
 }
 
 type BasicResourcesInterface interface {
@@ -454,11 +454,11 @@ type TerritoryStorage struct {
 
 type InTransitResources struct {
 	BasicResources `json:"Resources"` // Resources in transit
-	Origin         *Territory         `json:"-"`                // Origin territory of the resources in transit
-	Destination    *Territory         `json:"-"`                // Destination territory of the resources in transit
-	Next           *Territory         `json:"-"`                // Next territory in the trading route, can be nil if this is the last territory in the route
+	Origin         *Territory         `json:"-" etf:"-"`        // Origin territory of the resources in transit
+	Destination    *Territory         `json:"-" etf:"-"`        // Destination territory of the resources in transit
+	Next           *Territory         `json:"-" etf:"-"`        // Next territory in the trading route, can be nil if this is the last territory in the route
 	NextTax        float64            `json:"NextTerritoryTax"` // Tax for the next territory in the trading route, from 0.0 to 1.0, -1.0 for invalid tax due to no route or the territory is HQ
-	Route          []*Territory       `json:"-"`                // Full route from origin to destination
+	Route          []*Territory       `json:"-" etf:"-"`        // Full route from origin to destination
 	RouteIndex     int                `json:"RouteIndex"`       // Current position in the route (index of the territory that currently has this resource)
 
 	// JSON safe fields
@@ -477,12 +477,12 @@ type ResourceGeneration struct {
 	EmeraldDeltaTime  uint8          `json:"EmeraldDeltaTime"`  // Modulo for the emerald generation, used to calculate the next generation time
 
 	// Accumulators for resources that build up before being released
-	ResourceAccumulator BasicResourcesSecond `json:"-"` // Accumulates resources per second until released, not serialized
-	EmeraldAccumulator  float64              `json:"-"` // Accumulates emeralds per second until released, not serialized
+	ResourceAccumulator BasicResourcesSecond `json:"-" etf:"-"` // Accumulates resources per second until released, not serialized
+	EmeraldAccumulator  float64              `json:"-" etf:"-"` // Accumulates emeralds per second until released, not serialized
 
 	// Tracking for when to release accumulated resources
-	LastResourceTick uint64 `json:"-"` // Last tick when resources were generated and released, not serialized
-	LastEmeraldTick  uint64 `json:"-"` // Last tick when emeralds were generated and released, not serialized
+	LastResourceTick uint64 `json:"-" etf:"-"` // Last tick when resources were generated and released, not serialized
+	LastEmeraldTick  uint64 `json:"-" etf:"-"` // Last tick when emeralds were generated and released, not serialized
 }
 
 type TowerStats struct {
@@ -511,12 +511,12 @@ type Territory struct {
 
 	Options TerritoryTower `json:"TowerOptions"`
 
-	Costs BasicResources `json:"-"` // Calculate on the fly, not serialized
-	Net   BasicResources `json:"-"` // Calculate on the fly, not serialized
+	Costs BasicResources `json:"-" etf:"-"` // Calculate on the fly, not serialized
+	Net   BasicResources `json:"-" etf:"-"` // Calculate on the fly, not serialized
 
-	TowerStats  TowerStats   `json:"-"`           // Tower stats, calculated on the fly, not serialized
-	Level       DefenceLevel `json:"-"`           // Calculate on the fly, not serialized
-	LevelInt    uint16       `json:"-"`           // Calculate on the fly, not serialized
+	TowerStats  TowerStats   `json:"-" etf:"-"`   // Tower stats, calculated on the fly, not serialized
+	Level       DefenceLevel `json:"-" etf:"-"`   // Calculate on the fly, not serialized
+	LevelInt    uint16       `json:"-" etf:"-"`   // Calculate on the fly, not serialized
 	SetLevelInt uint16       `json:"SetLevelInt"` // Set level of the territory, used for serialization
 	SetLevel    DefenceLevel `json:"SetLevel"`    // Set level of the territory, used for serialization
 
@@ -529,6 +529,7 @@ type Territory struct {
 	TreasuryOverride TreasuryOverride `json:"TreasuryOverride"` // Override for the treasury level, can be TreasuryOverrideNone, TreasuryOverrideVeryLow, TreasuryOverrideLow, TreasuryOverrideMedium, TreasuryOverrideHigh, or TreasuryOverrideVeryHigh
 	GenerationBonus  float64          `json:"GenerationBonus"`  // Generation bonus in %
 	CapturedAt       uint64           `json:"CapturedAt"`       // State tick when the territory was captured, used for calculating treasury
+	HeldBefore       uint64           `json:"HeldBefore"`       // Seconds held before tick 0 for holds older than the simulation, CapturedAt is 0 then
 
 	// List of trading routes from this territory to HQ
 	// Can be nil if territory is owned by No Guild [NONE]
@@ -536,7 +537,7 @@ type Territory struct {
 	// HQ can have many routes to territories, but only one route from teritories to HQ
 	// TradingRoutes is a slice of slices, where each inner slice represents a route from this territory to the HQ
 	ConnectedTerritories []string       `json:"ConnectedTerritories"` // Territories connected to this territory, used for trading routes
-	TradingRoutes        [][]*Territory `json:"-"`
+	TradingRoutes        [][]*Territory `json:"-" etf:"-"`
 	TradingRoutesJSON    [][]string     `json:"TradingRoutes"` // Serialized trading routes, used for GUI and other purposes
 	RouteTax             float64        `json:"RouteTax"`      // Tax for the trading routes, from 0.0 to 1.0, -1.0 for invalid tax due to no HQ, no route, or the territory is HQ
 	RoutingMode          Routing        `json:"RoutingMode"`   // Routing mode for the trading routes, can be RoutingCheapest or RoutingFastest
@@ -548,10 +549,10 @@ type Territory struct {
 	HQ bool `json:"IsHQ"` // If true, the territory is set as a HQ and other HQ will be unset
 
 	// Next territory in the trading route, can be nil if this is the last territory in the route
-	NextTerritory *Territory `json:"-"`
+	NextTerritory *Territory `json:"-" etf:"-"`
 
 	// Destination territory for the trading route, can be nil if this is the last territory in the route, territory owned by No Guild or there's no route to the destination
-	Destination *Territory `json:"-"`
+	Destination *Territory `json:"-" etf:"-"`
 
 	// Storage of the territory, calculated on the fly, not serialized
 	Storage TerritoryStorage `json:"Storage"`
@@ -560,14 +561,14 @@ type Territory struct {
 	TransitResource []InTransitResources `json:"TransitResources"`
 
 	// Channel to set upgrades, bonuses and more, sent from GUI or other sources
-	SetCh   chan<- TerritoryOptions `json:"-"`
-	CloseCh func()                  `json:"-"` // Function to close the channel, used to clean up resources when the territory is no longer needed
-	Reset   func()                  `json:"-"` // Function to reset the territory, used to reset the territory to its initial state
+	SetCh   chan<- TerritoryOptions `json:"-" etf:"-"`
+	CloseCh func()                  `json:"-" etf:"-"` // Function to close the channel, used to clean up resources when the territory is no longer needed
+	Reset   func()                  `json:"-" etf:"-"` // Function to reset the territory, used to reset the territory to its initial state
 
 	Warning Warning `json:"ActiveWarnings"` // Warnings for the territory, can be WarningOverflowEmerald or WarningOverflowResources
 
 	// Warning tracking to prevent flickering - tracks when each warning was last triggered
-	WarningExpiration map[Warning]uint64 `json:"-"` // Maps warning type to tick when it should expire (not serialized)
+	WarningExpiration map[Warning]uint64 `json:"-" etf:"-"` // Maps warning type to tick when it should expire (not serialized)
 
 	// Really important mutex to protect the territory from concurrent access
 	Mu sync.RWMutex
@@ -702,17 +703,54 @@ type GuildJSON struct {
 }
 
 type ActiveTribute struct {
-	ID              string         `json:"ID"`              // Unique identifier for the tribute
-	From            *Guild         `json:"-"`               // Can be nil if the tribute is spawned in (not from any guild but gets added to HQ storage periodically)
-	FromGuildName   string         `json:"FromGuild"`       // Guild name of the tribute sender
-	To              *Guild         `json:"-"`               // Can be nil if the tribute is spawned in (not to any guild but gets removed HQ storage periodically)
-	ToGuildName     string         `json:"ToGuild"`         // Guild name of the tribute receiver
-	AmountPerHour   BasicResources `json:"AmountPerHour"`   // Amount of resources per hour (user input value)
-	AmountPerMinute BasicResources `json:"AmountPerMinute"` // Amount of resources per minute (calculated from AmountPerHour)
-	IntervalMinutes uint32         `json:"IntervalMinutes"` // How often the tribute transfers (in minutes, aligned with 60-tick cycles)
-	LastTransfer    uint64         `json:"LastTransfer"`    // Tick when the last transfer happened (0 if never transferred)
-	IsActive        bool           `json:"IsActive"`        // Whether this tribute is currently active
-	CreatedAt       uint64         `json:"CreatedAt"`       // Tick when this tribute was created
+	ID              string          `json:"ID"`              // Unique identifier for the tribute
+	From            *Guild          `json:"-" etf:"-"`       // Can be nil if the tribute is spawned in (not from any guild but gets added to HQ storage periodically)
+	FromGuildName   string          `json:"FromGuild"`       // Guild name of the tribute sender
+	To              *Guild          `json:"-" etf:"-"`       // Can be nil if the tribute is spawned in (not to any guild but gets removed HQ storage periodically)
+	ToGuildName     string          `json:"ToGuild"`         // Guild name of the tribute receiver
+	AmountPerHour   BasicResources  `json:"AmountPerHour"`   // Amount of resources per hour (user input value)
+	AmountPerMinute BasicResources  `json:"AmountPerMinute"` // Amount of resources per minute (calculated from AmountPerHour)
+	IntervalMinutes uint32          `json:"IntervalMinutes"` // How often the tribute transfers (in minutes, aligned with 60-tick cycles)
+	LastTransfer    uint64          `json:"LastTransfer"`    // Tick when the last transfer happened (0 if never transferred)
+	IsActive        bool            `json:"IsActive"`        // Whether this tribute is currently active
+	CreatedAt       uint64          `json:"CreatedAt"`       // Tick when this tribute was created
+	LastError       string          `json:"LastError"`       // Why the last transfer could not be delivered, empty if it went through
+	LastErrorTick   uint64          `json:"LastErrorTick"`   // Tick when LastError was recorded
+	Schedule        TributeSchedule `json:"Schedule"`        // When the tribute transfers; the zero value is a plain recurring tribute
+	TransferCount   uint32          `json:"TransferCount"`   // Number of transfers that went through
+	TotalSent       BasicResources  `json:"TotalSent"`       // Total resources sent by all transfers so far
+}
+
+// TributeKind selects how a tribute is scheduled.
+type TributeKind int8
+
+const (
+	TributeRecurring   TributeKind = iota // Transfers every IntervalMinutes until disabled
+	TributeOneShot                        // Transfers AmountPerHour once at Schedule.FireAt, then disables itself
+	TributeLimited                        // Recurring, but disables itself after MaxTransfers transfers or MaxTotal resources
+	TributeWindowed                       // Recurring, but only between Schedule.WindowStart and Schedule.WindowEnd
+	TributeConditional                    // Recurring, but only while Schedule.Condition holds
+)
+
+// TributeCondition is the storage check a conditional tribute performs before each transfer.
+type TributeCondition int8
+
+const (
+	TributeSenderAbove   TributeCondition = iota // Sender HQ storage of Resource is above Threshold
+	TributeReceiverBelow                         // Receiver HQ storage of Resource is below Threshold
+)
+
+// TributeSchedule holds the parameters for non-recurring tribute kinds. Fields not used by Kind are ignored.
+type TributeSchedule struct {
+	Kind         TributeKind      `json:"Kind"`
+	FireAt       uint64           `json:"FireAt"`       // One-shot: tick to transfer at
+	MaxTransfers uint32           `json:"MaxTransfers"` // Limited: stop after this many transfers, 0 for no count limit
+	MaxTotal     float64          `json:"MaxTotal"`     // Limited: stop after this many resources in total (all types summed), 0 for no amount limit
+	WindowStart  uint64           `json:"WindowStart"`  // Windowed: first tick the tribute may transfer
+	WindowEnd    uint64           `json:"WindowEnd"`    // Windowed: tick the tribute stops and disables itself, 0 for open-ended
+	Condition    TributeCondition `json:"Condition"`    // Conditional: which HQ storage check to perform
+	Resource     string           `json:"Resource"`     // Conditional: "emeralds", "ores", "wood", "fish" or "crops"
+	Threshold    float64          `json:"Threshold"`    // Conditional: storage amount compared against
 }
 
 // EventAction represents the type of action that can be performed in an event.
@@ -791,6 +829,24 @@ func DefaultResourceColors() ResourceColorOptions {
 	}
 }
 
+// WarParty describes the attacking party used by the tower combat calculator.
+type WarParty struct {
+	Players      int     `json:"Players"`      // Number of players in the war
+	PlayerDPS    float64 `json:"PlayerDPS"`    // Damage per second each damage dealer puts on the tower
+	PlayerHealth float64 `json:"PlayerHealth"` // Effective health of each player
+	Tanked       bool    `json:"Tanked"`       // If true, one player tanks the tower's single target attacks and deals no damage
+}
+
+// DefaultWarParty returns a typical five player war party.
+func DefaultWarParty() WarParty {
+	return WarParty{
+		Players:      5,
+		PlayerDPS:    20000,
+		PlayerHealth: 20000,
+		Tanked:       true,
+	}
+}
+
 // Tracks user options and settings
 type RuntimeOptions struct {
 	TreasuryEnabled bool // If true, the treasury is enabled and will be used for resource generation and storage
@@ -839,12 +895,19 @@ type RuntimeOptions struct {
 
 	ImposeCooldown bool // whether 10 mins is imposed
 
+	// DirectTributes delivers guild-to-guild tributes straight into the receiving HQ.
+	// When false, tributes travel as transits along the tribute route and pay territory taxes on the way.
+	DirectTributes bool `json:"DirectTributes"`
+
 	// User-configurable keyboard shortcuts for common UI actions.
 	Keybinds Keybinds `json:"keybinds"`
 
 	// Enable fade/slide reveal animations for side menus.
 	SidemenuAnimations bool `json:"SidemenuAnimations"`
 
+	// War party used by the tower combat calculator.
+	WarParty WarParty `json:"WarParty"`
+
 	// PluginKeybinds stores per-plugin keybind overrides keyed by "pluginID::bindID".
 	PluginKeybinds map[string]string `json:"pluginKeybinds,omitempty"`
 }